@@ -4,16 +4,131 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+)
+
+// Storage backends for Config.DBDriver
+const (
+	DBDriverSQLite   = "sqlite"   // Persistent SQLite database at DBPath (default)
+	DBDriverMemory   = "memory"   // Ephemeral in-memory storage, lost on restart
+	DBDriverPostgres = "postgres" // PostgreSQL at DBDSN, for multi-site deployments
 )
 
 type Config struct {
 	ServerPort string
-	DBPath     string
+	DBDriver   string // Storage backend, see DBDriver* constants
+	DBPath     string // SQLite database file (sqlite driver)
+	DBDSN      string // Connection string (postgres driver)
+
+	// TLS termination inside the server (HTTPS and WSS); plain HTTP when either is empty
+	TLSCertPath string // PEM certificate (chain) file
+	TLSKeyPath  string // PEM private key file
+
+	SQLiteBusyTimeoutMs int // How long SQLite waits for a lock before "database is locked"
 
 	// Auto-Sync default configuration
 	AutoSyncIntervalSec int // Default interval between syncs in seconds
 	AutoSyncSampleCount int // Default number of samples per sync
 	AutoSyncIntervalMs  int // Default interval between samples in milliseconds
+	AutoSyncTimeoutSec  int // Default timeout for each sample in seconds
+
+	// Auto-Sync defaults per device type pair (AUTO_SYNC_PAIR_DEFAULTS), keyed by AutoSyncPairKey.
+	// Used by AutoSyncDefaultsFor; unset fields fall back to the defaults above.
+	AutoSyncPairDefaults    map[string]AutoSyncDefaults
+	autoSyncPairDefaultsErr error // Reported by Validate
+
+	// Auto-Sync results below this confidence are not counted as successful (0 disables the gate)
+	AutoSyncMinConfidence float64
+
+	// Auto-Sync backoff on consecutive failures
+	AutoSyncBackoffMultiplier float64 // Interval multiplier applied after each failed sync (<= 1 disables backoff)
+	AutoSyncMaxBackoffSec     int     // Upper bound for the backed-off interval in seconds
+
+	// Auto-Sync scheduling jitter (spreads out jobs started at the same time)
+	AutoSyncJitterPercent float64 // Random adjustment of every interval by up to ±this percentage (0 disables)
+	AutoSyncInitialJitter bool    // Delay the first sync by a random fraction of the interval instead of running it immediately
+
+	// Default seconds before an Auto-Sync job's first sync: 0 runs it immediately, -1 skips it and
+	// leaves the first sync to the interval timer
+	AutoSyncInitialSyncDelaySec int
+
+	// How often running Auto-Sync jobs are checked against the repository and stopped if their pairing was deleted (0 disables)
+	AutoSyncReaperIntervalSec int
+
+	// Auto-Sync flags a sync whose offset differs from the pairing's active offset override by more than this many milliseconds
+	AutoSyncOverrideThresholdMs int
+
+	// Automatic aggregation of single-sync records (enabled per pairing)
+	AutoAggregateCheckIntervalSec int // How often the aggregator looks for new single-sync records
+	AutoAggregateWindowSec        int // Default look-back window in seconds
+	AutoAggregateMinCount         int // Default minimum number of records per aggregation
+
+	// Retention of sync records
+	RetentionDays             int // Delete records older than this many days (0 = keep forever)
+	RetentionCheckIntervalSec int // How often the cleanup job runs
+
+	// Partial completion of time sync requests
+	SyncPartialTimeoutMs int // Grace period after the first response before completing as PARTIAL (0 = wait full timeout)
+
+	// Time sync requests awaiting responses beyond this are rejected as server busy (0 = unlimited)
+	MaxPendingRequests int
+
+	// Save PARTIAL and FAILED sync records; when false only SUCCESS records are stored
+	PersistFailedSamples bool
+
+	// Weight of a new aggregation's best offset in the pairing's smoothed offset (EWMA, 0 = 0.3, 1 = no smoothing)
+	OffsetSmoothingAlpha float64
+
+	// Pairings of a disconnected device are kept (suspended) this long before they are purged (0 = purge immediately)
+	ReconnectGracePeriodSec int
+
+	// A device without a PONG for this many seconds is reported unhealthy (0 = 2.5× the app-level PING period)
+	HealthThresholdSec int
+
+	// Device type (PSG, WATCH or MOBILE) offsets are measured against: positive offsets mean the
+	// other device is ahead of it. Empty keeps the pairing's device order (device1 - device2).
+	ReferenceDeviceType string
+
+	// Largest WebSocket message in bytes a device may send; larger messages drop the connection
+	MaxMessageSize int
+
+	// I/O buffer sizes in bytes of each WebSocket connection (0 = 1024). Every connection holds
+	// both for its lifetime; they do not limit the message size.
+	WSReadBufferSize  int
+	WSWriteBufferSize int
+
+	// Handling of slow clients whose WebSocket send buffer is full
+	SendBufferSize     int    // Messages queued per client before the policy applies (0 = 256)
+	SendBufferPolicy   string // drop, block or disconnect
+	SendBlockTimeoutMs int    // How long the block policy waits for buffer space before dropping
+
+	// How long a WebSocket write may block before the client is treated as stalled and disconnected (0 = 10s)
+	WriteWaitMs int
+
+	// Rate limiting of sync-triggering endpoints (token bucket, 0 per minute disables)
+	SyncRateLimitPerMin   int // Requests per minute per pairing
+	SyncRateLimitBurst    int // Requests a pairing may make back to back
+	SyncIPRateLimitPerMin int // Requests per minute per client IP
+	SyncIPRateLimitBurst  int // Requests a client IP may make back to back
+
+	// WebSocket handshake authentication
+	WSAuthEnabled bool   // Require a token on /ws (disable for local development)
+	WSAuthSecret  string // Shared secret devices present as their token
+
+	// Bearer token required by the /api/admin endpoints (empty disables them)
+	AdminToken string
+
+	// Browser origins allowed to call the REST API and open WebSockets (empty allows all, for local development)
+	AllowedOrigins []string
+
+	// Structured logging
+	LogLevel  string // Minimum level: debug, info, warn or error
+	LogFormat string // Output format: json or text
+
+	// Webhook alert when an aggregated offset drifts beyond the threshold
+	AlertWebhookURL         string // POST target for alerts (empty disables alerting)
+	AlertOffsetThresholdMs  int    // Alert when |best offset| exceeds this many milliseconds
+	AlertWebhookMaxAttempts int    // Delivery attempts per alert, with exponential backoff between them
 }
 
 func Load() *Config {
@@ -22,25 +137,322 @@ func Load() *Config {
 		port = "8080"
 	}
 
+	// Load TLS configuration (plain HTTP unless both are set)
+	tlsCertPath := os.Getenv("TLS_CERT_PATH")
+	tlsKeyPath := os.Getenv("TLS_KEY_PATH")
+
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver == "" {
+		dbDriver = DBDriverSQLite
+	}
+
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
 		dbPath = "./time-sync.db"
 	}
 
+	dbDSN := os.Getenv("DB_DSN")
+	sqliteBusyTimeoutMs := getEnvAsInt("SQLITE_BUSY_TIMEOUT_MS", 5000)
+
 	// Load auto-sync configuration with defaults
 	autoSyncIntervalSec := getEnvAsInt("AUTO_SYNC_INTERVAL_SEC", 600)
 	autoSyncSampleCount := getEnvAsInt("AUTO_SYNC_SAMPLE_COUNT", 15)
 	autoSyncIntervalMs := getEnvAsInt("AUTO_SYNC_INTERVAL_MS", 200)
+	autoSyncTimeoutSec := getEnvAsInt("AUTO_SYNC_TIMEOUT_SEC", 5)
+	autoSyncMinConfidence := getEnvAsFloat("AUTO_SYNC_MIN_CONFIDENCE", 0)
+	autoSyncPairDefaults, autoSyncPairDefaultsErr := parseAutoSyncPairDefaults(os.Getenv("AUTO_SYNC_PAIR_DEFAULTS"))
+
+	// Load auto-sync backoff configuration
+	autoSyncBackoffMultiplier := getEnvAsFloat("AUTO_SYNC_BACKOFF_MULTIPLIER", 2.0)
+	autoSyncMaxBackoffSec := getEnvAsInt("AUTO_SYNC_MAX_BACKOFF_SEC", 3600)
+
+	// Load auto-sync jitter configuration (off by default)
+	autoSyncJitterPercent := getEnvAsFloat("AUTO_SYNC_JITTER_PERCENT", 0)
+	autoSyncInitialJitter := getEnvAsBool("AUTO_SYNC_INITIAL_JITTER", false)
+
+	// Load the first auto-sync delay (immediate by default)
+	autoSyncInitialSyncDelaySec := getEnvAsInt("AUTO_SYNC_INITIAL_SYNC_DELAY_SEC", 0)
+
+	// Load stale auto-sync job reaper interval
+	autoSyncReaperIntervalSec := getEnvAsInt("AUTO_SYNC_REAPER_INTERVAL_SEC", 300)
+
+	// Load offset override disagreement threshold
+	autoSyncOverrideThresholdMs := getEnvAsInt("AUTO_SYNC_OVERRIDE_THRESHOLD_MS", 20)
+
+	// Load single-sync auto-aggregation configuration with defaults
+	autoAggregateCheckIntervalSec := getEnvAsInt("AUTO_AGGREGATE_CHECK_INTERVAL_SEC", 60)
+	autoAggregateWindowSec := getEnvAsInt("AUTO_AGGREGATE_WINDOW_SEC", 600)
+	autoAggregateMinCount := getEnvAsInt("AUTO_AGGREGATE_MIN_COUNT", 5)
+
+	// Load retention configuration
+	retentionDays := getEnvAsInt("RETENTION_DAYS", 0)
+	retentionCheckIntervalSec := getEnvAsInt("RETENTION_CHECK_INTERVAL_SEC", 3600)
+
+	// Load partial completion grace period
+	syncPartialTimeoutMs := getEnvAsInt("SYNC_PARTIAL_TIMEOUT_MS", 500)
+
+	// Load pending request cap
+	maxPendingRequests := getEnvAsInt("MAX_PENDING_REQUESTS", 1000)
+
+	// Load failed sample persistence
+	persistFailedSamples := getEnvAsBool("PERSIST_FAILED_SAMPLES", true)
+
+	// Load offset smoothing
+	offsetSmoothingAlpha := getEnvAsFloat("OFFSET_SMOOTHING_ALPHA", 0.3)
+
+	// Load reconnect grace period
+	reconnectGracePeriodSec := getEnvAsInt("RECONNECT_GRACE_PERIOD_SEC", 30)
+
+	// Load device health threshold
+	healthThresholdSec := getEnvAsInt("HEALTH_THRESHOLD_SEC", 0)
+
+	// Load offset orientation
+	referenceDeviceType := strings.ToUpper(os.Getenv("REFERENCE_DEVICE_TYPE"))
+
+	// Load WebSocket message size limit
+	maxMessageSize := getEnvAsInt("WS_MAX_MESSAGE_SIZE", 4096)
+
+	// Load WebSocket connection buffer sizes
+	wsReadBufferSize := getEnvAsInt("WS_READ_BUFFER_SIZE", 1024)
+	wsWriteBufferSize := getEnvAsInt("WS_WRITE_BUFFER_SIZE", 1024)
+
+	// Load send buffer backpressure configuration
+	sendBufferSize := getEnvAsInt("SEND_BUFFER_SIZE", 256)
+	sendBufferPolicy := os.Getenv("SEND_BUFFER_POLICY")
+	if sendBufferPolicy == "" {
+		sendBufferPolicy = "drop"
+	}
+	sendBlockTimeoutMs := getEnvAsInt("SEND_BLOCK_TIMEOUT_MS", 100)
+
+	// Load WebSocket write deadline
+	writeWaitMs := getEnvAsInt("WS_WRITE_WAIT_MS", 10000)
+
+	// Load sync rate limiting configuration
+	syncRateLimitPerMin := getEnvAsInt("SYNC_RATE_LIMIT_PER_MIN", 60)
+	syncRateLimitBurst := getEnvAsInt("SYNC_RATE_LIMIT_BURST", 10)
+	syncIPRateLimitPerMin := getEnvAsInt("SYNC_IP_RATE_LIMIT_PER_MIN", 0)
+	syncIPRateLimitBurst := getEnvAsInt("SYNC_IP_RATE_LIMIT_BURST", 20)
+
+	// Load WebSocket authentication configuration
+	wsAuthEnabled := getEnvAsBool("WS_AUTH_ENABLED", true)
+	wsAuthSecret := os.Getenv("WS_AUTH_SECRET")
+
+	// Load admin API configuration
+	adminToken := os.Getenv("ADMIN_TOKEN")
+
+	// Load CORS configuration
+	allowedOrigins := getEnvAsList("ALLOWED_ORIGINS")
+
+	// Load logging configuration
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	logFormat := os.Getenv("LOG_FORMAT")
+	if logFormat == "" {
+		logFormat = "json"
+	}
+
+	// Load offset alert configuration
+	alertWebhookURL := os.Getenv("ALERT_WEBHOOK_URL")
+	alertOffsetThresholdMs := getEnvAsInt("ALERT_OFFSET_THRESHOLD_MS", 500)
+	alertWebhookMaxAttempts := getEnvAsInt("ALERT_WEBHOOK_MAX_ATTEMPTS", 3)
 
 	return &Config{
 		ServerPort:          port,
+		DBDriver:            dbDriver,
 		DBPath:              dbPath,
+		DBDSN:               dbDSN,
+		SQLiteBusyTimeoutMs: sqliteBusyTimeoutMs,
+
+		TLSCertPath: tlsCertPath,
+		TLSKeyPath:  tlsKeyPath,
+
 		AutoSyncIntervalSec: autoSyncIntervalSec,
 		AutoSyncSampleCount: autoSyncSampleCount,
 		AutoSyncIntervalMs:  autoSyncIntervalMs,
+		AutoSyncTimeoutSec:  autoSyncTimeoutSec,
+
+		AutoSyncMinConfidence: autoSyncMinConfidence,
+
+		AutoSyncPairDefaults:    autoSyncPairDefaults,
+		autoSyncPairDefaultsErr: autoSyncPairDefaultsErr,
+
+		AutoSyncBackoffMultiplier: autoSyncBackoffMultiplier,
+		AutoSyncMaxBackoffSec:     autoSyncMaxBackoffSec,
+
+		AutoSyncJitterPercent: autoSyncJitterPercent,
+		AutoSyncInitialJitter: autoSyncInitialJitter,
+
+		AutoSyncInitialSyncDelaySec: autoSyncInitialSyncDelaySec,
+
+		AutoSyncReaperIntervalSec: autoSyncReaperIntervalSec,
+
+		AutoSyncOverrideThresholdMs: autoSyncOverrideThresholdMs,
+
+		AutoAggregateCheckIntervalSec: autoAggregateCheckIntervalSec,
+		AutoAggregateWindowSec:        autoAggregateWindowSec,
+		AutoAggregateMinCount:         autoAggregateMinCount,
+
+		RetentionDays:             retentionDays,
+		RetentionCheckIntervalSec: retentionCheckIntervalSec,
+
+		SyncPartialTimeoutMs: syncPartialTimeoutMs,
+
+		MaxPendingRequests: maxPendingRequests,
+
+		PersistFailedSamples: persistFailedSamples,
+
+		OffsetSmoothingAlpha: offsetSmoothingAlpha,
+
+		ReconnectGracePeriodSec: reconnectGracePeriodSec,
+
+		HealthThresholdSec: healthThresholdSec,
+
+		ReferenceDeviceType: referenceDeviceType,
+
+		MaxMessageSize: maxMessageSize,
+
+		WSReadBufferSize:  wsReadBufferSize,
+		WSWriteBufferSize: wsWriteBufferSize,
+
+		SendBufferSize:     sendBufferSize,
+		SendBufferPolicy:   sendBufferPolicy,
+		SendBlockTimeoutMs: sendBlockTimeoutMs,
+
+		WriteWaitMs: writeWaitMs,
+
+		SyncRateLimitPerMin:   syncRateLimitPerMin,
+		SyncRateLimitBurst:    syncRateLimitBurst,
+		SyncIPRateLimitPerMin: syncIPRateLimitPerMin,
+		SyncIPRateLimitBurst:  syncIPRateLimitBurst,
+
+		WSAuthEnabled: wsAuthEnabled,
+		WSAuthSecret:  wsAuthSecret,
+
+		AdminToken: adminToken,
+
+		AllowedOrigins: allowedOrigins,
+
+		LogLevel:  logLevel,
+		LogFormat: logFormat,
+
+		AlertWebhookURL:         alertWebhookURL,
+		AlertOffsetThresholdMs:  alertOffsetThresholdMs,
+		AlertWebhookMaxAttempts: alertWebhookMaxAttempts,
 	}
 }
 
+// AutoSyncDefaults are the Auto-Sync settings a new pairing starts with. In AUTO_SYNC_PAIR_DEFAULTS
+// entries, zero fields are not set and fall back to the global defaults.
+type AutoSyncDefaults struct {
+	IntervalSec int
+	SampleCount int
+	IntervalMs  int
+	TimeoutSec  int
+}
+
+// AutoSyncPairKey is the AutoSyncPairDefaults key of two device types, independent of their order (e.g. "PSG-WATCH")
+func AutoSyncPairKey(type1, type2 string) string {
+	type1, type2 = strings.ToUpper(type1), strings.ToUpper(type2)
+	if type1 > type2 {
+		type1, type2 = type2, type1
+	}
+	return type1 + "-" + type2
+}
+
+// AutoSyncDefaultsFor returns the Auto-Sync defaults for a pairing of the two device types:
+// the global defaults overridden by the fields set for the type pair in AUTO_SYNC_PAIR_DEFAULTS
+func (c *Config) AutoSyncDefaultsFor(type1, type2 string) AutoSyncDefaults {
+	defaults := AutoSyncDefaults{
+		IntervalSec: c.AutoSyncIntervalSec,
+		SampleCount: c.AutoSyncSampleCount,
+		IntervalMs:  c.AutoSyncIntervalMs,
+		TimeoutSec:  c.AutoSyncTimeoutSec,
+	}
+
+	override, ok := c.AutoSyncPairDefaults[AutoSyncPairKey(type1, type2)]
+	if !ok {
+		return defaults
+	}
+	if override.IntervalSec > 0 {
+		defaults.IntervalSec = override.IntervalSec
+	}
+	if override.SampleCount > 0 {
+		defaults.SampleCount = override.SampleCount
+	}
+	if override.IntervalMs > 0 {
+		defaults.IntervalMs = override.IntervalMs
+	}
+	if override.TimeoutSec > 0 {
+		defaults.TimeoutSec = override.TimeoutSec
+	}
+	return defaults
+}
+
+// parseAutoSyncPairDefaults parses semicolon-separated "TYPE1-TYPE2:key=value,..." entries,
+// e.g. "PSG-WATCH:interval_sec=300,sample_count=20;MOBILE-WATCH:interval_ms=500".
+// Keys are interval_sec, sample_count, interval_ms and timeout_sec; values must be positive.
+func parseAutoSyncPairDefaults(value string) (map[string]AutoSyncDefaults, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	result := make(map[string]AutoSyncDefaults)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, settings, ok := strings.Cut(entry, ":")
+		type1, type2, pairOK := strings.Cut(strings.TrimSpace(pair), "-")
+		if !ok || !pairOK || type1 == "" || type2 == "" {
+			return nil, fmt.Errorf("entry %q must look like TYPE1-TYPE2:key=value,...", entry)
+		}
+		for _, deviceType := range []string{type1, type2} {
+			if !isDeviceType(strings.ToUpper(deviceType)) {
+				return nil, fmt.Errorf("unknown device type %q in %s (use PSG, WATCH or MOBILE)", deviceType, pair)
+			}
+		}
+
+		var defaults AutoSyncDefaults
+		for _, setting := range strings.Split(settings, ",") {
+			key, raw, ok := strings.Cut(strings.TrimSpace(setting), "=")
+			if !ok {
+				return nil, fmt.Errorf("setting %q of %s must look like key=value", setting, pair)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s of %s must be a positive integer, got %q", key, pair, raw)
+			}
+			switch strings.TrimSpace(key) {
+			case "interval_sec":
+				defaults.IntervalSec = n
+			case "sample_count":
+				defaults.SampleCount = n
+			case "interval_ms":
+				defaults.IntervalMs = n
+			case "timeout_sec":
+				defaults.TimeoutSec = n
+			default:
+				return nil, fmt.Errorf("unknown setting %q of %s (use interval_sec, sample_count, interval_ms or timeout_sec)", key, pair)
+			}
+		}
+		result[AutoSyncPairKey(type1, type2)] = defaults
+	}
+	return result, nil
+}
+
+// isDeviceType reports whether t is a device type devices may connect as (see models.DeviceType)
+func isDeviceType(t string) bool {
+	switch t {
+	case "PSG", "WATCH", "MOBILE":
+		return true
+	}
+	return false
+}
+
 // getEnvAsInt reads an environment variable as int, returns defaultVal if not set or invalid
 func getEnvAsInt(key string, defaultVal int) int {
 	valStr := os.Getenv(key)
@@ -54,12 +466,133 @@ func getEnvAsInt(key string, defaultVal int) int {
 	return val
 }
 
+// getEnvAsFloat reads an environment variable as float64, returns defaultVal if not set or invalid
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	valStr := os.Getenv(key)
+	if valStr == "" {
+		return defaultVal
+	}
+	val, err := strconv.ParseFloat(valStr, 64)
+	if err != nil {
+		return defaultVal
+	}
+	return val
+}
+
+// getEnvAsList reads a comma-separated environment variable, dropping empty entries. Returns nil if not set.
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvAsBool reads an environment variable as bool, returns defaultVal if not set or invalid
+func getEnvAsBool(key string, defaultVal bool) bool {
+	valStr := os.Getenv(key)
+	if valStr == "" {
+		return defaultVal
+	}
+	val, err := strconv.ParseBool(valStr)
+	if err != nil {
+		return defaultVal
+	}
+	return val
+}
+
+// TLSEnabled reports whether the server should serve HTTPS/WSS with TLSCertPath and TLSKeyPath
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertPath != "" && c.TLSKeyPath != ""
+}
+
 func (c *Config) Validate() error {
 	if c.ServerPort == "" {
 		return fmt.Errorf("server port is required")
 	}
-	if c.DBPath == "" {
-		return fmt.Errorf("database path is required")
+	if (c.TLSCertPath == "") != (c.TLSKeyPath == "") {
+		return fmt.Errorf("TLS_CERT_PATH and TLS_KEY_PATH must be set together")
+	}
+	if c.TLSEnabled() {
+		for _, file := range []struct{ name, path string }{{"TLS_CERT_PATH", c.TLSCertPath}, {"TLS_KEY_PATH", c.TLSKeyPath}} {
+			info, err := os.Stat(file.path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file.name, err)
+			}
+			if info.IsDir() {
+				return fmt.Errorf("%s %q is a directory", file.name, file.path)
+			}
+		}
+	}
+	switch c.DBDriver {
+	case DBDriverSQLite:
+		if c.DBPath == "" {
+			return fmt.Errorf("database path is required")
+		}
+	case DBDriverPostgres:
+		if c.DBDSN == "" {
+			return fmt.Errorf("DB_DSN is required when DB_DRIVER is %q", DBDriverPostgres)
+		}
+	case DBDriverMemory:
+	default:
+		return fmt.Errorf("unsupported DB_DRIVER %q (use %q, %q or %q)", c.DBDriver, DBDriverSQLite, DBDriverPostgres, DBDriverMemory)
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("unsupported LOG_LEVEL %q (use debug, info, warn or error)", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		return fmt.Errorf("unsupported LOG_FORMAT %q (use json or text)", c.LogFormat)
+	}
+	if c.autoSyncPairDefaultsErr != nil {
+		return fmt.Errorf("invalid AUTO_SYNC_PAIR_DEFAULTS: %w", c.autoSyncPairDefaultsErr)
+	}
+	if c.OffsetSmoothingAlpha < 0 || c.OffsetSmoothingAlpha > 1 {
+		return fmt.Errorf("OFFSET_SMOOTHING_ALPHA must be in [0, 1], got %v", c.OffsetSmoothingAlpha)
+	}
+	if c.AutoSyncMinConfidence < 0 || c.AutoSyncMinConfidence > 1 {
+		return fmt.Errorf("AUTO_SYNC_MIN_CONFIDENCE must be in [0, 1], got %v", c.AutoSyncMinConfidence)
+	}
+	if c.AutoSyncJitterPercent < 0 || c.AutoSyncJitterPercent >= 100 {
+		return fmt.Errorf("AUTO_SYNC_JITTER_PERCENT must be in [0, 100), got %v", c.AutoSyncJitterPercent)
+	}
+	if c.AutoSyncInitialSyncDelaySec < -1 {
+		return fmt.Errorf("AUTO_SYNC_INITIAL_SYNC_DELAY_SEC must be -1 (skip) or at least 0, got %d", c.AutoSyncInitialSyncDelaySec)
+	}
+	if c.AutoSyncOverrideThresholdMs < 0 {
+		return fmt.Errorf("AUTO_SYNC_OVERRIDE_THRESHOLD_MS must not be negative, got %d", c.AutoSyncOverrideThresholdMs)
+	}
+	if c.ReferenceDeviceType != "" && !isDeviceType(c.ReferenceDeviceType) {
+		return fmt.Errorf("unsupported REFERENCE_DEVICE_TYPE %q (use PSG, WATCH or MOBILE)", c.ReferenceDeviceType)
+	}
+	if c.HealthThresholdSec < 0 {
+		return fmt.Errorf("HEALTH_THRESHOLD_SEC must not be negative, got %d", c.HealthThresholdSec)
+	}
+	if c.MaxMessageSize <= 0 {
+		return fmt.Errorf("WS_MAX_MESSAGE_SIZE must be positive, got %d", c.MaxMessageSize)
+	}
+	if c.SendBufferSize < 0 {
+		return fmt.Errorf("SEND_BUFFER_SIZE must not be negative, got %d", c.SendBufferSize)
+	}
+	if c.WSReadBufferSize < 0 {
+		return fmt.Errorf("WS_READ_BUFFER_SIZE must not be negative, got %d", c.WSReadBufferSize)
+	}
+	if c.WSWriteBufferSize < 0 {
+		return fmt.Errorf("WS_WRITE_BUFFER_SIZE must not be negative, got %d", c.WSWriteBufferSize)
+	}
+	switch c.SendBufferPolicy {
+	case "drop", "block", "disconnect":
+	default:
+		return fmt.Errorf("unsupported SEND_BUFFER_POLICY %q (use drop, block or disconnect)", c.SendBufferPolicy)
+	}
+	if c.WriteWaitMs < 0 {
+		return fmt.Errorf("WS_WRITE_WAIT_MS must not be negative, got %d", c.WriteWaitMs)
+	}
+	if c.WSAuthEnabled && c.WSAuthSecret == "" {
+		return fmt.Errorf("WS_AUTH_SECRET is required when WebSocket authentication is enabled (set WS_AUTH_ENABLED=false for local development)")
 	}
 	return nil
 }
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoSyncDefaultsFor(t *testing.T) {
+	pairDefaults, err := parseAutoSyncPairDefaults("PSG-WATCH:interval_sec=300,sample_count=20; mobile-watch:interval_ms=500")
+	if err != nil {
+		t.Fatalf("parseAutoSyncPairDefaults() error = %v", err)
+	}
+	cfg := &Config{
+		AutoSyncIntervalSec:  600,
+		AutoSyncSampleCount:  15,
+		AutoSyncIntervalMs:   200,
+		AutoSyncTimeoutSec:   5,
+		AutoSyncPairDefaults: pairDefaults,
+	}
+
+	tests := []struct {
+		name         string
+		type1, type2 string
+		expected     AutoSyncDefaults
+	}{
+		{"type pair entry", "PSG", "WATCH", AutoSyncDefaults{IntervalSec: 300, SampleCount: 20, IntervalMs: 200, TimeoutSec: 5}},
+		{"order independent", "WATCH", "PSG", AutoSyncDefaults{IntervalSec: 300, SampleCount: 20, IntervalMs: 200, TimeoutSec: 5}},
+		{"partial entry", "MOBILE", "WATCH", AutoSyncDefaults{IntervalSec: 600, SampleCount: 15, IntervalMs: 500, TimeoutSec: 5}},
+		{"no entry", "PSG", "MOBILE", AutoSyncDefaults{IntervalSec: 600, SampleCount: 15, IntervalMs: 200, TimeoutSec: 5}},
+		{"unknown device type", "", "WATCH", AutoSyncDefaults{IntervalSec: 600, SampleCount: 15, IntervalMs: 200, TimeoutSec: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.AutoSyncDefaultsFor(tt.type1, tt.type2); got != tt.expected {
+				t.Errorf("AutoSyncDefaultsFor() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseAutoSyncPairDefaultsRejectsInvalidEntries(t *testing.T) {
+	for _, value := range []string{
+		"PSG-WATCH",                   // no settings
+		"PSG:interval_sec=300",        // single type
+		"PSG-WATCH:interval_sec",      // no value
+		"PSG-WATCH:interval_sec=-1",   // not positive
+		"PSG-WATCH:interval_min=300",  // unknown key
+		"PSG-TABLET:interval_sec=300", // unknown device type
+	} {
+		if _, err := parseAutoSyncPairDefaults(value); err == nil {
+			t.Errorf("parseAutoSyncPairDefaults(%q) should fail", value)
+		}
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "server.crt")
+	keyPath := filepath.Join(dir, "server.key")
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.WriteFile(path, []byte("pem"), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		certPath, keyPath string
+		valid             bool
+	}{
+		{"plain HTTP", "", "", true},
+		{"both files", certPath, keyPath, true},
+		{"cert only", certPath, "", false},
+		{"key only", "", keyPath, false},
+		{"missing cert", filepath.Join(dir, "missing.crt"), keyPath, false},
+		{"key is a directory", certPath, dir, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ServerPort:       "8080",
+				DBDriver:         DBDriverMemory,
+				LogLevel:         "info",
+				LogFormat:        "json",
+				MaxMessageSize:   4096,
+				SendBufferPolicy: "drop",
+				TLSCertPath:      tt.certPath,
+				TLSKeyPath:       tt.keyPath,
+			}
+			if err := cfg.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() error = %v, expected valid = %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -28,16 +28,85 @@ func NewNTPSelector(config models.NTPFilterConfig) *NTPSelector {
 	if config.TopPercentile == 0 {
 		config.TopPercentile = 0.5 // Top 50%
 	}
+	if config.OutlierMethod == "" {
+		config.OutlierMethod = models.OutlierMethodStdDev
+	}
+	if config.IQRMultiplier == 0 {
+		config.IQRMultiplier = 1.5 // Tukey's fences
+	}
+	if config.OffsetSelection == "" {
+		config.OffsetSelection = models.OffsetSelectionMedian
+	}
+	config.Confidence = withConfidenceDefaults(config.Confidence)
+	config.Grade = withGradeDefaults(config.Grade)
 
 	return &NTPSelector{config: config}
 }
 
+// withConfidenceDefaults fills unset confidence weights and thresholds with the defaults
+func withConfidenceDefaults(c models.ConfidenceConfig) models.ConfidenceConfig {
+	if c.SampleWeight == 0 && c.OffsetWeight == 0 && c.JitterWeight == 0 {
+		c.SampleWeight, c.OffsetWeight, c.JitterWeight = 0.3, 0.4, 0.3
+	}
+	if c.FullSampleCount == 0 {
+		c.FullSampleCount = 10
+	}
+	if c.MaxOffsetStdDev == 0 {
+		c.MaxOffsetStdDev = 20.0 // ms
+	}
+	if c.MaxJitter == 0 {
+		c.MaxJitter = 10000.0 // μs
+	}
+	return c
+}
+
+// defaultGradeThresholds are the grade thresholds used for unset GradeThreshold fields
+var defaultGradeThresholds = models.GradeConfig{
+	A: models.GradeThreshold{MinConfidence: 0.9, MaxOffset: 50, MaxJitter: 1000, MinValidSamples: 4},
+	B: models.GradeThreshold{MinConfidence: 0.7, MaxOffset: 200, MaxJitter: 3000, MinValidSamples: 3},
+	C: models.GradeThreshold{MinConfidence: 0.5, MaxOffset: 1000, MaxJitter: 6000, MinValidSamples: 2},
+	D: models.GradeThreshold{MinConfidence: 0.3, MaxOffset: 5000, MaxJitter: 10000, MinValidSamples: 1},
+}
+
+// withGradeDefaults fills unset grade thresholds with the defaults
+func withGradeDefaults(c models.GradeConfig) models.GradeConfig {
+	fill := func(t, def models.GradeThreshold) models.GradeThreshold {
+		if t.MinConfidence == 0 {
+			t.MinConfidence = def.MinConfidence
+		}
+		if t.MaxOffset == 0 {
+			t.MaxOffset = def.MaxOffset
+		}
+		if t.MaxJitter == 0 {
+			t.MaxJitter = def.MaxJitter
+		}
+		if t.MinValidSamples == 0 {
+			t.MinValidSamples = def.MinValidSamples
+		}
+		return t
+	}
+	c.A = fill(c.A, defaultGradeThresholds.A)
+	c.B = fill(c.B, defaultGradeThresholds.B)
+	c.C = fill(c.C, defaultGradeThresholds.C)
+	c.D = fill(c.D, defaultGradeThresholds.D)
+	return c
+}
+
+// Config returns the selector's configuration with defaults applied
+func (s *NTPSelector) Config() models.NTPFilterConfig {
+	return s.config
+}
+
 // SelectBestMeasurements applies the complete NTP selection algorithm
 // Steps:
 // 1. Filter by RTT (select top N% with lowest RTT)
-// 2. Sort by RTT symmetry (prefer symmetric network delays)
+// 2. Score RTT symmetry (prefer symmetric network delays)
 // 3. Remove statistical outliers based on offset
-// 4. Calculate median offset and statistics
+// 4. Calculate best offset (median or interval intersection) and statistics
+//
+// Only step 1 selects by order; the later steps neither depend on nor change the order of the
+// candidates. The result therefore depends on the order of records only where step 1 has to
+// choose between samples with the same RTT and asymmetry.
 func (s *NTPSelector) SelectBestMeasurements(records []*models.TimeSyncRecord) (*models.AggregatedSyncResult, error) {
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no measurements provided")
@@ -49,8 +118,8 @@ func (s *NTPSelector) SelectBestMeasurements(records []*models.TimeSyncRecord) (
 		return nil, fmt.Errorf("no valid samples with RTT data")
 	}
 
-	// Step 2: RTT symmetry filtering
-	analyses = s.FilterByRTTSymmetry(analyses)
+	// Step 2: RTT symmetry scoring
+	s.ScoreRTTSymmetry(analyses)
 
 	// Step 3: Outlier removal
 	validAnalyses := s.RemoveOutliers(analyses)
@@ -63,6 +132,10 @@ func (s *NTPSelector) SelectBestMeasurements(records []*models.TimeSyncRecord) (
 
 // FilterByRTT filters measurements by RTT, keeping the top N% with lowest total RTT
 // This is NTP Step 1: Select candidates with minimum network delay
+// The result is sorted by lessRTT: ascending total RTT, ties broken by asymmetry, samples equal in
+// both keep the order of records.
+// The adjusted offset is also recorded on every record with RTT data, including those cut here,
+// so the stored measurements can be compared raw vs adjusted.
 func (s *NTPSelector) FilterByRTT(records []*models.TimeSyncRecord) []*models.SampleAnalysis {
 	analyses := make([]*models.SampleAnalysis, 0, len(records))
 
@@ -78,23 +151,14 @@ func (s *NTPSelector) FilterByRTT(records []*models.TimeSyncRecord) []*models.Sa
 		totalRTT := *record.Device1RTT + *record.Device2RTT
 		rttDiff := abs(*record.Device1RTT - *record.Device2RTT)
 
-		// Apply network delay compensation (NTP standard method)
-		// TimeDifference contains raw offset (Device1Time - Device2Time)
-		// We need to compensate for one-way network delays
-		delay1 := float64(*record.Device1RTT) / 2000.0 // RTT/2 -> one-way delay, μs → ms
-		delay2 := float64(*record.Device2RTT) / 2000.0
-
-		// Adjust raw offset by removing network delay difference
-		// If Device1 has longer delay, it appears to be ahead (needs negative correction)
-		// If Device2 has longer delay, it appears to be behind (needs positive correction)
-		rawOffset := float64(*record.TimeDifference)
-		adjustedOffset := rawOffset - (delay1 - delay2)
+		adjustedOffset := AdjustOffset(record)
+		record.AdjustedOffset = &adjustedOffset
 
 		analyses = append(analyses, &models.SampleAnalysis{
 			Record:        record,
 			TotalRTT:      totalRTT,
 			RTTDifference: rttDiff,
-			Offset:        int64(math.Round(adjustedOffset)), // Network-compensated offset
+			Offset:        adjustedOffset, // Network-compensated offset
 			IsOutlier:     false,
 		})
 	}
@@ -104,8 +168,8 @@ func (s *NTPSelector) FilterByRTT(records []*models.TimeSyncRecord) []*models.Sa
 	}
 
 	// Sort by total RTT (ascending - lower is better)
-	sort.Slice(analyses, func(i, j int) bool {
-		return analyses[i].TotalRTT < analyses[j].TotalRTT
+	sort.SliceStable(analyses, func(i, j int) bool {
+		return lessRTT(analyses[i], analyses[j])
 	})
 
 	// Select top N%
@@ -117,47 +181,135 @@ func (s *NTPSelector) FilterByRTT(records []*models.TimeSyncRecord) []*models.Sa
 	return analyses[:cutoff]
 }
 
-// FilterByRTTSymmetry assigns scores based on RTT symmetry
+// OneWayDelay returns the one-way network delay in milliseconds assumed for an RTT in
+// microseconds: half the round trip
+func OneWayDelay(rtt int64) float64 {
+	return float64(rtt) / 2000.0 // RTT/2 -> one-way delay, μs → ms
+}
+
+// AdjustOffset applies network delay compensation (NTP standard method) to a record with an offset
+// and both RTTs, returning the adjusted offset in milliseconds.
+// TimeDifference contains the raw offset (Device1Time - Device2Time). If Device1 has the longer
+// delay, it appears to be ahead and needs a negative correction; if Device2 has the longer delay,
+// it appears to be behind and needs a positive correction. Records whose offset was computed from
+// four timestamps already exclude network delay and are returned unchanged.
+func AdjustOffset(record *models.TimeSyncRecord) int64 {
+	if record.OffsetCompensated {
+		return *record.TimeDifference
+	}
+	delayCorrection := OneWayDelay(*record.Device1RTT) - OneWayDelay(*record.Device2RTT)
+	return int64(math.Round(float64(*record.TimeDifference) - delayCorrection))
+}
+
+// lessRTT orders samples by total RTT, breaking ties by RTT asymmetry (the more symmetric first)
+func lessRTT(a, b *models.SampleAnalysis) bool {
+	if a.TotalRTT != b.TotalRTT {
+		return a.TotalRTT < b.TotalRTT
+	}
+	return a.RTTDifference < b.RTTDifference
+}
+
+// ScoreRTTSymmetry assigns each sample its SelectionScore without removing or reordering any.
 // Samples with more symmetric RTT (smaller difference between Device1 and Device2)
-// get better scores. This is NTP Step 2: Prefer symmetric network paths
-func (s *NTPSelector) FilterByRTTSymmetry(analyses []*models.SampleAnalysis) []*models.SampleAnalysis {
+// get better (lower) scores. This is NTP Step 2: Prefer symmetric network paths
+func (s *NTPSelector) ScoreRTTSymmetry(analyses []*models.SampleAnalysis) {
 	// Assign selection score: TotalRTT + (RTTDifference * 2)
 	// Asymmetric RTT gets penalty
 	for _, analysis := range analyses {
 		analysis.SelectionScore = float64(analysis.TotalRTT) +
 			float64(analysis.RTTDifference)*2.0
 	}
+}
+
+// FilterByRTTSymmetry scores the samples (see ScoreRTTSymmetry) and returns them sorted by
+// ascending SelectionScore, equal scores keeping their order. SelectBestMeasurements only needs the scores;
+// this is for callers that want the most symmetric samples first.
+func (s *NTPSelector) FilterByRTTSymmetry(analyses []*models.SampleAnalysis) []*models.SampleAnalysis {
+	s.ScoreRTTSymmetry(analyses)
 
 	// Re-sort by selection score (ascending - lower is better)
-	sort.Slice(analyses, func(i, j int) bool {
+	sort.SliceStable(analyses, func(i, j int) bool {
 		return analyses[i].SelectionScore < analyses[j].SelectionScore
 	})
 
 	return analyses
 }
 
-// RemoveOutliers removes statistical outliers based on offset values
-// Uses standard deviation to identify samples that deviate significantly
+// RemoveOutliers removes statistical outliers from the analyses based on offset
+// Uses the configured OutlierMethod to identify samples that deviate significantly:
+//   - stddev: |offset - mean| > threshold * stddev
+//   - mad:    |offset - median| / (1.4826 * MAD) > threshold (robust against large spikes)
+//   - iqr:    offset outside [Q1 - k*IQR, Q3 + k*IQR], k = IQRMultiplier
+//
+// Every analysis gets its Deviation from the method's center (the mean for stddev, the median
+// otherwise); outliers also get an OutlierReason. The decision depends only on the set of offsets,
+// and the returned samples keep their input order.
 // This is NTP Step 3: Statistical filtering
 func (s *NTPSelector) RemoveOutliers(analyses []*models.SampleAnalysis) []*models.SampleAnalysis {
 	if len(analyses) < s.config.MinSamples {
 		return analyses // Not enough samples to filter
 	}
 
-	// Calculate mean and standard deviation of offsets
-	mean, stdDev := calculateOffsetStats(analyses)
+	// check returns the offset's deviation from the center and why it is an outlier, "" if it is not
+	var check func(offset float64) (deviation float64, reason string)
+	switch s.config.OutlierMethod {
+	case models.OutlierMethodIQR:
+		if len(analyses) < minIQRSamples {
+			return analyses // Quartiles are meaningless for so few samples
+		}
+		q1, median, q3 := calculateOffsetQuartiles(analyses)
+		fence := s.config.IQRMultiplier * (q3 - q1)
+		lower, upper := q1-fence, q3+fence
+		check = func(offset float64) (float64, string) {
+			switch {
+			case offset < lower:
+				return offset - median, fmt.Sprintf("below the lower IQR fence %.1fms", lower)
+			case offset > upper:
+				return offset - median, fmt.Sprintf("above the upper IQR fence %.1fms", upper)
+			}
+			return offset - median, ""
+		}
+	case models.OutlierMethodMAD:
+		// Median absolute deviation, scaled to be comparable with a standard deviation
+		median, mad := calculateOffsetMAD(analyses)
+		scale := 1.4826 * mad
+		check = func(offset float64) (float64, string) {
+			deviation := offset - median
+			if scale == 0 {
+				if deviation != 0 { // Most samples agree exactly, anything else deviates
+					return deviation, "differs from the median most samples agree on exactly"
+				}
+				return deviation, ""
+			}
+			if score := math.Abs(deviation) / scale; score > s.config.OutlierThreshold {
+				return deviation, fmt.Sprintf("%.1f scaled MADs from the median (threshold %.1f)", score, s.config.OutlierThreshold)
+			}
+			return deviation, ""
+		}
+	default:
+		// Calculate mean and standard deviation of offsets
+		mean, stdDev := calculateOffsetStats(analyses)
+		threshold := stdDev * s.config.OutlierThreshold
+		check = func(offset float64) (float64, string) {
+			deviation := offset - mean
+			if math.Abs(deviation) > threshold {
+				return deviation, fmt.Sprintf("%.1f standard deviations from the mean (threshold %.1f)",
+					math.Abs(deviation)/stdDev, s.config.OutlierThreshold)
+			}
+			return deviation, ""
+		}
+	}
 
 	// Mark outliers
-	threshold := stdDev * s.config.OutlierThreshold
 	filtered := make([]*models.SampleAnalysis, 0, len(analyses))
 
 	for _, analysis := range analyses {
-		deviation := math.Abs(float64(analysis.Offset) - mean)
-		if deviation <= threshold {
+		analysis.Deviation, analysis.OutlierReason = check(float64(analysis.Offset))
+		if analysis.OutlierReason != "" {
+			analysis.IsOutlier = true
+		} else {
 			filtered = append(filtered, analysis)
 			analysis.IsOutlier = false
-		} else {
-			analysis.IsOutlier = true
 		}
 	}
 
@@ -166,6 +318,7 @@ func (s *NTPSelector) RemoveOutliers(analyses []*models.SampleAnalysis) []*model
 		// Reset outlier flags
 		for _, analysis := range analyses {
 			analysis.IsOutlier = false
+			analysis.OutlierReason = ""
 		}
 		return analyses
 	}
@@ -173,7 +326,8 @@ func (s *NTPSelector) RemoveOutliers(analyses []*models.SampleAnalysis) []*model
 	return filtered
 }
 
-// calculateStatistics computes all statistics for the aggregated result
+// calculateStatistics computes all statistics for the aggregated result.
+// None of them depend on the order of the analyses.
 func (s *NTPSelector) calculateStatistics(
 	allRecords []*models.TimeSyncRecord,
 	selectedAnalyses []*models.SampleAnalysis,
@@ -185,28 +339,66 @@ func (s *NTPSelector) calculateStatistics(
 
 	// Calculate mean and standard deviation
 	meanOffset, offsetStdDev := calculateOffsetStats(validAnalyses)
+	weightedOffset := calculateWeightedOffset(validAnalyses)
+	minRTTOffset := selectMinRTTOffset(validAnalyses)
 
 	// Calculate RTT statistics
 	minRTT, maxRTT, meanRTT, jitter := calculateRTTStats(validAnalyses)
+	rttP50, rttP90, rttP99 := calculateRTTPercentiles(validAnalyses)
 
 	// Calculate confidence score
-	confidence := calculateConfidence(validAnalyses, offsetStdDev, jitter)
+	confidence := calculateConfidence(validAnalyses, offsetStdDev, jitter, s.config.Confidence)
+
+	// Select best offset
+	bestOffset := medianOffset
+	switch s.config.OffsetSelection {
+	case models.OffsetSelectionIntersection:
+		bestOffset = selectIntersectionOffset(validAnalyses)
+	case models.OffsetSelectionWeighted:
+		bestOffset = int64(math.Round(weightedOffset))
+	}
+
+	grade := calculateGrade(confidence, bestOffset, jitter, len(validAnalyses), s.config.Grade)
 
 	return &models.AggregatedSyncResult{
-		BestOffset:   medianOffset,
-		MedianOffset: medianOffset,
-		MeanOffset:   meanOffset,
-		OffsetStdDev: offsetStdDev,
-		MinRTT:       minRTT,
-		MaxRTT:       maxRTT,
-		MeanRTT:      meanRTT,
-		Confidence:   confidence,
-		Jitter:       jitter,
-		TotalSamples: len(allRecords),
-		ValidSamples: len(validAnalyses),
-		OutlierCount: len(selectedAnalyses) - len(validAnalyses),
-		Measurements: allRecords,
+		BestOffset:      bestOffset,
+		MedianOffset:    medianOffset,
+		MeanOffset:      meanOffset,
+		OffsetStdDev:    offsetStdDev,
+		WeightedOffset:  weightedOffset,
+		MinRTTOffset:    minRTTOffset,
+		MinRTT:          minRTT,
+		MaxRTT:          maxRTT,
+		MeanRTT:         meanRTT,
+		RTTP50:          rttP50,
+		RTTP90:          rttP90,
+		RTTP99:          rttP99,
+		Confidence:      confidence,
+		Jitter:          jitter,
+		Grade:           grade,
+		TotalSamples:    len(allRecords),
+		ValidSamples:    len(validAnalyses),
+		OutlierCount:    len(selectedAnalyses) - len(validAnalyses),
+		RejectedSamples: rejectedSamples(selectedAnalyses),
+		Measurements:    allRecords,
+	}
+}
+
+// rejectedSamples lists the analyses RemoveOutliers marked as outliers, in RTT order
+func rejectedSamples(analyses []*models.SampleAnalysis) []models.RejectedSample {
+	var rejected []models.RejectedSample
+	for _, analysis := range analyses {
+		if !analysis.IsOutlier {
+			continue
+		}
+		rejected = append(rejected, models.RejectedSample{
+			RecordID:  analysis.Record.ID,
+			Offset:    analysis.Offset,
+			Deviation: analysis.Deviation,
+			Reason:    analysis.OutlierReason,
+		})
 	}
+	return rejected
 }
 
 // Helper functions
@@ -236,6 +428,151 @@ func calculateOffsetStats(analyses []*models.SampleAnalysis) (mean, stdDev float
 	return mean, stdDev
 }
 
+// calculateWeightedOffset calculates the mean offset weighted by 1/TotalRTT².
+// As in NTP, a sample's error bound grows with its round trip, so a 5ms-RTT sample
+// counts 400 times as much as a 100ms-RTT one. RTTs below 1μs are treated as 1μs.
+func calculateWeightedOffset(analyses []*models.SampleAnalysis) float64 {
+	if len(analyses) == 0 {
+		return 0
+	}
+
+	weightedSum, weightSum := 0.0, 0.0
+	for _, a := range analyses {
+		rtt := math.Max(float64(a.TotalRTT), 1)
+		weight := 1 / (rtt * rtt)
+		weightedSum += weight * float64(a.Offset)
+		weightSum += weight
+	}
+
+	return weightedSum / weightSum
+}
+
+// selectMinRTTOffset returns the offset of the sample with the lowest total RTT.
+// Its network delay leaves the least room for asymmetry error. Ties go to the more symmetric
+// sample (see lessRTT), then to the earlier one.
+func selectMinRTTOffset(analyses []*models.SampleAnalysis) int64 {
+	if len(analyses) == 0 {
+		return 0
+	}
+
+	best := analyses[0]
+	for _, a := range analyses[1:] {
+		if lessRTT(a, best) {
+			best = a
+		}
+	}
+	return best.Offset
+}
+
+// selectIntersectionOffset implements Marzullo's intersection algorithm.
+// Each sample is treated as the interval [offset - RTT/2, offset + RTT/2] (RTT = total RTT),
+// the smallest interval agreed upon by the largest number of samples is found,
+// and its midpoint is returned as the best offset.
+func selectIntersectionOffset(analyses []*models.SampleAnalysis) int64 {
+	if len(analyses) == 0 {
+		return 0
+	}
+
+	type edge struct {
+		value float64
+		start bool
+	}
+
+	edges := make([]edge, 0, len(analyses)*2)
+	for _, a := range analyses {
+		halfWidth := float64(a.TotalRTT) / 2000.0 // RTT/2, μs → ms
+		offset := float64(a.Offset)
+		edges = append(edges, edge{offset - halfWidth, true}, edge{offset + halfWidth, false})
+	}
+
+	// Sort by value; starts before ends so touching intervals count as overlapping
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].value != edges[j].value {
+			return edges[i].value < edges[j].value
+		}
+		return edges[i].start && !edges[j].start
+	})
+
+	best, count := 0, 0
+	var bestLow, bestHigh float64
+	for i, e := range edges {
+		if !e.start {
+			count--
+			continue
+		}
+
+		count++
+		if i+1 >= len(edges) {
+			continue
+		}
+		low, high := e.value, edges[i+1].value
+		if count > best || (count == best && high-low < bestHigh-bestLow) {
+			best = count
+			bestLow, bestHigh = low, high
+		}
+	}
+
+	return int64(math.Round((bestLow + bestHigh) / 2))
+}
+
+// calculateOffsetMAD calculates the median offset and the median absolute deviation from it
+func calculateOffsetMAD(analyses []*models.SampleAnalysis) (median, mad float64) {
+	offsets := make([]float64, len(analyses))
+	for i, analysis := range analyses {
+		offsets[i] = float64(analysis.Offset)
+	}
+	median = medianFloat(offsets)
+
+	deviations := make([]float64, len(offsets))
+	for i, offset := range offsets {
+		deviations[i] = math.Abs(offset - median)
+	}
+	mad = medianFloat(deviations)
+
+	return median, mad
+}
+
+// minIQRSamples is the fewest samples the iqr method filters; below it every sample is kept
+const minIQRSamples = 4
+
+// calculateOffsetQuartiles calculates the first quartile, median and third quartile of the offsets,
+// interpolating linearly between the closest ranks
+func calculateOffsetQuartiles(analyses []*models.SampleAnalysis) (q1, median, q3 float64) {
+	offsets := make([]float64, len(analyses))
+	for i, analysis := range analyses {
+		offsets[i] = float64(analysis.Offset)
+	}
+	sort.Float64s(offsets)
+
+	return quantileSorted(offsets, 0.25), quantileSorted(offsets, 0.5), quantileSorted(offsets, 0.75)
+}
+
+// quantileSorted returns the q-quantile (0..1) of sorted values with linear interpolation
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// medianFloat returns the median of the values (sorts the slice in place)
+func medianFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
 // calculateMedianOffset calculates the median offset from analyses
 func calculateMedianOffset(analyses []*models.SampleAnalysis) int64 {
 	if len(analyses) == 0 {
@@ -293,31 +630,82 @@ func calculateRTTStats(analyses []*models.SampleAnalysis) (minRTT, maxRTT int64,
 	return minRTT, maxRTT, meanRTT, jitter
 }
 
+// calculateRTTPercentiles returns the 50th, 90th and 99th percentile of the total RTT.
+// With the usual 8-15 samples interpolation would invent RTTs no sample had, so the
+// nearest-rank method is used: the smallest RTT that at least p% of the samples do not exceed.
+func calculateRTTPercentiles(analyses []*models.SampleAnalysis) (p50, p90, p99 int64) {
+	if len(analyses) == 0 {
+		return 0, 0, 0
+	}
+
+	rtts := make([]int64, len(analyses))
+	for i, a := range analyses {
+		rtts[i] = a.TotalRTT
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+
+	return nearestRank(rtts, 50), nearestRank(rtts, 90), nearestRank(rtts, 99)
+}
+
+// nearestRank returns the p-th percentile (0 < p <= 100) of ascending sorted values
+func nearestRank(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
 // calculateConfidence calculates a confidence score (0.0 to 1.0)
 // Higher confidence means more reliable synchronization
-// Factors: low offset variance, low jitter, sufficient samples
-func calculateConfidence(analyses []*models.SampleAnalysis, offsetStdDev, jitter float64) float64 {
+// Factors: low offset variance, low jitter, sufficient samples, weighted as configured
+// (cfg must have its defaults applied, see withConfidenceDefaults)
+func calculateConfidence(analyses []*models.SampleAnalysis, offsetStdDev, jitter float64, cfg models.ConfidenceConfig) float64 {
 	if len(analyses) == 0 {
 		return 0.0
 	}
 
 	// Factor 1: Sample count (more samples = higher confidence)
-	sampleFactor := math.Min(float64(len(analyses))/10.0, 1.0)
+	sampleFactor := math.Min(float64(len(analyses))/float64(cfg.FullSampleCount), 1.0)
 
 	// Factor 2: Offset consistency (lower stddev = higher confidence)
-	// Assume good if stddev < 5ms, poor if > 20ms
-	offsetFactor := 1.0 - math.Min(offsetStdDev/20.0, 1.0)
+	// By default good if stddev < 5ms, poor if > 20ms
+	offsetFactor := 1.0 - math.Min(offsetStdDev/cfg.MaxOffsetStdDev, 1.0)
 
 	// Factor 3: Network stability (lower jitter = higher confidence)
-	// Assume good if jitter < 1000μs, poor if > 10000μs
-	jitterFactor := 1.0 - math.Min(jitter/10000.0, 1.0)
+	// By default good if jitter < 1000μs, poor if > 10000μs
+	jitterFactor := 1.0 - math.Min(jitter/cfg.MaxJitter, 1.0)
 
 	// Weighted average
-	confidence := (sampleFactor*0.3 + offsetFactor*0.4 + jitterFactor*0.3)
+	totalWeight := cfg.SampleWeight + cfg.OffsetWeight + cfg.JitterWeight
+	confidence := (sampleFactor*cfg.SampleWeight + offsetFactor*cfg.OffsetWeight + jitterFactor*cfg.JitterWeight) / totalWeight
 
 	return math.Max(0.0, math.Min(1.0, confidence))
 }
 
+// calculateGrade returns the best grade whose thresholds the result meets, GradeF if none.
+// A grade needs enough valid samples too, so a result from one or two samples, whose stddev and
+// jitter look perfect, drops to a lower grade instead of getting an A.
+// (cfg must have its defaults applied, see withGradeDefaults)
+func calculateGrade(confidence float64, bestOffset int64, jitter float64, validSamples int, cfg models.GradeConfig) string {
+	for _, grade := range []struct {
+		name      string
+		threshold models.GradeThreshold
+	}{
+		{models.GradeA, cfg.A},
+		{models.GradeB, cfg.B},
+		{models.GradeC, cfg.C},
+		{models.GradeD, cfg.D},
+	} {
+		t := grade.threshold
+		if validSamples >= t.MinValidSamples && confidence >= t.MinConfidence &&
+			float64(abs(bestOffset)) <= t.MaxOffset && jitter <= t.MaxJitter {
+			return grade.name
+		}
+	}
+	return models.GradeF
+}
+
 // abs returns the absolute value of an int64
 func abs(n int64) int64 {
 	if n < 0 {
@@ -1,6 +1,9 @@
 package algorithms
 
 import (
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 
 	"time-sync-server/internal/models"
@@ -44,6 +47,40 @@ func TestNTPSelector_FilterByRTT(t *testing.T) {
 	}
 }
 
+func TestNTPSelector_FilterByRTT_SetsAdjustedOffset(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       1,
+		OutlierThreshold: 2.0,
+		TopPercentile:    0.5,
+	})
+
+	noRTT := &models.TimeSyncRecord{ID: 3, Status: models.SyncStatusSuccess}
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 4000, 5000, -148),   // Selected: -148 - (2 - 2.5) = -147.5
+		createTestRecord(2, 20000, 25000, -160), // Cut by RTT: -160 - (10 - 12.5) = -157.5
+		noRTT,
+	}
+
+	analyses := selector.FilterByRTT(records)
+	if len(analyses) != 1 {
+		t.Fatalf("Expected 1 analysis, got %d", len(analyses))
+	}
+
+	// Records cut by the RTT filter still carry their adjusted offset
+	expected := map[int64]int64{1: -148, 2: -158}
+	for _, record := range records[:2] {
+		if record.AdjustedOffset == nil || *record.AdjustedOffset != expected[record.ID] {
+			t.Errorf("Record %d AdjustedOffset = %v, expected %d", record.ID, record.AdjustedOffset, expected[record.ID])
+		}
+	}
+	if analyses[0].Offset != *records[0].AdjustedOffset {
+		t.Errorf("Analysis offset %d differs from record AdjustedOffset %d", analyses[0].Offset, *records[0].AdjustedOffset)
+	}
+	if noRTT.AdjustedOffset != nil {
+		t.Errorf("Expected no AdjustedOffset for a record without RTT data")
+	}
+}
+
 func TestNTPSelector_FilterByRTTSymmetry(t *testing.T) {
 	selector := NewNTPSelector(models.NTPFilterConfig{})
 
@@ -244,7 +281,7 @@ func TestCalculateConfidence(t *testing.T) {
 		{Offset: -150, TotalRTT: 10200},
 	}
 
-	confidence := calculateConfidence(analyses, 1.0, 500.0)
+	confidence := calculateConfidence(analyses, 1.0, 500.0, withConfidenceDefaults(models.ConfidenceConfig{}))
 
 	if confidence < 0.7 {
 		t.Errorf("Expected high confidence (>0.7) for consistent measurements, got %f", confidence)
@@ -256,7 +293,7 @@ func TestCalculateConfidence(t *testing.T) {
 		{Offset: -200, TotalRTT: 50000},
 	}
 
-	confidence2 := calculateConfidence(analyses2, 25.0, 20000.0)
+	confidence2 := calculateConfidence(analyses2, 25.0, 20000.0, withConfidenceDefaults(models.ConfidenceConfig{}))
 
 	if confidence2 > 0.3 {
 		t.Errorf("Expected low confidence (<0.3) for inconsistent measurements, got %f", confidence2)
@@ -414,3 +451,616 @@ func TestNTPSelector_RawVsAdjusted(t *testing.T) {
 func ptrInt64(v int64) *int64 {
 	return &v
 }
+
+// createOffsetAnalyses builds analyses with the given offsets (RTT is irrelevant for outlier removal)
+func createOffsetAnalyses(offsets ...int64) []*models.SampleAnalysis {
+	analyses := make([]*models.SampleAnalysis, len(offsets))
+	for i, offset := range offsets {
+		analyses[i] = &models.SampleAnalysis{
+			Record: createTestRecord(int64(i+1), 5000, 6000, offset),
+			Offset: offset,
+		}
+	}
+	return analyses
+}
+
+func TestNTPSelector_RemoveOutliers_DefaultsToStdDev(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{})
+
+	if selector.config.OutlierMethod != models.OutlierMethodStdDev {
+		t.Errorf("Expected default outlier method %q, got %q", models.OutlierMethodStdDev, selector.config.OutlierMethod)
+	}
+}
+
+func TestNTPSelector_RemoveOutliers_MADCatchesMaskedOutlier(t *testing.T) {
+	// A single huge spike (5000) inflates the standard deviation so much that
+	// the moderate outlier (130) stays within mean ± 2·stddev
+	offsets := []int64{100, 101, 99, 100, 102, 98, 130, 5000}
+
+	stdDevSelector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       3,
+		OutlierThreshold: 2.0,
+		OutlierMethod:    models.OutlierMethodStdDev,
+	})
+	stdDevFiltered := stdDevSelector.RemoveOutliers(createOffsetAnalyses(offsets...))
+
+	madSelector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       3,
+		OutlierThreshold: 2.0,
+		OutlierMethod:    models.OutlierMethodMAD,
+	})
+	madAnalyses := createOffsetAnalyses(offsets...)
+	madFiltered := madSelector.RemoveOutliers(madAnalyses)
+
+	if !containsOffset(stdDevFiltered, 130) {
+		t.Errorf("Expected stddev method to miss offset 130 (masked by the spike)")
+	}
+	if containsOffset(madFiltered, 130) {
+		t.Errorf("Expected MAD method to remove offset 130")
+	}
+	if containsOffset(madFiltered, 5000) {
+		t.Errorf("Expected MAD method to remove offset 5000")
+	}
+	if len(madFiltered) != 6 {
+		t.Errorf("Expected 6 valid analyses after MAD outlier removal, got %d", len(madFiltered))
+	}
+
+	for _, a := range madAnalyses {
+		expectedOutlier := a.Offset == 130 || a.Offset == 5000
+		if a.IsOutlier != expectedOutlier {
+			t.Errorf("Offset %d: IsOutlier = %v, expected %v", a.Offset, a.IsOutlier, expectedOutlier)
+		}
+	}
+}
+
+func TestNTPSelector_RemoveOutliers_MADZeroDeviation(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       3,
+		OutlierThreshold: 2.0,
+		OutlierMethod:    models.OutlierMethodMAD,
+	})
+
+	// Most samples agree exactly, so MAD is 0
+	filtered := selector.RemoveOutliers(createOffsetAnalyses(100, 100, 100, 100, 900))
+
+	if len(filtered) != 4 || containsOffset(filtered, 900) {
+		t.Errorf("Expected only offset 900 to be removed, got %d analyses", len(filtered))
+	}
+}
+
+func containsOffset(analyses []*models.SampleAnalysis, offset int64) bool {
+	for _, a := range analyses {
+		if a.Offset == offset {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNTPSelector_IntersectionVsMedian_WideRTT(t *testing.T) {
+	// Three precise samples (±5ms) agree on ~100ms; three wide-RTT samples (±100ms)
+	// are biased upwards but their intervals still contain 100ms
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 5000, 5000, 100),
+		createTestRecord(2, 5000, 5000, 102),
+		createTestRecord(3, 5000, 5000, 98),
+		createTestRecord(4, 100000, 100000, 150),
+		createTestRecord(5, 100000, 100000, 160),
+		createTestRecord(6, 100000, 100000, 170),
+	}
+
+	median := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:    3,
+		TopPercentile: 1.0, // Keep all samples
+	})
+	intersection := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:      3,
+		TopPercentile:   1.0,
+		OffsetSelection: models.OffsetSelectionIntersection,
+	})
+
+	medianResult, err := median.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("median SelectBestMeasurements() error = %v", err)
+	}
+	intersectionResult, err := intersection.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("intersection SelectBestMeasurements() error = %v", err)
+	}
+
+	// Median is pulled towards the wide samples: (102 + 150) / 2 = 126
+	if medianResult.BestOffset != 126 {
+		t.Errorf("median BestOffset = %d, expected 126", medianResult.BestOffset)
+	}
+
+	// All six intervals overlap in [97, 103] -> midpoint 100
+	if intersectionResult.BestOffset != 100 {
+		t.Errorf("intersection BestOffset = %d, expected 100", intersectionResult.BestOffset)
+	}
+
+	// MedianOffset is still reported as the median
+	if intersectionResult.MedianOffset != medianResult.MedianOffset {
+		t.Errorf("intersection MedianOffset = %d, expected %d", intersectionResult.MedianOffset, medianResult.MedianOffset)
+	}
+}
+
+func TestSelectIntersectionOffset_DisjointGroups(t *testing.T) {
+	// Two samples agree around 50ms, one lone sample at 200ms: the majority wins
+	analyses := []*models.SampleAnalysis{
+		{Offset: 48, TotalRTT: 10000},  // [43, 53]
+		{Offset: 52, TotalRTT: 10000},  // [47, 57]
+		{Offset: 200, TotalRTT: 10000}, // [195, 205]
+	}
+
+	if got := selectIntersectionOffset(analyses); got != 50 {
+		t.Errorf("selectIntersectionOffset() = %d, expected 50", got)
+	}
+}
+
+func TestNTPSelector_DefaultsToMedianSelection(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{})
+
+	if selector.config.OffsetSelection != models.OffsetSelectionMedian {
+		t.Errorf("Expected default offset selection %q, got %q", models.OffsetSelectionMedian, selector.config.OffsetSelection)
+	}
+}
+
+func TestNTPSelector_WeightedOffsetFavorsLowRTT(t *testing.T) {
+	// One precise sample (10ms total RTT) at 100ms, two noisy samples (200ms total RTT) at 200ms
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 5000, 5000, 100),
+		createTestRecord(2, 100000, 100000, 200),
+		createTestRecord(3, 100000, 100000, 200),
+	}
+
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:      3,
+		TopPercentile:   1.0,
+		OffsetSelection: models.OffsetSelectionWeighted,
+	})
+	result, err := selector.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+
+	// Weights 1/RTT² are 400:1:1 -> (400*100 + 200 + 200) / 402 ≈ 100.5
+	if result.WeightedOffset < 100 || result.WeightedOffset > 101 {
+		t.Errorf("WeightedOffset = %.2f, expected ≈100.5", result.WeightedOffset)
+	}
+	if result.BestOffset != 100 {
+		t.Errorf("BestOffset = %d, expected weighted offset 100", result.BestOffset)
+	}
+
+	// The plain median and mean follow the majority of noisy samples
+	if result.MedianOffset != 200 {
+		t.Errorf("MedianOffset = %d, expected 200", result.MedianOffset)
+	}
+	if result.MeanOffset < 166 || result.MeanOffset > 167 {
+		t.Errorf("MeanOffset = %.2f, expected ≈166.7", result.MeanOffset)
+	}
+}
+
+func TestCalculateWeightedOffset_EqualRTT(t *testing.T) {
+	// With equal RTTs the weighted offset is the plain mean
+	analyses := []*models.SampleAnalysis{
+		{Offset: 10, TotalRTT: 8000},
+		{Offset: 20, TotalRTT: 8000},
+		{Offset: 30, TotalRTT: 8000},
+	}
+
+	if got := calculateWeightedOffset(analyses); math.Abs(got-20) > 1e-9 {
+		t.Errorf("calculateWeightedOffset() = %.2f, expected 20", got)
+	}
+	if got := calculateWeightedOffset(nil); got != 0 {
+		t.Errorf("calculateWeightedOffset(nil) = %.2f, expected 0", got)
+	}
+}
+
+func TestNTPSelector_RemoveOutliers_IQR(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:    3,
+		OutlierMethod: models.OutlierMethodIQR,
+	})
+	if selector.config.IQRMultiplier != 1.5 {
+		t.Errorf("Expected default IQR multiplier 1.5, got %v", selector.config.IQRMultiplier)
+	}
+
+	// Q1 = 100, Q3 = 102, IQR = 2: fences at 97 and 105
+	analyses := createOffsetAnalyses(98, 99, 100, 100, 101, 101, 102, 104, 130)
+	filtered := selector.RemoveOutliers(analyses)
+
+	if len(filtered) != 8 || containsOffset(filtered, 130) {
+		t.Errorf("Expected only offset 130 to be removed, got %d analyses", len(filtered))
+	}
+	for _, a := range analyses {
+		expectedOutlier := a.Offset == 130
+		if a.IsOutlier != expectedOutlier {
+			t.Errorf("Offset %d: IsOutlier = %v, expected %v", a.Offset, a.IsOutlier, expectedOutlier)
+		}
+	}
+}
+
+func TestNTPSelector_RemoveOutliers_IQRMultiplier(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:    3,
+		OutlierMethod: models.OutlierMethodIQR,
+		IQRMultiplier: 0.5,
+	})
+
+	// Fences narrow to 99 and 103
+	filtered := selector.RemoveOutliers(createOffsetAnalyses(98, 99, 100, 100, 101, 101, 102, 104, 130))
+
+	if len(filtered) != 6 || containsOffset(filtered, 98) || containsOffset(filtered, 104) || containsOffset(filtered, 130) {
+		t.Errorf("Expected offsets 98, 104 and 130 to be removed, got %d analyses", len(filtered))
+	}
+}
+
+func TestNTPSelector_RemoveOutliers_IQRTooFewSamples(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:    3,
+		OutlierMethod: models.OutlierMethodIQR,
+	})
+
+	// Three samples are enough for MinSamples but not for quartiles
+	filtered := selector.RemoveOutliers(createOffsetAnalyses(100, 101, 900))
+
+	if len(filtered) != 3 {
+		t.Errorf("Expected all 3 analyses to be kept, got %d", len(filtered))
+	}
+}
+
+func TestNTPSelector_FilterByRTT_KeepsCompensatedOffset(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{MinSamples: 1, TopPercentile: 1.0})
+
+	// Asymmetric RTTs would shift a raw offset by (20000-4000)/2000 = 8ms
+	raw := createTestRecord(1, 20000, 4000, -150)
+	compensated := createTestRecord(2, 20000, 4000, -150)
+	compensated.OffsetCompensated = true
+
+	analyses := selector.FilterByRTT([]*models.TimeSyncRecord{raw, compensated})
+	if len(analyses) != 2 {
+		t.Fatalf("Expected 2 analyses, got %d", len(analyses))
+	}
+	if *raw.AdjustedOffset != -158 {
+		t.Errorf("Raw record: AdjustedOffset = %d, expected -158", *raw.AdjustedOffset)
+	}
+	if *compensated.AdjustedOffset != -150 {
+		t.Errorf("Compensated record: AdjustedOffset = %d, expected -150 (unchanged)", *compensated.AdjustedOffset)
+	}
+}
+
+func TestNTPSelector_MinRTTOffsetPicksLowestRTTSample(t *testing.T) {
+	// The lowest-RTT sample (6ms total) is at 104ms, away from the 101ms median
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 4000, 4000, 100),
+		createTestRecord(2, 3000, 3000, 104),
+		createTestRecord(3, 10000, 10000, 101),
+		createTestRecord(4, 9000, 9000, 99),
+		createTestRecord(5, 8000, 8000, 102),
+	}
+
+	selector := NewNTPSelector(models.NTPFilterConfig{MinSamples: 3, TopPercentile: 1.0})
+	result, err := selector.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+
+	if result.MinRTTOffset != 104 {
+		t.Errorf("MinRTTOffset = %d, expected 104 (offset of the 6ms RTT sample)", result.MinRTTOffset)
+	}
+	if result.BestOffset != 101 {
+		t.Errorf("BestOffset = %d, expected the median 101", result.BestOffset)
+	}
+}
+
+func TestSelectMinRTTOffset_Ties(t *testing.T) {
+	valid := []*models.SampleAnalysis{
+		{Offset: 50, TotalRTT: 9000},
+		{Offset: 40, TotalRTT: 7000},
+		{Offset: 45, TotalRTT: 7000},
+	}
+
+	if got := selectMinRTTOffset(valid); got != 40 {
+		t.Errorf("selectMinRTTOffset() = %d, expected 40 (first of the tied lowest RTTs)", got)
+	}
+	if got := selectMinRTTOffset(nil); got != 0 {
+		t.Errorf("selectMinRTTOffset(nil) = %d, expected 0", got)
+	}
+}
+
+func TestCalculateConfidence_Weights(t *testing.T) {
+	// 8 samples, offset stddev 1ms, jitter 5000μs
+	// Default factors: sample 0.8, offset 0.95, network stability 0.5
+	analyses := make([]*models.SampleAnalysis, 8)
+	for i := range analyses {
+		analyses[i] = &models.SampleAnalysis{Offset: -150, TotalRTT: 10000}
+	}
+
+	tests := []struct {
+		name     string
+		config   models.ConfidenceConfig
+		expected float64
+	}{
+		{"defaults", models.ConfidenceConfig{}, 0.3*0.8 + 0.4*0.95 + 0.3*0.5},
+		{"offset consistency only", models.ConfidenceConfig{OffsetWeight: 1}, 0.95},
+		{"weights need not sum to 1", models.ConfidenceConfig{SampleWeight: 1, JitterWeight: 1}, (0.8 + 0.5) / 2},
+		{"strict clinical thresholds", models.ConfidenceConfig{MaxOffsetStdDev: 2, MaxJitter: 5000}, 0.3*0.8 + 0.4*0.5 + 0.3*0},
+		{"fewer samples for full sample factor", models.ConfidenceConfig{FullSampleCount: 4}, 0.3*1 + 0.4*0.95 + 0.3*0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confidence := calculateConfidence(analyses, 1.0, 5000.0, withConfidenceDefaults(tt.config))
+			if math.Abs(confidence-tt.expected) > 1e-9 {
+				t.Errorf("calculateConfidence() = %f, expected %f", confidence, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNTPSelector_ConfidenceConfig(t *testing.T) {
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 5000, 5000, -150),
+		createTestRecord(2, 5100, 5000, -151),
+		createTestRecord(3, 5000, 5100, -149),
+	}
+	config := models.NTPFilterConfig{MinSamples: 3, TopPercentile: 1.0}
+
+	defaultResult, err := NewNTPSelector(config).SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+
+	// Ignoring the sample count factor raises the confidence of a 3 sample measurement
+	config.Confidence = models.ConfidenceConfig{OffsetWeight: 0.5, JitterWeight: 0.5}
+	tunedResult, err := NewNTPSelector(config).SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+
+	if tunedResult.Confidence <= defaultResult.Confidence {
+		t.Errorf("Confidence = %f with the sample factor left out, expected more than the default %f",
+			tunedResult.Confidence, defaultResult.Confidence)
+	}
+}
+
+func TestCalculateRTTPercentiles_NearestRank(t *testing.T) {
+	// 10 samples in shuffled order: ranks are ceil(p/100 * 10) = 5, 9 and 10
+	var analyses []*models.SampleAnalysis
+	for _, rtt := range []int64{7000, 2000, 10000, 4000, 1000, 9000, 3000, 6000, 5000, 8000} {
+		analyses = append(analyses, &models.SampleAnalysis{TotalRTT: rtt})
+	}
+
+	p50, p90, p99 := calculateRTTPercentiles(analyses)
+	if p50 != 5000 || p90 != 9000 || p99 != 10000 {
+		t.Errorf("calculateRTTPercentiles() = %d, %d, %d, expected 5000, 9000, 10000", p50, p90, p99)
+	}
+
+	// 8 samples: ranks 4, 8 and 8, so p90 is already the slowest sample
+	p50, p90, p99 = calculateRTTPercentiles(analyses[:8])
+	if p50 != 4000 || p90 != 10000 || p99 != 10000 {
+		t.Errorf("calculateRTTPercentiles() of 8 samples = %d, %d, %d, expected 4000, 10000, 10000", p50, p90, p99)
+	}
+
+	if p50, p90, p99 := calculateRTTPercentiles(analyses[:1]); p50 != 7000 || p90 != 7000 || p99 != 7000 {
+		t.Errorf("calculateRTTPercentiles() of 1 sample = %d, %d, %d, expected 7000 for all", p50, p90, p99)
+	}
+	if p50, p90, p99 := calculateRTTPercentiles(nil); p50 != 0 || p90 != 0 || p99 != 0 {
+		t.Errorf("calculateRTTPercentiles(nil) = %d, %d, %d, expected 0", p50, p90, p99)
+	}
+}
+
+func TestNTPSelector_SelectBestMeasurementsListsRejectedSamples(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       3,
+		OutlierThreshold: 3.0,
+		TopPercentile:    1.0,
+		OutlierMethod:    models.OutlierMethodMAD,
+	})
+
+	var records []*models.TimeSyncRecord
+	for i, offset := range []int64{100, 101, 99, 100, 102, 100, 500} {
+		records = append(records, createTestRecord(int64(i+1), 5000, 5000, offset))
+	}
+
+	result, err := selector.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+	if len(result.RejectedSamples) != result.OutlierCount || result.OutlierCount != 1 {
+		t.Fatalf("RejectedSamples = %+v, expected the one outlier", result.RejectedSamples)
+	}
+	rejected := result.RejectedSamples[0]
+	if rejected.RecordID != 7 || rejected.Offset != 500 || rejected.Deviation != 400 {
+		t.Errorf("rejected sample = %+v, expected record 7 with offset 500, 400ms from the median", rejected)
+	}
+	if !strings.Contains(rejected.Reason, "MADs from the median") {
+		t.Errorf("Reason = %q, expected the MAD score", rejected.Reason)
+	}
+
+	// Too few samples left after filtering keeps every sample, so nothing is listed as rejected
+	result, err = selector.SelectBestMeasurements(records[4:])
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+	if len(result.RejectedSamples) != 0 {
+		t.Errorf("RejectedSamples = %+v, expected none when the filter falls back to all samples", result.RejectedSamples)
+	}
+}
+
+func TestNTPSelector_SelectBestMeasurementsIgnoresRecordOrder(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{
+		MinSamples:       3,
+		OutlierThreshold: 2.0,
+		TopPercentile:    0.75,
+	})
+
+	// Distinct RTTs, the lowest one asymmetric enough to score worse than the second
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 9000, 9000, -148),
+		createTestRecord(2, 2000, 6000, -151),
+		createTestRecord(3, 5000, 5500, -150),
+		createTestRecord(4, 20000, 21000, -145),
+		createTestRecord(5, 7000, 7500, -149),
+		createTestRecord(6, 6000, 6000, -152),
+		createTestRecord(7, 8000, 8200, -400),
+		createTestRecord(8, 30000, 31000, -140),
+	}
+	reversed := make([]*models.TimeSyncRecord, len(records))
+	for i, record := range records {
+		reversed[len(records)-1-i] = record
+	}
+	rotated := append(append([]*models.TimeSyncRecord{}, records[3:]...), records[:3]...)
+
+	expected, err := selector.SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+	// -151 corrected by the 2ms difference in one-way delay
+	if expected.MinRTT != 8000 || expected.MinRTTOffset != -149 {
+		t.Errorf("MinRTT = %d, MinRTTOffset = %d, expected the 8000μs sample (offset -149)", expected.MinRTT, expected.MinRTTOffset)
+	}
+	for _, order := range [][]*models.TimeSyncRecord{reversed, rotated} {
+		result, err := selector.SelectBestMeasurements(order)
+		if err != nil {
+			t.Fatalf("SelectBestMeasurements() error = %v", err)
+		}
+		result.Measurements, expected.Measurements = nil, nil
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("result for reordered records = %+v, expected %+v", result, expected)
+		}
+	}
+}
+
+func TestNTPSelector_ScoreRTTSymmetryKeepsOrder(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{})
+
+	analyses := selector.FilterByRTT([]*models.TimeSyncRecord{
+		createTestRecord(1, 5000, 15000, -150), // Total 20000, asymmetric: score 40000
+		createTestRecord(2, 8000, 9000, -155),  // Total 17000, symmetric: score 19000
+		createTestRecord(3, 9000, 9000, -152),  // Total 18000, symmetric: score 18000
+	})
+	selector.ScoreRTTSymmetry(analyses)
+
+	for i, expected := range []struct {
+		id    int64
+		score float64
+	}{{2, 19000}, {3, 18000}} {
+		if analyses[i].Record.ID != expected.id || analyses[i].SelectionScore != expected.score {
+			t.Errorf("analyses[%d] = record %d with score %.0f, expected record %d with score %.0f",
+				i, analyses[i].Record.ID, analyses[i].SelectionScore, expected.id, expected.score)
+		}
+	}
+}
+
+func TestNTPSelector_StatisticsIgnoreAnalysisOrder(t *testing.T) {
+	selector := NewNTPSelector(models.NTPFilterConfig{MinSamples: 3, TopPercentile: 1.0})
+
+	// Adjusted offsets: -157, -150, -154 and -140
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 2000, 8000, -160), // Lowest RTT (10000) but the worst score
+		createTestRecord(2, 6000, 6000, -150),
+		createTestRecord(3, 5000, 7000, -155), // Same RTT as record 2, less symmetric
+		createTestRecord(4, 10000, 10000, -140),
+	}
+	analyses := selector.FilterByRTTSymmetry(selector.FilterByRTT(records))
+	if analyses[0].TotalRTT == 10000 {
+		t.Fatal("expected the symmetry order to move the lowest-RTT sample away from the front")
+	}
+
+	for _, order := range [][]*models.SampleAnalysis{analyses, {analyses[3], analyses[1], analyses[0], analyses[2]}} {
+		result := selector.calculateStatistics(records, order, order)
+		if result.MinRTT != 10000 || result.MaxRTT != 20000 {
+			t.Errorf("MinRTT, MaxRTT = %d, %d, expected 10000, 20000", result.MinRTT, result.MaxRTT)
+		}
+		if result.MinRTTOffset != -157 {
+			t.Errorf("MinRTTOffset = %d, expected -157 from the lowest-RTT sample", result.MinRTTOffset)
+		}
+	}
+
+	// Equal total RTTs go to the more symmetric sample wherever it is
+	var symmetric, asymmetric *models.SampleAnalysis
+	for _, analysis := range analyses {
+		switch analysis.Record.ID {
+		case 2:
+			symmetric = analysis
+		case 3:
+			asymmetric = analysis
+		}
+	}
+	for _, order := range [][]*models.SampleAnalysis{{symmetric, asymmetric}, {asymmetric, symmetric}} {
+		if offset := selectMinRTTOffset(order); offset != -150 {
+			t.Errorf("selectMinRTTOffset() = %d, expected -150 from the symmetric sample", offset)
+		}
+	}
+}
+
+func TestCalculateGrade(t *testing.T) {
+	tests := []struct {
+		name         string
+		confidence   float64
+		bestOffset   int64
+		jitter       float64
+		validSamples int
+		config       models.GradeConfig
+		expected     string
+	}{
+		{"excellent", 0.95, -20, 500, 8, models.GradeConfig{}, models.GradeA},
+		{"thresholds are inclusive", 0.9, 50, 1000, 4, models.GradeConfig{}, models.GradeA},
+		{"large negative offset", 0.95, -180, 500, 8, models.GradeConfig{}, models.GradeB},
+		{"high jitter", 0.95, 20, 5000, 8, models.GradeConfig{}, models.GradeC},
+		{"moderate confidence", 0.6, 20, 500, 8, models.GradeConfig{}, models.GradeC},
+		{"low confidence", 0.35, 20, 500, 8, models.GradeConfig{}, models.GradeD},
+		{"very low confidence", 0.2, 20, 500, 8, models.GradeConfig{}, models.GradeF},
+		{"offset beyond every grade", 0.95, 6000, 500, 8, models.GradeConfig{}, models.GradeF},
+		{"jitter beyond every grade", 0.95, 20, 12000, 8, models.GradeConfig{}, models.GradeF},
+		{"strict A offset", 0.95, 20, 500, 8, models.GradeConfig{A: models.GradeThreshold{MaxOffset: 5}}, models.GradeB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grade := calculateGrade(tt.confidence, tt.bestOffset, tt.jitter, tt.validSamples, withGradeDefaults(tt.config))
+			if grade != tt.expected {
+				t.Errorf("calculateGrade() = %q, expected %q", grade, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCalculateGrade_LowSampleCounts(t *testing.T) {
+	config := withGradeDefaults(models.GradeConfig{})
+
+	// Perfect metrics drop one grade per missing sample instead of failing outright
+	for _, tt := range []struct {
+		validSamples int
+		expected     string
+	}{{4, models.GradeA}, {3, models.GradeB}, {2, models.GradeC}, {1, models.GradeD}, {0, models.GradeF}} {
+		if grade := calculateGrade(1.0, 0, 0, tt.validSamples, config); grade != tt.expected {
+			t.Errorf("calculateGrade() with %d valid samples = %q, expected %q", tt.validSamples, grade, tt.expected)
+		}
+	}
+
+	// A single sample has no offset spread or jitter, which must not earn it a good grade
+	selector := NewNTPSelector(models.NTPFilterConfig{MinSamples: 1, TopPercentile: 1.0})
+	result, err := selector.SelectBestMeasurements([]*models.TimeSyncRecord{createTestRecord(1, 5000, 5000, -150)})
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+	if result.Grade != models.GradeD {
+		t.Errorf("Grade = %q with confidence %.2f from 1 sample, expected %q", result.Grade, result.Confidence, models.GradeD)
+	}
+
+	records := []*models.TimeSyncRecord{
+		createTestRecord(1, 5000, 5000, -10),
+		createTestRecord(2, 5100, 5000, -11),
+		createTestRecord(3, 5000, 5100, -9),
+		createTestRecord(4, 5050, 5050, -10),
+	}
+	result, err = NewNTPSelector(models.NTPFilterConfig{MinSamples: 3, TopPercentile: 1.0}).SelectBestMeasurements(records)
+	if err != nil {
+		t.Fatalf("SelectBestMeasurements() error = %v", err)
+	}
+	if result.Grade == "" || result.Grade == models.GradeF {
+		t.Errorf("Grade = %q for 4 consistent samples (confidence %.2f), expected a passing grade", result.Grade, result.Confidence)
+	}
+}
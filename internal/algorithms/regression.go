@@ -0,0 +1,53 @@
+package algorithms
+
+import (
+	"fmt"
+)
+
+// LinearRegression fits y = slope*x + intercept with ordinary least squares
+// and returns the coefficient of determination (R²) of the fit.
+// R² is 1 when all y values are equal (the horizontal line fits exactly).
+func LinearRegression(xs, ys []float64) (slope, intercept, rSquared float64, err error) {
+	if len(xs) != len(ys) {
+		return 0, 0, 0, fmt.Errorf("x and y must have the same length (%d != %d)", len(xs), len(ys))
+	}
+	n := float64(len(xs))
+	if n < 2 {
+		return 0, 0, 0, fmt.Errorf("at least 2 points are required, got %d", len(xs))
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX := sumX / n
+	meanY := sumY / n
+
+	var sxx, sxy float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		sxx += dx * dx
+		sxy += dx * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0, 0, fmt.Errorf("all x values are equal, slope is undefined")
+	}
+
+	slope = sxy / sxx
+	intercept = meanY - slope*meanX
+
+	// R² = 1 - SSres / SStot
+	var ssRes, ssTot float64
+	for i := range xs {
+		predicted := slope*xs[i] + intercept
+		ssRes += (ys[i] - predicted) * (ys[i] - predicted)
+		ssTot += (ys[i] - meanY) * (ys[i] - meanY)
+	}
+	if ssTot == 0 {
+		return slope, intercept, 1, nil
+	}
+	rSquared = 1 - ssRes/ssTot
+
+	return slope, intercept, rSquared, nil
+}
@@ -0,0 +1,71 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLinearRegression(t *testing.T) {
+	tests := []struct {
+		name              string
+		xs, ys            []float64
+		expectedSlope     float64
+		expectedIntercept float64
+		expectedR2        float64
+	}{
+		{
+			name:              "Perfect line",
+			xs:                []float64{0, 1, 2, 3},
+			ys:                []float64{10, 12, 14, 16},
+			expectedSlope:     2,
+			expectedIntercept: 10,
+			expectedR2:        1,
+		},
+		{
+			name:              "Constant values",
+			xs:                []float64{0, 1, 2},
+			ys:                []float64{5, 5, 5},
+			expectedSlope:     0,
+			expectedIntercept: 5,
+			expectedR2:        1,
+		},
+		{
+			name:              "Noisy line",
+			xs:                []float64{0, 1, 2, 3},
+			ys:                []float64{0, 2, 1, 3},
+			expectedSlope:     0.8,
+			expectedIntercept: 0.3,
+			expectedR2:        0.64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slope, intercept, r2, err := LinearRegression(tt.xs, tt.ys)
+			if err != nil {
+				t.Fatalf("LinearRegression() error = %v", err)
+			}
+			if math.Abs(slope-tt.expectedSlope) > 1e-9 {
+				t.Errorf("slope = %v, expected %v", slope, tt.expectedSlope)
+			}
+			if math.Abs(intercept-tt.expectedIntercept) > 1e-9 {
+				t.Errorf("intercept = %v, expected %v", intercept, tt.expectedIntercept)
+			}
+			if math.Abs(r2-tt.expectedR2) > 1e-9 {
+				t.Errorf("R² = %v, expected %v", r2, tt.expectedR2)
+			}
+		})
+	}
+}
+
+func TestLinearRegression_Errors(t *testing.T) {
+	if _, _, _, err := LinearRegression([]float64{1}, []float64{1}); err == nil {
+		t.Error("Expected error for a single point")
+	}
+	if _, _, _, err := LinearRegression([]float64{1, 1}, []float64{1, 2}); err == nil {
+		t.Error("Expected error when all x values are equal")
+	}
+	if _, _, _, err := LinearRegression([]float64{1, 2}, []float64{1}); err == nil {
+		t.Error("Expected error for mismatched lengths")
+	}
+}
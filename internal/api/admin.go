@@ -0,0 +1,54 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"time-sync-server/internal/logging"
+	"time-sync-server/internal/models"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin guards the admin endpoints with the ADMIN_TOKEN bearer token (or "token" query param).
+// Without a configured token the admin endpoints are unavailable.
+func (h *Handler) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.adminToken == "" {
+			respondError(c, http.StatusServiceUnavailable, models.ErrorCodeUnavailable, "admin API is not enabled (set ADMIN_TOKEN)")
+			return
+		}
+		token := extractToken(c.Request)
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			respondError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "unauthorized")
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListConnections lists the live WebSocket connections with their send buffer usage
+func (h *Handler) ListConnections(c *gin.Context) {
+	c.JSON(http.StatusOK, h.hub.GetConnections())
+}
+
+// CloseConnection forcibly closes a device's WebSocket connection.
+// The device is unregistered as for any other disconnect and may reconnect.
+func (h *Handler) CloseConnection(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	if err := h.hub.CloseConnection(deviceID); err != nil {
+		var notConnected *ws.DeviceNotConnectedError
+		if errors.As(err, &notConnected) {
+			respondError(c, http.StatusNotFound, models.ErrorCodeDeviceOffline, err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	h.log().Warn("connection closed by admin", logging.KeyDeviceID, deviceID, "remote_addr", c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"message": "connection closed", "deviceId": deviceID})
+}
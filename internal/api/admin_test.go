@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func newAdminTestRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/api/admin", h.requireAdmin())
+	admin.GET("/connections", h.ListConnections)
+	admin.DELETE("/connections/:deviceId", h.CloseConnection)
+	return r
+}
+
+func doAdminRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminEndpointsRequireToken(t *testing.T) {
+	hub := ws.NewHub()
+
+	disabled := newAdminTestRouter(&Handler{hub: hub})
+	if w := doAdminRequest(disabled, http.MethodGet, "/api/admin/connections", "anything"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without ADMIN_TOKEN: status = %d, expected 503", w.Code)
+	}
+
+	r := newAdminTestRouter(&Handler{hub: hub, adminToken: "s3cret"})
+	for _, token := range []string{"", "wrong"} {
+		if w := doAdminRequest(r, http.MethodGet, "/api/admin/connections", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, expected 401", token, w.Code)
+		}
+		if w := doAdminRequest(r, http.MethodDelete, "/api/admin/connections/watch-001", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: DELETE status = %d, expected 401", token, w.Code)
+		}
+	}
+}
+
+func TestListConnections(t *testing.T) {
+	hub := ws.NewHub()
+	watch := &ws.Client{DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, LastRTT: 12, Send: make(chan []byte, 4)}
+	watch.Send <- []byte("queued")
+	hub.Clients["watch-001"] = watch
+	hub.Clients["psg-001"] = &ws.Client{DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+
+	r := newAdminTestRouter(&Handler{hub: hub, adminToken: "s3cret"})
+	w := doAdminRequest(r, http.MethodGet, "/api/admin/connections", "s3cret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+
+	var connections []models.ConnectionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &connections); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(connections) != 2 || connections[0].DeviceID != "psg-001" || connections[1].DeviceID != "watch-001" {
+		t.Fatalf("connections = %+v, expected psg-001 and watch-001 in order", connections)
+	}
+	if c := connections[1]; c.LastRTT != 12 || c.SendBufferDepth != 1 || c.SendBufferSize != 4 {
+		t.Errorf("watch-001 = %+v, expected lastRtt 12 and 1 of 4 buffered messages", c)
+	}
+
+	if w := doAdminRequest(r, http.MethodDelete, "/api/admin/connections/mobile-001", "s3cret"); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE unknown device: status = %d, expected 404", w.Code)
+	}
+}
+
+func TestCloseConnection(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	bus := events.NewEventBus()
+	hub := ws.NewHub()
+	hub.SetEventBus(bus)
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+	_, eventCh := bus.Subscribe()
+
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, &config.Config{AdminToken: "s3cret"}, repo)
+	r := newAdminTestRouter(h)
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?deviceId=watch-001&deviceType=WATCH"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if event := <-eventCh; event.Type != models.EventTypeDeviceConnected {
+		t.Fatalf("event = %s, expected DEVICE_CONNECTED", event.Type)
+	}
+
+	if w := doAdminRequest(r, http.MethodDelete, "/api/admin/connections/watch-001", "s3cret"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case event := <-eventCh:
+		data, _ := event.Data.(*models.DeviceConnectionEvent)
+		if event.Type != models.EventTypeDeviceDisconnected || data == nil || data.Reason != models.DisconnectReasonAdminClosed {
+			t.Errorf("event = %s %+v, expected DEVICE_DISCONNECTED with reason %s", event.Type, event.Data, models.DisconnectReasonAdminClosed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection was not unregistered")
+	}
+	if connections := hub.GetConnections(); len(connections) != 0 {
+		t.Errorf("connections after close = %+v, expected none", connections)
+	}
+}
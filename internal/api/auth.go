@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidToken is returned by token validators when a token is missing or not accepted
+var ErrInvalidToken = errors.New("invalid or missing token")
+
+// TokenValidator authenticates the token a device presents on the WebSocket handshake.
+// It returns the authenticated identity that is stored on the client.
+type TokenValidator interface {
+	ValidateToken(token, deviceID string) (identity string, err error)
+}
+
+// SharedSecretValidator accepts devices presenting a single shared secret.
+// The authenticated identity is the device ID.
+type SharedSecretValidator struct {
+	Secret string
+}
+
+// NewSharedSecretValidator creates a validator for the given shared secret
+func NewSharedSecretValidator(secret string) *SharedSecretValidator {
+	return &SharedSecretValidator{Secret: secret}
+}
+
+func (v *SharedSecretValidator) ValidateToken(token, deviceID string) (string, error) {
+	if token == "" || v.Secret == "" {
+		return "", ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(v.Secret)) != 1 {
+		return "", ErrInvalidToken
+	}
+	return deviceID, nil
+}
+
+// extractToken returns the token from the "token" query param or an "Authorization: Bearer" header
+func extractToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return ""
+}
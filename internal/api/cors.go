@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"time-sync-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OriginPolicy decides which cross-origin browsers may call the REST API and open WebSockets.
+// A nil policy allows every origin (local development).
+type OriginPolicy struct {
+	allowed map[string]bool // Normalized origins, e.g. "https://dashboard.example.com"
+}
+
+// NewOriginPolicy creates a policy allowing only the given origins.
+// Returns nil (allow all) if the list is empty.
+func NewOriginPolicy(origins []string) *OriginPolicy {
+	if len(origins) == 0 {
+		return nil
+	}
+	p := &OriginPolicy{allowed: make(map[string]bool, len(origins))}
+	for _, origin := range origins {
+		p.allowed[normalizeOrigin(origin)] = true
+	}
+	return p
+}
+
+// normalizeOrigin lowercases an origin and drops a trailing slash, so configured values match the Origin header
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// Allows reports whether a request from origin served at host is permitted.
+// Requests without an Origin header (non-browser clients) and same-origin requests are always allowed.
+func (p *OriginPolicy) Allows(origin, host string) bool {
+	if p == nil || origin == "" {
+		return true
+	}
+	if p.allowed[normalizeOrigin(origin)] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// CheckOrigin is the WebSocket upgrader's origin check
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	return p.Allows(r.Header.Get("Origin"), r.Host)
+}
+
+// corsMiddleware answers CORS preflights and rejects cross-origin requests the policy does not allow
+func (h *Handler) corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !h.origins.Allows(origin, c.Request.Host) {
+			respondError(c, http.StatusForbidden, models.ErrorCodeForbidden, "origin not allowed")
+			return
+		}
+
+		if h.origins == nil {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
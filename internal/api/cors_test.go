@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCORSTestRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(h.corsMiddleware())
+	r.GET("/api/devices", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return r
+}
+
+func doCORSRequest(r *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://sync.example.com/api/devices", nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSRestrictsOrigins(t *testing.T) {
+	r := newCORSTestRouter(&Handler{origins: NewOriginPolicy([]string{"https://dashboard.example.com/"})})
+
+	w := doCORSRequest(r, http.MethodGet, "https://dashboard.example.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://dashboard.example.com" {
+		t.Errorf("allowed origin: status = %d, Access-Control-Allow-Origin = %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	if w := doCORSRequest(r, http.MethodOptions, "https://dashboard.example.com"); w.Code != http.StatusNoContent {
+		t.Errorf("allowed preflight status = %d, expected 204", w.Code)
+	}
+	if w := doCORSRequest(r, http.MethodOptions, "https://evil.example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("denied preflight status = %d, expected 403", w.Code)
+	}
+	if w := doCORSRequest(r, http.MethodGet, "https://evil.example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("denied origin status = %d, expected 403", w.Code)
+	}
+
+	// Same-origin browser requests and non-browser clients are not cross-origin
+	if w := doCORSRequest(r, http.MethodGet, "http://sync.example.com"); w.Code != http.StatusOK {
+		t.Errorf("same-origin status = %d, expected 200", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/devices", ""); w.Code != http.StatusOK {
+		t.Errorf("request without Origin status = %d, expected 200", w.Code)
+	}
+}
+
+func TestCORSAllowsAllWithoutOrigins(t *testing.T) {
+	r := newCORSTestRouter(&Handler{origins: NewOriginPolicy(nil)})
+
+	w := doCORSRequest(r, http.MethodGet, "https://anything.example.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("status = %d, Access-Control-Allow-Origin = %q, expected 200 and *", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestOriginPolicyCheckOrigin(t *testing.T) {
+	policy := NewOriginPolicy([]string{"https://dashboard.example.com"})
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://dashboard.example.com", true},
+		{"HTTPS://Dashboard.Example.com", true},
+		{"https://evil.example.com", false},
+		{"http://sync.example.com", true}, // same origin
+		{"", true},                        // device firmware sends no Origin
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://sync.example.com/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := policy.CheckOrigin(req); got != tt.expected {
+			t.Errorf("CheckOrigin(%q) = %v, expected %v", tt.origin, got, tt.expected)
+		}
+	}
+}
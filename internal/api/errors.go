@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation failures by JSON field name rather than Go struct field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				name = strings.SplitN(field.Tag.Get("form"), ",", 2)[0]
+			}
+			return name
+		})
+	}
+}
+
+// respondError aborts the request with status and an APIError body
+func respondError(c *gin.Context, status int, code models.ErrorCode, message string) {
+	respondErrorDetails(c, status, code, message, nil)
+}
+
+// respondErrorDetails is respondError with a details payload, e.g. the invalid fields of a request
+func respondErrorDetails(c *gin.Context, status int, code models.ErrorCode, message string, details interface{}) {
+	c.AbortWithStatusJSON(status, models.APIError{
+		Code:    code,
+		Message: message,
+		Details: details,
+	})
+}
+
+// respondBindError answers a failed ShouldBindJSON/ShouldBindQuery with 400 VALIDATION_FAILED,
+// listing the invalid fields instead of gin's raw binding message
+func respondBindError(c *gin.Context, err error) {
+	var (
+		validationErrs validator.ValidationErrors
+		syntaxErr      *json.SyntaxError
+		typeErr        *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]models.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, models.FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		respondErrorDetails(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "request validation failed", fields)
+	case errors.As(err, &typeErr):
+		respondErrorDetails(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "request validation failed", []models.FieldError{
+			{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", typeErr.Type)},
+		})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "malformed JSON body")
+	default:
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid request")
+	}
+}
+
+// validationMessage describes a failed validator tag in plain words
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+// respondSyncError answers a failed sync request with its HTTP status and error code
+func respondSyncError(c *gin.Context, err error) {
+	status, code := syncError(err)
+	respondError(c, status, code, err.Error())
+}
+
+// syncError maps a failed sync request to its HTTP status and error code, so clients can tell
+// a missing pairing (404) from an offline device or a disabled pairing (409). A device that is within its reconnect
+// grace period, or a server with too many pending requests, is reported as 503 so clients can
+// retry shortly.
+func syncError(err error) (int, models.ErrorCode) {
+	var (
+		pairingNotFound         *ws.PairingNotFoundError
+		notConnected            *ws.DeviceNotConnectedError
+		temporarilyDisconnected *ws.DeviceTemporarilyDisconnectedError
+		serverBusy              *ws.ServerBusyError
+		pairingDisabled         *service.PairingDisabledError
+	)
+	switch {
+	case errors.As(err, &pairingNotFound):
+		return http.StatusNotFound, models.ErrorCodePairingNotFound
+	case errors.As(err, &pairingDisabled):
+		return http.StatusConflict, models.ErrorCodePairingDisabled
+	case errors.As(err, &serverBusy):
+		return http.StatusServiceUnavailable, models.ErrorCodeServerBusy
+	case errors.As(err, &temporarilyDisconnected):
+		return http.StatusServiceUnavailable, models.ErrorCodeDeviceTemporarilyDisconnected
+	case errors.As(err, &notConnected):
+		return http.StatusConflict, models.ErrorCodeDeviceOffline
+	case errors.Is(err, context.Canceled):
+		return http.StatusConflict, models.ErrorCodeSyncCancelled
+	}
+	return http.StatusBadRequest, models.ErrorCodeSyncFailed
+}
+
+// pairingErrorCode picks the error code for a pairing the hub refused to create
+func pairingErrorCode(err error) models.ErrorCode {
+	var notConnected *ws.DeviceNotConnectedError
+	if errors.As(err, &notConnected) {
+		return models.ErrorCodeDeviceOffline
+	}
+	return models.ErrorCodeValidationFailed
+}
+
+// notFoundCode picks the error code for a repository lookup that failed with err
+func notFoundCode(err error) models.ErrorCode {
+	switch {
+	case errors.Is(err, repository.ErrPairingNotFound):
+		return models.ErrorCodePairingNotFound
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return models.ErrorCodeRecordNotFound
+	case errors.Is(err, repository.ErrAggregationNotFound):
+		return models.ErrorCodeAggregationNotFound
+	}
+	return models.ErrorCodeNotFound
+}
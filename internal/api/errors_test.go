@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindErrorsReturnValidationFailed(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/pairings", h.CreatePairing)
+	r.POST("/api/group-pairings", h.CreateGroupPairing)
+
+	tests := []struct {
+		path, body string
+		fields     []string
+	}{
+		{"/api/pairings", `{"device1Id": "psg-001"}`, []string{"device2Id"}},
+		{"/api/pairings", `{}`, []string{"device1Id", "device2Id"}},
+		{"/api/group-pairings", `{"deviceIds": ["psg-001"]}`, []string{"deviceIds"}},
+		{"/api/pairings", `{"device1Id": 1, "device2Id": "psg-001"}`, []string{"device1Id"}},
+		{"/api/pairings", `{"device1Id": `, nil},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodPost, tt.path, tt.body)
+		var resp struct {
+			models.APIError
+			Details []models.FieldError `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("POST %s %s: failed to decode response: %v", tt.path, tt.body, err)
+		}
+		if w.Code != http.StatusBadRequest || resp.Code != models.ErrorCodeValidationFailed || resp.Message == "" {
+			t.Errorf("POST %s %s = %d %q %q, expected 400 VALIDATION_FAILED", tt.path, tt.body, w.Code, resp.Code, resp.Message)
+			continue
+		}
+		if len(resp.Details) != len(tt.fields) {
+			t.Errorf("POST %s %s details = %+v, expected fields %v", tt.path, tt.body, resp.Details, tt.fields)
+			continue
+		}
+		for i, field := range tt.fields {
+			if resp.Details[i].Field != field || resp.Details[i].Message == "" {
+				t.Errorf("POST %s %s details[%d] = %+v, expected field %q", tt.path, tt.body, i, resp.Details[i], field)
+			}
+		}
+	}
+}
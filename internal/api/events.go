@@ -0,0 +1,94 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write an event to a dashboard
+	eventsWriteWait = 10 * time.Second
+
+	// Dashboards must answer protocol pings within this period
+	eventsPongWait = 60 * time.Second
+
+	// Protocol ping period (must be less than eventsPongWait)
+	eventsPingPeriod = (eventsPongWait * 9) / 10
+)
+
+// SetEventBus enables GET /ws/events
+func (h *Handler) SetEventBus(bus *events.EventBus) {
+	h.eventBus = bus
+}
+
+// HandleEventsWebSocket streams dashboard events (new aggregated results, auto-sync state changes,
+// device connects and disconnects)
+// to the connection until it is closed. Messages from the client are ignored.
+func (h *Handler) HandleEventsWebSocket(c *gin.Context) {
+	if h.eventBus == nil {
+		respondError(c, http.StatusServiceUnavailable, models.ErrorCodeUnavailable, "event stream is not enabled")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade events connection: %v", err)
+		return
+	}
+
+	subscriberID, eventCh := h.eventBus.Subscribe()
+	log.Printf("Events subscriber %d connected from %s", subscriberID, c.ClientIP())
+
+	// Read side: only needed to process control frames and notice the disconnect
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadDeadline(time.Now().Add(eventsPongWait))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(eventsPongWait))
+			return nil
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(eventsPingPeriod)
+	defer func() {
+		pingTicker.Stop()
+		h.eventBus.Unsubscribe(subscriberID)
+		conn.Close()
+		log.Printf("Events subscriber %d disconnected", subscriberID)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case event, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+
+		case <-pingTicker.C:
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
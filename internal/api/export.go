@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rows written between flushes of the CSV stream
+const exportFlushEvery = 500
+
+var syncRecordCSVHeader = []string{
+	"id", "device1_id", "device1_type", "device1_timestamp",
+	"device2_id", "device2_type", "device2_timestamp",
+	"server_request_time", "server_response_time",
+	"device1_rtt", "device2_rtt", "time_difference",
+	"status", "error_message", "created_at",
+}
+
+var aggregatedResultCSVHeader = []string{
+	"aggregation_id", "pairing_id", "reference_device_id", "member_device_id",
+	"best_offset", "median_offset", "mean_offset", "weighted_offset", "smoothed_offset", "min_rtt_offset", "offset_std_dev",
+	"min_rtt", "max_rtt", "mean_rtt", "rtt_p50", "rtt_p90", "rtt_p99", "confidence", "jitter", "grade",
+	"total_samples", "valid_samples", "outlier_count", "created_at",
+}
+
+// ExportSyncRecords streams sync records as CSV
+// Filters: deviceId, startTime, endTime (RFC3339), combined with AND
+func (h *Handler) ExportSyncRecords(c *gin.Context) {
+	filter, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+	filter.DeviceID = c.Query("deviceId")
+
+	w := startCSVExport(c, "sync-records")
+	if err := w.Write(syncRecordCSVHeader); err != nil {
+		log.Printf("CSV export of sync records aborted: %v", err)
+		return
+	}
+
+	count := 0
+	err := h.syncService.ExportSyncRecords(filter, func(record *models.TimeSyncRecord) error {
+		row := []string{
+			strconv.FormatInt(record.ID, 10),
+			record.Device1ID,
+			string(record.Device1Type),
+			formatNullableInt(record.Device1Timestamp),
+			record.Device2ID,
+			string(record.Device2Type),
+			formatNullableInt(record.Device2Timestamp),
+			strconv.FormatInt(record.ServerRequestTime, 10),
+			formatNullableInt(record.ServerResponseTime),
+			formatNullableInt(record.Device1RTT),
+			formatNullableInt(record.Device2RTT),
+			formatNullableInt(record.TimeDifference),
+			string(record.Status),
+			formatNullableString(record.ErrorMessage),
+			strconv.FormatInt(record.CreatedAt, 10),
+		}
+		return writeCSVRow(w, row, &count)
+	})
+	finishCSVExport(w, "sync records", count, err)
+}
+
+// ExportAggregatedResults streams aggregated results as CSV (without individual measurements)
+// Filters: pairingId, startTime, endTime (RFC3339), combined with AND
+func (h *Handler) ExportAggregatedResults(c *gin.Context) {
+	filter, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+	filter.PairingID = c.Query("pairingId")
+
+	w := startCSVExport(c, "aggregated-results")
+	if err := w.Write(aggregatedResultCSVHeader); err != nil {
+		log.Printf("CSV export of aggregated results aborted: %v", err)
+		return
+	}
+
+	count := 0
+	err := h.syncService.ExportAggregatedResults(filter, func(result *models.AggregatedSyncResult) error {
+		row := []string{
+			result.AggregationID,
+			result.PairingID,
+			result.ReferenceDeviceID,
+			result.MemberDeviceID,
+			strconv.FormatInt(result.BestOffset, 10),
+			strconv.FormatInt(result.MedianOffset, 10),
+			strconv.FormatFloat(result.MeanOffset, 'f', -1, 64),
+			strconv.FormatFloat(result.WeightedOffset, 'f', -1, 64),
+			formatNullableFloat(result.SmoothedOffset),
+			strconv.FormatInt(result.MinRTTOffset, 10),
+			strconv.FormatFloat(result.OffsetStdDev, 'f', -1, 64),
+			strconv.FormatInt(result.MinRTT, 10),
+			strconv.FormatInt(result.MaxRTT, 10),
+			strconv.FormatFloat(result.MeanRTT, 'f', -1, 64),
+			strconv.FormatInt(result.RTTP50, 10),
+			strconv.FormatInt(result.RTTP90, 10),
+			strconv.FormatInt(result.RTTP99, 10),
+			strconv.FormatFloat(result.Confidence, 'f', -1, 64),
+			strconv.FormatFloat(result.Jitter, 'f', -1, 64),
+			result.Grade,
+			strconv.Itoa(result.TotalSamples),
+			strconv.Itoa(result.ValidSamples),
+			strconv.Itoa(result.OutlierCount),
+			strconv.FormatInt(result.CreatedAt, 10),
+		}
+		return writeCSVRow(w, row, &count)
+	})
+	finishCSVExport(w, "aggregated results", count, err)
+}
+
+// ExportPairing streams a pairing's configuration, aggregated results and their measurements
+// as one JSON bundle for archiving:
+// {"schema_version": 1, "exported_at": ..., "pairing": {...}, "offset_override": {...}, "aggregations": [...]}
+func (h *Handler) ExportPairing(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	bundle, err := h.syncService.GetPairingExport(pairingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPairingNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+	header, err := json.Marshal(bundle)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("pairing-%s-%s.json", pairingID, bundle.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// The header object is reopened to append the aggregations one at a time
+	w := c.Writer
+	count := 0
+	_, err = w.Write(append(header[:len(header)-1], `,"aggregations":[`...))
+	if err == nil {
+		err = h.syncService.ExportPairingAggregations(pairingID, func(result *models.AggregatedSyncResult) error {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				data = append([]byte{','}, data...)
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			count++
+			w.Flush()
+			return nil
+		})
+	}
+	if err == nil {
+		_, err = w.Write([]byte("]}\n"))
+	}
+	if err != nil {
+		log.Printf("Export of pairing %s aborted after %d aggregations: %v", pairingID, count, err)
+	}
+}
+
+// parseExportFilter parses the optional startTime/endTime query parameters.
+// It writes a 400 response and returns false if either is malformed.
+func parseExportFilter(c *gin.Context) (models.ExportFilter, bool) {
+	var filter models.ExportFilter
+
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid time format, use RFC3339")
+			return filter, false
+		}
+		filter.StartTime = &startTime
+	}
+
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid time format, use RFC3339")
+			return filter, false
+		}
+		filter.EndTime = &endTime
+	}
+
+	return filter, true
+}
+
+// startCSVExport writes the CSV response headers and returns a writer on the response body
+func startCSVExport(c *gin.Context, name string) *csv.Writer {
+	filename := fmt.Sprintf("%s-%s.csv", name, time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	return csv.NewWriter(c.Writer)
+}
+
+// writeCSVRow writes a row and flushes the stream every exportFlushEvery rows
+func writeCSVRow(w *csv.Writer, row []string, count *int) error {
+	if err := w.Write(row); err != nil {
+		return err
+	}
+
+	*count++
+	if *count%exportFlushEvery == 0 {
+		w.Flush()
+		return w.Error()
+	}
+	return nil
+}
+
+// finishCSVExport flushes the remaining rows. The status code is already sent,
+// so errors during streaming can only be logged.
+func finishCSVExport(w *csv.Writer, what string, count int, err error) {
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		log.Printf("CSV export of %s aborted after %d rows: %v", what, count, err)
+	}
+}
+
+func formatNullableInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func formatNullableFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func formatNullableString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -1,13 +1,22 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"time-sync-server/config"
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/logging"
 	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
 	"time-sync-server/internal/service"
 	ws "time-sync-server/internal/websocket"
 
@@ -15,64 +24,130 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for development
-		// In production, you should restrict this
-		return true
-	},
-}
-
 type Handler struct {
 	syncService     *service.SyncService
 	autoSyncMonitor *service.AutoSyncMonitor
 	hub             *ws.Hub
 	config          *config.Config
 	repository      service.Repository
+	tokenValidator  TokenValidator   // nil when WebSocket authentication is disabled
+	adminToken      string           // Bearer token of the admin endpoints, empty disables them
+	eventBus        *events.EventBus // nil until SetEventBus; /ws/events is unavailable without it
+	pairingLimiter  *RateLimiter     // nil when per-pairing sync rate limiting is disabled
+	ipLimiter       *RateLimiter     // nil when per-IP sync rate limiting is disabled
+	logger          *slog.Logger     // nil = slog.Default()
+	startedAt       time.Time        // Reported as uptime by /health
+	origins         *OriginPolicy    // nil allows every origin (ALLOWED_ORIGINS unset)
+	upgrader        websocket.Upgrader
+}
+
+// defaultWSBufferSize is the read and write buffer size of WebSocket connections when none is configured
+const defaultWSBufferSize = 1024
+
+// bufferSizeOrDefault returns size, or defaultWSBufferSize if it is not set
+func bufferSizeOrDefault(size int) int {
+	if size <= 0 {
+		return defaultWSBufferSize
+	}
+	return size
 }
 
 func NewHandler(syncService *service.SyncService, autoSyncMonitor *service.AutoSyncMonitor, hub *ws.Hub, cfg *config.Config, repo service.Repository) *Handler {
-	return &Handler{
+	h := &Handler{
 		syncService:     syncService,
 		autoSyncMonitor: autoSyncMonitor,
 		hub:             hub,
 		config:          cfg,
 		repository:      repo,
+		pairingLimiter:  NewRateLimiter(float64(cfg.SyncRateLimitPerMin), cfg.SyncRateLimitBurst),
+		ipLimiter:       NewRateLimiter(float64(cfg.SyncIPRateLimitPerMin), cfg.SyncIPRateLimitBurst),
+		adminToken:      cfg.AdminToken,
+		startedAt:       time.Now(),
+	}
+
+	h.origins = NewOriginPolicy(cfg.AllowedOrigins)
+	if h.origins == nil {
+		log.Printf("Warning: ALLOWED_ORIGINS is not set, all cross-origin requests are allowed")
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  bufferSizeOrDefault(cfg.WSReadBufferSize),
+		WriteBufferSize: bufferSizeOrDefault(cfg.WSWriteBufferSize),
+		CheckOrigin:     h.origins.CheckOrigin,
+	}
+
+	if cfg.WSAuthEnabled {
+		h.tokenValidator = NewSharedSecretValidator(cfg.WSAuthSecret)
+	} else {
+		log.Printf("Warning: WebSocket authentication is disabled")
+	}
+
+	return h
+}
+
+// SetTokenValidator replaces the WebSocket token validator (e.g. with a per-device token store).
+// Passing nil disables authentication.
+func (h *Handler) SetTokenValidator(validator TokenValidator) {
+	h.tokenValidator = validator
+}
+
+// SetLogger sets the structured logger. nil uses slog.Default().
+func (h *Handler) SetLogger(logger *slog.Logger) {
+	h.logger = logger
+}
+
+func (h *Handler) log() *slog.Logger {
+	if h.logger != nil {
+		return h.logger
 	}
+	return slog.Default()
 }
 
 // WebSocket Handler
 func (h *Handler) HandleWebSocket(c *gin.Context) {
 	deviceID := c.Query("deviceId")
 	deviceTypeStr := c.Query("deviceType")
+	logger := h.log().With(logging.KeyDeviceID, deviceID, "device_type", deviceTypeStr, "remote_addr", c.ClientIP())
 
 	if deviceID == "" || deviceTypeStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "deviceId and deviceType are required"})
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "deviceId and deviceType are required")
 		return
 	}
 
-	var deviceType models.DeviceType
-	switch deviceTypeStr {
-	case "PSG":
-		deviceType = models.DeviceTypePSG
-	case "WATCH":
-		deviceType = models.DeviceTypeWatch
-	case "MOBILE":
-		deviceType = models.DeviceTypeMobile
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deviceType, must be PSG or WATCH"})
+	deviceType := models.DeviceType(deviceTypeStr)
+	if !deviceType.Valid() {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid deviceType, must be PSG, WATCH or MOBILE")
+		return
+	}
+
+	model := c.Query("model")
+	firmware := c.Query("firmware")
+	if len(model) > maxDeviceMetadataLength || len(firmware) > maxDeviceMetadataLength {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, fmt.Sprintf("model and firmware must be at most %d characters", maxDeviceMetadataLength))
 		return
 	}
 
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	// Authenticate before upgrading the connection
+	identity := ""
+	if h.tokenValidator != nil {
+		var err error
+		identity, err = h.tokenValidator.ValidateToken(extractToken(c.Request), deviceID)
+		if err != nil {
+			logger.Warn("websocket authentication failed", "error", err)
+			respondError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "unauthorized")
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
+		logger.Warn("failed to upgrade websocket connection", "error", err)
 		return
 	}
+	logger.Info("websocket connection established")
 
-	client := ws.NewClient(h.hub, conn, deviceID, deviceType)
+	client := ws.NewClient(h.hub, conn, deviceID, deviceType, int64(h.config.MaxMessageSize), h.config.SendBufferSize)
+	client.Identity = identity
+	client.Model, client.Firmware = h.saveDeviceInfo(deviceID, deviceType, model, firmware, client.ConnectedAt, logger)
 	h.hub.Register <- client
 
 	// Start client pumps in goroutines
@@ -80,6 +155,33 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 	go client.ReadPump()
 }
 
+// maxDeviceMetadataLength bounds the model and firmware strings a device can report on connect
+const maxDeviceMetadataLength = 128
+
+// saveDeviceInfo persists the metadata reported on connect and returns the stored model and firmware,
+// so a reconnect that omits them keeps the previously reported values.
+// Storage errors are logged and the reported values are used as-is.
+func (h *Handler) saveDeviceInfo(deviceID string, deviceType models.DeviceType, model, firmware string, seenAt time.Time, logger *slog.Logger) (string, string) {
+	info := &models.DeviceInfo{
+		DeviceID:   deviceID,
+		DeviceType: deviceType,
+		Model:      model,
+		Firmware:   firmware,
+		LastSeenAt: seenAt,
+	}
+	if err := h.repository.SaveDeviceInfo(info); err != nil {
+		logger.Error("failed to save device info", "error", err)
+		return model, firmware
+	}
+
+	stored, err := h.repository.GetDeviceInfo(deviceID)
+	if err != nil {
+		logger.Error("failed to load device info", "error", err)
+		return model, firmware
+	}
+	return stored.Model, stored.Firmware
+}
+
 // Device Handlers
 func (h *Handler) GetDevices(c *gin.Context) {
 	devices := h.syncService.GetConnectedDevices()
@@ -93,7 +195,7 @@ func (h *Handler) GetDeviceHealth(c *gin.Context) {
 		// Get health for specific device
 		health, err := h.hub.GetDeviceHealthByID(deviceID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondError(c, http.StatusNotFound, models.ErrorCodeDeviceOffline, err.Error())
 			return
 		}
 		c.JSON(http.StatusOK, health)
@@ -104,12 +206,47 @@ func (h *Handler) GetDeviceHealth(c *gin.Context) {
 	}
 }
 
+func (h *Handler) ProbeDeviceRTT(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	rtt, err := h.syncService.ProbeRTT(deviceID)
+	if err != nil {
+		var notConnected *ws.DeviceNotConnectedError
+		var timeout *ws.ProbeRTTTimeoutError
+		switch {
+		case errors.As(err, &notConnected):
+			respondError(c, http.StatusNotFound, models.ErrorCodeDeviceOffline, err.Error())
+		case errors.As(err, &timeout):
+			respondError(c, http.StatusGatewayTimeout, models.ErrorCodeDeviceTimeout, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RTTProbeResponse{
+		DeviceID: deviceID,
+		RTT:      rtt,
+	})
+}
+
 // Pairing Handlers
+// GetPairingOverviews lists all pairings with connection state, auto-sync status and last sync
+func (h *Handler) GetPairingOverviews(c *gin.Context) {
+	overviews, err := h.syncService.GetPairingOverviews(h.autoSyncMonitor)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, overviews)
+}
+
 func (h *Handler) GetPairings(c *gin.Context) {
 	// Query pairings from database (persistent storage)
 	persistentPairings, err := h.repository.GetAllPairings()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
@@ -130,63 +267,94 @@ func (h *Handler) GetPairings(c *gin.Context) {
 func (h *Handler) CreatePairing(c *gin.Context) {
 	var req models.CreatePairingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	// 1. The DB is authoritative: an existing pairing of the two devices (in either order) is returned as is
+	existing, err := h.syncService.RestoreSavedPairing(req.Device1ID, req.Device2ID)
+	if !errors.Is(err, repository.ErrPairingNotFound) {
+		h.respondExistingPairing(c, existing, err)
 		return
 	}
 
-	// 1. Create in-memory pairing in Hub
+	// 2. Create in-memory pairing in Hub
 	pairing, err := h.syncService.CreatePairing(req.Device1ID, req.Device2ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, pairingErrorCode(err), err.Error())
 		return
 	}
 
-	// Use request values if provided, otherwise use config defaults
-	intervalSec := h.config.AutoSyncIntervalSec
+	// Use request values if provided, otherwise the config defaults for the two device types
+	device1Type, _ := h.hub.GetDeviceType(pairing.Device1ID)
+	device2Type, _ := h.hub.GetDeviceType(pairing.Device2ID)
+	defaults := h.config.AutoSyncDefaultsFor(string(device1Type), string(device2Type))
+
+	intervalSec := defaults.IntervalSec
 	if req.AutoSyncIntervalSec != nil {
 		intervalSec = *req.AutoSyncIntervalSec
 	}
 
-	sampleCount := h.config.AutoSyncSampleCount
+	sampleCount := defaults.SampleCount
 	if req.AutoSyncSampleCount != nil {
 		sampleCount = *req.AutoSyncSampleCount
 	}
 
-	intervalMs := h.config.AutoSyncIntervalMs
+	intervalMs := defaults.IntervalMs
 	if req.AutoSyncIntervalMs != nil {
 		intervalMs = *req.AutoSyncIntervalMs
 	}
 
-	// 2. Save pairing to database for persistence
+	timeoutSec := defaults.TimeoutSec
+	if req.AutoSyncTimeoutSec != nil {
+		timeoutSec = *req.AutoSyncTimeoutSec
+	}
+
+	// 3. Save pairing to database for persistence
 	persistentPairing := &models.PersistentPairing{
 		PairingID:           pairing.PairingID,
 		Device1ID:           pairing.Device1ID,
 		Device2ID:           pairing.Device2ID,
 		CreatedAt:           pairing.CreatedAt,
+		Enabled:             true,
 		AutoSyncIntervalSec: &intervalSec,
 		AutoSyncSampleCount: &sampleCount,
 		AutoSyncIntervalMs:  &intervalMs,
+		AutoSyncTimeoutSec:  &timeoutSec,
 	}
 
 	if err := h.repository.SavePairing(persistentPairing); err != nil {
-		log.Printf("Failed to save pairing to DB: %v", err)
-		// Don't fail the request, in-memory pairing is already created
+		// Don't keep an in-memory pairing whose ID diverges from the DB
+		if deleteErr := h.syncService.DeletePairing(pairing.PairingID); deleteErr != nil {
+			log.Printf("Failed to roll back in-memory pairing %s: %v", pairing.PairingID, deleteErr)
+		}
+		// A concurrent request may have created the pairing in the meantime
+		if existing, lookupErr := h.syncService.RestoreSavedPairing(req.Device1ID, req.Device2ID); !errors.Is(lookupErr, repository.ErrPairingNotFound) {
+			h.respondExistingPairing(c, existing, lookupErr)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, fmt.Sprintf("failed to save pairing: %v", err))
+		return
 	}
 
-	// 3. Automatically start auto-sync with configuration
+	// 4. Automatically start auto-sync with configuration
 	autoSyncConfig := models.AutoSyncConfig{
-		PairingID:   pairing.PairingID,
-		IntervalSec: intervalSec,
-		SampleCount: sampleCount,
-		IntervalMs:  intervalMs,
+		PairingID:     pairing.PairingID,
+		IntervalSec:   intervalSec,
+		SampleCount:   sampleCount,
+		IntervalMs:    intervalMs,
+		TimeoutSec:    timeoutSec,
+		MinConfidence: h.config.AutoSyncMinConfidence,
+
+		InitialSyncDelaySec: h.config.AutoSyncInitialSyncDelaySec,
 	}
 
 	if err := h.autoSyncMonitor.StartAutoSync(autoSyncConfig); err != nil {
 		log.Printf("Warning: Failed to start auto-sync for pairing %s: %v", pairing.PairingID, err)
 		// Don't fail the pairing creation, just log the warning
 	} else {
-		log.Printf("Auto-sync automatically started for pairing %s (interval: %ds, samples: %d, interval_ms: %dms)",
-			pairing.PairingID, intervalSec, sampleCount, intervalMs)
+		log.Printf("Auto-sync automatically started for pairing %s (interval: %ds, samples: %d, interval_ms: %dms, timeout: %ds)",
+			pairing.PairingID, intervalSec, sampleCount, intervalMs, timeoutSec)
 	}
 
 	c.JSON(http.StatusCreated, models.CreatePairingResponse{
@@ -194,13 +362,31 @@ func (h *Handler) CreatePairing(c *gin.Context) {
 	})
 }
 
+// respondExistingPairing answers a pairing creation for two devices that are already paired with 200 and
+// the persisted pairing ID, or with the error of looking it up or restoring it in memory
+func (h *Handler) respondExistingPairing(c *gin.Context, existing *models.Pairing, err error) {
+	if err != nil {
+		var notConnected *ws.DeviceNotConnectedError
+		if errors.As(err, &notConnected) {
+			respondError(c, http.StatusBadRequest, pairingErrorCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CreatePairingResponse{
+		PairingID: existing.PairingID,
+	})
+}
+
 func (h *Handler) DeletePairing(c *gin.Context) {
 	pairingID := c.Param("pairingId")
 
 	// 1. Check if pairing exists in DB (source of truth)
 	_, err := h.repository.GetPairingByID(pairingID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "pairing not found"})
+		respondError(c, http.StatusNotFound, models.ErrorCodePairingNotFound, "pairing not found")
 		return
 	}
 
@@ -221,233 +407,1027 @@ func (h *Handler) DeletePairing(c *gin.Context) {
 	// 4. Delete from database (source of truth)
 	if err := h.repository.DeletePairing(pairingID); err != nil {
 		log.Printf("Failed to delete pairing from DB: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete pairing from database"})
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "failed to delete pairing from database")
 		return
 	}
 
+	// 5. Drop its offset override, if any
+	if err := h.syncService.DeleteOffsetOverride(pairingID); err != nil && !errors.Is(err, repository.ErrOffsetOverrideNotFound) {
+		log.Printf("Failed to delete offset override of pairing %s: %v", pairingID, err)
+	}
+
 	log.Printf("Pairing deleted: %s", pairingID)
 	c.JSON(http.StatusOK, gin.H{"message": "pairing deleted"})
 }
 
-// Sync Handlers
-func (h *Handler) RequestSync(c *gin.Context) {
-	pairingID := c.Param("pairingId")
-
-	record, err := h.syncService.RequestTimeSync(pairingID)
+// Group Pairing Handlers
+func (h *Handler) GetGroupPairings(c *gin.Context) {
+	// Query group pairings from database (persistent storage)
+	pairings, err := h.repository.GetAllGroupPairings()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SyncResponse{
-		Success: true,
-		Record:  record,
-	})
-}
+	if pairings == nil {
+		pairings = []*models.GroupPairing{}
+	}
 
-func (h *Handler) GetSyncRecord(c *gin.Context) {
-	recordIDStr := c.Param("recordId")
+	c.JSON(http.StatusOK, pairings)
+}
 
-	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record ID"})
+func (h *Handler) CreateGroupPairing(c *gin.Context) {
+	var req models.CreateGroupPairingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
-	record, err := h.syncService.GetSyncRecord(recordID)
+	// 1. Create in-memory group pairing in Hub
+	pairing, err := h.syncService.CreateGroupPairing(req.DeviceIDs, req.ReferenceDeviceID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, pairingErrorCode(err), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, record)
+	// 2. Save group pairing to database for persistence
+	if err := h.repository.SaveGroupPairing(pairing); err != nil {
+		log.Printf("Failed to save group pairing to DB: %v", err)
+		// Don't fail the request, in-memory pairing is already created
+	}
+
+	c.JSON(http.StatusCreated, pairing)
 }
 
-func (h *Handler) GetSyncRecords(c *gin.Context) {
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
-	deviceID := c.Query("deviceId")
-	startTimeStr := c.Query("startTime")
-	endTimeStr := c.Query("endTime")
+func (h *Handler) DeleteGroupPairing(c *gin.Context) {
+	pairingID := c.Param("pairingId")
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+	// 1. Check if group pairing exists in DB (source of truth)
+	if _, err := h.repository.GetGroupPairingByID(pairingID); err != nil {
+		respondError(c, http.StatusNotFound, models.ErrorCodePairingNotFound, "group pairing not found")
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset parameter"})
-		return
+	// 2. Delete from in-memory Hub (if exists, don't fail if not)
+	if err := h.syncService.DeleteGroupPairing(pairingID); err != nil {
+		log.Printf("Note: Group pairing not in memory (devices may be disconnected): %s", pairingID)
 	}
 
-	var records []*models.TimeSyncRecord
+	// 3. Delete from database (source of truth)
+	if err := h.repository.DeleteGroupPairing(pairingID); err != nil {
+		log.Printf("Failed to delete group pairing from DB: %v", err)
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "failed to delete group pairing from database")
+		return
+	}
 
-	// Filter by device ID if provided
-	if deviceID != "" {
-		records, err = h.syncService.GetSyncRecordsByDevice(deviceID, limit, offset)
-	} else if startTimeStr != "" && endTimeStr != "" {
-		// Filter by time range if provided
-		startTime, err1 := time.Parse(time.RFC3339, startTimeStr)
-		endTime, err2 := time.Parse(time.RFC3339, endTimeStr)
-
-		if err1 != nil || err2 != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time format, use RFC3339"})
-			return
-		}
+	log.Printf("Group pairing deleted: %s", pairingID)
+	c.JSON(http.StatusOK, gin.H{"message": "group pairing deleted"})
+}
 
-		records, err = h.syncService.GetSyncRecordsByTimeRange(startTime, endTime, limit, offset)
-	} else {
-		// Get all records
-		records, err = h.syncService.GetSyncRecords(limit, offset)
-	}
+func (h *Handler) RequestGroupSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
 
+	result, err := h.syncService.RequestGroupTimeSync(pairingID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondSyncError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, records)
+	c.JSON(http.StatusOK, models.GroupSyncResponse{
+		Success: true,
+		Result:  result,
+	})
 }
 
-// RequestMultiSync handles NTP-style multi-sampling sync request
-func (h *Handler) RequestMultiSync(c *gin.Context) {
+func (h *Handler) RequestGroupMultiSync(c *gin.Context) {
 	var req models.MultiSyncRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
 		return
 	}
 
-	result, err := h.syncService.RequestMultipleTimeSyncs(&req)
+	result, err := h.syncService.RequestGroupMultipleTimeSyncs(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.MultiSyncResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondSyncError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, models.MultiSyncResponse{
+	c.JSON(http.StatusOK, models.GroupMultiSyncResponse{
 		Success: true,
 		Result:  result,
 	})
 }
 
-// GetAggregatedResults retrieves aggregated sync results
-// Supports filtering by pairingId or time range (startTime, endTime)
-func (h *Handler) GetAggregatedResults(c *gin.Context) {
-	pairingID := c.Query("pairingId")
-	startTimeStr := c.Query("startTime")
-	endTimeStr := c.Query("endTime")
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
+// UpdateAutoAggregation enables or disables automatic aggregation of single-sync records for a pairing
+func (h *Handler) UpdateAutoAggregation(c *gin.Context) {
+	pairingID := c.Param("pairingId")
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+	var req models.AutoAggregationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset parameter"})
+	if (req.WindowSec != nil && *req.WindowSec <= 0) || (req.MinCount != nil && *req.MinCount <= 0) {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "window_sec and min_count must be positive")
 		return
 	}
 
-	var results []*models.AggregatedSyncResult
+	if err := h.repository.UpdatePairingAutoAggregation(pairingID, req.Enabled, req.WindowSec, req.MinCount); err != nil {
+		if errors.Is(err, repository.ErrPairingNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
 
-	// Filter by pairing ID if provided
-	if pairingID != "" {
-		results, err = h.syncService.GetAggregatedSyncResults(pairingID, limit, offset)
-	} else if startTimeStr != "" && endTimeStr != "" {
-		// Filter by time range if provided
-		startTime, err1 := time.Parse(time.RFC3339, startTimeStr)
-		endTime, err2 := time.Parse(time.RFC3339, endTimeStr)
-
-		if err1 != nil || err2 != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time format, use RFC3339"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-aggregation updated",
+		"pairing_id": pairingID,
+		"enabled":    req.Enabled,
+	})
+}
+
+// EnablePairing re-enables syncing for a disabled pairing and restarts its saved Auto-Sync
+// if both devices are connected
+func (h *Handler) EnablePairing(c *gin.Context) {
+	h.setPairingEnabled(c, true)
+}
+
+// DisablePairing stops syncing for a pairing without deleting its configuration or history.
+// Its Auto-Sync job is stopped and sync requests fail with PAIRING_DISABLED until it is enabled.
+func (h *Handler) DisablePairing(c *gin.Context) {
+	h.setPairingEnabled(c, false)
+}
+
+func (h *Handler) setPairingEnabled(c *gin.Context, enabled bool) {
+	pairingID := c.Param("pairingId")
+
+	pairing, err := h.syncService.SetPairingEnabled(pairingID, enabled)
+	if err != nil {
+		if errors.Is(err, repository.ErrPairingNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
 			return
 		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
 
-		results, err = h.syncService.GetAggregatedSyncResultsByTimeRange(startTime, endTime, limit, offset)
-	} else {
-		// Get all results if no filter is provided
-		results, err = h.syncService.GetAllAggregatedSyncResults(limit, offset)
+	message := "pairing disabled"
+	if enabled {
+		message = "pairing enabled"
+		if config, ok := service.PersistedAutoSyncConfig(pairing, h.config); ok && h.hub.IsPairingRestored(pairingID) && !h.autoSyncMonitor.HasJob(pairingID) {
+			if err := h.autoSyncMonitor.StartAutoSync(config); err != nil {
+				h.log().Warn("failed to restart auto-sync of enabled pairing", logging.KeyPairingID, pairingID, "error", err)
+			}
+		}
+	} else if h.autoSyncMonitor.HasJob(pairingID) {
+		if err := h.autoSyncMonitor.StopAutoSync(pairingID); err != nil {
+			h.log().Warn("failed to stop auto-sync of disabled pairing", logging.KeyPairingID, pairingID, "error", err)
+		}
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"message":    message,
+		"pairing_id": pairingID,
+		"enabled":    enabled,
+	})
+}
+
+// SetOffsetOverride pins a pairing's offset to a manually calibrated value until expiresAt.
+// While it is active, apply, timeseries and the latest aggregated result use the pinned offset.
+func (h *Handler) SetOffsetOverride(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	var req models.OffsetOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "expiresAt must be in the future")
+		return
+	}
+
+	override, err := h.syncService.SetOffsetOverride(pairingID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, repository.ErrPairingNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, results)
+	c.JSON(http.StatusOK, override)
 }
 
-// GetAggregatedResult retrieves a single aggregated sync result by ID
-func (h *Handler) GetAggregatedResult(c *gin.Context) {
-	aggregationID := c.Param("aggregationId")
+// GetOffsetOverride returns the pairing's active offset override
+func (h *Handler) GetOffsetOverride(c *gin.Context) {
+	pairingID := c.Param("pairingId")
 
-	result, err := h.syncService.GetAggregatedSyncResult(aggregationID)
+	override, err := h.syncService.GetActiveOffsetOverride(pairingID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+	if override == nil {
+		respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, "no active offset override for pairing "+pairingID)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, override)
 }
 
-// Health Check
-func (h *Handler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-		"time":   time.Now().Unix(),
+// DeleteOffsetOverride removes the pairing's offset override, so measured offsets are used again
+func (h *Handler) DeleteOffsetOverride(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	if err := h.syncService.DeleteOffsetOverride(pairingID); err != nil {
+		if errors.Is(err, repository.ErrOffsetOverrideNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "offset override deleted"})
+}
+
+// Sync Handlers
+func (h *Handler) RequestSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	record, err := h.syncService.RequestTimeSync(pairingID)
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SyncResponse{
+		Success: true,
+		Record:  record,
 	})
 }
 
-// Auto-Sync Handlers
+func (h *Handler) GetSyncRecord(c *gin.Context) {
+	recordIDStr := c.Param("recordId")
 
-// StartAutoSync starts automatic periodic synchronization for a pairing
-func (h *Handler) StartAutoSync(c *gin.Context) {
-	var req models.AutoSyncStartRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid record ID")
 		return
 	}
 
-	config := models.AutoSyncConfig{
-		PairingID:   req.PairingID,
-		IntervalSec: req.IntervalSec,
-		SampleCount: req.SampleCount,
-		IntervalMs:  req.IntervalMs,
+	record, err := h.syncService.GetSyncRecord(recordID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+		return
 	}
 
-	if err := h.autoSyncMonitor.StartAutoSync(config); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, record)
+}
+
+// GetSyncRecordAnalysis returns a record's timestamps, RTTs and the one-way delays and adjusted
+// offset the NTP selector derives from them
+func (h *Handler) GetSyncRecordAnalysis(c *gin.Context) {
+	recordID, err := strconv.ParseInt(c.Param("recordId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid record ID")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "auto-sync started",
-		"pairing_id": req.PairingID,
-	})
+	analysis, err := h.syncService.AnalyzeSyncRecord(recordID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
 }
 
-// StopAutoSync stops automatic synchronization for a pairing
-func (h *Handler) StopAutoSync(c *gin.Context) {
-	pairingID := c.Param("pairingId")
+// DeleteSyncRecord deletes a single sync record (and its links to aggregations)
+func (h *Handler) DeleteSyncRecord(c *gin.Context) {
+	recordIDStr := c.Param("recordId")
 
-	if err := h.autoSyncMonitor.StopAutoSync(pairingID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid record ID")
+		return
+	}
+
+	if err := h.syncService.DeleteSyncRecord(recordID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "auto-sync stopped",
+		"message":   "sync record deleted",
+		"record_id": recordID,
+	})
+}
+
+// GetSyncRecords retrieves individual sync records
+// Supports filtering by deviceId, status and time range (startTime, endTime), combined with AND,
+// and sorting with sortBy (createdAt, offset) and order (asc, desc)
+func (h *Handler) GetSyncRecords(c *gin.Context) {
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+	deviceID := c.Query("deviceId")
+	status := c.Query("status")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid limit parameter")
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid offset parameter")
+		return
+	}
+
+	timeRange, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+	filter := models.RecordFilter{
+		DeviceID:  deviceID,
+		Status:    models.SyncStatus(status),
+		StartTime: timeRange.StartTime,
+		EndTime:   timeRange.EndTime,
+	}
+	switch filter.Status {
+	case "", models.SyncStatusSuccess, models.SyncStatusPartial, models.SyncStatusFailed:
+	default:
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid status, must be SUCCESS, PARTIAL or FAILED")
+		return
+	}
+
+	// Cursor pagination (?cursor=, empty for the first page) returns a page with nextCursor
+	if token, ok := c.GetQuery("cursor"); ok {
+		h.getSyncRecordsPage(c, filter, token, limit)
+		return
+	}
+
+	if filter.Sort, ok = parseListSort(c, models.RecordSortKeys); !ok {
+		return
+	}
+
+	records, err := h.syncService.GetSyncRecordsFiltered(filter, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// getSyncRecordsPage responds with the page of records after the cursor token.
+// The order is fixed (newest first), so offset and sortBy cannot be combined with a cursor.
+func (h *Handler) getSyncRecordsPage(c *gin.Context, filter models.RecordFilter, token string, limit int) {
+	if c.Query("offset") != "" || c.Query("sortBy") != "" || c.Query("order") != "" {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "cursor cannot be combined with offset, sortBy or order")
+		return
+	}
+
+	var cursor *models.RecordCursor
+	if token != "" {
+		var err error
+		if cursor, err = models.ParseRecordCursor(token); err != nil {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+			return
+		}
+	}
+
+	page, err := h.syncService.GetSyncRecordsPage(filter, cursor, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// RequestMultiSync handles NTP-style multi-sampling sync request
+func (h *Handler) RequestMultiSync(c *gin.Context) {
+	var req models.MultiSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	h.runMultiSync(c, &req)
+}
+
+// RequestMultiSyncDryRun performs a multi-sync like RequestMultiSync but persists nothing
+func (h *Handler) RequestMultiSyncDryRun(c *gin.Context) {
+	var req models.MultiSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.DryRun = true
+
+	h.runMultiSync(c, &req)
+}
+
+// runMultiSync validates req, runs the multi-sync and writes the response
+func (h *Handler) runMultiSync(c *gin.Context, req *models.MultiSyncRequest) {
+	if err := req.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+		return
+	}
+
+	result, err := h.syncService.RequestMultipleTimeSyncs(c.Request.Context(), req)
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MultiSyncResponse{
+		Success: true,
+		DryRun:  req.DryRun,
+		Result:  result,
+	})
+}
+
+// GetAggregatedResults retrieves aggregated sync results
+// Supports filtering by pairingId, time range (startTime, endTime) and minConfidence, combined with AND,
+// and sorting with sortBy (createdAt, confidence, offset) and order (asc, desc)
+// parseConfidenceParam parses an optional confidence bound in [0, 1] from the query.
+// On an invalid value it responds with 400 and returns false.
+func parseConfidenceParam(c *gin.Context, name string) (*float64, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	confidence, err := strconv.ParseFloat(v, 64)
+	if err != nil || confidence < 0 || confidence > 1 {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, fmt.Sprintf("invalid %s, must be between 0 and 1", name))
+		return nil, false
+	}
+	return &confidence, true
+}
+
+func (h *Handler) GetAggregatedResults(c *gin.Context) {
+	pairingID := c.Query("pairingId")
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid limit parameter")
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid offset parameter")
+		return
+	}
+
+	timeRange, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+	filter := models.AggregatedResultFilter{
+		PairingID: pairingID,
+		StartTime: timeRange.StartTime,
+		EndTime:   timeRange.EndTime,
+	}
+	if filter.MinConfidence, ok = parseConfidenceParam(c, "minConfidence"); !ok {
+		return
+	}
+	if filter.MaxConfidence, ok = parseConfidenceParam(c, "maxConfidence"); !ok {
+		return
+	}
+	if filter.MinConfidence != nil && filter.MaxConfidence != nil && *filter.MinConfidence > *filter.MaxConfidence {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "minConfidence must not exceed maxConfidence")
+		return
+	}
+	if filter.Sort, ok = parseListSort(c, models.AggregatedSortKeys); !ok {
+		return
+	}
+
+	results, err := h.syncService.GetAggregatedSyncResultsFiltered(filter, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// parseListSort parses the optional sortBy/order query parameters against the allowed sort keys.
+// It writes a 400 response and returns false for an unknown key or order.
+func parseListSort(c *gin.Context, keys []string) (models.ListSort, bool) {
+	sort := models.ListSort{
+		By:    c.Query("sortBy"),
+		Order: models.SortOrder(strings.ToLower(c.Query("order"))),
+	}
+	if sort.By != "" && !slices.Contains(keys, sort.By) {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid sortBy, must be one of "+strings.Join(keys, ", "))
+		return sort, false
+	}
+	switch sort.Order {
+	case "", models.SortAsc, models.SortDesc:
+	default:
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid order, must be asc or desc")
+		return sort, false
+	}
+	return sort, true
+}
+
+// GetAggregatedResult retrieves a single aggregated sync result by ID
+func (h *Handler) GetAggregatedResult(c *gin.Context) {
+	aggregationID := c.Param("aggregationId")
+
+	result, err := h.syncService.GetAggregatedSyncResult(aggregationID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLatestAggregatedResult returns the most recent aggregated result of a pairing (its current offset)
+func (h *Handler) GetLatestAggregatedResult(c *gin.Context) {
+	pairingID := c.Query("pairingId")
+	if pairingID == "" {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "pairingId is required")
+		return
+	}
+
+	result, err := h.syncService.GetLatestAggregatedSyncResult(pairingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAggregationNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLatestAggregatedResults returns the most recent aggregated result of every pairing in one query,
+// e.g. for an overview of all current offsets. Optional pairingIds (comma separated) limits the pairings.
+func (h *Handler) GetLatestAggregatedResults(c *gin.Context) {
+	var pairingIDs []string
+	for _, id := range strings.Split(c.Query("pairingIds"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			pairingIDs = append(pairingIDs, id)
+		}
+	}
+
+	results, err := h.syncService.GetLatestAggregatedSyncResults(pairingIDs)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+	if results == nil {
+		results = []*models.AggregatedSyncResult{}
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// DeletePairingRecords purges all aggregated results of a pairing and the sync records of its devices,
+// keeping the pairing itself. Requires confirm=true to guard against accidental purges.
+func (h *Handler) DeletePairingRecords(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	if confirmed, err := strconv.ParseBool(c.Query("confirm")); err != nil || !confirmed {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed,
+			"deleting all records of a pairing requires confirm=true")
+		return
+	}
+
+	deletion, err := h.syncService.DeletePairingRecords(pairingID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	log.Printf("Records of pairing %s deleted: %d record(s), %d aggregation(s), %d link(s)",
+		pairingID, deletion.RecordsDeleted, deletion.AggregationsDeleted, deletion.LinksDeleted)
+	c.JSON(http.StatusOK, deletion)
+}
+
+// DeleteAggregatedResult deletes a (bad) aggregated result.
+// Query param deleteRecords=true also deletes the measurements it was computed from.
+func (h *Handler) DeleteAggregatedResult(c *gin.Context) {
+	aggregationID := c.Param("aggregationId")
+
+	deleteRecords := false
+	if v := c.Query("deleteRecords"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid deleteRecords, must be true or false")
+			return
+		}
+		deleteRecords = parsed
+	}
+
+	if err := h.syncService.DeleteAggregatedSyncResult(aggregationID, deleteRecords); err != nil {
+		if errors.Is(err, repository.ErrAggregationNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "aggregated result deleted",
+		"aggregation_id":  aggregationID,
+		"records_deleted": deleteRecords,
+	})
+}
+
+// CompareAggregations compares two aggregated results (query params a and b, B - A)
+// Returns 404 if either aggregation does not exist
+func (h *Handler) CompareAggregations(c *gin.Context) {
+	aggregationIDA, aggregationIDB := c.Query("a"), c.Query("b")
+	if aggregationIDA == "" || aggregationIDB == "" {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "both a and b aggregation IDs are required")
+		return
+	}
+
+	comparison, err := h.syncService.CompareAggregations(aggregationIDA, aggregationIDB)
+	if err != nil {
+		if errors.Is(err, repository.ErrAggregationNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// RecomputeAggregation re-runs the NTP selection over a stored aggregation's measurements.
+// The body is optional: {"filter": {...}} overrides the selection settings and {"save": true}
+// stores the result as a new aggregation.
+func (h *Handler) RecomputeAggregation(c *gin.Context) {
+	aggregationID := c.Param("aggregationId")
+
+	var req models.RecomputeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+	if req.Filter != nil {
+		if err := req.Filter.Validate(); err != nil {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+			return
+		}
+	}
+
+	result, err := h.syncService.RecomputeAggregation(aggregationID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrAggregationNotFound):
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+		case errors.Is(err, service.ErrNoStoredMeasurements):
+			respondError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		default:
+			// Typically too few samples left for the requested filter
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if result.Saved {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
+
+// ApplyOffset converts device timestamps to the reference device's clock using a stored aggregation
+func (h *Handler) ApplyOffset(c *gin.Context) {
+	var req models.ApplyOffsetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(req.Timestamps) > models.MaxApplyOffsetTimestamps {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, fmt.Sprintf("at most %d timestamps can be converted per request", models.MaxApplyOffsetTimestamps))
+		return
+	}
+
+	result, err := h.syncService.ApplyOffset(&req)
+	if err != nil {
+		if errors.Is(err, repository.ErrAggregationNotFound) {
+			respondError(c, http.StatusNotFound, notFoundCode(err), err.Error())
+			return
+		}
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetDeviceTypeStats returns sync reliability metrics grouped by device type
+func (h *Handler) GetDeviceTypeStats(c *gin.Context) {
+	stats, err := h.syncService.GetDeviceTypeStats()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	if stats == nil {
+		stats = []*models.DeviceTypeStats{}
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetDeviceSyncStats returns sync statistics over one device's records
+// Optional startTime/endTime (RFC3339) limit the records to a time range
+func (h *Handler) GetDeviceSyncStats(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	timeRange, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.syncService.GetDeviceSyncStats(deviceID, timeRange.StartTime, timeRange.EndTime)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetDeviceEvents returns a device's connect/disconnect events, newest first
+// Optional startTime/endTime (RFC3339) limit the events to a time range
+func (h *Handler) GetDeviceEvents(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	timeRange, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid limit parameter")
+		return
+	}
+
+	events, err := h.syncService.GetDeviceEvents(deviceID, timeRange.StartTime, timeRange.EndTime, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	if events == nil {
+		events = []*models.DeviceEvent{}
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetClockDrift estimates a pairing's clock drift from its recent aggregated results
+func (h *Handler) GetClockDrift(c *gin.Context) {
+	pairingID := c.Query("pairingId")
+	if pairingID == "" {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "pairingId is required")
+		return
+	}
+
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil || window <= 0 {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid window parameter, use a duration such as 24h")
+		return
+	}
+
+	estimate, err := h.syncService.EstimateClockDrift(pairingID, window)
+	if err != nil {
+		var insufficient *service.InsufficientDriftDataError
+		if errors.As(err, &insufficient) {
+			respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
+
+// minTimeSeriesBucket is the smallest bucket GetOffsetTimeSeries accepts
+const minTimeSeriesBucket = time.Second
+
+// GetOffsetTimeSeries returns a pairing's offset over time, downsampled into buckets for charting
+// Query params: pairingId (required), startTime, endTime (RFC3339, optional), bucket (duration, default 1h)
+func (h *Handler) GetOffsetTimeSeries(c *gin.Context) {
+	pairingID := c.Query("pairingId")
+	if pairingID == "" {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "pairingId is required")
+		return
+	}
+
+	bucket, err := time.ParseDuration(c.DefaultQuery("bucket", "1h"))
+	if err != nil || bucket < minTimeSeriesBucket {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "invalid bucket parameter, use a duration of at least 1s such as 1h")
+		return
+	}
+
+	timeRange, ok := parseExportFilter(c)
+	if !ok {
+		return
+	}
+	if timeRange.StartTime != nil && timeRange.EndTime != nil && timeRange.EndTime.Before(*timeRange.StartTime) {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, "endTime must not be before startTime")
+		return
+	}
+
+	series, err := h.syncService.GetOffsetTimeSeries(pairingID, timeRange.StartTime, timeRange.EndTime, bucket)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// Health Check
+// HealthCheck is a readiness probe: it pings the database and reports the server's load.
+// Returns 503 with status "unavailable" if the database cannot be reached.
+func (h *Handler) HealthCheck(c *gin.Context) {
+	status := http.StatusOK
+	body := gin.H{
+		"status":            "ok",
+		"time":              time.Now().Unix(),
+		"uptime_sec":        int64(time.Since(h.startedAt).Seconds()),
+		"database":          "ok",
+		"connected_devices": len(h.hub.GetConnectedDevices()),
+		"active_pairings":   len(h.hub.GetPairings()) + len(h.hub.GetGroupPairings()),
+		"auto_sync_running": h.autoSyncMonitor.RunningCount(),
+		"pending_requests":  h.hub.PendingRequestCount(),
+		"goroutines":        runtime.NumGoroutine(),
+	}
+
+	if err := h.repository.Ping(); err != nil {
+		h.log().Error("health check database ping failed", "error", err)
+		status = http.StatusServiceUnavailable
+		body["status"] = "unavailable"
+		body["database"] = err.Error()
+	}
+
+	c.JSON(status, body)
+}
+
+// Auto-Sync Handlers
+
+// StartAutoSync starts automatic periodic synchronization for a pairing
+func (h *Handler) StartAutoSync(c *gin.Context) {
+	var req models.AutoSyncStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if pairing, err := h.repository.GetPairingByID(req.PairingID); err == nil && !pairing.Enabled {
+		respondError(c, http.StatusConflict, models.ErrorCodePairingDisabled, "pairing disabled: "+req.PairingID)
+		return
+	}
+
+	config := models.AutoSyncConfig{
+		PairingID:     req.PairingID,
+		IntervalSec:   req.IntervalSec,
+		SampleCount:   req.SampleCount,
+		IntervalMs:    req.IntervalMs,
+		TimeoutSec:    req.TimeoutSec,
+		MinConfidence: h.config.AutoSyncMinConfidence,
+
+		InitialSyncDelaySec: h.config.AutoSyncInitialSyncDelaySec,
+	}
+	if req.MinConfidence != nil {
+		config.MinConfidence = *req.MinConfidence
+	}
+	if req.InitialSyncDelaySec != nil {
+		config.InitialSyncDelaySec = *req.InitialSyncDelaySec
+	}
+
+	if err := h.autoSyncMonitor.StartAutoSync(config); err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-sync started",
+		"pairing_id": req.PairingID,
+	})
+}
+
+// UpdateAutoSync reconfigures a running auto-sync job without restarting it
+func (h *Handler) UpdateAutoSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	var req models.AutoSyncUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+		return
+	}
+
+	job, err := h.autoSyncMonitor.UpdateAutoSync(pairingID, &req)
+	if err != nil {
+		if h.autoSyncMonitor.HasJob(pairingID) {
+			respondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
+			return
+		}
+		respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-sync updated",
+		"pairing_id": pairingID,
+		"config":     job.Config,
+	})
+}
+
+// StopAutoSync stops automatic synchronization for a pairing
+func (h *Handler) StopAutoSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	if err := h.autoSyncMonitor.StopAutoSync(pairingID); err != nil {
+		respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-sync stopped",
+		"pairing_id": pairingID,
+	})
+}
+
+// PauseAutoSync pauses automatic synchronization for a pairing without discarding its state
+func (h *Handler) PauseAutoSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	if err := h.autoSyncMonitor.PauseAutoSync(pairingID); err != nil {
+		if h.autoSyncMonitor.HasJob(pairingID) {
+			respondError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+			return
+		}
+		respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-sync paused",
+		"pairing_id": pairingID,
+	})
+}
+
+// ResumeAutoSync resumes a paused auto-sync job
+func (h *Handler) ResumeAutoSync(c *gin.Context) {
+	pairingID := c.Param("pairingId")
+
+	if err := h.autoSyncMonitor.ResumeAutoSync(pairingID); err != nil {
+		if h.autoSyncMonitor.HasJob(pairingID) {
+			respondError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+			return
+		}
+		respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "auto-sync resumed",
 		"pairing_id": pairingID,
 	})
 }
@@ -460,7 +1440,7 @@ func (h *Handler) GetAutoSyncStatus(c *gin.Context) {
 		// Get status for specific pairing
 		job, err := h.autoSyncMonitor.GetStatus(pairingID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
 			return
 		}
 		c.JSON(http.StatusOK, job)
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unreachableRepository is a repository whose database cannot be reached
+type unreachableRepository struct {
+	service.Repository
+}
+
+func (unreachableRepository) Ping() error {
+	return errors.New("connection refused")
+}
+
+func newHealthTestRouter(repo service.Repository) *gin.Engine {
+	h := &Handler{
+		hub:             ws.NewHub(),
+		autoSyncMonitor: service.NewAutoSyncMonitor(nil),
+		repository:      repo,
+		startedAt:       time.Now().Add(-time.Minute),
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", h.HealthCheck)
+	return r
+}
+
+func TestHealthCheckReportsReady(t *testing.T) {
+	r := newHealthTestRouter(repository.NewInMemoryRepository())
+
+	w := doRequest(r, http.MethodGet, "/health", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200", w.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body["status"] != "ok" || body["database"] != "ok" {
+		t.Errorf("unexpected body: %v", body)
+	}
+	if uptime, _ := body["uptime_sec"].(float64); uptime < 60 {
+		t.Errorf("uptime_sec = %v, expected at least 60", body["uptime_sec"])
+	}
+	for _, key := range []string{"connected_devices", "active_pairings", "auto_sync_running", "pending_requests", "goroutines"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("missing %q in %v", key, body)
+		}
+	}
+}
+
+func TestHealthCheckDatabaseDown(t *testing.T) {
+	r := newHealthTestRouter(unreachableRepository{})
+
+	w := doRequest(r, http.MethodGet, "/health", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, expected 503", w.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body["status"] != "unavailable" || body["database"] != "connection refused" {
+		t.Errorf("unexpected body: %v", body)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newListingTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	repo := repository.NewInMemoryRepository()
+	now := time.Now()
+	for i, confidence := range []float64{0.9, 0.5, 0.7} {
+		result := &models.AggregatedSyncResult{
+			AggregationID: []string{"agg-a", "agg-b", "agg-c"}[i],
+			PairingID:     "pair-123",
+			Confidence:    confidence,
+			CreatedAt:     now.Add(time.Duration(i) * time.Minute).UnixMilli(),
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/sync/records", h.GetSyncRecords)
+	r.GET("/api/sync/aggregated", h.GetAggregatedResults)
+	r.GET("/api/sync/aggregated/latest", h.GetLatestAggregatedResult)
+	r.GET("/api/sync/aggregated/latest-all", h.GetLatestAggregatedResults)
+	return r
+}
+
+func TestGetAggregatedResultsSorting(t *testing.T) {
+	r := newListingTestRouter(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"agg-c", "agg-b", "agg-a"}},
+		{"?order=asc", []string{"agg-a", "agg-b", "agg-c"}},
+		{"?sortBy=confidence&order=desc", []string{"agg-a", "agg-c", "agg-b"}},
+		{"?sortBy=confidence&order=ASC", []string{"agg-b", "agg-c", "agg-a"}},
+		{"?minConfidence=0.5&maxConfidence=0.7", []string{"agg-c", "agg-b"}},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, "/api/sync/aggregated"+tt.query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, expected 200: %s", tt.query, w.Code, w.Body.String())
+		}
+		var results []models.AggregatedSyncResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.AggregationID)
+		}
+		if len(got) != len(tt.expected) {
+			t.Fatalf("GET %s = %v, expected %v", tt.query, got, tt.expected)
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("GET %s = %v, expected %v", tt.query, got, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestListSortingRejectsInvalidParameters(t *testing.T) {
+	r := newListingTestRouter(t)
+
+	for _, path := range []string{
+		"/api/sync/aggregated?sortBy=created_at%3BDROP%20TABLE%20aggregated_sync_results",
+		"/api/sync/aggregated?sortBy=createdAt&order=sideways",
+		// confidence is only a sort key of aggregated results
+		"/api/sync/records?sortBy=confidence",
+	} {
+		if w := doRequest(r, http.MethodGet, path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, expected 400", path, w.Code)
+		}
+	}
+}
+
+func TestGetAggregatedResultsRejectsInvalidConfidenceRange(t *testing.T) {
+	r := newListingTestRouter(t)
+
+	for _, query := range []string{"?maxConfidence=1.5", "?minConfidence=abc", "?minConfidence=0.8&maxConfidence=0.2"} {
+		if w := doRequest(r, http.MethodGet, "/api/sync/aggregated"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, expected 400", query, w.Code)
+		}
+	}
+}
+
+func TestGetLatestAggregatedResult(t *testing.T) {
+	r := newListingTestRouter(t)
+
+	w := doRequest(r, http.MethodGet, "/api/sync/aggregated/latest?pairingId=pair-123", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	var result models.AggregatedSyncResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.AggregationID != "agg-c" {
+		t.Errorf("AggregationID = %q, expected agg-c", result.AggregationID)
+	}
+
+	if w := doRequest(r, http.MethodGet, "/api/sync/aggregated/latest?pairingId=pair-unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown pairing status = %d, expected 404", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/sync/aggregated/latest", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("missing pairingId status = %d, expected 400", w.Code)
+	}
+}
+
+func TestGetLatestAggregatedResults(t *testing.T) {
+	r := newListingTestRouter(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"agg-c"}},
+		{"?pairingIds=pair-unknown,%20pair-123", []string{"agg-c"}},
+		{"?pairingIds=pair-unknown", []string{}},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, "/api/sync/aggregated/latest-all"+tt.query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, expected 200: %s", tt.query, w.Code, w.Body.String())
+		}
+		var results []models.AggregatedSyncResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || results == nil {
+			t.Fatalf("GET %s body = %s, expected a JSON array", tt.query, w.Body.String())
+		}
+		got := []string{}
+		for _, result := range results {
+			got = append(got, result.AggregationID)
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("GET %s = %v, expected %v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestGetSyncRecordsCursorPagination(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	now := time.Now().UnixMilli()
+	for i := 0; i < 3; i++ {
+		if err := repo.SaveTimeSyncRecord(&models.TimeSyncRecord{
+			Device1ID: "psg-001",
+			Device2ID: "watch-001",
+			Status:    models.SyncStatusSuccess,
+			CreatedAt: now + int64(i),
+		}); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/sync/records", h.GetSyncRecords)
+
+	var ids []int64
+	path := "/api/sync/records?limit=2&cursor="
+	for pages := 0; pages < 3; pages++ {
+		w := doRequest(r, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, expected 200: %s", path, w.Code, w.Body.String())
+		}
+		var page models.RecordPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("failed to decode page: %v", err)
+		}
+		for _, record := range page.Records {
+			ids = append(ids, record.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/api/sync/records?limit=2&cursor=" + page.NextCursor
+	}
+	if len(ids) != 3 || ids[0] != 3 || ids[2] != 1 {
+		t.Errorf("record IDs across pages = %v, expected [3 2 1]", ids)
+	}
+
+	for _, path := range []string{
+		"/api/sync/records?cursor=not-a-cursor",
+		"/api/sync/records?cursor=&offset=10",
+		"/api/sync/records?cursor=&sortBy=offset",
+	} {
+		if w := doRequest(r, http.MethodGet, path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, expected 400", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"time-sync-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIOperation documents one REST endpoint. Request and response bodies are given as
+// zero values of the models types, from which the component schemas are generated.
+type openAPIOperation struct {
+	method   string
+	path     string // gin syntax, e.g. /api/sync/:pairingId
+	summary  string
+	query    []string    // optional query parameters
+	request  interface{} // JSON request body, nil for none
+	response interface{} // JSON success body, nil for a plain {"message": ...} object
+	status   int         // success status, 0 = 200
+	csv      bool        // success body is a CSV stream
+	admin    bool        // requires the ADMIN_TOKEN bearer token
+}
+
+// openAPIOperations lists the REST endpoints served by SetupRoutes.
+// Keep in sync with routes.go; TestOpenAPISpecCoversRoutes fails for an undocumented route.
+var openAPIOperations = []openAPIOperation{
+	{method: http.MethodGet, path: "/health", summary: "Readiness probe (503 if the database is unreachable)"},
+
+	{method: http.MethodGet, path: "/api/devices", summary: "List connected devices", response: []*models.Device{}},
+	{method: http.MethodGet, path: "/api/devices/health", summary: "Connection health of all devices, or one with deviceId", query: []string{"deviceId"}, response: []*models.DeviceHealth{}},
+	{method: http.MethodPost, path: "/api/devices/:deviceId/probe", summary: "Measure a device's RTT", response: models.RTTProbeResponse{}},
+	{method: http.MethodGet, path: "/api/devices/:deviceId/stats", summary: "Sync statistics of a device", query: []string{"startTime", "endTime"}, response: models.DeviceSyncStats{}},
+	{method: http.MethodGet, path: "/api/devices/:deviceId/events", summary: "Connect/disconnect events of a device, newest first", query: []string{"startTime", "endTime", "limit"}, response: []*models.DeviceEvent{}},
+
+	{method: http.MethodGet, path: "/api/pairings", summary: "List pairings", response: []*models.Pairing{}},
+	{method: http.MethodGet, path: "/api/pairings/overview", summary: "Pairings with their latest results and auto-sync state", response: []*models.PairingOverview{}},
+	{method: http.MethodPost, path: "/api/pairings", summary: "Create a pairing (200 with the existing one if already paired)", request: models.CreatePairingRequest{}, response: models.CreatePairingResponse{}, status: http.StatusCreated},
+	{method: http.MethodDelete, path: "/api/pairings/:pairingId", summary: "Delete a pairing"},
+	{method: http.MethodDelete, path: "/api/pairings/:pairingId/records", summary: "Purge all records of a pairing (requires confirm=true)", query: []string{"confirm"}, response: models.PairingRecordsDeletion{}},
+	{method: http.MethodPut, path: "/api/pairings/:pairingId/auto-aggregation", summary: "Configure automatic aggregation of a pairing", request: models.AutoAggregationRequest{}},
+	{method: http.MethodPost, path: "/api/pairings/:pairingId/disable", summary: "Disable syncing for a pairing"},
+	{method: http.MethodPost, path: "/api/pairings/:pairingId/enable", summary: "Re-enable syncing for a pairing"},
+	{method: http.MethodPost, path: "/api/pairings/:pairingId/override", summary: "Pin a pairing's offset", request: models.OffsetOverrideRequest{}, response: models.OffsetOverride{}},
+	{method: http.MethodGet, path: "/api/pairings/:pairingId/override", summary: "Get a pairing's active offset override", response: models.OffsetOverride{}},
+	{method: http.MethodDelete, path: "/api/pairings/:pairingId/override", summary: "Remove a pairing's offset override"},
+	{method: http.MethodGet, path: "/api/pairings/:pairingId/export", summary: "Export a pairing with its aggregated results and measurements as a JSON bundle", response: models.PairingExport{}},
+	{method: http.MethodGet, path: "/api/pairings/group", summary: "List group pairings", response: []*models.GroupPairing{}},
+	{method: http.MethodPost, path: "/api/pairings/group", summary: "Create a group pairing", request: models.CreateGroupPairingRequest{}, response: models.GroupPairing{}, status: http.StatusCreated},
+	{method: http.MethodDelete, path: "/api/pairings/group/:pairingId", summary: "Delete a group pairing"},
+
+	{method: http.MethodPost, path: "/api/sync/:pairingId", summary: "Run a single time sync", response: models.SyncResponse{}},
+	{method: http.MethodPost, path: "/api/sync/multi", summary: "Run a multi-sample time sync and aggregate it", request: models.MultiSyncRequest{}, response: models.MultiSyncResponse{}},
+	{method: http.MethodPost, path: "/api/sync/multi/dryrun", summary: "Multi-sample time sync without saving", request: models.MultiSyncRequest{}, response: models.MultiSyncResponse{}},
+	{method: http.MethodPost, path: "/api/sync/group/:pairingId", summary: "Run a single group time sync", response: models.GroupSyncResponse{}},
+	{method: http.MethodPost, path: "/api/sync/group/multi", summary: "Run a multi-sample group time sync", request: models.MultiSyncRequest{}, response: models.GroupMultiSyncResponse{}},
+	{method: http.MethodGet, path: "/api/sync/records", summary: "List sync records (a RecordPage when cursor is given)", query: []string{"deviceId", "status", "startTime", "endTime", "limit", "offset", "cursor", "sortBy", "order"}, response: []*models.TimeSyncRecord{}},
+	{method: http.MethodGet, path: "/api/sync/records/export", summary: "Export sync records as CSV", query: []string{"deviceId", "startTime", "endTime"}, csv: true},
+	{method: http.MethodGet, path: "/api/sync/records/:recordId", summary: "Get a sync record", response: models.TimeSyncRecord{}},
+	{method: http.MethodGet, path: "/api/sync/records/:recordId/analysis", summary: "Per-sample offset math of a sync record", response: models.SyncRecordAnalysis{}},
+	{method: http.MethodDelete, path: "/api/sync/records/:recordId", summary: "Delete a sync record"},
+	{method: http.MethodGet, path: "/api/sync/aggregated", summary: "List aggregated results", query: []string{"pairingId", "startTime", "endTime", "minConfidence", "maxConfidence", "limit", "offset", "sortBy", "order"}, response: []*models.AggregatedSyncResult{}},
+	{method: http.MethodGet, path: "/api/sync/aggregated/export", summary: "Export aggregated results as CSV", query: []string{"pairingId", "startTime", "endTime"}, csv: true},
+	{method: http.MethodGet, path: "/api/sync/aggregated/latest", summary: "Latest aggregated result of a pairing", query: []string{"pairingId"}, response: models.AggregatedSyncResult{}},
+	{method: http.MethodGet, path: "/api/sync/aggregated/latest-all", summary: "Latest aggregated result of every pairing", query: []string{"pairingIds"}, response: []*models.AggregatedSyncResult{}},
+	{method: http.MethodGet, path: "/api/sync/aggregated/:aggregationId", summary: "Get an aggregated result with its measurements", response: models.AggregatedSyncResult{}},
+	{method: http.MethodDelete, path: "/api/sync/aggregated/:aggregationId", summary: "Delete an aggregated result", query: []string{"deleteRecords"}},
+	{method: http.MethodPost, path: "/api/sync/aggregated/:aggregationId/recompute", summary: "Re-run the NTP selection over stored measurements", request: models.RecomputeRequest{}, response: models.RecomputeResult{}},
+	{method: http.MethodGet, path: "/api/sync/timeseries", summary: "Bucketed offset time series of a pairing", query: []string{"pairingId", "startTime", "endTime", "bucket"}, response: models.OffsetTimeSeries{}},
+	{method: http.MethodGet, path: "/api/sync/compare", summary: "Compare two aggregated results", query: []string{"a", "b"}, response: models.AggregationComparison{}},
+	{method: http.MethodPost, path: "/api/sync/apply", summary: "Apply an offset to device timestamps", request: models.ApplyOffsetRequest{}, response: models.ApplyOffsetResult{}},
+
+	{method: http.MethodGet, path: "/api/stats/by-device-type", summary: "Sync statistics per device type", response: []*models.DeviceTypeStats{}},
+
+	{method: http.MethodPost, path: "/api/auto-sync/start", summary: "Start an auto-sync job", request: models.AutoSyncStartRequest{}},
+	{method: http.MethodPost, path: "/api/auto-sync/stop/:pairingId", summary: "Stop an auto-sync job"},
+	{method: http.MethodPost, path: "/api/auto-sync/pause/:pairingId", summary: "Pause an auto-sync job"},
+	{method: http.MethodPost, path: "/api/auto-sync/resume/:pairingId", summary: "Resume a paused auto-sync job"},
+	{method: http.MethodPatch, path: "/api/auto-sync/:pairingId", summary: "Reconfigure a running auto-sync job", request: models.AutoSyncUpdateRequest{}},
+	{method: http.MethodGet, path: "/api/auto-sync/status", summary: "Status of all auto-sync jobs (an AutoSyncJob with pairingId)", query: []string{"pairingId"}, response: models.AutoSyncStatusResponse{}},
+
+	{method: http.MethodGet, path: "/api/admin/connections", summary: "List live WebSocket connections", response: []*models.ConnectionInfo{}, admin: true},
+	{method: http.MethodDelete, path: "/api/admin/connections/:deviceId", summary: "Close a device's WebSocket connection", admin: true},
+}
+
+var (
+	openAPISpecOnce sync.Once
+	openAPISpec     map[string]interface{}
+)
+
+// OpenAPISpec serves the OpenAPI 3 description of the REST API.
+// The component schemas are generated from the models types, so they follow the JSON field names.
+func (h *Handler) OpenAPISpec(c *gin.Context) {
+	openAPISpecOnce.Do(func() {
+		openAPISpec = buildOpenAPISpec(openAPIOperations)
+	})
+	c.JSON(http.StatusOK, openAPISpec)
+}
+
+var ginPathParam = regexp.MustCompile(`:(\w+)`)
+
+// buildOpenAPISpec assembles the OpenAPI document for ops
+func buildOpenAPISpec(ops []openAPIOperation) map[string]interface{} {
+	g := &schemaGenerator{components: map[string]interface{}{}}
+	errorSchema := g.schemaOf(reflect.TypeOf(models.APIError{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, op := range ops {
+		var params []map[string]interface{}
+		for _, m := range ginPathParam.FindAllStringSubmatch(op.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, name := range op.query {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+
+		success := map[string]interface{}{"description": "Success"}
+		switch {
+		case op.csv:
+			success["content"] = map[string]interface{}{"text/csv": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+		case op.response != nil:
+			success["content"] = jsonContent(g.schemaOf(reflect.TypeOf(op.response)))
+		default:
+			success["content"] = jsonContent(map[string]interface{}{"type": "object", "additionalProperties": true})
+		}
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		operation := map[string]interface{}{
+			"summary": op.summary,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): success,
+				"default":            map[string]interface{}{"description": "Error", "content": jsonContent(errorSchema)},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schemaOf(reflect.TypeOf(op.request))),
+			}
+		}
+		if op.admin {
+			operation["security"] = []map[string][]string{{"adminToken": {}}}
+		}
+
+		path := ginPathParam.ReplaceAllString(op.path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "HeteroSync time sync server",
+			"description": "REST API for pairing devices and measuring their clock offsets. Errors use the APIError envelope.",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+			"securitySchemes": map[string]interface{}{
+				"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator derives JSON schemas from Go types the way encoding/json marshals them.
+// Named struct types become components referenced by $ref.
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.components[t.Name()]; !ok {
+			g.components[t.Name()] = nil // placeholder against recursive types
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.structSchema(t)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	}
+	// interface{} and anything else: any JSON value
+	return map[string]interface{}{}
+}
+
+// structSchema lists the exported fields under their JSON names. Fields without omitempty
+// that are not pointers are always present and therefore required.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	g.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schemaOf(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func fetchOpenAPISpec(t *testing.T) (*gin.Engine, *openAPIDocument) {
+	t.Helper()
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	h := NewHandler(service.NewSyncService(hub, repo), service.NewAutoSyncMonitor(nil), hub, &config.Config{}, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, h)
+
+	w := doRequest(r, http.MethodGet, "/openapi.json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d, expected 200", w.Code)
+	}
+	var doc openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode spec: %v", err)
+	}
+	return r, &doc
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	r, doc := fetchOpenAPISpec(t)
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, expected a 3.x version", doc.OpenAPI)
+	}
+
+	undocumented := map[string]bool{"/metrics": true, "/ws": true, "/ws/events": true, "/openapi.json": true}
+	routed := map[string]bool{}
+	for _, route := range r.Routes() {
+		if undocumented[route.Path] {
+			continue
+		}
+		path := ginPathParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		routed[method+" "+path] = true
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("%s %s is not documented", route.Method, path)
+		}
+	}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if !routed[method+" "+path] {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPISpecSchemasFollowJSONNames(t *testing.T) {
+	_, doc := fetchOpenAPISpec(t)
+
+	result, ok := doc.Components.Schemas["AggregatedSyncResult"]
+	if !ok {
+		t.Fatal("no AggregatedSyncResult schema")
+	}
+	for _, name := range []string{"aggregation_id", "best_offset", "confidence", "retry_count"} {
+		if _, ok := result.Properties[name]; !ok {
+			t.Errorf("AggregatedSyncResult has no %q property", name)
+		}
+	}
+	if _, ok := result.Properties["BestOffset"]; ok {
+		t.Error("AggregatedSyncResult lists the Go field name BestOffset")
+	}
+
+	apiError, ok := doc.Components.Schemas["APIError"]
+	if !ok {
+		t.Fatal("no APIError schema for the error envelope")
+	}
+	if strings.Join(apiError.Required, ",") != "code,message" {
+		t.Errorf("APIError required = %v, expected [code message]", apiError.Required)
+	}
+}
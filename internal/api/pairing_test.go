@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreatePairingReturnsExistingPairing(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	for _, deviceID := range []string{"psg-001", "watch-001"} {
+		hub.Clients[deviceID] = &ws.Client{DeviceID: deviceID}
+	}
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/pairings", h.CreatePairing)
+
+	// Both directions resolve to the persisted pairing, repeated requests do not create new ones
+	for _, body := range []string{
+		`{"device1Id": "watch-001", "device2Id": "psg-001"}`,
+		`{"device1Id": "psg-001", "device2Id": "watch-001"}`,
+	} {
+		w := doRequest(r, http.MethodPost, "/api/pairings", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+		}
+		var resp models.CreatePairingResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if resp.PairingID != "pair-123" {
+			t.Errorf("PairingID = %q, expected pair-123", resp.PairingID)
+		}
+	}
+
+	pairings := hub.GetPairings()
+	if len(pairings) != 1 || pairings[0].PairingID != "pair-123" {
+		t.Errorf("in-memory pairings = %+v, expected only pair-123", pairings)
+	}
+}
+
+func TestDeletePairingRecordsRequiresConfirmation(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	record := &models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusSuccess}
+	if err := repo.SaveTimeSyncRecord(record); err != nil {
+		t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+		AggregationID: "agg-123",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{record},
+	}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/api/pairings/:pairingId/records", h.DeletePairingRecords)
+
+	for _, path := range []string{"/api/pairings/pair-123/records", "/api/pairings/pair-123/records?confirm=false"} {
+		if w := doRequest(r, http.MethodDelete, path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("DELETE %s = %d, expected 400", path, w.Code)
+		}
+	}
+	if _, err := repo.GetAggregatedSyncResult("agg-123"); err != nil {
+		t.Fatalf("unconfirmed request deleted the aggregation: %v", err)
+	}
+
+	w := doRequest(r, http.MethodDelete, "/api/pairings/pair-123/records?confirm=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	var resp models.PairingRecordsDeletion
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.RecordsDeleted != 1 || resp.AggregationsDeleted != 1 || resp.LinksDeleted != 1 {
+		t.Errorf("response = %+v, expected one record, aggregation and link deleted", resp)
+	}
+}
+
+func TestDisablePairingRefusesSyncsUntilEnabled(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	intervalSec, sampleCount, intervalMs := 3600, 4, 100
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID:           "pair-123",
+		Device1ID:           "psg-001",
+		Device2ID:           "watch-001",
+		CreatedAt:           time.Now(),
+		Enabled:             true,
+		AutoSyncIntervalSec: &intervalSec,
+		AutoSyncSampleCount: &sampleCount,
+		AutoSyncIntervalMs:  &intervalMs,
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+	syncService := service.NewSyncService(hub, repo)
+	monitor := service.NewAutoSyncMonitor(syncService)
+	defer monitor.Shutdown(context.Background())
+	monitor.SetJitter(0, true) // Keep the first sync from running during the test
+	if err := monitor.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: intervalSec, SampleCount: sampleCount, IntervalMs: intervalMs}); err != nil {
+		t.Fatalf("StartAutoSync() error = %v", err)
+	}
+
+	h := &Handler{syncService: syncService, autoSyncMonitor: monitor, hub: hub, config: &config.Config{}, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/pairings/:pairingId/enable", h.EnablePairing)
+	r.POST("/api/pairings/:pairingId/disable", h.DisablePairing)
+	r.POST("/api/sync/:pairingId", h.RequestSync)
+
+	if w := doRequest(r, http.MethodPost, "/api/pairings/pair-123/disable", ""); w.Code != http.StatusOK {
+		t.Fatalf("disable status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	if monitor.HasJob("pair-123") {
+		t.Error("auto-sync job still running after the pairing was disabled")
+	}
+
+	w := doRequest(r, http.MethodPost, "/api/sync/pair-123", "")
+	var apiErr models.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if w.Code != http.StatusConflict || apiErr.Code != models.ErrorCodePairingDisabled {
+		t.Errorf("sync of disabled pairing = %d %s, expected 409 %s", w.Code, apiErr.Code, models.ErrorCodePairingDisabled)
+	}
+	if pairing, err := repo.GetPairingByID("pair-123"); err != nil || pairing.Enabled || pairing.AutoSyncIntervalSec == nil {
+		t.Errorf("GetPairingByID() = %+v, %v, expected a disabled pairing that kept its config", pairing, err)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/pairings/pair-123/enable", ""); w.Code != http.StatusOK {
+		t.Fatalf("enable status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	if !monitor.HasJob("pair-123") {
+		t.Error("saved auto-sync config was not restarted when the pairing was enabled")
+	}
+	if pairing, err := repo.GetPairingByID("pair-123"); err != nil || !pairing.Enabled {
+		t.Errorf("GetPairingByID() = %+v, %v, expected the pairing to be enabled", pairing, err)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/pairings/pair-missing/disable", ""); w.Code != http.StatusNotFound {
+		t.Errorf("disable of missing pairing = %d, expected 404", w.Code)
+	}
+}
+
+func TestExportPairingBundle(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+	if err := repo.SaveOffsetOverride(&models.OffsetOverride{PairingID: "pair-123", ReferenceDeviceID: "watch-001", OffsetMs: -42, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOffsetOverride() error = %v", err)
+	}
+	now := time.Now().UnixMilli()
+	for i, id := range []string{"agg-old", "agg-new"} {
+		record := &models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusSuccess, CreatedAt: now + int64(i)}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+			AggregationID: id,
+			PairingID:     "pair-123",
+			BestOffset:    -100,
+			Measurements:  []*models.TimeSyncRecord{record},
+			CreatedAt:     now + int64(i),
+		}); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{AggregationID: "agg-other", PairingID: "pair-456", CreatedAt: now}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/pairings/:pairingId/export", h.ExportPairing)
+
+	w := doRequest(r, http.MethodGet, "/api/pairings/pair-123/export", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	var bundle models.PairingExport
+	if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil {
+		t.Fatalf("invalid JSON bundle: %v\n%s", err, w.Body.String())
+	}
+	if bundle.SchemaVersion != models.PairingExportSchemaVersion || bundle.ExportedAt.IsZero() {
+		t.Errorf("schema_version, exported_at = %d, %v, expected %d and the export time",
+			bundle.SchemaVersion, bundle.ExportedAt, models.PairingExportSchemaVersion)
+	}
+	if bundle.Pairing == nil || bundle.Pairing.PairingID != "pair-123" || bundle.OffsetOverride == nil || bundle.OffsetOverride.OffsetMs != -42 {
+		t.Errorf("pairing, offset_override = %+v, %+v, expected pair-123 with its override", bundle.Pairing, bundle.OffsetOverride)
+	}
+	if len(bundle.Aggregations) != 2 || bundle.Aggregations[0].AggregationID != "agg-old" || bundle.Aggregations[1].AggregationID != "agg-new" {
+		t.Fatalf("aggregations = %+v, expected agg-old and agg-new of the pairing only", bundle.Aggregations)
+	}
+	for _, result := range bundle.Aggregations {
+		if len(result.Measurements) != 1 {
+			t.Errorf("aggregation %s has %d measurements, expected its linked record", result.AggregationID, len(result.Measurements))
+		}
+		if result.BestOffset != -100 || result.OffsetOverride != nil {
+			t.Errorf("aggregation %s best_offset = %d, expected the stored offset without the override", result.AggregationID, result.BestOffset)
+		}
+	}
+
+	if w := doRequest(r, http.MethodGet, "/api/pairings/pair-unknown/export", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown pairing status = %d, expected 404", w.Code)
+	}
+}
+
+func TestUpdateAutoAggregation(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository() error = %v", err)
+	}
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/api/pairings/:pairingId/auto-aggregation", h.UpdateAutoAggregation)
+
+	w := doRequest(r, http.MethodPut, "/api/pairings/pair-123/auto-aggregation", `{"enabled": true, "window_sec": 120, "min_count": 5}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	pairing, err := repo.GetPairingByID("pair-123")
+	if err != nil {
+		t.Fatalf("GetPairingByID() error = %v", err)
+	}
+	if !pairing.AutoAggregateEnabled || pairing.AutoAggregateWindowSec == nil || *pairing.AutoAggregateWindowSec != 120 ||
+		pairing.AutoAggregateMinCount == nil || *pairing.AutoAggregateMinCount != 5 {
+		t.Errorf("stored settings = %v, %v, %v, expected enabled with 120s and 5", pairing.AutoAggregateEnabled, pairing.AutoAggregateWindowSec, pairing.AutoAggregateMinCount)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		pairingID string
+		body      string
+		status    int
+		code      models.ErrorCode
+	}{
+		{"non-positive window", "pair-123", `{"enabled": true, "window_sec": 0}`, http.StatusBadRequest, models.ErrorCodeValidationFailed},
+		{"unknown pairing", "pair-missing", `{"enabled": true}`, http.StatusNotFound, models.ErrorCodePairingNotFound},
+	} {
+		w := doRequest(r, http.MethodPut, "/api/pairings/"+tc.pairingID+"/auto-aggregation", tc.body)
+		var resp models.APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tc.name, err)
+		}
+		if w.Code != tc.status || resp.Code != tc.code {
+			t.Errorf("%s: status = %d %q, expected %d %q", tc.name, w.Code, resp.Code, tc.status, tc.code)
+		}
+	}
+
+	// Storage failures are not reported as a missing pairing
+	repo.Close()
+	w = doRequest(r, http.MethodPut, "/api/pairings/pair-123/auto-aggregation", `{"enabled": false}`)
+	var resp models.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || resp.Code != models.ErrorCodeInternal {
+		t.Errorf("closed repository: status = %d %q, expected 500 %q", w.Code, resp.Code, models.ErrorCodeInternal)
+	}
+}
+
+func TestGetPairingOverviews(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	for _, pairing := range []*models.PersistentPairing{
+		{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001", CreatedAt: time.Now(), Enabled: true},
+		{PairingID: "pair-456", Device1ID: "psg-002", Device2ID: "watch-002", CreatedAt: time.Now(), Enabled: true},
+	} {
+		if err := repo.SavePairing(pairing); err != nil {
+			t.Fatalf("SavePairing(%s) error = %v", pairing.PairingID, err)
+		}
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{AggregationID: "agg-123", PairingID: "pair-123", BestOffset: 42, CreatedAt: time.Now().UnixMilli()}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+	syncService := service.NewSyncService(hub, repo)
+	monitor := service.NewAutoSyncMonitor(syncService)
+	defer monitor.Shutdown(context.Background())
+	if err := monitor.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 3600, InitialSyncDelaySec: models.InitialSyncSkip}); err != nil {
+		t.Fatalf("StartAutoSync() error = %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		name            string
+		autoSync        *service.AutoSyncMonitor
+		expectedRunning bool
+	}{
+		{"with auto-sync job", monitor, true},
+		{"without auto-sync monitor", nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{syncService: syncService, autoSyncMonitor: tt.autoSync, hub: hub, repository: repo}
+			r := gin.New()
+			r.GET("/api/pairings/overview", h.GetPairingOverviews)
+
+			w := doRequest(r, http.MethodGet, "/api/pairings/overview", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+			}
+			var overviews []map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &overviews); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			byID := make(map[string]map[string]interface{}, len(overviews))
+			for _, overview := range overviews {
+				byID[overview["pairingId"].(string)] = overview
+			}
+			if len(overviews) != 2 || byID["pair-123"] == nil || byID["pair-456"] == nil {
+				t.Fatalf("overviews = %s, expected pair-123 and pair-456", w.Body.String())
+			}
+
+			synced := byID["pair-123"]
+			if synced["autoSyncRunning"] != tt.expectedRunning || synced["lastBestOffset"] != float64(42) {
+				t.Errorf("pair-123 autoSyncRunning, lastBestOffset = %v, %v, expected %v, 42", synced["autoSyncRunning"], synced["lastBestOffset"], tt.expectedRunning)
+			}
+			// The never aggregated pairing has no job and omits the last sync
+			for _, field := range []string{"autoSyncStatus", "lastSyncAt", "lastBestOffset", "lastConfidence"} {
+				if value, ok := byID["pair-456"][field]; ok {
+					t.Errorf("pair-456 %s = %v, expected it to be omitted", field, value)
+				}
+			}
+		})
+	}
+
+	// A failing repository is reported as an internal error
+	sqliteRepo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository() error = %v", err)
+	}
+	sqliteRepo.Close()
+	h := &Handler{syncService: service.NewSyncService(hub, sqliteRepo), hub: hub, repository: sqliteRepo}
+	r := gin.New()
+	r.GET("/api/pairings/overview", h.GetPairingOverviews)
+	w := doRequest(r, http.MethodGet, "/api/pairings/overview", "")
+	var resp models.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || resp.Code != models.ErrorCodeInternal {
+		t.Errorf("closed repository: status = %d %q, expected 500 %q", w.Code, resp.Code, models.ErrorCodeInternal)
+	}
+}
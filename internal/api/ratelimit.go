@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"time-sync-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRateLimitBuckets is the number of tracked keys above which idle (refilled) buckets are dropped
+const maxRateLimitBuckets = 10000
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a keyed token-bucket limiter. Each key starts with burst tokens and regains
+// ratePerMin tokens per minute; a request consumes one token.
+type RateLimiter struct {
+	ratePerSec float64
+	burst      float64
+	buckets    map[string]*tokenBucket
+	mu         sync.Mutex
+	now        func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing ratePerMin requests per minute per key with the given burst.
+// Returns nil (no limiting) if ratePerMin <= 0.
+func NewRateLimiter(ratePerMin float64, burst int) *RateLimiter {
+	if ratePerMin <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		ratePerSec: ratePerMin / 60,
+		burst:      float64(burst),
+		buckets:    make(map[string]*tokenBucket),
+		now:        time.Now,
+	}
+}
+
+// Allow consumes a token for key. If none is available it returns false and how long
+// until the next token. A nil RateLimiter allows everything.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.pruneLocked(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.ratePerSec)
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.ratePerSec * float64(time.Second))
+	return false, wait
+}
+
+// pruneLocked drops buckets that have refilled completely; they behave the same as new ones
+func (l *RateLimiter) pruneLocked(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.ratePerSec >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// syncRateLimit limits sync-triggering endpoints per pairing and per client IP.
+// The pairing is taken from the :pairingId path param, or from "pairing_id" in the JSON body
+// for the multi-sync endpoints (the body is restored for the handler).
+func (h *Handler) syncRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.ipLimiter != nil {
+			if ok, wait := h.ipLimiter.Allow(c.ClientIP()); !ok {
+				abortRateLimited(c, wait, "too many sync requests from this client")
+				return
+			}
+		}
+
+		if h.pairingLimiter != nil {
+			if pairingID := requestPairingID(c); pairingID != "" {
+				if ok, wait := h.pairingLimiter.Allow(pairingID); !ok {
+					abortRateLimited(c, wait, "too many sync requests for pairing "+pairingID)
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// requestPairingID returns the pairing a sync request targets, or "" if it cannot be determined
+// (the handler then rejects the request itself)
+func requestPairingID(c *gin.Context) string {
+	if pairingID := c.Param("pairingId"); pairingID != "" {
+		return pairingID
+	}
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		PairingID string `json:"pairing_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.PairingID
+}
+
+func abortRateLimited(c *gin.Context, wait time.Duration, message string) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	respondError(c, http.StatusTooManyRequests, models.ErrorCodeRateLimited, message)
+}
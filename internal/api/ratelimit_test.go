@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitTestRouter(h *Handler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.POST("/api/sync/:pairingId", h.syncRateLimit(), ok)
+	r.POST("/api/sync/multi", h.syncRateLimit(), func(c *gin.Context) {
+		var req struct {
+			PairingID string `json:"pairing_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.PairingID == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/api/sync/records", ok)
+	return r
+}
+
+func doRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSyncRateLimitPerPairing(t *testing.T) {
+	h := &Handler{pairingLimiter: NewRateLimiter(60, 3)}
+	r := newRateLimitTestRouter(h)
+
+	for i := 0; i < 3; i++ {
+		if w := doRequest(r, http.MethodPost, "/api/sync/pair-1", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	w := doRequest(r, http.MethodPost, "/api/sync/pair-1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after exhausting the bucket, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	// Other pairings and read endpoints are unaffected
+	if w := doRequest(r, http.MethodPost, "/api/sync/pair-2", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for another pairing, got %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/sync/records", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for GET endpoint, got %d", w.Code)
+	}
+}
+
+func TestSyncRateLimitReadsPairingFromBody(t *testing.T) {
+	h := &Handler{pairingLimiter: NewRateLimiter(60, 1)}
+	r := newRateLimitTestRouter(h)
+
+	body := `{"pairing_id": "pair-1", "sample_count": 8}`
+	if w := doRequest(r, http.MethodPost, "/api/sync/multi", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 (body must be restored for the handler), got %d", w.Code)
+	}
+	if w := doRequest(r, http.MethodPost, "/api/sync/multi", body); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	// Same pairing through the single-sync endpoint shares the bucket
+	if w := doRequest(r, http.MethodPost, "/api/sync/pair-1", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+}
+
+func TestSyncRateLimitPerIP(t *testing.T) {
+	h := &Handler{ipLimiter: NewRateLimiter(60, 2)}
+	r := newRateLimitTestRouter(h)
+
+	doRequest(r, http.MethodPost, "/api/sync/pair-1", "")
+	doRequest(r, http.MethodPost, "/api/sync/pair-2", "")
+	if w := doRequest(r, http.MethodPost, "/api/sync/pair-3", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for third request from the same IP, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := NewRateLimiter(60, 2) // one token per second
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("k")
+	limiter.Allow("k")
+	ok, wait := limiter.Allow("k")
+	if ok {
+		t.Fatal("expected bucket to be empty")
+	}
+	if wait != time.Second {
+		t.Errorf("expected wait of 1s, got %v", wait)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if ok, _ := limiter.Allow("k"); !ok {
+		t.Fatal("expected a token after refill")
+	}
+	if ok, _ := limiter.Allow("k"); ok {
+		t.Fatal("expected only one token to have refilled")
+	}
+}
+
+func TestNewRateLimiterDisabled(t *testing.T) {
+	limiter := NewRateLimiter(0, 10)
+	if limiter != nil {
+		t.Fatal("expected nil limiter when rate is 0")
+	}
+	if ok, _ := limiter.Allow("k"); !ok {
+		t.Fatal("nil limiter must allow requests")
+	}
+}
@@ -2,17 +2,35 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func SetupRoutes(r *gin.Engine, handler *Handler) {
-	// Health check
-	// Output: {"status": "ok"}
+	// CORS: only ALLOWED_ORIGINS may call the API cross-origin (all origins when unset)
+	// Registered first so it also covers preflight requests to unregistered OPTIONS routes
+	r.Use(handler.corsMiddleware())
+
+	// Health check (readiness): pings the database, 503 if it is unreachable
+	// Output: {"status": "ok", "uptime_sec": 86400, "database": "ok", "connected_devices": 4, "active_pairings": 2, "auto_sync_running": 2, "goroutines": 37}
 	r.GET("/health", handler.HealthCheck)
 
+	// Prometheus metrics
+	// Sync/timeout counters, RTT histogram, connected devices, active pairings and auto-sync job gauges
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// OpenAPI 3 description of the REST API
+	// Component schemas are generated from the models types
+	r.GET("/openapi.json", handler.OpenAPISpec)
+
 	// WebSocket endpoint
 	// Upgrade to WebSocket connection for real-time communication
+	// Optional model/firmware query params are persisted in the devices table
 	r.GET("/ws", handler.HandleWebSocket)
 
+	// Dashboard event stream (WebSocket)
+	// Pushes {"type": "AGGREGATED_RESULT" | "AUTO_SYNC_STATE" | "DEVICE_CONNECTED" | "DEVICE_DISCONNECTED", "timestamp": ..., "data": {...}}
+	r.GET("/ws/events", handler.HandleEventsWebSocket)
+
 	// API routes
 	api := r.Group("/api")
 	{
@@ -31,6 +49,25 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 			//   - GET /api/devices/health?deviceId=psg-001 (specific device)
 			// Output: {"deviceId": "psg-001", "isHealthy": true, "lastRtt": 15, "timeSinceLastPong": 5000, ...}
 			devices.GET("/health", handler.GetDeviceHealth)
+
+			// POST /api/devices/:deviceId/probe
+			// Measure a device's RTT on demand with an RTT_PROBE/RTT_PROBE_ACK exchange
+			// Example: POST /api/devices/watch-001/probe
+			// Output: {"deviceId": "watch-001", "rtt": 15400}  (microseconds)
+			devices.POST("/:deviceId/probe", handler.ProbeDeviceRTT)
+
+			// GET /api/devices/:deviceId/stats
+			// Sync statistics over the device's records, optionally within startTime/endTime (RFC3339)
+			// Example: GET /api/devices/watch-001/stats?startTime=2025-10-01T00:00:00Z
+			// Output: {"device_id": "watch-001", "total_syncs": 120, "success_rate": 0.97, "median_time_difference": -150, ...}
+			devices.GET("/:deviceId/stats", handler.GetDeviceSyncStats)
+
+			// GET /api/devices/:deviceId/events
+			// Connect/disconnect history of the device, newest first, optionally within startTime/endTime (RFC3339)
+			// Disconnect reasons: clean_close, dead_connection_timeout, read_error, send_buffer_full, server_shutdown, admin_closed
+			// Example: GET /api/devices/watch-001/events?startTime=2025-10-01T00:00:00Z&limit=20
+			// Output: [{"id": 42, "deviceId": "watch-001", "eventType": "DISCONNECTED", "reason": "dead_connection_timeout", "timestamp": "..."}]
+			devices.GET("/:deviceId/events", handler.GetDeviceEvents)
 		}
 
 		// Pairing management
@@ -40,6 +77,12 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 			// Output: [{"id": "pair-123", "device1_id": "psg-001", "device2_id": "watch-001", "status": "active"}]
 			pairings.GET("", handler.GetPairings)
 
+			// GET /api/pairings/overview
+			// All pairings with live state in one call
+			// Output: [{"pairingId": "pair-123", "device1Connected": true, "device2Connected": false, "active": false,
+			//           "autoSyncStatus": "RUNNING", "autoSyncRunning": true, "lastSyncAt": "...", "lastConfidence": 0.94, ...}]
+			pairings.GET("/overview", handler.GetPairingOverviews)
+
 			// POST /api/pairings
 			// Input: {"device1Id": "psg-001", "device2Id": "watch-001"}
 			// Output: {"id": "pair-123", "device1_id": "psg-001", "device2_id": "watch-001", "status": "active"}
@@ -49,51 +92,230 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 			// Example: DELETE /api/pairings/pair-123
 			// Output: {"message": "Pairing deleted successfully"}
 			pairings.DELETE("/:pairingId", handler.DeletePairing)
+
+			// DELETE /api/pairings/:pairingId/records?confirm=true
+			// Purge the aggregated results of a pairing, their links and the linked sync records in one
+			// transaction; the pairing itself is kept. Records another pairing's aggregation links to are kept.
+			// Example: DELETE /api/pairings/pair-123/records?confirm=true
+			// Output: {"pairing_id": "pair-123", "records_deleted": 120, "aggregations_deleted": 12, "links_deleted": 120}
+			pairings.DELETE("/:pairingId/records", handler.DeletePairingRecords)
+
+			// PUT /api/pairings/:pairingId/auto-aggregation
+			// Opt a pairing in/out of automatic aggregation of single-sync records
+			// Input: {"enabled": true, "window_sec": 600, "min_count": 5}
+			// Output: {"message": "auto-aggregation updated", "pairing_id": "pair-123", "enabled": true}
+			pairings.PUT("/:pairingId/auto-aggregation", handler.UpdateAutoAggregation)
+
+			// POST /api/pairings/:pairingId/disable
+			// Stop syncing a pairing without deleting it: its auto-sync job stops and sync requests
+			// fail with 409 PAIRING_DISABLED; configuration and history are kept
+			// Output: {"message": "pairing disabled", "pairing_id": "pair-123", "enabled": false}
+			pairings.POST("/:pairingId/disable", handler.DisablePairing)
+
+			// POST /api/pairings/:pairingId/enable
+			// Allow syncing again; the saved auto-sync config is restarted if both devices are connected
+			// Output: {"message": "pairing enabled", "pairing_id": "pair-123", "enabled": true}
+			pairings.POST("/:pairingId/enable", handler.EnablePairing)
+
+			// POST /api/pairings/:pairingId/override
+			// Pin the pairing's offset to a calibrated value; apply, timeseries and the latest
+			// aggregated result use it until it expires or is deleted
+			// Input: {"offsetMs": -42, "expiresAt": "2025-10-02T00:00:00Z"} (expiresAt optional)
+			pairings.POST("/:pairingId/override", handler.SetOffsetOverride)
+
+			// GET /api/pairings/:pairingId/override
+			// Get the pairing's active offset override (404 if none)
+			pairings.GET("/:pairingId/override", handler.GetOffsetOverride)
+
+			// DELETE /api/pairings/:pairingId/override
+			// Remove the override so measured offsets are used again
+			pairings.DELETE("/:pairingId/override", handler.DeleteOffsetOverride)
+
+			// GET /api/pairings/:pairingId/export
+			// Download the pairing's configuration, aggregated results and their measurements
+			// as one JSON bundle (streamed) for archiving a completed study
+			pairings.GET("/:pairingId/export", handler.ExportPairing)
+
+			// GET /api/pairings/group
+			// List pairings of two or more devices
+			// Output: [{"pairingId": "grp-123", "deviceIds": ["psg-001", "watch-001", "mobile-001"], "referenceDeviceId": "psg-001", ...}]
+			pairings.GET("/group", handler.GetGroupPairings)
+
+			// POST /api/pairings/group
+			// Create a pairing of two or more devices; offsets are reported relative to the reference device
+			// Input: {"deviceIds": ["psg-001", "watch-001", "mobile-001"], "referenceDeviceId": "psg-001"}
+			// Output: {"pairingId": "grp-123", "deviceIds": [...], "referenceDeviceId": "psg-001", "createdAt": "..."}
+			pairings.POST("/group", handler.CreateGroupPairing)
+
+			// DELETE /api/pairings/group/:pairingId
+			// Example: DELETE /api/pairings/group/grp-123
+			// Output: {"message": "group pairing deleted"}
+			pairings.DELETE("/group/:pairingId", handler.DeleteGroupPairing)
 		}
 
 		// Time synchronization
+		// The POST endpoints are rate limited per pairing (and optionally per client IP);
+		// over the limit they return 429 with a Retry-After header
+		// A device that disconnected less than the reconnect grace period ago yields 503
 		sync := api.Group("/sync")
 		{
 			// POST /api/sync/:pairingId
 			// Single time synchronization request
 			// Example: POST /api/sync/pair-123
 			// Output: {"success": true, "record": {...}}
-			sync.POST("/:pairingId", handler.RequestSync)
+			sync.POST("/:pairingId", handler.syncRateLimit(), handler.RequestSync)
 
 			// POST /api/sync/multi
 			// NTP-style multi-sampling synchronization
 			// Input: {"pairing_id": "pair-123", "sample_count": 10, "interval_ms": 200}
+			// Optional: "interval_strategy": "adaptive" with "min_interval_ms"/"max_interval_ms" bounds
+			// Optional NTP selection tuning: "min_samples", "outlier_threshold", "top_percentile"
 			// Output: {"success": true, "result": {"best_offset": -150, "confidence": 0.94, ...}}
-			sync.POST("/multi", handler.RequestMultiSync)
+			sync.POST("/multi", handler.syncRateLimit(), handler.RequestMultiSync)
+
+			// POST /api/sync/multi/dryrun
+			// Multi-sampling synchronization that measures and selects but saves nothing
+			// Input: same as POST /api/sync/multi ("dry_run": true on /multi does the same)
+			// Output: {"success": true, "dry_run": true, "result": {"best_offset": -150, ...}} (no aggregation_id)
+			sync.POST("/multi/dryrun", handler.syncRateLimit(), handler.RequestMultiSyncDryRun)
+
+			// POST /api/sync/group/:pairingId
+			// Single time synchronization across all members of a group pairing
+			// Example: POST /api/sync/group/grp-123
+			// Output: {"success": true, "result": {"referenceDeviceId": "psg-001", "status": "SUCCESS", "records": [...]}}
+			sync.POST("/group/:pairingId", handler.syncRateLimit(), handler.RequestGroupSync)
+
+			// POST /api/sync/group/multi
+			// NTP-style multi-sampling over a group pairing, one aggregated result per non-reference member
+			// Input: {"pairing_id": "grp-123", "sample_count": 8, "interval_ms": 200}
+			// Output: {"success": true, "result": {"reference_device_id": "psg-001", "results": {"watch-001": {"best_offset": -150, ...}, ...}}}
+			sync.POST("/group/multi", handler.syncRateLimit(), handler.RequestGroupMultiSync)
 
 			// GET /api/sync/records
 			// Get individual sync records
+			// Query params (optional, combined with AND): deviceId, status (SUCCESS/PARTIAL/FAILED), startTime, endTime (RFC3339), limit, offset
+			// Sorting: sortBy (createdAt, offset = |time difference|), order (asc/desc, default newest first)
+			// Cursor pagination: cursor (empty for the first page) instead of offset, newest first
+			// Example: GET /api/sync/records?status=FAILED&deviceId=watch-001
 			// Output: [{"id": 1, "device1_id": "psg-001", "time_difference": -150, ...}]
+			// Output with cursor: {"records": [...], "nextCursor": "MTcyNzg3MDQwMTAwMDoxMjM"}
 			sync.GET("/records", handler.GetSyncRecords)
 
+			// GET /api/sync/records/export
+			// Stream sync records as CSV (text/csv attachment), oldest first
+			// Query params (optional, combined with AND): deviceId, startTime, endTime (RFC3339)
+			// Example: GET /api/sync/records/export?deviceId=watch-001&startTime=2024-01-01T00:00:00Z
+			// Output: id,device1_id,device1_type,...,created_at
+			sync.GET("/records/export", handler.ExportSyncRecords)
+
 			// GET /api/sync/records/:recordId
 			// Get a single sync record by ID
 			// Example: GET /api/sync/records/123
 			// Output: {"id": 123, "device1_id": "psg-001", "time_difference": -150, ...}
 			sync.GET("/records/:recordId", handler.GetSyncRecord)
 
+			// GET /api/sync/records/:recordId/analysis
+			// Get the record's timestamps and RTTs with the one-way delays, delay correction and
+			// adjusted offset the NTP selector derives from them
+			// Example: GET /api/sync/records/123/analysis
+			sync.GET("/records/:recordId/analysis", handler.GetSyncRecordAnalysis)
+
+			// DELETE /api/sync/records/:recordId
+			// Delete a single sync record (e.g. a bad measurement) and its aggregation links
+			// Example: DELETE /api/sync/records/123
+			// Output: {"message": "sync record deleted", "record_id": 123}
+			sync.DELETE("/records/:recordId", handler.DeleteSyncRecord)
+
 			// GET /api/sync/aggregated
 			// Get aggregated NTP results with optional filters
 			// Query params:
 			//   - pairingId (optional): Filter by specific pairing
 			//   - startTime, endTime (optional): Filter by time range (RFC3339 format)
+			//   - minConfidence, maxConfidence (optional): Only results with confidence in [minConfidence, maxConfidence]
 			//   - limit, offset: Pagination
+			//   - sortBy (optional): createdAt (default), confidence or offset (|best_offset|); unknown keys are rejected with 400
+			//   - order (optional): asc or desc (default)
+			//   Filters are combined with AND
 			// Examples:
 			//   - GET /api/sync/aggregated?pairingId=pair-123&limit=10
 			//   - GET /api/sync/aggregated?startTime=2024-01-01T00:00:00Z&endTime=2024-01-31T23:59:59Z
+			//   - GET /api/sync/aggregated?pairingId=pair-123&minConfidence=0.9
+			//   - GET /api/sync/aggregated?maxConfidence=0.6&startTime=2024-01-01T00:00:00Z (low-confidence results of all pairings)
+			//   - GET /api/sync/aggregated?pairingId=pair-123&sortBy=offset&order=desc
 			//   - GET /api/sync/aggregated (all results)
 			// Output: [{"aggregation_id": "agg-123", "best_offset": -150, "confidence": 0.94, ...}]
 			sync.GET("/aggregated", handler.GetAggregatedResults)
 
+			// GET /api/sync/aggregated/export
+			// Stream aggregated results (without measurements) as CSV, oldest first
+			// Query params (optional, combined with AND): pairingId, startTime, endTime (RFC3339)
+			// Example: GET /api/sync/aggregated/export?pairingId=pair-123
+			// Output: aggregation_id,pairing_id,reference_device_id,best_offset,...,created_at
+			sync.GET("/aggregated/export", handler.ExportAggregatedResults)
+
+			// GET /api/sync/aggregated/latest
+			// Get the most recent aggregated result of a pairing (without measurements), 404 if there is none
+			// Example: GET /api/sync/aggregated/latest?pairingId=pair-123
+			// Output: {"aggregation_id": "agg-123", "best_offset": -150, "confidence": 0.94, ...}
+			sync.GET("/aggregated/latest", handler.GetLatestAggregatedResult)
+
+			// GET /api/sync/aggregated/latest-all
+			// Get the most recent aggregated result of every pairing in one query, ordered by pairing ID
+			// Optional pairingIds (comma separated) limits the pairings; pairings without results are left out
+			// Example: GET /api/sync/aggregated/latest-all?pairingIds=pair-123,pair-456
+			sync.GET("/aggregated/latest-all", handler.GetLatestAggregatedResults)
+
 			// GET /api/sync/aggregated/:aggregationId
 			// Get a single aggregated result with all measurements
 			// Output: {"aggregation_id": "agg-123", "measurements": [...], ...}
 			sync.GET("/aggregated/:aggregationId", handler.GetAggregatedResult)
+
+			// DELETE /api/sync/aggregated/:aggregationId
+			// Delete an aggregated result; its measurements are kept unless deleteRecords=true
+			// (records linked to another aggregation are always kept)
+			// Example: DELETE /api/sync/aggregated/agg-123?deleteRecords=true
+			// Output: {"message": "aggregated result deleted", "aggregation_id": "agg-123", "records_deleted": true}
+			sync.DELETE("/aggregated/:aggregationId", handler.DeleteAggregatedResult)
+
+			// POST /api/sync/aggregated/:aggregationId/recompute
+			// Re-run the NTP selection over the stored measurements of an aggregation without measuring again,
+			// e.g. to compare algorithm versions on historical data. The source aggregation is not changed.
+			// Body (optional): {"filter": {"outlier_method": "mad", "offset_selection": "intersection", ...}, "save": true}
+			//   - filter omitted: the settings recorded with the aggregation are reused
+			//   - save: store the result as a new aggregation (201) instead of only returning it (200)
+			// Returns 404 if the aggregation does not exist, 409 if its measurements are no longer stored
+			// Output: {"source_aggregation_id": "agg-123", "saved": true, "result": {"aggregation_id": "agg-456", "best_offset": -148, ...}}
+			sync.POST("/aggregated/:aggregationId/recompute", handler.RecomputeAggregation)
+
+			// GET /api/sync/timeseries
+			// A pairing's best offset over time, downsampled into fixed-size buckets for charting
+			// Query params: pairingId (required), startTime, endTime (RFC3339, optional), bucket (Go duration, default 1h, min 1s)
+			// Example: GET /api/sync/timeseries?pairingId=pair-123&startTime=2024-01-01T00:00:00Z&bucket=1h
+			// Output: {"pairing_id": "pair-123", "bucket_ms": 3600000, "buckets": [{"bucket_start": 1704067200000, "count": 6, "mean_offset": -148.5, "min_offset": -160, "max_offset": -140, "mean_confidence": 0.92}]}
+			sync.GET("/timeseries", handler.GetOffsetTimeSeries)
+
+			// GET /api/sync/compare
+			// Compare two aggregated results side by side (e.g. before/after a firmware change), deltas are B - A
+			// Returns 404 if either aggregation does not exist
+			// Example: GET /api/sync/compare?a=agg-123&b=agg-456
+			// Output: {"a": {...}, "b": {...}, "offset_delta": 30, "abs_offset_delta": -30, "confidence_delta": 0.12, "jitter_delta": -150, "verdict": "B is 30ms closer, 0.12 more confident, 150μs less jitter"}
+			sync.GET("/compare", handler.CompareAggregations)
+
+			// POST /api/sync/apply
+			// Convert device timestamps (Unix ms) to the reference device's clock using an aggregation's offset
+			// The device must belong to the aggregation's pairing; 404 if the aggregation does not exist
+			// Input: {"aggregationId": "agg-123", "deviceId": "watch-001", "timestamps": [1727870400000]}
+			// Output: {"aggregationId": "agg-123", "deviceId": "watch-001", "referenceDeviceId": "psg-001", "offsetMs": -150, "timestamps": [1727870400150]}
+			sync.POST("/apply", handler.ApplyOffset)
+		}
+
+		// Fleet statistics
+		stats := api.Group("/stats")
+		{
+			// GET /api/stats/by-device-type
+			// Sync reliability metrics grouped by device type
+			// Output: [{"device_type": "WATCH", "total_syncs": 120, "success_rate": 0.97, "avg_rtt": 15400, "avg_confidence": 0.88, ...}]
+			stats.GET("/by-device-type", handler.GetDeviceTypeStats)
 		}
 
 		// Auto-Sync management
@@ -101,7 +323,7 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 		{
 			// POST /api/auto-sync/start
 			// Start automatic periodic synchronization for a pairing
-			// Input: {"pairing_id": "pair-123", "interval_sec": 60, "sample_count": 8, "interval_ms": 200}
+			// Input: {"pairing_id": "pair-123", "interval_sec": 60, "sample_count": 8, "interval_ms": 200, "min_confidence": 0.7}
 			// Output: {"message": "auto-sync started", "pairing_id": "pair-123"}
 			autoSync.POST("/start", handler.StartAutoSync)
 
@@ -111,6 +333,24 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 			// Output: {"message": "auto-sync stopped", "pairing_id": "pair-123"}
 			autoSync.POST("/stop/:pairingId", handler.StopAutoSync)
 
+			// POST /api/auto-sync/pause/:pairingId
+			// Pause automatic synchronization, keeping the job and its counters
+			// Example: POST /api/auto-sync/pause/pair-123
+			// Output: {"message": "auto-sync paused", "pairing_id": "pair-123"}
+			autoSync.POST("/pause/:pairingId", handler.PauseAutoSync)
+
+			// POST /api/auto-sync/resume/:pairingId
+			// Resume a paused auto-sync job
+			// Example: POST /api/auto-sync/resume/pair-123
+			// Output: {"message": "auto-sync resumed", "pairing_id": "pair-123"}
+			autoSync.POST("/resume/:pairingId", handler.ResumeAutoSync)
+
+			// PATCH /api/auto-sync/:pairingId
+			// Reconfigure a running or paused job in place, keeping its counters; omitted fields are unchanged
+			// Input: {"interval_sec": 30, "sample_count": 16, "interval_ms": 200, "timeout_sec": 5, "min_confidence": 0.7}
+			// Output: {"message": "auto-sync updated", "pairing_id": "pair-123", "config": {...}}
+			autoSync.PATCH("/:pairingId", handler.UpdateAutoSync)
+
 			// GET /api/auto-sync/status
 			// Get status of all auto-sync jobs or specific pairing
 			// Query params: pairingId (optional)
@@ -120,5 +360,21 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 			// Output: {"jobs": [{"pairing_id": "pair-123", "status": "RUNNING", ...}]}
 			autoSync.GET("/status", handler.GetAutoSyncStatus)
 		}
+
+		// Operations (require "Authorization: Bearer <ADMIN_TOKEN>"; 503 if ADMIN_TOKEN is not set)
+		admin := api.Group("/admin", handler.requireAdmin())
+		{
+			// GET /api/admin/connections
+			// Live WebSocket connections ordered by device ID
+			// Output: [{"deviceId": "watch-001", "deviceType": "WATCH", "remoteAddr": "10.0.0.7:51234", "connectedAt": "...",
+			//           "lastRtt": 12, "sendBufferDepth": 0, "sendBufferSize": 256, "droppedMessages": 0}]
+			admin.GET("/connections", handler.ListConnections)
+
+			// DELETE /api/admin/connections/:deviceId
+			// Forcibly close a (stuck) connection; the device is unregistered and may reconnect
+			// Example: DELETE /api/admin/connections/watch-001
+			// Output: {"message": "connection closed", "deviceId": "watch-001"}
+			admin.DELETE("/connections/:deviceId", handler.CloseConnection)
+		}
 	}
 }
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"log"
+
+	"time-sync-server/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Run serves r on the configured port until it fails. With TLS configured it serves HTTPS,
+// and the WebSocket endpoints are reachable as wss://; otherwise it serves plain HTTP.
+func Run(r *gin.Engine, cfg *config.Config) error {
+	addr := ":" + cfg.ServerPort
+	if cfg.TLSEnabled() {
+		log.Printf("Serving HTTPS/WSS on %s", addr)
+		return r.RunTLS(addr, cfg.TLSCertPath, cfg.TLSKeyPath)
+	}
+	log.Printf("Serving HTTP/WS on %s", addr)
+	return r.Run(addr)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestSyncErrorCodes(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	hub.Clients["psg-001"] = &ws.Client{DeviceID: "psg-001"}
+	hub.Pairings["pair-offline"] = &models.Pairing{PairingID: "pair-offline", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/sync/multi", h.RequestMultiSync)
+	r.POST("/api/sync/:pairingId", h.RequestSync)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               models.ErrorCode
+	}{
+		{http.MethodPost, "/api/sync/pair-missing", "", http.StatusNotFound, models.ErrorCodePairingNotFound},
+		{http.MethodPost, "/api/sync/pair-offline", "", http.StatusConflict, models.ErrorCodeDeviceOffline},
+		{http.MethodPost, "/api/sync/multi", `{"pairing_id": "pair-missing", "sample_count": 1}`, http.StatusNotFound, models.ErrorCodePairingNotFound},
+		{http.MethodPost, "/api/sync/multi", `{"pairing_id": "pair-offline", "sample_count": 1}`, http.StatusConflict, models.ErrorCodeDeviceOffline},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, tt.method, tt.path, tt.body)
+		var resp models.APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", tt.method, tt.path, err)
+		}
+		if w.Code != tt.status || resp.Code != tt.code || resp.Message == "" {
+			t.Errorf("%s %s %s = %d %q, expected %d %q", tt.method, tt.path, tt.body, w.Code, resp.Code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestRequestSyncRejectedWhenPendingCapReached(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	hub.SetMaxPendingRequests(2)
+	for _, deviceID := range []string{"psg-001", "watch-001"} {
+		hub.Clients[deviceID] = &ws.Client{DeviceID: deviceID}
+	}
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+	// Requests against unresponsive devices that have not timed out yet
+	for _, requestID := range []string{"req-1", "req-2"} {
+		hub.PendingRequests[requestID] = &ws.PendingRequest{RequestID: requestID, PairingID: "pair-123"}
+	}
+
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/sync/:pairingId", h.RequestSync)
+
+	w := doRequest(r, http.MethodPost, "/api/sync/pair-123", "")
+	var resp models.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusServiceUnavailable || resp.Code != models.ErrorCodeServerBusy {
+		t.Errorf("POST /api/sync/pair-123 = %d %q, expected 503 %q", w.Code, resp.Code, models.ErrorCodeServerBusy)
+	}
+	if count := hub.PendingRequestCount(); count != 2 {
+		t.Errorf("PendingRequestCount() = %d, expected the rejected request not to be queued", count)
+	}
+}
+
+func TestGetSyncRecordAnalysis(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	int64Ptr := func(v int64) *int64 { return &v }
+	records := []*models.TimeSyncRecord{
+		{Device1ID: "psg-001", Device2ID: "watch-001", Device1Timestamp: int64Ptr(1727870400123), Device2Timestamp: int64Ptr(1727870400456),
+			Device1RTT: int64Ptr(5000), Device2RTT: int64Ptr(8000), TimeDifference: int64Ptr(-333), Status: models.SyncStatusSuccess},
+		{Device1ID: "psg-001", Device2ID: "watch-001", Device1Timestamp: int64Ptr(1727870400123), Device1RTT: int64Ptr(5000), Status: models.SyncStatusPartial},
+	}
+	for _, record := range records {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/sync/records/:recordId/analysis", h.GetSyncRecordAnalysis)
+
+	w := doRequest(r, http.MethodGet, fmt.Sprintf("/api/sync/records/%d/analysis", records[0].ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET analysis = %d, expected 200", w.Code)
+	}
+	var analysis models.SyncRecordAnalysis
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if analysis.Device1OneWayDelay == nil || *analysis.Device1OneWayDelay != 2.5 || analysis.Device2OneWayDelay == nil || *analysis.Device2OneWayDelay != 4 {
+		t.Errorf("one-way delays = %v, %v, expected 2.5 and 4", analysis.Device1OneWayDelay, analysis.Device2OneWayDelay)
+	}
+	if analysis.DelayCorrection == nil || *analysis.DelayCorrection != -1.5 {
+		t.Errorf("DelayCorrection = %v, expected -1.5", analysis.DelayCorrection)
+	}
+	if analysis.AdjustedOffset == nil || *analysis.AdjustedOffset != -332 || analysis.TotalRTT == nil || *analysis.TotalRTT != 13000 {
+		t.Errorf("AdjustedOffset, TotalRTT = %v, %v, expected -332 and 13000", analysis.AdjustedOffset, analysis.TotalRTT)
+	}
+
+	// A timed-out device leaves nothing for the selector to adjust
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/sync/records/%d/analysis", records[1].ID), "")
+	analysis = models.SyncRecordAnalysis{}
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET analysis of partial record = %d, %v", w.Code, err)
+	}
+	if analysis.Device1OneWayDelay == nil || analysis.AdjustedOffset != nil || analysis.DelayCorrection != nil {
+		t.Errorf("partial record analysis = %+v, expected only device1's delay", analysis)
+	}
+
+	for path, status := range map[string]int{"/api/sync/records/999/analysis": http.StatusNotFound, "/api/sync/records/abc/analysis": http.StatusBadRequest} {
+		if w := doRequest(r, http.MethodGet, path, ""); w.Code != status {
+			t.Errorf("GET %s = %d, expected %d", path, w.Code, status)
+		}
+	}
+}
+
+// unreadableRecordRepository fails every record lookup as if the database were down
+type unreadableRecordRepository struct {
+	*repository.InMemoryRepository
+}
+
+func (r *unreadableRecordRepository) GetTimeSyncRecord(int64) (*models.TimeSyncRecord, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestGetSyncRecordAnalysisErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		repo   service.Repository
+		status int
+		code   models.ErrorCode
+	}{
+		{"missing record", repository.NewInMemoryRepository(), http.StatusNotFound, models.ErrorCodeRecordNotFound},
+		{"lookup failure", &unreadableRecordRepository{repository.NewInMemoryRepository()}, http.StatusInternalServerError, models.ErrorCodeInternal},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := ws.NewHub()
+			h := &Handler{syncService: service.NewSyncService(hub, tt.repo), hub: hub, repository: tt.repo}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/api/sync/records/:recordId/analysis", h.GetSyncRecordAnalysis)
+
+			w := doRequest(r, http.MethodGet, "/api/sync/records/999/analysis", "")
+			var resp models.APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Code != tt.status || resp.Code != tt.code {
+				t.Errorf("GET analysis = %d %q, expected %d %q", w.Code, resp.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestDeleteSyncRecord(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	var records []*models.TimeSyncRecord
+	for i := 0; i < 2; i++ {
+		record := &models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusSuccess}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		records = append(records, record)
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{AggregationID: "agg-123", PairingID: "pair-123", Measurements: records}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	hub := ws.NewHub()
+	h := &Handler{syncService: service.NewSyncService(hub, repo), hub: hub, repository: repo}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/api/sync/records/:recordId", h.DeleteSyncRecord)
+
+	path := fmt.Sprintf("/api/sync/records/%d", records[0].ID)
+	if w := doRequest(r, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected 200: %s", w.Code, w.Body.String())
+	}
+	// The aggregation no longer links to the deleted record
+	result, err := repo.GetAggregatedSyncResult("agg-123")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(result.Measurements) != 1 || result.Measurements[0].ID != records[1].ID {
+		t.Errorf("agg-123 measurements = %v, expected only record %d", result.Measurements, records[1].ID)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCode   models.ErrorCode
+	}{
+		{"already deleted", path, http.StatusNotFound, models.ErrorCodeRecordNotFound},
+		{"unknown record", "/api/sync/records/999", http.StatusNotFound, models.ErrorCodeRecordNotFound},
+		{"invalid ID", "/api/sync/records/abc", http.StatusBadRequest, models.ErrorCodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodDelete, tt.path, "")
+			var resp models.APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Code != tt.expectedStatus || resp.Code != tt.expectedCode {
+				t.Errorf("status = %d %q, expected %d %q", w.Code, resp.Code, tt.expectedStatus, tt.expectedCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/service"
+	ws "time-sync-server/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketBufferSizes(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, &config.Config{}, repo)
+	if h.upgrader.ReadBufferSize != 1024 || h.upgrader.WriteBufferSize != 1024 {
+		t.Errorf("buffer sizes = %d/%d, expected the 1024 byte default", h.upgrader.ReadBufferSize, h.upgrader.WriteBufferSize)
+	}
+
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	cfg := &config.Config{MaxMessageSize: 4096, WSReadBufferSize: 256, WSWriteBufferSize: 512}
+	h = NewHandler(service.NewSyncService(hub, repo), nil, hub, cfg, repo)
+	if h.upgrader.ReadBufferSize != 256 || h.upgrader.WriteBufferSize != 512 {
+		t.Errorf("buffer sizes = %d/%d, expected 256/512", h.upgrader.ReadBufferSize, h.upgrader.WriteBufferSize)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?deviceId=watch-001&deviceType=WATCH", nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	// The buffers only size the I/O, messages larger than them still go through
+	if err := conn.WriteMessage(websocket.TextMessage, paddedMessage(t, models.MessageTypePing, 3000)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if !awaitMessage(conn, models.MessageTypePong) {
+		t.Error("no PONG for a PING larger than the read buffer")
+	}
+}
+
+func TestWebSocketMessageSizeLimit(t *testing.T) {
+	const maxMessageSize = 1024
+
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	cfg := &config.Config{MaxMessageSize: maxMessageSize}
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, cfg, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?deviceId=watch-001&deviceType=WATCH"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	// A PING padded to just below the limit is still answered with a PONG
+	ping := paddedMessage(t, models.MessageTypePing, maxMessageSize-8)
+	if err := conn.WriteMessage(websocket.TextMessage, ping); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if !awaitMessage(conn, models.MessageTypePong) {
+		t.Fatalf("no PONG for a %d byte PING, expected the connection to stay open", len(ping))
+	}
+
+	// One past the limit closes the connection
+	ping = paddedMessage(t, models.MessageTypePing, maxMessageSize+1)
+	if err := conn.WriteMessage(websocket.TextMessage, ping); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+				t.Errorf("ReadMessage() error = %v, expected close %d (message too big)", err, websocket.CloseMessageTooBig)
+			}
+			break
+		}
+	}
+}
+
+func TestWebSocketPublishesDeviceConnectionEvents(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	bus := events.NewEventBus()
+	hub := ws.NewHub()
+	hub.SetEventBus(bus)
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	_, eventCh := bus.Subscribe()
+
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, &config.Config{}, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?deviceId=watch-001&deviceType=WATCH"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	awaitEvent := func(eventType models.EventType) *models.DeviceConnectionEvent {
+		t.Helper()
+		select {
+		case event := <-eventCh:
+			data, ok := event.Data.(*models.DeviceConnectionEvent)
+			if event.Type != eventType || !ok {
+				t.Fatalf("event = %s %T, expected %s with DeviceConnectionEvent data", event.Type, event.Data, eventType)
+			}
+			return data
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s event", eventType)
+		}
+		return nil
+	}
+
+	connected := awaitEvent(models.EventTypeDeviceConnected)
+	if connected.DeviceID != "watch-001" || connected.DeviceType != models.DeviceTypeWatch || connected.Timestamp == 0 {
+		t.Errorf("DEVICE_CONNECTED data = %+v, expected watch-001 of type WATCH with a timestamp", connected)
+	}
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	conn.Close()
+	disconnected := awaitEvent(models.EventTypeDeviceDisconnected)
+	if disconnected.DeviceID != "watch-001" || disconnected.Reason != models.DisconnectReasonCleanClose {
+		t.Errorf("DEVICE_DISCONNECTED data = %+v, expected watch-001 with reason %s", disconnected, models.DisconnectReasonCleanClose)
+	}
+}
+
+func TestWebSocketAcceptsEveryDeviceType(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, &config.Config{}, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	baseURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"?deviceId=mobile-001&deviceType=MOBILE", nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if !awaitMessage(conn, models.MessageTypeConnected) {
+		t.Fatal("no CONNECTED message for the MOBILE device")
+	}
+	if deviceType, ok := hub.GetDeviceType("mobile-001"); !ok || deviceType != models.DeviceTypeMobile {
+		t.Errorf("GetDeviceType() = %q, %v, expected MOBILE", deviceType, ok)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(baseURL+"?deviceId=tablet-001&deviceType=TABLET", nil)
+	if err == nil {
+		t.Fatal("Dial() with an unknown device type succeeded")
+	}
+	var apiErr models.APIError
+	if decodeErr := json.NewDecoder(resp.Body).Decode(&apiErr); decodeErr != nil {
+		t.Fatalf("failed to decode error response: %v", decodeErr)
+	}
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(apiErr.Message, "MOBILE") {
+		t.Errorf("unknown device type = %d %q, expected 400 listing every valid type", resp.StatusCode, apiErr.Message)
+	}
+}
+
+// paddedMessage builds a message of msgType that is exactly size bytes long
+func paddedMessage(t *testing.T, msgType models.MessageType, size int) []byte {
+	t.Helper()
+	msg := map[string]interface{}{"type": msgType, "timestamp": time.Now().UnixMilli(), "padding": ""}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	msg["padding"] = strings.Repeat("x", size-len(data))
+	data, _ = json.Marshal(msg)
+	if len(data) != size {
+		t.Fatalf("padded message is %d bytes, expected %d", len(data), size)
+	}
+	return data
+}
+
+// awaitMessage reads until a message of msgType arrives, returning false on error or after 2 seconds.
+// The server batches queued messages into one frame separated by newlines.
+func awaitMessage(conn *websocket.Conn, msgType models.MessageType) bool {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return false
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			var msg models.WSMessage
+			if json.Unmarshal([]byte(line), &msg) == nil && msg.Type == msgType {
+				return true
+			}
+		}
+	}
+}
+
+// staticTokenValidator accepts one token per device and maps it to an identity
+type staticTokenValidator map[string]string
+
+func (v staticTokenValidator) ValidateToken(token, deviceID string) (string, error) {
+	if token == "" || v[deviceID] != token {
+		return "", ErrInvalidToken
+	}
+	return "device:" + deviceID, nil
+}
+
+func TestWebSocketTokenAuth(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	cfg := &config.Config{WSAuthEnabled: true, WSAuthSecret: "s3cret"}
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, cfg, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	baseURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?deviceType=WATCH"
+
+	// Rejected before the upgrade, so the handshake fails with the 401 JSON error
+	for name, url := range map[string]string{
+		"missing token": baseURL + "&deviceId=watch-001",
+		"invalid token": baseURL + "&deviceId=watch-001&token=wrong",
+	} {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			conn.Close()
+			t.Errorf("%s: Dial() succeeded, expected the handshake to be refused", name)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: response = %v, expected 401", name, resp)
+			continue
+		}
+		var apiErr models.APIError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Code != models.ErrorCodeUnauthorized {
+			t.Errorf("%s: body code = %q, %v, expected %s", name, apiErr.Code, err, models.ErrorCodeUnauthorized)
+		}
+		resp.Body.Close()
+	}
+	if count := len(hub.GetConnections()); count != 0 {
+		t.Errorf("%d connection(s) registered after refused handshakes, expected none", count)
+	}
+
+	// The token is accepted as a query param or a Bearer header
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL+"&deviceId=watch-001&token=s3cret", nil)
+	if err != nil {
+		t.Fatalf("Dial() with the query token error = %v", err)
+	}
+	defer conn.Close()
+	header := http.Header{"Authorization": []string{"Bearer s3cret"}}
+	conn2, _, err := websocket.DefaultDialer.Dial(baseURL+"&deviceId=watch-002", header)
+	if err != nil {
+		t.Fatalf("Dial() with the Bearer token error = %v", err)
+	}
+	defer conn2.Close()
+
+	// Identity comes from the validator: the device ID for the shared secret
+	h.SetTokenValidator(staticTokenValidator{"watch-003": "token-3"})
+	conn3, _, err := websocket.DefaultDialer.Dial(baseURL+"&deviceId=watch-003&token=token-3", nil)
+	if err != nil {
+		t.Fatalf("Dial() with a per-device token error = %v", err)
+	}
+	defer conn3.Close()
+
+	for _, c := range []*websocket.Conn{conn, conn2, conn3} {
+		if !awaitMessage(c, models.MessageTypeConnected) {
+			t.Fatal("no CONNECTED message after an accepted handshake")
+		}
+	}
+	identities := map[string]string{}
+	for _, info := range hub.GetConnections() {
+		identities[info.DeviceID] = info.Identity
+	}
+	expected := map[string]string{"watch-001": "watch-001", "watch-002": "watch-002", "watch-003": "device:watch-003"}
+	for deviceID, identity := range expected {
+		if identities[deviceID] != identity {
+			t.Errorf("Identity of %s = %q, expected %q", deviceID, identities[deviceID], identity)
+		}
+	}
+}
+
+func TestWebSocketAuthDisabledWithoutTokens(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := ws.NewHub()
+	go hub.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+	}()
+
+	// No secret and no validator configured
+	h := NewHandler(service.NewSyncService(hub, repo), nil, hub, &config.Config{}, repo)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", h.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?deviceId=watch-001&deviceType=WATCH", nil)
+	if err != nil {
+		t.Fatalf("Dial() without a token error = %v, expected authentication to be disabled", err)
+	}
+	defer conn.Close()
+	if !awaitMessage(conn, models.MessageTypeConnected) {
+		t.Fatal("no CONNECTED message")
+	}
+	connections := hub.GetConnections()
+	if len(connections) != 1 || connections[0].Identity != "" {
+		t.Errorf("connections = %+v, expected one without an identity", connections)
+	}
+}
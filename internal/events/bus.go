@@ -0,0 +1,84 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// subscriberBufferSize is how many events a slow subscriber may lag behind before events are dropped
+const subscriberBufferSize = 64
+
+// EventBus is a small in-process pub/sub for dashboard events.
+// Publish never blocks: a subscriber whose buffer is full misses the event.
+type EventBus struct {
+	subscribers map[int]chan *models.Event
+	nextID      int
+	mu          sync.RWMutex
+}
+
+// NewEventBus creates an EventBus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]chan *models.Event),
+	}
+}
+
+// Subscribe registers a subscriber and returns its ID and event channel.
+// The channel is closed by Unsubscribe.
+func (b *EventBus) Subscribe() (int, <-chan *models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	ch := make(chan *models.Event, subscriberBufferSize)
+	b.subscribers[id] = ch
+	return id, ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *EventBus) Unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch, ok := b.subscribers[id]; ok {
+		delete(b.subscribers, id)
+		close(ch)
+	}
+}
+
+// Publish sends an event to every subscriber. It is safe to call on a nil EventBus.
+func (b *EventBus) Publish(eventType models.EventType, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	event := &models.Event{
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Event subscriber %d is too slow, dropping %s event", id, eventType)
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers
+func (b *EventBus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers)
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"testing"
+
+	"time-sync-server/internal/models"
+)
+
+func TestPublishDeliversToAllSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	_, ch1 := bus.Subscribe()
+	_, ch2 := bus.Subscribe()
+
+	bus.Publish(models.EventTypeAutoSyncState, "payload")
+
+	for i, ch := range []<-chan *models.Event{ch1, ch2} {
+		select {
+		case event := <-ch:
+			if event.Type != models.EventTypeAutoSyncState || event.Data != "payload" {
+				t.Errorf("subscriber %d: unexpected event %+v", i, event)
+			}
+		default:
+			t.Errorf("subscriber %d: no event delivered", i)
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	bus := NewEventBus()
+	id, ch := bus.Subscribe()
+
+	bus.Unsubscribe(id)
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed after Unsubscribe")
+	}
+	if got := bus.SubscriberCount(); got != 0 {
+		t.Errorf("expected 0 subscribers, got %d", got)
+	}
+
+	// Publishing after unsubscribe and unsubscribing twice must not panic
+	bus.Publish(models.EventTypeAggregatedResult, nil)
+	bus.Unsubscribe(id)
+}
+
+func TestPublishDropsEventsForFullSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	_, ch := bus.Subscribe()
+
+	for i := 0; i < subscriberBufferSize+10; i++ {
+		bus.Publish(models.EventTypeAggregatedResult, i)
+	}
+
+	if got := len(ch); got != subscriberBufferSize {
+		t.Errorf("expected %d buffered events, got %d", subscriberBufferSize, got)
+	}
+}
+
+func TestPublishOnNilBus(t *testing.T) {
+	var bus *EventBus
+	bus.Publish(models.EventTypeAggregatedResult, nil)
+}
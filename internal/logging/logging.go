@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Output formats for Setup
+const (
+	FormatJSON = "json" // One JSON object per line (default)
+	FormatText = "text" // key=value pairs, easier to read in a terminal
+)
+
+// Common attribute keys, so one sync flow can be followed with a single grep/jq filter
+const (
+	KeyCorrelationID = "correlation_id"
+	KeyRequestID     = "request_id"
+	KeyPairingID     = "pairing_id"
+	KeyDeviceID      = "device_id"
+)
+
+// ParseLevel parses "debug", "info", "warn" or "error" (case-insensitive)
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (use debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// New creates a logger writing to w in the given format at the given minimum level
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// Setup installs a stderr logger as the slog default and returns it.
+// Plain log.Printf output is routed through it as well, at INFO level.
+func Setup(level, format string) (*slog.Logger, error) {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := New(os.Stderr, parsed, format)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// NewCorrelationID returns an ID that ties together all log lines of one sync flow
+func NewCorrelationID() string {
+	return uuid.New().String()
+}
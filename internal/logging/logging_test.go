@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{" warn ", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.input)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) error = %v", tt.input, err)
+			continue
+		}
+		if level != tt.expected {
+			t.Errorf("ParseLevel(%q) = %v, expected %v", tt.input, level, tt.expected)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(\"verbose\") expected an error")
+	}
+}
+
+func TestNewJSONLoggerFiltersByLevelAndKeepsFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, FormatJSON)
+
+	logger.Debug("dropped")
+	logger.With(KeyCorrelationID, "corr-1").Info("time sync completed", KeyDeviceID, "watch-001")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "time sync completed" {
+		t.Errorf("msg = %v, expected \"time sync completed\"", entry["msg"])
+	}
+	if entry[KeyCorrelationID] != "corr-1" || entry[KeyDeviceID] != "watch-001" {
+		t.Errorf("missing fields in %v", entry)
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "timesync"
+
+var (
+	// SyncRequestsTotal counts completed time sync requests (one per TIME_REQUEST round)
+	SyncRequestsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_requests_total",
+		Help:      "Total number of completed time sync requests.",
+	})
+
+	// SyncFailuresTotal counts sync requests that did not get a response from every device
+	SyncFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_failures_total",
+		Help:      "Total number of time sync requests that ended PARTIAL or FAILED.",
+	}, []string{"status"})
+
+	// SyncTimeoutsTotal counts sync requests completed by their timeout timer
+	SyncTimeoutsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_timeouts_total",
+		Help:      "Total number of time sync requests that timed out.",
+	})
+
+	// SampleRTTSeconds is the distribution of per-device round trip times
+	SampleRTTSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sample_rtt_seconds",
+		Help:      "Round trip time of a single TIME_REQUEST/TIME_RESPONSE exchange per device.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12), // 1ms ~ 2s
+	}, []string{"device_type"})
+
+	// ConnectedDevices is the number of devices currently connected over WebSocket
+	ConnectedDevices = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connected_devices",
+		Help:      "Number of currently connected devices.",
+	})
+
+	// ActivePairings is the number of pairings currently held in memory
+	ActivePairings = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_pairings",
+		Help:      "Number of active (in-memory) pairings.",
+	})
+
+	// AutoSyncJobsRunning is the number of auto-sync jobs that are running, paused jobs are not counted
+	AutoSyncJobsRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auto_sync_jobs_running",
+		Help:      "Number of running auto-sync jobs.",
+	})
+)
+
+// ObserveRTT records a device RTT given in microseconds
+func ObserveRTT(deviceType string, rttMicros *int64) {
+	if rttMicros == nil {
+		return
+	}
+	SampleRTTSeconds.WithLabelValues(deviceType).Observe(float64(*rttMicros) / 1e6)
+}
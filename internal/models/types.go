@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
 
 // DeviceType represents the type of device
 type DeviceType string
@@ -11,6 +15,15 @@ const (
 	DeviceTypeMobile DeviceType = "MOBILE"
 )
 
+// Valid reports whether t is one of the known device types
+func (t DeviceType) Valid() bool {
+	switch t {
+	case DeviceTypePSG, DeviceTypeWatch, DeviceTypeMobile:
+		return true
+	}
+	return false
+}
+
 // SyncStatus represents the status of a time synchronization
 type SyncStatus string
 
@@ -24,6 +37,8 @@ const (
 type Device struct {
 	DeviceID    string     `json:"deviceId"`
 	DeviceType  DeviceType `json:"deviceType"`
+	Model       string     `json:"model,omitempty"`    // Reported on connect, empty if unknown
+	Firmware    string     `json:"firmware,omitempty"` // Reported on connect, empty if unknown
 	ConnectedAt time.Time  `json:"connectedAt"`
 }
 
@@ -31,12 +46,109 @@ type Device struct {
 type DeviceHealth struct {
 	DeviceID       string     `json:"deviceId"`
 	DeviceType     DeviceType `json:"deviceType"`
+	Model          string     `json:"model,omitempty"`
+	Firmware       string     `json:"firmware,omitempty"`
 	ConnectedAt    time.Time  `json:"connectedAt"`
 	LastPingSent   time.Time  `json:"lastPingSent"`
 	LastPongRecv   time.Time  `json:"lastPongRecv"`
 	LastRTT        int64      `json:"lastRtt"`        // milliseconds
 	IsHealthy      bool       `json:"isHealthy"`      // true if PONG received within threshold
 	TimeSinceLastPong int64   `json:"timeSinceLastPong"` // milliseconds
+	SendBufferDepth int       `json:"sendBufferDepth"` // messages currently queued for the device
+	SendBufferSize int        `json:"sendBufferSize"`  // capacity of the send buffer (SEND_BUFFER_SIZE)
+	DroppedMessages int64     `json:"droppedMessages"` // messages dropped because the send buffer was full
+	WriteTimeouts  int64      `json:"writeTimeouts"`   // connections of this device dropped for exceeding the write deadline
+}
+
+// ConnectionInfo describes a live WebSocket connection for the admin API
+type ConnectionInfo struct {
+	DeviceID        string     `json:"deviceId"`
+	DeviceType      DeviceType `json:"deviceType"`
+	Identity        string     `json:"identity,omitempty"` // Authenticated identity, if auth is enabled
+	RemoteAddr      string     `json:"remoteAddr"`
+	ConnectedAt     time.Time  `json:"connectedAt"`
+	LastRTT         int64      `json:"lastRtt"`         // milliseconds
+	SendBufferDepth int        `json:"sendBufferDepth"` // Messages queued for the device
+	SendBufferSize  int        `json:"sendBufferSize"`  // Capacity of the send buffer
+	DroppedMessages int64      `json:"droppedMessages"`
+}
+
+// OffsetOverride pins a pairing's offset to a manually calibrated value, e.g. after a known-good
+// calibration. OffsetMs follows the aggregated results' convention: device time - reference time.
+type OffsetOverride struct {
+	PairingID         string     `json:"pairingId"`
+	ReferenceDeviceID string     `json:"referenceDeviceId"`
+	OffsetMs          int64      `json:"offsetMs"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"` // nil = active until removed
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// Active reports whether the override has not expired at now
+func (o *OffsetOverride) Active(now time.Time) bool {
+	return o.ExpiresAt == nil || now.Before(*o.ExpiresAt)
+}
+
+// OffsetAgainst returns the pinned offset relative to referenceDeviceID, negated when that is
+// the pairing's other device
+func (o *OffsetOverride) OffsetAgainst(referenceDeviceID string) int64 {
+	if referenceDeviceID != "" && referenceDeviceID != o.ReferenceDeviceID {
+		return -o.OffsetMs
+	}
+	return o.OffsetMs
+}
+
+// OffsetForDevice returns the pinned offset of the device's clock (device time - reference time);
+// the reference device itself is 0
+func (o *OffsetOverride) OffsetForDevice(deviceID string) int64 {
+	if deviceID == o.ReferenceDeviceID {
+		return 0
+	}
+	return o.OffsetMs
+}
+
+// OffsetOverrideRequest is the body of POST /api/pairings/:pairingId/override
+type OffsetOverrideRequest struct {
+	OffsetMs  *int64     `json:"offsetMs" binding:"required"` // Device time - reference time
+	ExpiresAt *time.Time `json:"expiresAt"`                   // RFC3339, must be in the future; omit to pin until removed
+}
+
+// DeviceInfo is the persisted metadata of a device, updated every time it connects
+type DeviceInfo struct {
+	DeviceID    string     `json:"deviceId"`
+	DeviceType  DeviceType `json:"deviceType"`
+	Model       string     `json:"model,omitempty"`
+	Firmware    string     `json:"firmware,omitempty"`
+	FirstSeenAt time.Time  `json:"firstSeenAt"`
+	LastSeenAt  time.Time  `json:"lastSeenAt"`
+}
+
+// DeviceEventType is the kind of a recorded device connectivity change
+type DeviceEventType string
+
+const (
+	DeviceEventConnected    DeviceEventType = "CONNECTED"
+	DeviceEventDisconnected DeviceEventType = "DISCONNECTED"
+)
+
+// Reasons recorded with DISCONNECTED device events
+const (
+	DisconnectReasonCleanClose     = "clean_close"             // The device sent a normal close frame
+	DisconnectReasonDeadConnection = "dead_connection_timeout" // No PONG within the dead connection timeout
+	DisconnectReasonReadError      = "read_error"              // The connection failed while reading
+	DisconnectReasonSendBufferFull = "send_buffer_full"        // Slow client dropped by SendPolicyDisconnect
+	DisconnectReasonWriteTimeout   = "write_timeout"           // A write to the device exceeded the write deadline
+	DisconnectReasonWriteError     = "write_error"             // The connection failed while writing
+	DisconnectReasonServerShutdown = "server_shutdown"
+	DisconnectReasonAdminClosed    = "admin_closed" // Closed via DELETE /api/admin/connections/:deviceId
+)
+
+// DeviceEvent is one entry of a device's connect/disconnect audit trail
+type DeviceEvent struct {
+	ID        int64           `json:"id"`
+	DeviceID  string          `json:"deviceId"`
+	EventType DeviceEventType `json:"eventType"`
+	Reason    string          `json:"reason,omitempty"` // Only for DISCONNECTED events
+	Timestamp time.Time       `json:"timestamp"`
 }
 
 // Pairing represents a pairing between two devices (in-memory)
@@ -47,6 +159,47 @@ type Pairing struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// PairingOverview is a persistent pairing joined with its live state:
+// device connections, auto-sync job and the latest aggregated result
+type PairingOverview struct {
+	PairingID string    `json:"pairingId"`
+	Device1ID string    `json:"device1Id"`
+	Device2ID string    `json:"device2Id"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	Device1Connected bool `json:"device1Connected"`
+	Device2Connected bool `json:"device2Connected"`
+	Active           bool `json:"active"`  // Restored in memory, i.e. syncs can be requested
+	Enabled          bool `json:"enabled"` // false while syncing is disabled for the pairing
+
+	AutoSyncStatus  AutoSyncStatus `json:"autoSyncStatus,omitempty"` // Empty if there is no auto-sync job
+	AutoSyncRunning bool           `json:"autoSyncRunning"`
+
+	// Latest aggregated result, omitted if the pairing was never aggregated
+	LastSyncAt     *time.Time `json:"lastSyncAt,omitempty"`
+	LastBestOffset *int64     `json:"lastBestOffset,omitempty"`
+	LastConfidence *float64   `json:"lastConfidence,omitempty"`
+}
+
+// GroupPairing represents a pairing of two or more devices synchronized in one session
+// (e.g. PSG + watch + mobile). Offsets are expressed relative to the reference device.
+type GroupPairing struct {
+	PairingID         string    `json:"pairingId"`
+	DeviceIDs         []string  `json:"deviceIds"`
+	ReferenceDeviceID string    `json:"referenceDeviceId"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// HasDevice reports whether the device is a member of the group
+func (g *GroupPairing) HasDevice(deviceID string) bool {
+	for _, id := range g.DeviceIDs {
+		if id == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
 // PersistentPairing represents a pairing stored in the database (includes Auto-Sync config)
 type PersistentPairing struct {
 	PairingID string    `json:"pairingId"`
@@ -54,12 +207,27 @@ type PersistentPairing struct {
 	Device2ID string    `json:"device2Id"`
 	CreatedAt time.Time `json:"createdAt"`
 
+	// Disabled pairings keep their configuration and history but refuse to sync. New pairings are enabled.
+	Enabled bool `json:"enabled"`
+
 	// Auto-Sync configuration (nullable)
 	AutoSyncIntervalSec *int `json:"autoSyncIntervalSec,omitempty"`
 	AutoSyncSampleCount *int `json:"autoSyncSampleCount,omitempty"`
 	AutoSyncIntervalMs  *int `json:"autoSyncIntervalMs,omitempty"`
+	AutoSyncTimeoutSec  *int `json:"autoSyncTimeoutSec,omitempty"` // nil for pairings saved before it was configurable
+
+	// Automatic aggregation of single-sync records (opt-in)
+	AutoAggregateEnabled   bool `json:"autoAggregateEnabled"`
+	AutoAggregateWindowSec *int `json:"autoAggregateWindowSec,omitempty"` // Look-back window, server default if nil
+	AutoAggregateMinCount  *int `json:"autoAggregateMinCount,omitempty"`  // Minimum records per aggregation, server default if nil
 }
 
+// Sources of a TimeSyncRecord
+const (
+	RecordSourceSingle = "single" // A single sync (POST /api/sync/:pairingId)
+	RecordSourceMulti  = "multi"  // A sample of a multi-sync, including Auto-Sync
+)
+
 // TimeSyncRecord represents a time synchronization record
 type TimeSyncRecord struct {
 	ID                 int64      `json:"id"`
@@ -80,6 +248,15 @@ type TimeSyncRecord struct {
 	Status             SyncStatus `json:"status"`
 	ErrorMessage       *string    `json:"errorMessage,omitempty"`
 	CreatedAt          int64      `json:"createdAt"` // Milliseconds
+	// True if both devices reported recvTime/sendTime: TimeDifference is then the difference of
+	// their four-timestamp offsets, already compensated for network delay
+	OffsetCompensated bool `json:"offsetCompensated,omitempty"`
+	// How the record was measured (RecordSourceSingle or RecordSourceMulti). Only single-sync
+	// records are picked up by auto-aggregation. Empty for records saved before it was recorded.
+	Source string `json:"source,omitempty"`
+	// Network-compensated offset computed by NTPSelector (ms).
+	// Only set on the measurements of an aggregated result; stored per aggregation link.
+	AdjustedOffset *int64 `json:"adjustedOffset,omitempty"`
 }
 
 // WebSocket Message Types
@@ -92,6 +269,14 @@ const (
 	MessageTypeError        MessageType = "ERROR"
 	MessageTypePing         MessageType = "PING"
 	MessageTypePong         MessageType = "PONG"
+	MessageTypePairRequest  MessageType = "PAIR_REQUEST"
+	MessageTypePairConfirm  MessageType = "PAIR_CONFIRM"
+	MessageTypePairResult   MessageType = "PAIR_RESULT"
+	MessageTypeRTTProbe     MessageType = "RTT_PROBE"
+	MessageTypeRTTProbeAck  MessageType = "RTT_PROBE_ACK"
+
+	// Sent after CONNECTED once the device's saved pairings are restored; clients should wait for it before requesting syncs
+	MessageTypePairingsRestored MessageType = "PAIRINGS_RESTORED"
 )
 
 // WebSocket Messages
@@ -105,6 +290,15 @@ type ConnectedMessage struct {
 	ServerTime int64       `json:"serverTime"`
 }
 
+// PairingsRestoredMessage lists the pairings of a device that are active once restoration has finished.
+// Pairings whose other devices are not connected yet are restored later and not listed.
+type PairingsRestoredMessage struct {
+	Type            MessageType `json:"type"`
+	DeviceID        string      `json:"deviceId"`
+	PairingIDs      []string    `json:"pairingIds"`
+	GroupPairingIDs []string    `json:"groupPairingIds"`
+}
+
 type TimeRequestMessage struct {
 	Type      MessageType `json:"type"`
 	RequestID string      `json:"requestId"`
@@ -115,6 +309,11 @@ type TimeResponseMessage struct {
 	Type      MessageType `json:"type"`
 	RequestID string      `json:"requestId"`
 	Timestamp int64       `json:"timestamp"`
+	// Optional device times (milliseconds, same clock as Timestamp) at which the TIME_REQUEST
+	// was received and this response was sent. With both, the offset is computed from four
+	// timestamps instead of assuming the device answered at RTT/2.
+	RecvTime *int64 `json:"recvTime,omitempty"`
+	SendTime *int64 `json:"sendTime,omitempty"`
 }
 
 type ErrorMessage struct {
@@ -133,6 +332,58 @@ type PongMessage struct {
 	Timestamp int64       `json:"timestamp"`
 }
 
+// RTTProbeMessage is sent by the server to measure a device's RTT on demand
+type RTTProbeMessage struct {
+	Type      MessageType `json:"type"`
+	ProbeID   string      `json:"probeId"`
+	Timestamp int64       `json:"timestamp"` // Server send time (milliseconds)
+}
+
+// RTTProbeAckMessage is the device's immediate answer to an RTT_PROBE
+type RTTProbeAckMessage struct {
+	Type    MessageType `json:"type"`
+	ProbeID string      `json:"probeId"`
+}
+
+// PairRequestMessage is sent by a device to request pairing with another device.
+// When the server forwards it to the target for confirmation, RequestID and
+// RequesterID are filled in by the server.
+type PairRequestMessage struct {
+	Type           MessageType `json:"type"`
+	RequestID      string      `json:"requestId,omitempty"`
+	TargetDeviceID string      `json:"targetDeviceId"`
+	RequesterID    string      `json:"requesterId,omitempty"`
+	RequireConfirm bool        `json:"requireConfirm"` // Target must answer with PAIR_CONFIRM
+}
+
+// PairConfirmMessage is sent by the target device to accept or reject a pairing request
+type PairConfirmMessage struct {
+	Type      MessageType `json:"type"`
+	RequestID string      `json:"requestId"`
+	Accepted  bool        `json:"accepted"`
+}
+
+// PairResultStatus represents the outcome of a device-initiated pairing request
+type PairResultStatus string
+
+const (
+	PairResultAccepted PairResultStatus = "ACCEPTED"
+	PairResultRejected PairResultStatus = "REJECTED"
+	PairResultTimeout  PairResultStatus = "TIMEOUT"
+	PairResultFailed   PairResultStatus = "FAILED"
+)
+
+// PairResultMessage notifies devices of the outcome of a pairing request
+type PairResultMessage struct {
+	Type      MessageType      `json:"type"`
+	RequestID string           `json:"requestId,omitempty"`
+	Status    PairResultStatus `json:"status"`
+	PairingID string           `json:"pairingId,omitempty"`
+	Device1ID string           `json:"device1Id"`
+	Device2ID string           `json:"device2Id"`
+	Error     string           `json:"error,omitempty"`
+}
+
 // NTP Multi-Sampling Models
 
 // AggregatedSyncResult represents the result of NTP-style multi-sampling synchronization
@@ -140,46 +391,557 @@ type AggregatedSyncResult struct {
 	AggregationID string `json:"aggregation_id"`
 	PairingID     string `json:"pairing_id"`
 
+	// Device the offsets are measured against.
+	// Offsets are "other device time - reference time": positive = the other device is ahead.
+	ReferenceDeviceID string `json:"reference_device_id,omitempty"`
+
+	// Group member the offsets belong to, for results of a group multi-sync (one per
+	// non-reference member). Empty for pairings of two devices.
+	MemberDeviceID string `json:"member_device_id,omitempty"`
+
 	// Final calculated results
 	BestOffset   int64   `json:"best_offset"`   // Best offset in milliseconds
 	MedianOffset int64   `json:"median_offset"` // Median offset in milliseconds
 	MeanOffset   float64 `json:"mean_offset"`   // Mean offset in milliseconds
 
+	// Mean offset weighted by 1/RTT², so low-RTT samples dominate (milliseconds)
+	WeightedOffset float64 `json:"weighted_offset"`
+
+	// Offset of the single valid sample with the lowest total RTT (milliseconds). Classic NTP's
+	// best-sample estimate, an alternative to the more robust median in BestOffset.
+	MinRTTOffset int64 `json:"min_rtt_offset"`
+
+	// Exponentially weighted moving average of BestOffset over the pairing's aggregated results,
+	// for plotting without the noise of single aggregations (milliseconds). Nil for dry runs and
+	// results saved before it was recorded.
+	SmoothedOffset *float64 `json:"smoothed_offset,omitempty"`
+
 	// Statistical information
 	OffsetStdDev float64 `json:"offset_std_dev"` // Standard deviation of offsets
 	MinRTT       int64   `json:"min_rtt"`        // Minimum RTT in microseconds
 	MaxRTT       int64   `json:"max_rtt"`        // Maximum RTT in microseconds
 	MeanRTT      float64 `json:"mean_rtt"`       // Mean RTT in microseconds
 
+	// Nearest-rank percentiles of the total RTT in microseconds, showing the tail min/max/mean hide
+	// (0 for results saved before they were recorded)
+	RTTP50 int64 `json:"rtt_p50"`
+	RTTP90 int64 `json:"rtt_p90"`
+	RTTP99 int64 `json:"rtt_p99"`
+
 	// Quality metrics
 	Confidence float64 `json:"confidence"` // Confidence score 0.0 ~ 1.0
 	Jitter     float64 `json:"jitter"`     // RTT variability in microseconds
 
+	// Quality grade (GradeA ~ GradeF) from confidence, |BestOffset|, jitter and the valid sample
+	// count, see GradeConfig. Empty for results saved before it was recorded.
+	Grade string `json:"grade,omitempty"`
+
 	// Measurement information
 	TotalSamples int `json:"total_samples"`  // Total number of samples attempted
 	ValidSamples int `json:"valid_samples"`  // Number of valid samples used
 	OutlierCount int `json:"outlier_count"`  // Number of outliers removed
 
+	// Samples the outlier filter removed, with their offset and why. Only loaded with the
+	// measurements (a single aggregated result), like AdjustedOffset.
+	RejectedSamples []RejectedSample `json:"rejected_samples,omitempty"`
+
+	// Samples by sync status; PARTIAL (one device answered) and FAILED samples carry no offset
+	SuccessSamples int `json:"success_samples"`
+	PartialSamples int `json:"partial_samples"`
+	FailedSamples  int `json:"failed_samples"`
+	// Extra attempts made for failed samples (MultiSyncRequest.MaxRetriesPerSample); only the last
+	// attempt of each sample is counted above and kept in Measurements
+	RetryCount int `json:"retry_count"`
+
+	// Filter configuration the result was selected with, after defaults (unset for results saved before it was recorded)
+	MinSamples       int     `json:"min_samples,omitempty"`
+	OutlierThreshold float64 `json:"outlier_threshold,omitempty"`
+	TopPercentile    float64 `json:"top_percentile,omitempty"`
+	OutlierMethod    string  `json:"outlier_method,omitempty"`
+
 	// All measurement records
 	Measurements []*TimeSyncRecord `json:"measurements"`
 
+	// Set when an active offset override replaced BestOffset; MeasuredOffset keeps the measured value
+	OffsetOverride *OffsetOverride `json:"offset_override,omitempty"`
+	MeasuredOffset *int64          `json:"measured_offset,omitempty"`
+
 	// Metadata
 	CreatedAt int64 `json:"created_at"` // Milliseconds
 }
 
+// OffsetForDevice returns the signed offset of the device's clock relative to the reference
+// device in milliseconds (device time - reference time). The reference device itself is 0.
+// When measurements are loaded, devices that are not part of them are rejected.
+func (r *AggregatedSyncResult) OffsetForDevice(deviceID string) (int64, error) {
+	if r.ReferenceDeviceID == "" {
+		return 0, fmt.Errorf("aggregation %s has no reference device", r.AggregationID)
+	}
+	if deviceID == r.ReferenceDeviceID {
+		return 0, nil
+	}
+
+	if len(r.Measurements) > 0 {
+		found := false
+		for _, m := range r.Measurements {
+			if m.Device1ID == deviceID || m.Device2ID == deviceID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("device %s is not part of aggregation %s", deviceID, r.AggregationID)
+		}
+	}
+
+	return r.BestOffset, nil
+}
+
+// DeviceTypeStats represents fleet-wide sync reliability metrics for one device type
+type DeviceTypeStats struct {
+	DeviceType      DeviceType `json:"device_type"`
+	TotalSyncs      int        `json:"total_syncs"`      // Sync records involving this device type
+	SuccessfulSyncs int        `json:"successful_syncs"` // Records with SUCCESS status
+	SuccessRate     float64    `json:"success_rate"`     // SuccessfulSyncs / TotalSyncs (0.0 ~ 1.0)
+	AvgRTT          float64    `json:"avg_rtt"`          // Mean RTT of devices of this type in microseconds
+	Aggregations    int        `json:"aggregations"`     // Aggregated results involving this device type
+	AvgConfidence   float64    `json:"avg_confidence"`   // Mean confidence of those aggregated results
+}
+
+// PairingRecordsDeletion reports what DELETE /api/pairings/:pairingId/records removed.
+// Sync records are only deleted when no aggregation of another pairing still links to them.
+type PairingRecordsDeletion struct {
+	PairingID           string `json:"pairing_id"`
+	RecordsDeleted      int64  `json:"records_deleted"`
+	AggregationsDeleted int64  `json:"aggregations_deleted"`
+	LinksDeleted        int64  `json:"links_deleted"` // aggregation_measurements rows
+}
+
+// DeviceSyncStats summarizes the sync records of one device, optionally within a time range.
+// Time difference statistics use the SUCCESS records with an offset, as stored (device1 - device2).
+type DeviceSyncStats struct {
+	DeviceID             string     `json:"device_id"`
+	StartTime            *time.Time `json:"start_time,omitempty"`
+	EndTime              *time.Time `json:"end_time,omitempty"`
+	TotalSyncs           int        `json:"total_syncs"`            // Records involving the device
+	SuccessfulSyncs      int        `json:"successful_syncs"`       // Records with SUCCESS status
+	SuccessRate          float64    `json:"success_rate"`           // SuccessfulSyncs / TotalSyncs (0.0 ~ 1.0)
+	MeanTimeDifference   float64    `json:"mean_time_difference"`   // ms
+	MedianTimeDifference float64    `json:"median_time_difference"` // ms
+	TimeDifferenceStdDev float64    `json:"time_difference_std_dev"`
+	MeanRTT              float64    `json:"mean_rtt"` // Mean RTT of this device in microseconds
+}
+
+// ExportFilter narrows CSV exports; empty fields are not applied and set fields are combined with AND
+type ExportFilter struct {
+	DeviceID  string     // Records where the device is either side
+	PairingID string     // Aggregated results of the pairing
+	StartTime *time.Time // created_at >= StartTime
+	EndTime   *time.Time // created_at <= EndTime
+}
+
+// PairingExportSchemaVersion is the version of the pairing export bundle format.
+// Bump it when fields of the bundle are renamed or removed.
+const PairingExportSchemaVersion = 1
+
+// PairingExport is a pairing's JSON export bundle. The server leaves Aggregations empty and
+// streams the pairing's aggregated results, each with its measurements, after the other fields.
+type PairingExport struct {
+	SchemaVersion  int                `json:"schema_version"`
+	ExportedAt     time.Time          `json:"exported_at"`
+	Pairing        *PersistentPairing `json:"pairing"`
+	OffsetOverride *OffsetOverride    `json:"offset_override,omitempty"` // Active override, if any
+
+	Aggregations []*AggregatedSyncResult `json:"aggregations,omitempty"` // Oldest first
+}
+
+// RecordFilter narrows sync record listings; empty fields are not applied and set fields are combined with AND
+type RecordFilter struct {
+	DeviceID  string     // Records where the device is either side
+	Status    SyncStatus // SUCCESS, PARTIAL or FAILED
+	StartTime *time.Time // created_at >= StartTime
+	EndTime   *time.Time // created_at <= EndTime
+	Sort      ListSort   // Keys: RecordSortKeys
+}
+
+// RecordCursor is the keyset position after which the next page of records starts.
+// Cursor pages list records newest first by created_at, then id.
+type RecordCursor struct {
+	CreatedAt int64 // Milliseconds
+	ID        int64
+}
+
+// Encode returns the opaque token clients pass back as ?cursor=
+func (c RecordCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.CreatedAt, c.ID)))
+}
+
+// ParseRecordCursor decodes a token produced by RecordCursor.Encode
+func ParseRecordCursor(token string) (*RecordCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c RecordCursor
+	if n, err := fmt.Sscanf(string(raw), "%d:%d", &c.CreatedAt, &c.ID); err != nil || n != 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+// RecordPage is one cursor-paginated page of sync records.
+// NextCursor is empty on the last page.
+type RecordPage struct {
+	Records    []*TimeSyncRecord `json:"records"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// AggregatedResultFilter narrows aggregated result listings; empty fields are not applied and set fields are combined with AND
+type AggregatedResultFilter struct {
+	PairingID     string
+	MinConfidence *float64   // confidence >= MinConfidence
+	MaxConfidence *float64   // confidence <= MaxConfidence
+	StartTime     *time.Time // created_at >= StartTime
+	EndTime       *time.Time // created_at <= EndTime
+	Sort          ListSort   // Keys: AggregatedSortKeys
+}
+
+// Sort keys accepted by the list endpoints (sortBy query parameter)
+const (
+	SortByCreatedAt  = "createdAt"
+	SortByConfidence = "confidence" // Aggregated results only
+	SortByOffset     = "offset"     // Offset magnitude: |timeDifference| of records, |best_offset| of aggregated results
+)
+
+// RecordSortKeys and AggregatedSortKeys list the sort keys each listing supports
+var (
+	RecordSortKeys     = []string{SortByCreatedAt, SortByOffset}
+	AggregatedSortKeys = []string{SortByCreatedAt, SortByConfidence, SortByOffset}
+)
+
+// SortOrder is the direction of a ListSort
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// ListSort orders a listing; the zero value is newest first.
+// Ties on a key other than createdAt are broken by created_at in the same direction.
+type ListSort struct {
+	By    string    // Sort key, empty = createdAt
+	Order SortOrder // Empty = desc
+}
+
+// AggregationComparison compares two aggregated results side by side. Deltas are B - A.
+type AggregationComparison struct {
+	A *AggregatedSyncResult `json:"a"` // Without measurements
+	B *AggregatedSyncResult `json:"b"` // Without measurements
+
+	OffsetDelta     int64   `json:"offset_delta"`     // Difference of the best offsets in milliseconds
+	AbsOffsetDelta  int64   `json:"abs_offset_delta"` // |B| - |A| in milliseconds: negative = B is closer to zero
+	ConfidenceDelta float64 `json:"confidence_delta"` // Positive = B is more confident
+	JitterDelta     float64 `json:"jitter_delta"`     // In microseconds: negative = B is more stable
+	Verdict         string  `json:"verdict"`          // Short summary, e.g. "B is 30ms closer, 0.12 more confident"
+}
+
+// ApplyOffsetRequest asks the server to convert device timestamps to the reference device's clock
+type ApplyOffsetRequest struct {
+	AggregationID string  `json:"aggregationId" binding:"required"`
+	DeviceID      string  `json:"deviceId" binding:"required"`
+	Timestamps    []int64 `json:"timestamps" binding:"required"` // Device clock, Unix ms
+}
+
+// MaxApplyOffsetTimestamps bounds the timestamps converted by one ApplyOffsetRequest
+const MaxApplyOffsetTimestamps = 10000
+
+// ApplyOffsetResult holds timestamps converted to the reference device's clock
+type ApplyOffsetResult struct {
+	AggregationID     string  `json:"aggregationId"`
+	DeviceID          string  `json:"deviceId"`
+	ReferenceDeviceID string  `json:"referenceDeviceId"`
+	OffsetMs          int64   `json:"offsetMs"`   // Device time - reference time, subtracted from every timestamp
+	Timestamps        []int64 `json:"timestamps"` // Reference clock, Unix ms, in request order
+	Overridden        bool    `json:"overridden"` // OffsetMs comes from the pairing's active offset override
+}
+
+// OffsetBucket summarizes a pairing's aggregated results within one time bucket
+type OffsetBucket struct {
+	BucketStart    int64   `json:"bucket_start"`    // Start of the bucket (Unix ms, a multiple of the bucket size)
+	Count          int     `json:"count"`           // Aggregated results in the bucket
+	MeanOffset     float64 `json:"mean_offset"`     // Mean best offset in milliseconds
+	MinOffset      int64   `json:"min_offset"`      // Smallest best offset in milliseconds
+	MaxOffset      int64   `json:"max_offset"`      // Largest best offset in milliseconds
+	MeanConfidence float64 `json:"mean_confidence"` // Mean confidence 0.0 ~ 1.0
+}
+
+// OffsetTimeSeries is a pairing's best offset over time, downsampled into fixed-size buckets for charting
+type OffsetTimeSeries struct {
+	PairingID string          `json:"pairing_id"`
+	BucketMs  int64           `json:"bucket_ms"` // Bucket size in milliseconds
+	Buckets   []*OffsetBucket `json:"buckets"`   // Oldest first; buckets without results are omitted
+
+	// The pairing's active offset override; its offset takes precedence over the measured buckets
+	Override *OffsetOverride `json:"override,omitempty"`
+}
+
+// DriftEstimate is a linear fit of BestOffset over time across a pairing's aggregated results
+type DriftEstimate struct {
+	PairingID         string  `json:"pairing_id"`
+	ReferenceDeviceID string  `json:"reference_device_id,omitempty"` // Device the fitted offsets are measured against
+	SampleCount       int     `json:"sample_count"`                  // Aggregated results used for the fit
+	StartTime         int64   `json:"start_time"`                    // CreatedAt of the oldest result (Unix ms)
+	EndTime           int64   `json:"end_time"`                      // CreatedAt of the newest result (Unix ms)
+	SlopeMsPerHour    float64 `json:"slope_ms_per_hour"`             // Offset change per hour in milliseconds
+	DriftPPM          float64 `json:"drift_ppm"`                     // Same slope in parts per million
+	InterceptMs       float64 `json:"intercept_ms"`                  // Fitted offset at StartTime in milliseconds
+	RSquared          float64 `json:"r_squared"`                     // Goodness of fit 0.0 ~ 1.0
+}
+
 // MultiSyncRequest represents a request for NTP-style multi-sampling
 type MultiSyncRequest struct {
 	PairingID   string `json:"pairing_id" binding:"required"`
 	SampleCount int    `json:"sample_count"` // Default: 8
 	IntervalMs  int    `json:"interval_ms"`  // Interval between samples in ms, default: 200
 	TimeoutSec  int    `json:"timeout_sec"`  // Timeout for each sample in seconds, default: 5
+
+	// "fixed" (default) waits IntervalMs between samples; "adaptive" derives the wait
+	// from the previous sample's RTT, bounded by MinIntervalMs and MaxIntervalMs
+	IntervalStrategy string `json:"interval_strategy"`
+	MinIntervalMs    int    `json:"min_interval_ms"` // Adaptive lower bound in ms, default: 50
+	MaxIntervalMs    int    `json:"max_interval_ms"` // Adaptive upper bound in ms, default: 1000
+
+	// Measure and select without saving records or the result, publishing events or sending alerts
+	DryRun bool `json:"dry_run"`
+
+	// Retry a failed, timed out or partial sample up to this many times before moving on (default: 0).
+	// Retries stop once the multi-sync has used the time it could take without retries.
+	MaxRetriesPerSample int `json:"max_retries_per_sample"`
+
+	// Optional NTP selection tuning, see NTPFilterConfig (0 = selector default)
+	MinSamples       int     `json:"min_samples"`       // Default: 3
+	OutlierThreshold float64 `json:"outlier_threshold"` // Default: 2.0
+	TopPercentile    float64 `json:"top_percentile"`    // Default: 0.5
 }
 
+// FilterConfig returns the NTP selection settings of the request; unset fields use the selector defaults
+func (r *MultiSyncRequest) FilterConfig() NTPFilterConfig {
+	return NTPFilterConfig{
+		MinSamples:       r.MinSamples,
+		OutlierThreshold: r.OutlierThreshold,
+		TopPercentile:    r.TopPercentile,
+	}
+}
+
+// Sample interval strategies for MultiSyncRequest.IntervalStrategy
+const (
+	IntervalStrategyFixed    = "fixed"    // IntervalMs between every sample (default)
+	IntervalStrategyAdaptive = "adaptive" // A multiple of the previous sample's RTT, clamped to [MinIntervalMs, MaxIntervalMs]
+)
+
+// Upper bounds of MultiSyncRequest fields
+const (
+	MaxMultiSyncSampleCount = 100
+	MaxMultiSyncIntervalMs  = 60000 // Also bounds MinIntervalMs and MaxIntervalMs
+	MaxMultiSyncTimeoutSec  = 60
+	MaxOutlierThreshold     = 10.0 // Standard deviations (or MAD/IQR multiples)
+	MaxRetriesPerSample     = 5
+)
+
+// Validate rejects negative and out-of-range fields. Zero means unset and is replaced by a default later.
+func (r *MultiSyncRequest) Validate() error {
+	if r.SampleCount < 0 || r.SampleCount > MaxMultiSyncSampleCount {
+		return fmt.Errorf("sample_count must be between 1 and %d, got %d", MaxMultiSyncSampleCount, r.SampleCount)
+	}
+	if r.IntervalMs < 0 || r.IntervalMs > MaxMultiSyncIntervalMs {
+		return fmt.Errorf("interval_ms must be between 1 and %d, got %d", MaxMultiSyncIntervalMs, r.IntervalMs)
+	}
+	if r.TimeoutSec < 0 || r.TimeoutSec > MaxMultiSyncTimeoutSec {
+		return fmt.Errorf("timeout_sec must be between 1 and %d, got %d", MaxMultiSyncTimeoutSec, r.TimeoutSec)
+	}
+	if r.MaxRetriesPerSample < 0 || r.MaxRetriesPerSample > MaxRetriesPerSample {
+		return fmt.Errorf("max_retries_per_sample must be between 0 and %d, got %d", MaxRetriesPerSample, r.MaxRetriesPerSample)
+	}
+
+	switch r.IntervalStrategy {
+	case "", IntervalStrategyFixed, IntervalStrategyAdaptive:
+	default:
+		return fmt.Errorf("interval_strategy must be %s or %s, got %q", IntervalStrategyFixed, IntervalStrategyAdaptive, r.IntervalStrategy)
+	}
+	if r.MinIntervalMs < 0 || r.MinIntervalMs > MaxMultiSyncIntervalMs {
+		return fmt.Errorf("min_interval_ms must be between 1 and %d, got %d", MaxMultiSyncIntervalMs, r.MinIntervalMs)
+	}
+	if r.MaxIntervalMs < 0 || r.MaxIntervalMs > MaxMultiSyncIntervalMs {
+		return fmt.Errorf("max_interval_ms must be between 1 and %d, got %d", MaxMultiSyncIntervalMs, r.MaxIntervalMs)
+	}
+	if r.MinIntervalMs > 0 && r.MaxIntervalMs > 0 && r.MinIntervalMs > r.MaxIntervalMs {
+		return fmt.Errorf("min_interval_ms (%d) must not exceed max_interval_ms (%d)", r.MinIntervalMs, r.MaxIntervalMs)
+	}
+
+	if r.MinSamples < 0 || r.MinSamples > MaxMultiSyncSampleCount {
+		return fmt.Errorf("min_samples must be between 1 and %d, got %d", MaxMultiSyncSampleCount, r.MinSamples)
+	}
+	if r.MinSamples > 0 && r.SampleCount > 0 && r.MinSamples > r.SampleCount {
+		return fmt.Errorf("min_samples (%d) must not exceed sample_count (%d)", r.MinSamples, r.SampleCount)
+	}
+	if r.OutlierThreshold < 0 || r.OutlierThreshold > MaxOutlierThreshold {
+		return fmt.Errorf("outlier_threshold must be greater than 0 and at most %g, got %g", MaxOutlierThreshold, r.OutlierThreshold)
+	}
+	if r.TopPercentile < 0 || r.TopPercentile > 1 {
+		return fmt.Errorf("top_percentile must be greater than 0 and at most 1, got %g", r.TopPercentile)
+	}
+	return nil
+}
+
+// Outlier detection methods for NTPFilterConfig.OutlierMethod
+const (
+	OutlierMethodStdDev = "stddev" // mean ± threshold·stddev (default)
+	OutlierMethodMAD    = "mad"    // median ± threshold·1.4826·MAD, robust against large spikes
+	OutlierMethodIQR    = "iqr"    // [Q1 - k·IQR, Q3 + k·IQR] with k = IQRMultiplier (Tukey's fences)
+)
+
+// Offset selection strategies for NTPFilterConfig.OffsetSelection
+const (
+	OffsetSelectionMedian       = "median"       // Median of the valid offsets (default)
+	OffsetSelectionIntersection = "intersection" // Marzullo's algorithm over offset ± RTT/2 intervals
+	OffsetSelectionWeighted     = "weighted"     // Mean weighted by 1/RTT² (see AggregatedSyncResult.WeightedOffset)
+)
+
 // NTPFilterConfig represents configuration for NTP filtering algorithm
 type NTPFilterConfig struct {
 	MinSamples       int     `json:"min_samples"`        // Minimum valid samples required
 	OutlierThreshold float64 `json:"outlier_threshold"`  // Outlier detection threshold (stddev multiplier)
 	TopPercentile    float64 `json:"top_percentile"`     // Top N% of samples by RTT to select (0.5 = 50%)
+	OutlierMethod    string  `json:"outlier_method"`     // "stddev" (default), "mad" or "iqr"
+	IQRMultiplier    float64 `json:"iqr_multiplier"`     // Fence distance in IQRs for the iqr method (default 1.5)
+	OffsetSelection  string  `json:"offset_selection"`   // "median" (default), "intersection" or "weighted"
+
+	Confidence ConfidenceConfig `json:"confidence"` // How the confidence score is computed
+	Grade      GradeConfig      `json:"grade"`      // Thresholds of the quality grade
+}
+
+// ConfidenceConfig tunes the confidence score: a weighted average of a sample count factor,
+// an offset consistency factor and a network stability factor, each between 0 and 1.
+// Zero thresholds are replaced by the defaults; if all weights are zero the default weights
+// 0.3/0.4/0.3 are used, otherwise a zero weight leaves that factor out.
+type ConfidenceConfig struct {
+	SampleWeight float64 `json:"sample_weight"`
+	OffsetWeight float64 `json:"offset_weight"`
+	JitterWeight float64 `json:"jitter_weight"`
+
+	FullSampleCount int     `json:"full_sample_count"`  // Valid samples at which the sample factor is 1 (default 10)
+	MaxOffsetStdDev float64 `json:"max_offset_std_dev"` // Offset stddev in ms at which the offset factor is 0 (default 20)
+	MaxJitter       float64 `json:"max_jitter"`         // RTT jitter in μs at which the stability factor is 0 (default 10000)
+}
+
+// Validate rejects negative weights and thresholds
+func (c *ConfidenceConfig) Validate() error {
+	if c.SampleWeight < 0 || c.OffsetWeight < 0 || c.JitterWeight < 0 {
+		return fmt.Errorf("confidence weights must not be negative, got %g/%g/%g", c.SampleWeight, c.OffsetWeight, c.JitterWeight)
+	}
+	if c.FullSampleCount < 0 || c.FullSampleCount > MaxMultiSyncSampleCount {
+		return fmt.Errorf("full_sample_count must be between 1 and %d, got %d", MaxMultiSyncSampleCount, c.FullSampleCount)
+	}
+	if c.MaxOffsetStdDev < 0 {
+		return fmt.Errorf("max_offset_std_dev must be greater than 0, got %g", c.MaxOffsetStdDev)
+	}
+	if c.MaxJitter < 0 {
+		return fmt.Errorf("max_jitter must be greater than 0, got %g", c.MaxJitter)
+	}
+	return nil
+}
+
+// Quality grades of an aggregated result, from best to worst
+const (
+	GradeA = "A"
+	GradeB = "B"
+	GradeC = "C"
+	GradeD = "D"
+	GradeF = "F"
+)
+
+// GradeConfig sets the thresholds of the quality grades. A result gets the best grade whose
+// thresholds it meets on every metric, GradeF if it meets none. Zero fields use the defaults.
+type GradeConfig struct {
+	A GradeThreshold `json:"a"` // Default: confidence 0.9, offset 50ms, jitter 1000μs, 4 samples
+	B GradeThreshold `json:"b"` // Default: confidence 0.7, offset 200ms, jitter 3000μs, 3 samples
+	C GradeThreshold `json:"c"` // Default: confidence 0.5, offset 1000ms, jitter 6000μs, 2 samples
+	D GradeThreshold `json:"d"` // Default: confidence 0.3, offset 5000ms, jitter 10000μs, 1 sample
+}
+
+// GradeThreshold is what a result needs for one grade
+type GradeThreshold struct {
+	MinConfidence   float64 `json:"min_confidence"`    // Lowest confidence score
+	MaxOffset       float64 `json:"max_offset"`        // Largest |best_offset| in ms
+	MaxJitter       float64 `json:"max_jitter"`        // Largest RTT jitter in μs
+	MinValidSamples int     `json:"min_valid_samples"` // Fewest valid samples, so few-sample results cannot get the top grades
+}
+
+// Validate rejects negative and out-of-range thresholds
+func (c *GradeConfig) Validate() error {
+	for _, grade := range []struct {
+		name      string
+		threshold GradeThreshold
+	}{{GradeA, c.A}, {GradeB, c.B}, {GradeC, c.C}, {GradeD, c.D}} {
+		t := grade.threshold
+		if t.MinConfidence < 0 || t.MinConfidence > 1 {
+			return fmt.Errorf("grade %s min_confidence must be between 0 and 1, got %g", grade.name, t.MinConfidence)
+		}
+		if t.MaxOffset < 0 || t.MaxJitter < 0 {
+			return fmt.Errorf("grade %s max_offset and max_jitter must not be negative, got %g/%g", grade.name, t.MaxOffset, t.MaxJitter)
+		}
+		if t.MinValidSamples < 0 || t.MinValidSamples > MaxMultiSyncSampleCount {
+			return fmt.Errorf("grade %s min_valid_samples must be between 1 and %d, got %d", grade.name, MaxMultiSyncSampleCount, t.MinValidSamples)
+		}
+	}
+	return nil
+}
+
+// Validate rejects negative, out-of-range and unknown fields. Zero means unset and is replaced by a default later.
+func (c *NTPFilterConfig) Validate() error {
+	if c.MinSamples < 0 || c.MinSamples > MaxMultiSyncSampleCount {
+		return fmt.Errorf("min_samples must be between 1 and %d, got %d", MaxMultiSyncSampleCount, c.MinSamples)
+	}
+	if c.OutlierThreshold < 0 || c.OutlierThreshold > MaxOutlierThreshold {
+		return fmt.Errorf("outlier_threshold must be greater than 0 and at most %g, got %g", MaxOutlierThreshold, c.OutlierThreshold)
+	}
+	if c.TopPercentile < 0 || c.TopPercentile > 1 {
+		return fmt.Errorf("top_percentile must be greater than 0 and at most 1, got %g", c.TopPercentile)
+	}
+	switch c.OutlierMethod {
+	case "", OutlierMethodStdDev, OutlierMethodMAD, OutlierMethodIQR:
+	default:
+		return fmt.Errorf("outlier_method must be %s, %s or %s, got %q", OutlierMethodStdDev, OutlierMethodMAD, OutlierMethodIQR, c.OutlierMethod)
+	}
+	if c.IQRMultiplier < 0 || c.IQRMultiplier > MaxOutlierThreshold {
+		return fmt.Errorf("iqr_multiplier must be greater than 0 and at most %g, got %g", MaxOutlierThreshold, c.IQRMultiplier)
+	}
+	switch c.OffsetSelection {
+	case "", OffsetSelectionMedian, OffsetSelectionIntersection, OffsetSelectionWeighted:
+	default:
+		return fmt.Errorf("offset_selection must be %s, %s or %s, got %q", OffsetSelectionMedian, OffsetSelectionIntersection, OffsetSelectionWeighted, c.OffsetSelection)
+	}
+	if err := c.Confidence.Validate(); err != nil {
+		return err
+	}
+	return c.Grade.Validate()
+}
+
+// RecomputeRequest re-runs the NTP selection over the measurements of a stored aggregation
+type RecomputeRequest struct {
+	// Selection settings to recompute with; nil reuses the ones recorded with the aggregation
+	// (selector defaults for aggregations saved before they were recorded)
+	Filter *NTPFilterConfig `json:"filter"`
+	// Save the recomputed result as a new aggregation linked to the same measurements
+	Save bool `json:"save"`
+}
+
+// RecomputeResult is a stored aggregation recomputed from its measurements
+type RecomputeResult struct {
+	SourceAggregationID string                `json:"source_aggregation_id"`
+	Saved               bool                  `json:"saved"`  // Result has its own AggregationID when saved
+	Result              *AggregatedSyncResult `json:"result"` // Includes the measurements with their recomputed adjusted offsets
 }
 
 // SampleAnalysis represents analysis of a single sync sample for NTP algorithm
@@ -190,9 +952,51 @@ type SampleAnalysis struct {
 	Offset         int64           `json:"offset"`          // TimeDifference
 	IsOutlier      bool            `json:"is_outlier"`      // Whether this sample is an outlier
 	SelectionScore float64         `json:"selection_score"` // Score for selection (lower is better)
+	Deviation      float64         `json:"deviation"`       // Offset minus the outlier filter's center (mean or median), ms
+	OutlierReason  string          `json:"outlier_reason"`  // Why the outlier filter rejected the sample, "" if kept
+}
+
+// RejectedSample is a sample the outlier filter removed from an aggregation, kept so a low
+// valid_samples count can be explained
+type RejectedSample struct {
+	RecordID  int64   `json:"record_id"` // 0 if the sample was not saved (dry runs)
+	Offset    int64   `json:"offset"`    // Network-compensated offset in milliseconds
+	Deviation float64 `json:"deviation"` // Offset minus the outlier filter's center (mean or median), ms
+	Reason    string  `json:"reason"`
+}
+
+// SyncRecordAnalysis breaks a sync record down into the values the NTP selector derives from it.
+// Derived values are omitted when the record lacks the data (e.g. a timed-out device).
+type SyncRecordAnalysis struct {
+	RecordID           int64      `json:"recordId"`
+	Status             SyncStatus `json:"status"`
+	ServerRequestTime  int64      `json:"serverRequestTime"`  // Milliseconds
+	ServerResponseTime *int64     `json:"serverResponseTime"` // Milliseconds
+
+	Device1ID          string   `json:"device1Id"`
+	Device1Timestamp   *int64   `json:"device1Timestamp"`             // Milliseconds
+	Device1RTT         *int64   `json:"device1Rtt,omitempty"`         // Microseconds
+	Device1OneWayDelay *float64 `json:"device1OneWayDelay,omitempty"` // RTT/2, milliseconds
+	Device2ID          string   `json:"device2Id"`
+	Device2Timestamp   *int64   `json:"device2Timestamp"`
+	Device2RTT         *int64   `json:"device2Rtt,omitempty"`
+	Device2OneWayDelay *float64 `json:"device2OneWayDelay,omitempty"`
+
+	TotalRTT      *int64 `json:"totalRtt,omitempty"`      // Device1RTT + Device2RTT (μs), the selector's RTT filter key
+	RTTDifference *int64 `json:"rttDifference,omitempty"` // |Device1RTT - Device2RTT| (μs), the symmetry key
+
+	RawOffset         *int64   `json:"rawOffset,omitempty"`       // TimeDifference: Device1Time - Device2Time (ms)
+	OffsetCompensated bool     `json:"offsetCompensated"`         // Raw offset came from four timestamps, no delay correction
+	DelayCorrection   *float64 `json:"delayCorrection,omitempty"` // Device1OneWayDelay - Device2OneWayDelay (ms), subtracted from the raw offset
+	AdjustedOffset    *int64   `json:"adjustedOffset,omitempty"`  // Offset the selector uses (ms)
 }
 
 // API Request/Response Models
+type RTTProbeResponse struct {
+	DeviceID string `json:"deviceId"`
+	RTT      int64  `json:"rtt"` // Microseconds
+}
+
 type CreatePairingRequest struct {
 	Device1ID string `json:"device1Id" binding:"required"`
 	Device2ID string `json:"device2Id" binding:"required"`
@@ -201,22 +1005,132 @@ type CreatePairingRequest struct {
 	AutoSyncIntervalSec *int `json:"autoSyncIntervalSec,omitempty"` // Optional: interval between syncs in seconds
 	AutoSyncSampleCount *int `json:"autoSyncSampleCount,omitempty"` // Optional: number of samples per sync
 	AutoSyncIntervalMs  *int `json:"autoSyncIntervalMs,omitempty"`  // Optional: interval between samples in ms
+	AutoSyncTimeoutSec  *int `json:"autoSyncTimeoutSec,omitempty"`  // Optional: timeout for each sample in seconds
 }
 
 type CreatePairingResponse struct {
 	PairingID string `json:"pairingId"`
 }
 
+type CreateGroupPairingRequest struct {
+	DeviceIDs         []string `json:"deviceIds" binding:"required,min=2"`
+	ReferenceDeviceID string   `json:"referenceDeviceId,omitempty"` // Optional: defaults to the first device
+}
+
+// GroupSyncRecord is the outcome of one fan-out TIME_REQUEST to every member of a group pairing.
+// It holds one record per non-reference member with Device1 = member and Device2 = reference,
+// so TimeDifference is the member's raw offset relative to the reference device.
+type GroupSyncRecord struct {
+	PairingID         string            `json:"pairingId"`
+	ReferenceDeviceID string            `json:"referenceDeviceId"`
+	Status            SyncStatus        `json:"status"`
+	Records           []*TimeSyncRecord `json:"records"`
+}
+
+type GroupSyncResponse struct {
+	Success bool             `json:"success"`
+	Result  *GroupSyncRecord `json:"result,omitempty"`
+}
+
+// GroupAggregatedSyncResult is the result of NTP-style multi-sampling over a group pairing.
+// Results holds one aggregated result per non-reference member, keyed by device ID;
+// each offset is "member time - reference time".
+type GroupAggregatedSyncResult struct {
+	PairingID         string                           `json:"pairing_id"`
+	ReferenceDeviceID string                           `json:"reference_device_id"`
+	Results           map[string]*AggregatedSyncResult `json:"results"`
+	FailedDevices     map[string]string                `json:"failed_devices,omitempty"` // Members whose aggregation failed, with reason
+	CreatedAt         int64                            `json:"created_at"`               // Milliseconds
+}
+
+type GroupMultiSyncResponse struct {
+	Success bool                       `json:"success"`
+	Result  *GroupAggregatedSyncResult `json:"result,omitempty"`
+}
+
 type SyncResponse struct {
 	Success bool            `json:"success"`
 	Record  *TimeSyncRecord `json:"record,omitempty"`
-	Error   string          `json:"error,omitempty"`
 }
 
 type MultiSyncResponse struct {
 	Success bool                  `json:"success"`
+	DryRun  bool                  `json:"dry_run,omitempty"` // The result was not persisted and has no aggregation_id
 	Result  *AggregatedSyncResult `json:"result,omitempty"`
-	Error   string                `json:"error,omitempty"`
+}
+
+// ErrorCode is the machine-readable reason a REST request failed
+type ErrorCode string
+
+const (
+	ErrorCodeValidationFailed              ErrorCode = "VALIDATION_FAILED"               // 400, the request body or query is invalid
+	ErrorCodeUnauthorized                  ErrorCode = "UNAUTHORIZED"                    // 401
+	ErrorCodeForbidden                     ErrorCode = "FORBIDDEN"                       // 403, e.g. a disallowed CORS origin
+	ErrorCodeNotFound                      ErrorCode = "NOT_FOUND"                       // 404, any other missing resource
+	ErrorCodePairingNotFound               ErrorCode = "PAIRING_NOT_FOUND"               // 404
+	ErrorCodeRecordNotFound                ErrorCode = "RECORD_NOT_FOUND"                // 404
+	ErrorCodeAggregationNotFound           ErrorCode = "AGGREGATION_NOT_FOUND"           // 404
+	ErrorCodeDeviceOffline                 ErrorCode = "DEVICE_OFFLINE"                  // The device is not connected
+	ErrorCodeDeviceTemporarilyDisconnected ErrorCode = "DEVICE_TEMPORARILY_DISCONNECTED" // 503, retry shortly
+	ErrorCodeDeviceTimeout                 ErrorCode = "DEVICE_TIMEOUT"                  // 504, the device did not answer in time
+	ErrorCodeConflict                      ErrorCode = "CONFLICT"                        // 409
+	ErrorCodeSyncCancelled                 ErrorCode = "SYNC_CANCELLED"                  // 409, e.g. the pairing was deleted
+	ErrorCodePairingDisabled               ErrorCode = "PAIRING_DISABLED"                // 409, syncing is disabled for the pairing
+	ErrorCodeSyncFailed                    ErrorCode = "SYNC_FAILED"                     // 400, any other sync failure
+	ErrorCodeRateLimited                   ErrorCode = "RATE_LIMITED"                    // 429
+	ErrorCodeServerBusy                    ErrorCode = "SERVER_BUSY"                     // 503, too many sync requests pending, retry shortly
+	ErrorCodeUnavailable                   ErrorCode = "UNAVAILABLE"                     // 503, the feature is disabled
+	ErrorCodeInternal                      ErrorCode = "INTERNAL_ERROR"                  // 500
+)
+
+// APIError is the body of every failed REST request
+type APIError struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"` // e.g. []FieldError for VALIDATION_FAILED
+}
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Dashboard Event Models
+
+// EventType identifies an event pushed on /ws/events
+type EventType string
+
+const (
+	EventTypeAggregatedResult   EventType = "AGGREGATED_RESULT"   // Data: AggregatedSyncResult (without measurements)
+	EventTypeAutoSyncState      EventType = "AUTO_SYNC_STATE"     // Data: AutoSyncJob after the state change
+	EventTypeDeviceConnected    EventType = "DEVICE_CONNECTED"    // Data: DeviceConnectionEvent
+	EventTypeDeviceDisconnected EventType = "DEVICE_DISCONNECTED" // Data: DeviceConnectionEvent
+)
+
+// DeviceConnectionEvent is the data of DEVICE_CONNECTED and DEVICE_DISCONNECTED events
+type DeviceConnectionEvent struct {
+	DeviceID   string     `json:"device_id"`
+	DeviceType DeviceType `json:"device_type"`
+	Reason     string     `json:"reason,omitempty"` // Disconnect reason, see DisconnectReason*
+	Timestamp  int64      `json:"timestamp"`        // Milliseconds
+}
+
+// Event is a message pushed to /ws/events subscribers
+type Event struct {
+	Type      EventType   `json:"type"`
+	Timestamp int64       `json:"timestamp"` // Milliseconds
+	Data      interface{} `json:"data"`
+}
+
+// OffsetAlert is POSTed to the alert webhook when an aggregated offset exceeds the threshold
+type OffsetAlert struct {
+	PairingID     string  `json:"pairing_id"`
+	AggregationID string  `json:"aggregation_id"`
+	Offset        int64   `json:"offset"`       // BestOffset in milliseconds
+	ThresholdMs   int64   `json:"threshold_ms"` // Configured threshold that was exceeded
+	Confidence    float64 `json:"confidence"`
+	Timestamp     int64   `json:"timestamp"` // Aggregation time, Unix milliseconds
 }
 
 // Auto-Sync Monitor Models
@@ -228,6 +1142,7 @@ const (
 	AutoSyncStatusRunning AutoSyncStatus = "RUNNING"
 	AutoSyncStatusStopped AutoSyncStatus = "STOPPED"
 	AutoSyncStatusFailed  AutoSyncStatus = "FAILED"
+	AutoSyncStatusPaused  AutoSyncStatus = "PAUSED"
 )
 
 // AutoSyncConfig represents configuration for auto-sync monitoring
@@ -236,19 +1151,41 @@ type AutoSyncConfig struct {
 	IntervalSec int    `json:"interval_sec"` // Interval between syncs in seconds, default: 60
 	SampleCount int    `json:"sample_count"` // Number of samples per sync, default: 8
 	IntervalMs  int    `json:"interval_ms"`  // Interval between samples in ms, default: 200
+	TimeoutSec  int    `json:"timeout_sec"`  // Timeout for each sample in seconds, default: 5
+	// Results with a lower confidence are not counted as successful syncs, default: 0 (disabled)
+	MinConfidence float64 `json:"min_confidence"`
+	// Seconds before the first sync, default: 0 (immediately). InitialSyncSkip leaves the
+	// first sync to the interval timer, like every later one.
+	InitialSyncDelaySec int `json:"initial_sync_delay_sec"`
 }
 
+// InitialSyncSkip as AutoSyncConfig.InitialSyncDelaySec skips the sync a job runs when it starts
+const InitialSyncSkip = -1
+
 // AutoSyncJob represents a running auto-sync job
 type AutoSyncJob struct {
 	PairingID       string         `json:"pairing_id"`
 	Status          AutoSyncStatus `json:"status"`
 	Config          AutoSyncConfig `json:"config"`
 	StartedAt       time.Time      `json:"started_at"`
+	PausedAt        *time.Time     `json:"paused_at,omitempty"`
 	LastSyncAt      *time.Time     `json:"last_sync_at,omitempty"`
 	LastSyncSuccess bool           `json:"last_sync_success"`
 	LastError       string         `json:"last_error,omitempty"`
 	TotalSyncs      int            `json:"total_syncs"`
 	FailedSyncs     int            `json:"failed_syncs"`
+	// Syncs that produced a result below Config.MinConfidence
+	LowConfidenceSyncs int `json:"low_confidence_syncs"`
+
+	// Backoff state: the effective interval grows after consecutive failures
+	// and resets to Config.IntervalSec on the first success
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	CurrentIntervalSec  int `json:"current_interval_sec"`
+
+	// Syncs whose measured offset differed from the pairing's active offset override by more
+	// than the threshold, and the difference of the last sync made while an override was active
+	OverrideDisagreements int    `json:"override_disagreements"`
+	LastOverrideDeltaMs   *int64 `json:"last_override_delta_ms,omitempty"`
 }
 
 // AutoSyncStartRequest represents a request to start auto-sync
@@ -257,6 +1194,67 @@ type AutoSyncStartRequest struct {
 	IntervalSec int    `json:"interval_sec"` // Default: 60
 	SampleCount int    `json:"sample_count"` // Default: 8
 	IntervalMs  int    `json:"interval_ms"`  // Default: 200
+	TimeoutSec  int    `json:"timeout_sec"`  // Default: 5
+	// Default: AUTO_SYNC_MIN_CONFIDENCE
+	MinConfidence *float64 `json:"min_confidence,omitempty"`
+	// Default: AUTO_SYNC_INITIAL_SYNC_DELAY_SEC (-1 skips the first sync)
+	InitialSyncDelaySec *int `json:"initial_sync_delay_sec,omitempty"`
+}
+
+// AutoSyncUpdateRequest reconfigures a running auto-sync job in place; omitted fields keep their current value
+type AutoSyncUpdateRequest struct {
+	IntervalSec   *int     `json:"interval_sec,omitempty"`
+	SampleCount   *int     `json:"sample_count,omitempty"`
+	IntervalMs    *int     `json:"interval_ms,omitempty"`
+	TimeoutSec    *int     `json:"timeout_sec,omitempty"`
+	MinConfidence *float64 `json:"min_confidence,omitempty"`
+}
+
+// Validate rejects non-positive and out-of-range fields
+func (r *AutoSyncUpdateRequest) Validate() error {
+	if r.IntervalSec != nil && *r.IntervalSec < 1 {
+		return fmt.Errorf("interval_sec must be positive, got %d", *r.IntervalSec)
+	}
+	if r.SampleCount != nil && (*r.SampleCount < 1 || *r.SampleCount > MaxMultiSyncSampleCount) {
+		return fmt.Errorf("sample_count must be between 1 and %d, got %d", MaxMultiSyncSampleCount, *r.SampleCount)
+	}
+	if r.IntervalMs != nil && (*r.IntervalMs < 1 || *r.IntervalMs > MaxMultiSyncIntervalMs) {
+		return fmt.Errorf("interval_ms must be between 1 and %d, got %d", MaxMultiSyncIntervalMs, *r.IntervalMs)
+	}
+	if r.TimeoutSec != nil && (*r.TimeoutSec < 1 || *r.TimeoutSec > MaxMultiSyncTimeoutSec) {
+		return fmt.Errorf("timeout_sec must be between 1 and %d, got %d", MaxMultiSyncTimeoutSec, *r.TimeoutSec)
+	}
+	if r.MinConfidence != nil && (*r.MinConfidence < 0 || *r.MinConfidence > 1) {
+		return fmt.Errorf("min_confidence must be between 0 and 1, got %v", *r.MinConfidence)
+	}
+	return nil
+}
+
+// Apply returns config with the request's fields applied
+func (r *AutoSyncUpdateRequest) Apply(config AutoSyncConfig) AutoSyncConfig {
+	if r.IntervalSec != nil {
+		config.IntervalSec = *r.IntervalSec
+	}
+	if r.SampleCount != nil {
+		config.SampleCount = *r.SampleCount
+	}
+	if r.IntervalMs != nil {
+		config.IntervalMs = *r.IntervalMs
+	}
+	if r.TimeoutSec != nil {
+		config.TimeoutSec = *r.TimeoutSec
+	}
+	if r.MinConfidence != nil {
+		config.MinConfidence = *r.MinConfidence
+	}
+	return config
+}
+
+// AutoAggregationRequest configures automatic aggregation of single-sync records for a pairing
+type AutoAggregationRequest struct {
+	Enabled   bool `json:"enabled"`
+	WindowSec *int `json:"window_sec,omitempty"` // Look-back window in seconds (optional)
+	MinCount  *int `json:"min_count,omitempty"`  // Minimum number of records to aggregate (optional)
 }
 
 // AutoSyncStatusResponse represents the response for auto-sync status
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestOffsetForDevice(t *testing.T) {
+	result := &AggregatedSyncResult{
+		AggregationID:     "agg-123",
+		ReferenceDeviceID: "psg-001",
+		BestOffset:        -150,
+		Measurements: []*TimeSyncRecord{
+			{Device1ID: "watch-001", Device2ID: "psg-001"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		deviceID       string
+		expectedOffset int64
+		expectError    bool
+	}{
+		{name: "Reference device", deviceID: "psg-001", expectedOffset: 0},
+		{name: "Other device", deviceID: "watch-001", expectedOffset: -150},
+		{name: "Unknown device", deviceID: "mobile-001", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := result.OffsetForDevice(tt.deviceID)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("OffsetForDevice(%s) expected error, got offset %d", tt.deviceID, offset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OffsetForDevice(%s) error = %v", tt.deviceID, err)
+			}
+			if offset != tt.expectedOffset {
+				t.Errorf("OffsetForDevice(%s) = %d, expected %d", tt.deviceID, offset, tt.expectedOffset)
+			}
+		})
+	}
+}
+
+func TestOffsetForDeviceWithoutReference(t *testing.T) {
+	result := &AggregatedSyncResult{AggregationID: "agg-legacy", BestOffset: 100}
+
+	if _, err := result.OffsetForDevice("psg-001"); err == nil {
+		t.Error("OffsetForDevice() expected error for result without reference device")
+	}
+}
+
+func TestMultiSyncRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     MultiSyncRequest
+		wantErr bool
+	}{
+		{"unset fields", MultiSyncRequest{PairingID: "pair-123"}, false},
+		{"intentional values", MultiSyncRequest{PairingID: "pair-123", SampleCount: 50, IntervalMs: 1000, TimeoutSec: 10}, false},
+		{"adaptive bounds", MultiSyncRequest{PairingID: "pair-123", IntervalStrategy: IntervalStrategyAdaptive, MinIntervalMs: 20, MaxIntervalMs: 500}, false},
+		{"negative sample count", MultiSyncRequest{SampleCount: -1}, true},
+		{"too many samples", MultiSyncRequest{SampleCount: 101}, true},
+		{"interval too long", MultiSyncRequest{IntervalMs: 60001}, true},
+		{"negative timeout", MultiSyncRequest{TimeoutSec: -5}, true},
+		{"unknown strategy", MultiSyncRequest{IntervalStrategy: "exponential"}, true},
+		{"inverted bounds", MultiSyncRequest{MinIntervalMs: 500, MaxIntervalMs: 100}, true},
+		{"selection tuning", MultiSyncRequest{SampleCount: 15, MinSamples: 5, OutlierThreshold: 3.5, TopPercentile: 0.3}, false},
+		{"min samples above sample count", MultiSyncRequest{SampleCount: 8, MinSamples: 10}, true},
+		{"negative outlier threshold", MultiSyncRequest{OutlierThreshold: -1}, true},
+		{"top percentile above 1", MultiSyncRequest{TopPercentile: 1.5}, true},
+		{"sample retries", MultiSyncRequest{MaxRetriesPerSample: 3}, false},
+		{"too many sample retries", MultiSyncRequest{MaxRetriesPerSample: 6}, true},
+		{"negative sample retries", MultiSyncRequest{MaxRetriesPerSample: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNTPFilterConfigValidateConfidence(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  NTPFilterConfig
+		wantErr bool
+	}{
+		{"unset", NTPFilterConfig{}, false},
+		{"custom weights", NTPFilterConfig{Confidence: ConfidenceConfig{OffsetWeight: 0.7, JitterWeight: 0.3}}, false},
+		{"custom thresholds", NTPFilterConfig{Confidence: ConfidenceConfig{FullSampleCount: 20, MaxOffsetStdDev: 2, MaxJitter: 3000}}, false},
+		{"negative weight", NTPFilterConfig{Confidence: ConfidenceConfig{SampleWeight: -0.1, OffsetWeight: 1}}, true},
+		{"negative offset threshold", NTPFilterConfig{Confidence: ConfidenceConfig{MaxOffsetStdDev: -5}}, true},
+		{"too many samples for full confidence", NTPFilterConfig{Confidence: ConfidenceConfig{FullSampleCount: 101}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNTPFilterConfigValidateGrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  NTPFilterConfig
+		wantErr bool
+	}{
+		{"custom thresholds", NTPFilterConfig{Grade: GradeConfig{A: GradeThreshold{MinConfidence: 0.95, MaxOffset: 5, MaxJitter: 500, MinValidSamples: 6}}}, false},
+		{"confidence above 1", NTPFilterConfig{Grade: GradeConfig{B: GradeThreshold{MinConfidence: 1.5}}}, true},
+		{"negative offset", NTPFilterConfig{Grade: GradeConfig{C: GradeThreshold{MaxOffset: -1}}}, true},
+		{"negative jitter", NTPFilterConfig{Grade: GradeConfig{D: GradeThreshold{MaxJitter: -1}}}, true},
+		{"too many samples", NTPFilterConfig{Grade: GradeConfig{A: GradeThreshold{MinValidSamples: 101}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordCursorRoundTrip(t *testing.T) {
+	cursor := RecordCursor{CreatedAt: 1727870401000, ID: 123}
+	parsed, err := ParseRecordCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("ParseRecordCursor() error = %v", err)
+	}
+	if *parsed != cursor {
+		t.Errorf("ParseRecordCursor() = %+v, expected %+v", *parsed, cursor)
+	}
+
+	for _, token := range []string{"not-a-cursor!", "MTIz"} {
+		if _, err := ParseRecordCursor(token); err == nil {
+			t.Errorf("ParseRecordCursor(%q) should fail", token)
+		}
+	}
+}
@@ -0,0 +1,1232 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// InMemoryRepository keeps everything in process memory. It mirrors the behaviour of
+// SQLiteRepository and is meant for tests and ephemeral deployments; data is lost on exit.
+type InMemoryRepository struct {
+	mu sync.RWMutex
+
+	records      map[int64]*models.TimeSyncRecord
+	nextRecordID int64
+
+	aggregated map[string]*models.AggregatedSyncResult // Stored without measurements
+	links      map[string][]int64                      // aggregation ID -> linked record IDs
+	adjusted   map[string]map[int64]int64              // aggregation ID -> record ID -> adjusted offset
+	rejected   map[string][]models.RejectedSample      // aggregation ID -> rejected outliers with a record ID
+
+	pairings      map[string]*models.PersistentPairing
+	groupPairings map[string]*models.GroupPairing
+	devices       map[string]*models.DeviceInfo
+	overrides     map[string]*models.OffsetOverride
+
+	deviceEvents []*models.DeviceEvent // In insertion order
+	nextEventID  int64
+}
+
+// NewInMemoryRepository creates an empty in-memory repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		records:       make(map[int64]*models.TimeSyncRecord),
+		nextRecordID:  1,
+		aggregated:    make(map[string]*models.AggregatedSyncResult),
+		links:         make(map[string][]int64),
+		adjusted:      make(map[string]map[int64]int64),
+		rejected:      make(map[string][]models.RejectedSample),
+		pairings:      make(map[string]*models.PersistentPairing),
+		groupPairings: make(map[string]*models.GroupPairing),
+		devices:       make(map[string]*models.DeviceInfo),
+		overrides:     make(map[string]*models.OffsetOverride),
+	}
+}
+
+// Time Sync Records
+
+func (r *InMemoryRepository) SaveTimeSyncRecord(record *models.TimeSyncRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.ID = r.nextRecordID
+	r.nextRecordID++
+
+	stored := *record
+	stored.AdjustedOffset = nil // Belongs to an aggregation link, not to the record
+	r.records[record.ID] = &stored
+	return nil
+}
+
+// SaveTimeSyncRecords saves several records at once and sets their IDs
+func (r *InMemoryRepository) SaveTimeSyncRecords(records []*models.TimeSyncRecord) error {
+	for _, record := range records {
+		if err := r.SaveTimeSyncRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTimeSyncRecord retrieves a single time sync record by ID.
+// Returns ErrRecordNotFound if the record does not exist.
+func (r *InMemoryRepository) GetTimeSyncRecord(id int64) (*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrRecordNotFound, id)
+	}
+
+	recordCopy := *record
+	return &recordCopy, nil
+}
+
+// DeleteTimeSyncRecord deletes a time sync record and its links to aggregations.
+// Returns ErrRecordNotFound if the record does not exist.
+func (r *InMemoryRepository) DeleteTimeSyncRecord(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[id]; !ok {
+		return fmt.Errorf("%w: %d", ErrRecordNotFound, id)
+	}
+
+	r.unlinkRecords(map[int64]bool{id: true})
+	delete(r.records, id)
+	return nil
+}
+
+func (r *InMemoryRepository) GetTimeSyncRecords(limit, offset int) ([]*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := r.filterRecords(func(*models.TimeSyncRecord) bool { return true }, true)
+	return paginate(records, limit, offset), nil
+}
+
+func (r *InMemoryRepository) GetTimeSyncRecordsByDeviceID(deviceID string, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := r.filterRecords(func(record *models.TimeSyncRecord) bool {
+		return record.Device1ID == deviceID || record.Device2ID == deviceID
+	}, true)
+	return paginate(records, limit, offset), nil
+}
+
+func (r *InMemoryRepository) GetTimeSyncRecordsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start, end := startTime.UnixMilli(), endTime.UnixMilli()
+	records := r.filterRecords(func(record *models.TimeSyncRecord) bool {
+		return record.CreatedAt >= start && record.CreatedAt <= end
+	}, true)
+	return paginate(records, limit, offset), nil
+}
+
+// GetUnaggregatedTimeSyncRecords retrieves single-sync records for a device pair created since the
+// given time that are not linked to any aggregation yet, oldest first
+func (r *InMemoryRepository) GetUnaggregatedTimeSyncRecords(device1ID, device2ID string, since time.Time) ([]*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	linked := r.linkedRecordIDs()
+	sinceMillis := since.UnixMilli()
+	return r.filterRecords(func(record *models.TimeSyncRecord) bool {
+		return record.Device1ID == device1ID && record.Device2ID == device2ID &&
+			record.CreatedAt >= sinceMillis && record.Source == models.RecordSourceSingle && !linked[record.ID]
+	}, false), nil
+}
+
+// GetTimeSyncRecordsAfterCursor retrieves up to limit records matching the filter that come after cursor,
+// newest first by created_at, then id. A nil cursor starts at the newest record; filter.Sort is not applied.
+func (r *InMemoryRepository) GetTimeSyncRecordsAfterCursor(filter models.RecordFilter, cursor *models.RecordCursor, limit int) ([]*models.TimeSyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	match := recordFilterMatch(filter)
+	records := r.filterRecords(func(record *models.TimeSyncRecord) bool {
+		if cursor != nil && (record.CreatedAt > cursor.CreatedAt || (record.CreatedAt == cursor.CreatedAt && record.ID >= cursor.ID)) {
+			return false
+		}
+		return match(record)
+	}, true)
+	return paginate(records, limit, 0), nil
+}
+
+// recordFilterMatch returns a predicate applying the conditions of a RecordFilter, without its sort
+func recordFilterMatch(filter models.RecordFilter) func(*models.TimeSyncRecord) bool {
+	return func(record *models.TimeSyncRecord) bool {
+		if filter.DeviceID != "" && record.Device1ID != filter.DeviceID && record.Device2ID != filter.DeviceID {
+			return false
+		}
+		if filter.Status != "" && record.Status != filter.Status {
+			return false
+		}
+		return matchesTimeRange(filter.StartTime, filter.EndTime, record.CreatedAt)
+	}
+}
+
+// GetTimeSyncRecordsFiltered retrieves records matching all set fields of the filter in the order of filter.Sort
+func (r *InMemoryRepository) GetTimeSyncRecordsFiltered(filter models.RecordFilter, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	by, ascending, err := resolveSort(filter.Sort, recordSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := r.filterRecords(recordFilterMatch(filter), !ascending)
+	if by == models.SortByOffset {
+		sortByKey(records, ascending, func(record *models.TimeSyncRecord) (float64, bool) {
+			if record.TimeDifference == nil {
+				return 0, false
+			}
+			return float64(abs64(*record.TimeDifference)), true
+		})
+	}
+	return paginate(records, limit, offset), nil
+}
+
+// ForEachTimeSyncRecord streams the records matching the filter to fn, oldest first.
+// Iteration stops at the first error returned by fn.
+func (r *InMemoryRepository) ForEachTimeSyncRecord(filter models.ExportFilter, fn func(*models.TimeSyncRecord) error) error {
+	r.mu.RLock()
+	records := r.filterRecords(func(record *models.TimeSyncRecord) bool {
+		if filter.DeviceID != "" && record.Device1ID != filter.DeviceID && record.Device2ID != filter.DeviceID {
+			return false
+		}
+		return matchesTimeRange(filter.StartTime, filter.EndTime, record.CreatedAt)
+	}, false)
+	r.mu.RUnlock()
+
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aggregated Results
+
+// SaveAggregatedSyncResult saves an aggregated sync result with its measurements
+func (r *InMemoryRepository) SaveAggregatedSyncResult(result *models.AggregatedSyncResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.aggregated[result.AggregationID]; exists {
+		return fmt.Errorf("failed to insert aggregated result: duplicate aggregation ID %s", result.AggregationID)
+	}
+
+	stored := *result
+	stored.Measurements = nil
+	stored.RejectedSamples = nil
+	r.aggregated[result.AggregationID] = &stored
+
+	skipped := 0
+	var linked []int64
+	adjusted := make(map[int64]int64)
+	for _, measurement := range result.Measurements {
+		if measurement.ID == 0 {
+			skipped++ // Measurement was never saved, nothing to link to
+			continue
+		}
+		linked = append(linked, measurement.ID)
+		if measurement.AdjustedOffset != nil {
+			adjusted[measurement.ID] = *measurement.AdjustedOffset
+		}
+	}
+	r.links[result.AggregationID] = linked
+	r.adjusted[result.AggregationID] = adjusted
+
+	var rejected []models.RejectedSample
+	for _, sample := range result.RejectedSamples {
+		if slices.Contains(linked, sample.RecordID) {
+			rejected = append(rejected, sample)
+		}
+	}
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].RecordID < rejected[j].RecordID })
+	r.rejected[result.AggregationID] = rejected
+
+	// The aggregated result itself is saved; report that some links are missing
+	if skipped > 0 {
+		return &UnlinkedMeasurementsError{
+			AggregationID: result.AggregationID,
+			Skipped:       skipped,
+			Total:         len(result.Measurements),
+		}
+	}
+
+	return nil
+}
+
+// GetAggregatedSyncResult retrieves an aggregated sync result by ID
+func (r *InMemoryRepository) GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result, ok := r.aggregated[aggregationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAggregationNotFound, aggregationID)
+	}
+
+	resultCopy := *result
+	var measurements []*models.TimeSyncRecord
+	for _, id := range r.links[aggregationID] {
+		if record, ok := r.records[id]; ok {
+			recordCopy := *record
+			if offset, ok := r.adjusted[aggregationID][id]; ok {
+				recordCopy.AdjustedOffset = &offset
+			}
+			measurements = append(measurements, &recordCopy)
+		}
+	}
+	sortRecords(measurements, false)
+	resultCopy.Measurements = measurements
+
+	// Like the SQL stores, outliers whose record was unlinked (deleted) are dropped
+	for _, sample := range r.rejected[aggregationID] {
+		if slices.Contains(r.links[aggregationID], sample.RecordID) {
+			resultCopy.RejectedSamples = append(resultCopy.RejectedSamples, sample)
+		}
+	}
+
+	return &resultCopy, nil
+}
+
+// DeleteAggregatedSyncResult deletes an aggregated result and its measurement links.
+// With deleteRecords the linked records are deleted too, except those another aggregation still links to.
+// Returns ErrAggregationNotFound if the aggregation does not exist.
+func (r *InMemoryRepository) DeleteAggregatedSyncResult(aggregationID string, deleteRecords bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.aggregated[aggregationID]; !ok {
+		return fmt.Errorf("%w: %s", ErrAggregationNotFound, aggregationID)
+	}
+
+	linked := r.links[aggregationID]
+	delete(r.links, aggregationID)
+	delete(r.adjusted, aggregationID)
+	delete(r.rejected, aggregationID)
+	delete(r.aggregated, aggregationID)
+
+	if !deleteRecords {
+		return nil
+	}
+
+	stillLinked := make(map[int64]bool)
+	for _, ids := range r.links {
+		for _, id := range ids {
+			stillLinked[id] = true
+		}
+	}
+	for _, id := range linked {
+		if !stillLinked[id] {
+			delete(r.records, id)
+		}
+	}
+	return nil
+}
+
+// GetAggregatedSyncResultsByPairing retrieves aggregated results for a pairing
+func (r *InMemoryRepository) GetAggregatedSyncResultsByPairing(pairingID string, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.PairingID == pairingID
+	}, true)
+	return paginate(results, limit, offset), nil
+}
+
+// GetLatestAggregatedSyncResult retrieves the most recent aggregated result of a pairing, without measurements
+func (r *InMemoryRepository) GetLatestAggregatedSyncResult(pairingID string) (*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.PairingID == pairingID
+	}, true)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w for pairing %s", ErrAggregationNotFound, pairingID)
+	}
+	return results[0], nil
+}
+
+// GetLatestMemberAggregatedSyncResult retrieves the most recent aggregated result of one group member
+// of a pairing, without measurements. An empty memberDeviceID selects the results of a two-device pairing.
+func (r *InMemoryRepository) GetLatestMemberAggregatedSyncResult(pairingID, memberDeviceID string) (*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.PairingID == pairingID && result.MemberDeviceID == memberDeviceID
+	}, true)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w for pairing %s member %q", ErrAggregationNotFound, pairingID, memberDeviceID)
+	}
+	return results[0], nil
+}
+
+// GetLatestAggregatedSyncResults retrieves the most recent aggregated result of every pairing (or only of
+// pairingIDs), without measurements, ordered by pairing ID
+func (r *InMemoryRepository) GetLatestAggregatedSyncResults(pairingIDs []string) ([]*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return len(pairingIDs) == 0 || slices.Contains(pairingIDs, result.PairingID)
+	}, true)
+
+	var latest []*models.AggregatedSyncResult
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if !seen[result.PairingID] {
+			seen[result.PairingID] = true
+			latest = append(latest, result)
+		}
+	}
+	sort.Slice(latest, func(i, j int) bool { return latest[i].PairingID < latest[j].PairingID })
+	return latest, nil
+}
+
+// GetAggregatedSyncResultsByPairingSince retrieves a pairing's aggregated results created at or after since, oldest first
+func (r *InMemoryRepository) GetAggregatedSyncResultsByPairingSince(pairingID string, since time.Time) ([]*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sinceMillis := since.UnixMilli()
+	return r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.PairingID == pairingID && result.CreatedAt >= sinceMillis
+	}, false), nil
+}
+
+// GetAllAggregatedSyncResults retrieves all aggregated results
+func (r *InMemoryRepository) GetAllAggregatedSyncResults(limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(*models.AggregatedSyncResult) bool { return true }, true)
+	return paginate(results, limit, offset), nil
+}
+
+// GetAggregatedSyncResultsByTimeRange retrieves aggregated results within a time range
+func (r *InMemoryRepository) GetAggregatedSyncResultsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start, end := startTime.UnixMilli(), endTime.UnixMilli()
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.CreatedAt >= start && result.CreatedAt <= end
+	}, true)
+	return paginate(results, limit, offset), nil
+}
+
+// GetAggregatedSyncResultsFiltered retrieves aggregated results matching all set fields of the filter in the order of filter.Sort
+func (r *InMemoryRepository) GetAggregatedSyncResultsFiltered(filter models.AggregatedResultFilter, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	by, ascending, err := resolveSort(filter.Sort, aggregatedSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		if filter.PairingID != "" && result.PairingID != filter.PairingID {
+			return false
+		}
+		if filter.MinConfidence != nil && result.Confidence < *filter.MinConfidence {
+			return false
+		}
+		if filter.MaxConfidence != nil && result.Confidence > *filter.MaxConfidence {
+			return false
+		}
+		return matchesTimeRange(filter.StartTime, filter.EndTime, result.CreatedAt)
+	}, !ascending)
+	switch by {
+	case models.SortByConfidence:
+		sortByKey(results, ascending, func(result *models.AggregatedSyncResult) (float64, bool) {
+			return result.Confidence, true
+		})
+	case models.SortByOffset:
+		sortByKey(results, ascending, func(result *models.AggregatedSyncResult) (float64, bool) {
+			return float64(abs64(result.BestOffset)), true
+		})
+	}
+	return paginate(results, limit, offset), nil
+}
+
+// GetAggregatedOffsetBuckets groups a pairing's aggregated results into buckets of bucketMs
+// milliseconds by created_at, oldest first. Buckets without results are not returned.
+func (r *InMemoryRepository) GetAggregatedOffsetBuckets(pairingID string, startTime, endTime *time.Time, bucketMs int64) ([]*models.OffsetBucket, error) {
+	if bucketMs <= 0 {
+		return nil, fmt.Errorf("invalid bucket size %d ms", bucketMs)
+	}
+
+	r.mu.RLock()
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		return result.PairingID == pairingID && matchesTimeRange(startTime, endTime, result.CreatedAt)
+	}, false)
+	r.mu.RUnlock()
+
+	var buckets []*models.OffsetBucket
+	var current *models.OffsetBucket
+	var offsetSum, confidenceSum float64
+	flush := func() {
+		if current != nil {
+			current.MeanOffset = offsetSum / float64(current.Count)
+			current.MeanConfidence = confidenceSum / float64(current.Count)
+			buckets = append(buckets, current)
+		}
+	}
+	for _, result := range results {
+		start := result.CreatedAt / bucketMs * bucketMs
+		if current == nil || current.BucketStart != start {
+			flush()
+			current = &models.OffsetBucket{BucketStart: start, MinOffset: result.BestOffset, MaxOffset: result.BestOffset}
+			offsetSum, confidenceSum = 0, 0
+		}
+		current.Count++
+		offsetSum += float64(result.BestOffset)
+		confidenceSum += result.Confidence
+		current.MinOffset = min(current.MinOffset, result.BestOffset)
+		current.MaxOffset = max(current.MaxOffset, result.BestOffset)
+	}
+	flush()
+
+	return buckets, nil
+}
+
+// ForEachAggregatedSyncResult streams the aggregated results matching the filter to fn, oldest first.
+// Measurements are not loaded. Iteration stops at the first error returned by fn.
+func (r *InMemoryRepository) ForEachAggregatedSyncResult(filter models.ExportFilter, fn func(*models.AggregatedSyncResult) error) error {
+	r.mu.RLock()
+	results := r.filterAggregated(func(result *models.AggregatedSyncResult) bool {
+		if filter.PairingID != "" && result.PairingID != filter.PairingID {
+			return false
+		}
+		return matchesTimeRange(filter.StartTime, filter.EndTime, result.CreatedAt)
+	}, false)
+	r.mu.RUnlock()
+
+	for _, result := range results {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRecordsOlderThan deletes sync records created before t together with their aggregation
+// links, then removes aggregated results older than t that no longer reference any measurement.
+// Returns the total number of deleted rows, counted the same way as SQLiteRepository.
+func (r *InMemoryRepository) DeleteRecordsOlderThan(t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := t.UnixMilli()
+
+	old := make(map[int64]bool)
+	for id, record := range r.records {
+		if record.CreatedAt < cutoff {
+			old[id] = true
+		}
+	}
+
+	total := r.unlinkRecords(old)
+	for id := range old {
+		delete(r.records, id)
+	}
+	total += int64(len(old))
+
+	for aggregationID, result := range r.aggregated {
+		if result.CreatedAt < cutoff && len(r.links[aggregationID]) == 0 {
+			delete(r.aggregated, aggregationID)
+			delete(r.links, aggregationID)
+			delete(r.adjusted, aggregationID)
+			delete(r.rejected, aggregationID)
+			total++
+		}
+	}
+
+	return total, nil
+}
+
+// DeletePairingRecords deletes every aggregated result of a pairing, their measurement links, the
+// linked sync records and the unaggregated records of the pairing's devices. Records that an
+// aggregation of another pairing still links to are kept.
+func (r *InMemoryRepository) DeletePairingRecords(pairingID string) (*models.PairingRecordsDeletion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deletion := &models.PairingRecordsDeletion{PairingID: pairingID}
+
+	linked := make(map[int64]bool)
+	stillLinked := make(map[int64]bool)
+	for aggregationID, result := range r.aggregated {
+		for _, id := range r.links[aggregationID] {
+			if result.PairingID == pairingID {
+				linked[id] = true
+			} else {
+				stillLinked[id] = true
+			}
+		}
+	}
+
+	for aggregationID, result := range r.aggregated {
+		if result.PairingID != pairingID {
+			continue
+		}
+		deletion.LinksDeleted += int64(len(r.links[aggregationID]))
+		deletion.AggregationsDeleted++
+		delete(r.aggregated, aggregationID)
+		delete(r.links, aggregationID)
+		delete(r.adjusted, aggregationID)
+		delete(r.rejected, aggregationID)
+	}
+
+	if pairing, ok := r.pairings[pairingID]; ok {
+		for id, record := range r.records {
+			if (record.Device1ID == pairing.Device1ID && record.Device2ID == pairing.Device2ID) ||
+				(record.Device1ID == pairing.Device2ID && record.Device2ID == pairing.Device1ID) {
+				linked[id] = true
+			}
+		}
+	}
+
+	for id := range linked {
+		if _, ok := r.records[id]; ok && !stillLinked[id] {
+			delete(r.records, id)
+			deletion.RecordsDeleted++
+		}
+	}
+
+	return deletion, nil
+}
+
+// GetDeviceTypeStats computes sync reliability metrics grouped by device type.
+// A record or aggregation counts once for every distinct device type involved in it.
+// GetDeviceSyncStats computes sync statistics over the records of a device
+func (r *InMemoryRepository) GetDeviceSyncStats(deviceID string, startTime, endTime *time.Time) (*models.DeviceSyncStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &models.DeviceSyncStats{DeviceID: deviceID, StartTime: startTime, EndTime: endTime}
+	var offsets []int64
+	var rttSum, rttCount int64
+	for _, record := range r.records {
+		if record.Device1ID != deviceID && record.Device2ID != deviceID {
+			continue
+		}
+		if !matchesTimeRange(startTime, endTime, record.CreatedAt) {
+			continue
+		}
+		stats.TotalSyncs++
+		if record.Status == models.SyncStatusSuccess {
+			stats.SuccessfulSyncs++
+		}
+		if record.TimeDifference != nil {
+			offsets = append(offsets, *record.TimeDifference)
+		}
+		rtt := record.Device2RTT
+		if record.Device1ID == deviceID {
+			rtt = record.Device1RTT
+		}
+		if rtt != nil {
+			rttSum += *rtt
+			rttCount++
+		}
+	}
+
+	if stats.TotalSyncs > 0 {
+		stats.SuccessRate = float64(stats.SuccessfulSyncs) / float64(stats.TotalSyncs)
+	}
+	if rttCount > 0 {
+		stats.MeanRTT = float64(rttSum) / float64(rttCount)
+	}
+	if len(offsets) == 0 {
+		return stats, nil
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	var sum, squares float64
+	for _, offset := range offsets {
+		sum += float64(offset)
+		squares += float64(offset) * float64(offset)
+	}
+	n := float64(len(offsets))
+	stats.MeanTimeDifference = sum / n
+	stats.TimeDifferenceStdDev = math.Sqrt(math.Max(0, squares/n-stats.MeanTimeDifference*stats.MeanTimeDifference))
+	mid := len(offsets) / 2
+	stats.MedianTimeDifference = float64(offsets[mid])
+	if len(offsets)%2 == 0 {
+		stats.MedianTimeDifference = float64(offsets[mid-1]+offsets[mid]) / 2
+	}
+
+	return stats, nil
+}
+
+func (r *InMemoryRepository) GetDeviceTypeStats() ([]*models.DeviceTypeStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type rttSum struct {
+		sum   int64
+		count int
+	}
+
+	statsByType := make(map[models.DeviceType]*models.DeviceTypeStats)
+	rtts := make(map[models.DeviceType]*rttSum)
+	addRecord := func(deviceType models.DeviceType, status models.SyncStatus, rtt *int64, counted bool) {
+		if deviceType == "" {
+			return
+		}
+		stat, ok := statsByType[deviceType]
+		if !ok {
+			stat = &models.DeviceTypeStats{DeviceType: deviceType}
+			statsByType[deviceType] = stat
+			rtts[deviceType] = &rttSum{}
+		}
+		if counted {
+			stat.TotalSyncs++
+			if status == models.SyncStatusSuccess {
+				stat.SuccessfulSyncs++
+			}
+		}
+		if rtt != nil {
+			rtts[deviceType].sum += *rtt
+			rtts[deviceType].count++
+		}
+	}
+
+	// Both devices contribute their RTT; a record between two devices of the same type
+	// counts once towards that type's syncs
+	for _, record := range r.records {
+		addRecord(record.Device1Type, record.Status, record.Device1RTT, true)
+		addRecord(record.Device2Type, record.Status, record.Device2RTT, record.Device2Type != record.Device1Type)
+	}
+
+	var stats []*models.DeviceTypeStats
+	for deviceType, stat := range statsByType {
+		if stat.TotalSyncs > 0 {
+			stat.SuccessRate = float64(stat.SuccessfulSyncs) / float64(stat.TotalSyncs)
+		}
+		if sum := rtts[deviceType]; sum.count > 0 {
+			stat.AvgRTT = float64(sum.sum) / float64(sum.count)
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].DeviceType < stats[j].DeviceType })
+
+	// Aggregations are linked to device types through their measurements
+	confidenceSums := make(map[models.DeviceType]float64)
+	var aggregationTypes []models.DeviceType
+	for aggregationID, result := range r.aggregated {
+		types := make(map[models.DeviceType]bool)
+		for _, id := range r.links[aggregationID] {
+			if record, ok := r.records[id]; ok {
+				types[record.Device1Type] = true
+				types[record.Device2Type] = true
+			}
+		}
+		for deviceType := range types {
+			if deviceType == "" {
+				continue
+			}
+			stat, ok := statsByType[deviceType]
+			if !ok {
+				stat = &models.DeviceTypeStats{DeviceType: deviceType}
+				statsByType[deviceType] = stat
+				aggregationTypes = append(aggregationTypes, deviceType)
+			}
+			stat.Aggregations++
+			confidenceSums[deviceType] += result.Confidence
+		}
+	}
+
+	// Types that only appear in aggregations go after the record-based ones
+	sort.Slice(aggregationTypes, func(i, j int) bool { return aggregationTypes[i] < aggregationTypes[j] })
+	for _, deviceType := range aggregationTypes {
+		stats = append(stats, statsByType[deviceType])
+	}
+	for deviceType, sum := range confidenceSums {
+		stat := statsByType[deviceType]
+		stat.AvgConfidence = sum / float64(stat.Aggregations)
+	}
+
+	return stats, nil
+}
+
+// Pairings
+
+// SavePairing saves a pairing
+func (r *InMemoryRepository) SavePairing(pairing *models.PersistentPairing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.pairings[pairing.PairingID]; exists {
+		return fmt.Errorf("failed to save pairing: duplicate pairing ID %s", pairing.PairingID)
+	}
+	for _, existing := range r.pairings {
+		if existing.Device1ID == pairing.Device1ID && existing.Device2ID == pairing.Device2ID {
+			return fmt.Errorf("failed to save pairing: devices %s, %s are already paired", pairing.Device1ID, pairing.Device2ID)
+		}
+	}
+
+	stored := *pairing
+	r.pairings[pairing.PairingID] = &stored
+	return nil
+}
+
+// GetPairingByID retrieves a pairing by its ID
+func (r *InMemoryRepository) GetPairingByID(pairingID string) (*models.PersistentPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pairing, ok := r.pairings[pairingID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	pairingCopy := *pairing
+	return &pairingCopy, nil
+}
+
+// GetPairingsByDeviceID retrieves all pairings that include the specified device
+func (r *InMemoryRepository) GetPairingsByDeviceID(deviceID string) ([]*models.PersistentPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.filterPairings(func(pairing *models.PersistentPairing) bool {
+		return pairing.Device1ID == deviceID || pairing.Device2ID == deviceID
+	}), nil
+}
+
+// GetPairingByDevices retrieves a pairing by device IDs (bidirectional check)
+func (r *InMemoryRepository) GetPairingByDevices(device1ID, device2ID string) (*models.PersistentPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, pairing := range r.pairings {
+		if (pairing.Device1ID == device1ID && pairing.Device2ID == device2ID) ||
+			(pairing.Device1ID == device2ID && pairing.Device2ID == device1ID) {
+			pairingCopy := *pairing
+			return &pairingCopy, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w for devices: %s, %s", ErrPairingNotFound, device1ID, device2ID)
+}
+
+// DeletePairing deletes a pairing
+func (r *InMemoryRepository) DeletePairing(pairingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pairings[pairingID]; !ok {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	delete(r.pairings, pairingID)
+	return nil
+}
+
+// UpdatePairingEnabled enables or disables syncing for a pairing
+func (r *InMemoryRepository) UpdatePairingEnabled(pairingID string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairing, ok := r.pairings[pairingID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	pairing.Enabled = enabled
+	return nil
+}
+
+// UpdatePairingAutoAggregation updates the automatic single-sync aggregation settings of a pairing
+func (r *InMemoryRepository) UpdatePairingAutoAggregation(pairingID string, enabled bool, windowSec, minCount *int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairing, ok := r.pairings[pairingID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	pairing.AutoAggregateEnabled = enabled
+	pairing.AutoAggregateWindowSec = windowSec
+	pairing.AutoAggregateMinCount = minCount
+	return nil
+}
+
+// UpdatePairingAutoSync updates the persisted auto-sync configuration of a pairing
+func (r *InMemoryRepository) UpdatePairingAutoSync(pairingID string, intervalSec, sampleCount, intervalMs, timeoutSec int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairing, ok := r.pairings[pairingID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	pairing.AutoSyncIntervalSec = &intervalSec
+	pairing.AutoSyncSampleCount = &sampleCount
+	pairing.AutoSyncIntervalMs = &intervalMs
+	pairing.AutoSyncTimeoutSec = &timeoutSec
+	return nil
+}
+
+// GetAllPairings retrieves all pairings
+func (r *InMemoryRepository) GetAllPairings() ([]*models.PersistentPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.filterPairings(func(*models.PersistentPairing) bool { return true }), nil
+}
+
+// Group Pairings
+
+// SaveGroupPairing saves a group pairing and its device membership
+func (r *InMemoryRepository) SaveGroupPairing(pairing *models.GroupPairing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.groupPairings[pairing.PairingID]; exists {
+		return fmt.Errorf("failed to save group pairing: duplicate pairing ID %s", pairing.PairingID)
+	}
+
+	r.groupPairings[pairing.PairingID] = copyGroupPairing(pairing)
+	return nil
+}
+
+// GetGroupPairingByID retrieves a group pairing with its members
+func (r *InMemoryRepository) GetGroupPairingByID(pairingID string) (*models.GroupPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pairing, ok := r.groupPairings[pairingID]
+	if !ok {
+		return nil, fmt.Errorf("group pairing not found: %s", pairingID)
+	}
+
+	return copyGroupPairing(pairing), nil
+}
+
+// GetGroupPairingsByDeviceID retrieves all group pairings the device is a member of
+func (r *InMemoryRepository) GetGroupPairingsByDeviceID(deviceID string) ([]*models.GroupPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.filterGroupPairings(func(pairing *models.GroupPairing) bool {
+		return pairing.HasDevice(deviceID)
+	}), nil
+}
+
+// GetAllGroupPairings retrieves all group pairings
+func (r *InMemoryRepository) GetAllGroupPairings() ([]*models.GroupPairing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.filterGroupPairings(func(*models.GroupPairing) bool { return true }), nil
+}
+
+// DeleteGroupPairing deletes a group pairing and its membership
+func (r *InMemoryRepository) DeleteGroupPairing(pairingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groupPairings[pairingID]; !ok {
+		return fmt.Errorf("group pairing not found: %s", pairingID)
+	}
+
+	delete(r.groupPairings, pairingID)
+	return nil
+}
+
+// Devices
+
+// SaveDeviceInfo inserts or updates the metadata of a device; empty fields keep the stored values
+func (r *InMemoryRepository) SaveDeviceInfo(info *models.DeviceInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.devices[info.DeviceID]
+	if !ok {
+		copied := *info
+		copied.FirstSeenAt = info.LastSeenAt
+		r.devices[info.DeviceID] = &copied
+		return nil
+	}
+
+	stored.DeviceType = info.DeviceType
+	if info.Model != "" {
+		stored.Model = info.Model
+	}
+	if info.Firmware != "" {
+		stored.Firmware = info.Firmware
+	}
+	stored.LastSeenAt = info.LastSeenAt
+	return nil
+}
+
+// GetDeviceInfo retrieves the stored metadata of a device
+func (r *InMemoryRepository) GetDeviceInfo(deviceID string) (*models.DeviceInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.devices[deviceID]
+	if !ok {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+	copied := *info
+	return &copied, nil
+}
+
+// SaveOffsetOverride inserts or replaces the offset override of a pairing
+func (r *InMemoryRepository) SaveOffsetOverride(override *models.OffsetOverride) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *override
+	r.overrides[override.PairingID] = &copied
+	return nil
+}
+
+// GetOffsetOverride retrieves the offset override of a pairing, whether or not it has expired
+func (r *InMemoryRepository) GetOffsetOverride(pairingID string) (*models.OffsetOverride, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	override, ok := r.overrides[pairingID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOffsetOverrideNotFound, pairingID)
+	}
+	copied := *override
+	return &copied, nil
+}
+
+// DeleteOffsetOverride removes the offset override of a pairing
+func (r *InMemoryRepository) DeleteOffsetOverride(pairingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.overrides[pairingID]; !ok {
+		return fmt.Errorf("%w: %s", ErrOffsetOverrideNotFound, pairingID)
+	}
+	delete(r.overrides, pairingID)
+	return nil
+}
+
+// SaveDeviceEvent appends a connect/disconnect event to a device's audit trail
+func (r *InMemoryRepository) SaveDeviceEvent(event *models.DeviceEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextEventID++
+	event.ID = r.nextEventID
+	copied := *event
+	r.deviceEvents = append(r.deviceEvents, &copied)
+	return nil
+}
+
+// GetDeviceEvents retrieves a device's connect/disconnect events, newest first
+func (r *InMemoryRepository) GetDeviceEvents(deviceID string, startTime, endTime *time.Time, limit int) ([]*models.DeviceEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []*models.DeviceEvent
+	for _, event := range r.deviceEvents {
+		if event.DeviceID != deviceID {
+			continue
+		}
+		if !matchesTimeRange(startTime, endTime, event.Timestamp.UnixMilli()) {
+			continue
+		}
+		copied := *event
+		events = append(events, &copied)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].Timestamp.After(events[j].Timestamp)
+		}
+		return events[i].ID > events[j].ID
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// Ping always succeeds; there is no database to reach
+func (r *InMemoryRepository) Ping() error {
+	return nil
+}
+
+// Close is a no-op; it exists so InMemoryRepository can replace SQLiteRepository
+func (r *InMemoryRepository) Close() error {
+	return nil
+}
+
+// Helpers (callers hold r.mu)
+
+// filterRecords returns copies of the matching records sorted by created_at
+func (r *InMemoryRepository) filterRecords(match func(*models.TimeSyncRecord) bool, newestFirst bool) []*models.TimeSyncRecord {
+	var records []*models.TimeSyncRecord
+	for _, record := range r.records {
+		if match(record) {
+			recordCopy := *record
+			records = append(records, &recordCopy)
+		}
+	}
+	sortRecords(records, newestFirst)
+	return records
+}
+
+// filterAggregated returns copies of the matching aggregated results (without measurements) sorted by created_at
+func (r *InMemoryRepository) filterAggregated(match func(*models.AggregatedSyncResult) bool, newestFirst bool) []*models.AggregatedSyncResult {
+	var results []*models.AggregatedSyncResult
+	for _, result := range r.aggregated {
+		if match(result) {
+			resultCopy := *result
+			results = append(results, &resultCopy)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].CreatedAt != results[j].CreatedAt {
+			return (results[i].CreatedAt > results[j].CreatedAt) == newestFirst
+		}
+		return results[i].AggregationID < results[j].AggregationID
+	})
+	return results
+}
+
+// filterPairings returns copies of the matching pairings, newest first
+func (r *InMemoryRepository) filterPairings(match func(*models.PersistentPairing) bool) []*models.PersistentPairing {
+	var pairings []*models.PersistentPairing
+	for _, pairing := range r.pairings {
+		if match(pairing) {
+			pairingCopy := *pairing
+			pairings = append(pairings, &pairingCopy)
+		}
+	}
+	sort.Slice(pairings, func(i, j int) bool { return pairings[i].CreatedAt.After(pairings[j].CreatedAt) })
+	return pairings
+}
+
+// filterGroupPairings returns copies of the matching group pairings, newest first
+func (r *InMemoryRepository) filterGroupPairings(match func(*models.GroupPairing) bool) []*models.GroupPairing {
+	var pairings []*models.GroupPairing
+	for _, pairing := range r.groupPairings {
+		if match(pairing) {
+			pairings = append(pairings, copyGroupPairing(pairing))
+		}
+	}
+	sort.Slice(pairings, func(i, j int) bool { return pairings[i].CreatedAt.After(pairings[j].CreatedAt) })
+	return pairings
+}
+
+// linkedRecordIDs returns the IDs of all records linked to an aggregation
+func (r *InMemoryRepository) linkedRecordIDs() map[int64]bool {
+	linked := make(map[int64]bool)
+	for _, ids := range r.links {
+		for _, id := range ids {
+			linked[id] = true
+		}
+	}
+	return linked
+}
+
+// unlinkRecords removes the given records from every aggregation and returns the number of removed links
+func (r *InMemoryRepository) unlinkRecords(ids map[int64]bool) int64 {
+	var removed int64
+	for aggregationID, linked := range r.links {
+		kept := linked[:0]
+		for _, id := range linked {
+			if ids[id] {
+				removed++
+				continue
+			}
+			kept = append(kept, id)
+		}
+		r.links[aggregationID] = kept
+	}
+	return removed
+}
+
+// sortRecords orders records by created_at, breaking ties by ID
+func sortRecords(records []*models.TimeSyncRecord, newestFirst bool) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].CreatedAt != records[j].CreatedAt {
+			return (records[i].CreatedAt > records[j].CreatedAt) == newestFirst
+		}
+		return (records[i].ID > records[j].ID) == newestFirst
+	})
+}
+
+// matchesTimeRange applies optional created_at bounds
+func matchesTimeRange(start, end *time.Time, createdAt int64) bool {
+	if start != nil && createdAt < start.UnixMilli() {
+		return false
+	}
+	if end != nil && createdAt > end.UnixMilli() {
+		return false
+	}
+	return true
+}
+
+// paginate applies LIMIT/OFFSET semantics to a sorted slice
+// sortByKey stable-sorts items by key; items without a key go last and ties keep their
+// created_at order, matching orderByClause
+func sortByKey[T any](items []T, ascending bool, key func(T) (float64, bool)) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ki, okI := key(items[i])
+		kj, okJ := key(items[j])
+		if !okI || !okJ {
+			return okI && !okJ
+		}
+		if ascending {
+			return ki < kj
+		}
+		return ki > kj
+	})
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func copyGroupPairing(pairing *models.GroupPairing) *models.GroupPairing {
+	pairingCopy := *pairing
+	pairingCopy.DeviceIDs = append([]string(nil), pairing.DeviceIDs...)
+	return &pairingCopy
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+func TestInMemoryRecordsNewestFirstWithPagination(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	now := time.Now()
+	var saved []*models.TimeSyncRecord
+	for i := 0; i < 3; i++ {
+		record := newTestRecord(int64(100 + i))
+		record.CreatedAt = now.Add(time.Duration(i) * time.Minute).UnixMilli()
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		saved = append(saved, record)
+	}
+
+	if saved[0].ID == 0 || saved[0].ID == saved[1].ID {
+		t.Fatalf("Expected distinct non-zero IDs, got %d and %d", saved[0].ID, saved[1].ID)
+	}
+
+	records, err := repo.GetTimeSyncRecords(2, 1)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecords() error = %v", err)
+	}
+	if len(records) != 2 || records[0].ID != saved[1].ID || records[1].ID != saved[0].ID {
+		t.Errorf("records = %v, expected IDs [%d %d]", records, saved[1].ID, saved[0].ID)
+	}
+
+	if _, err := repo.GetTimeSyncRecord(999); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetTimeSyncRecord(999) error = %v, expected ErrRecordNotFound", err)
+	}
+	if err := repo.DeleteTimeSyncRecord(999); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteTimeSyncRecord() error = %v, expected ErrRecordNotFound", err)
+	}
+}
+
+func TestInMemorySaveAggregatedSyncResultReportsUnlinkedMeasurements(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	saved := newTestRecord(100)
+	saved.Source = models.RecordSourceSingle
+	if err := repo.SaveTimeSyncRecord(saved); err != nil {
+		t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+	}
+	unsaved := newTestRecord(120)
+
+	result := &models.AggregatedSyncResult{
+		AggregationID: "agg-unlinked",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{saved, unsaved},
+		CreatedAt:     time.Now().UnixMilli(),
+	}
+
+	var unlinked *UnlinkedMeasurementsError
+	if err := repo.SaveAggregatedSyncResult(result); !errors.As(err, &unlinked) {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v, expected *UnlinkedMeasurementsError", err)
+	}
+	if unlinked.Skipped != 1 || unlinked.Total != 2 {
+		t.Errorf("Skipped/Total = %d/%d, expected 1/2", unlinked.Skipped, unlinked.Total)
+	}
+
+	stored, err := repo.GetAggregatedSyncResult(result.AggregationID)
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(stored.Measurements) != 1 || stored.Measurements[0].ID != saved.ID {
+		t.Errorf("linked measurements = %v, expected only record %d", stored.Measurements, saved.ID)
+	}
+
+	// Linked records are no longer single-sync records
+	pending, err := repo.GetUnaggregatedTimeSyncRecords(saved.Device1ID, saved.Device2ID, time.Time{})
+	if err != nil {
+		t.Fatalf("GetUnaggregatedTimeSyncRecords() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("unaggregated records = %d, expected 0", len(pending))
+	}
+}
+
+func TestInMemoryDeleteRecordsOlderThan(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	now := time.Now()
+	old := newTestRecord(100)
+	old.CreatedAt = now.Add(-48 * time.Hour).UnixMilli()
+	recent := newTestRecord(110)
+	for _, record := range []*models.TimeSyncRecord{old, recent} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+	for _, result := range []*models.AggregatedSyncResult{
+		{AggregationID: "agg-old", Measurements: []*models.TimeSyncRecord{old}, CreatedAt: old.CreatedAt},
+		{AggregationID: "agg-recent", Measurements: []*models.TimeSyncRecord{recent}, CreatedAt: recent.CreatedAt},
+	} {
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	deleted, err := repo.DeleteRecordsOlderThan(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteRecordsOlderThan() error = %v", err)
+	}
+
+	// Same accounting as SQLite: 1 link + 1 record + 1 aggregated result
+	if deleted != 3 {
+		t.Errorf("deleted = %d, expected 3", deleted)
+	}
+	if _, err := repo.GetAggregatedSyncResult("agg-old"); err == nil {
+		t.Errorf("Expected old aggregation to be deleted")
+	}
+	if _, err := repo.GetAggregatedSyncResult("agg-recent"); err != nil {
+		t.Errorf("Expected recent aggregation to be kept, got error %v", err)
+	}
+}
+
+func TestInMemoryPairings(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	pairing := &models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+	}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	duplicate := *pairing
+	duplicate.PairingID = "pair-456"
+	if err := repo.SavePairing(&duplicate); err == nil {
+		t.Errorf("Expected error when pairing the same devices twice")
+	}
+
+	found, err := repo.GetPairingByDevices("watch-001", "psg-001")
+	if err != nil || found.PairingID != pairing.PairingID {
+		t.Errorf("GetPairingByDevices() = %v, %v, expected %s", found, err, pairing.PairingID)
+	}
+
+	windowSec := 300
+	if err := repo.UpdatePairingAutoAggregation(pairing.PairingID, true, &windowSec, nil); err != nil {
+		t.Fatalf("UpdatePairingAutoAggregation() error = %v", err)
+	}
+	stored, _ := repo.GetPairingByID(pairing.PairingID)
+	if !stored.AutoAggregateEnabled || stored.AutoAggregateWindowSec == nil || *stored.AutoAggregateWindowSec != windowSec {
+		t.Errorf("auto-aggregation not updated: %+v", stored)
+	}
+
+	if err := repo.DeletePairing(pairing.PairingID); err != nil {
+		t.Fatalf("DeletePairing() error = %v", err)
+	}
+	if err := repo.DeletePairing(pairing.PairingID); err == nil {
+		t.Errorf("Expected error when deleting a missing pairing")
+	}
+}
+
+func TestInMemoryDeleteTimeSyncRecord(t *testing.T) {
+	testDeleteTimeSyncRecord(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeleteAggregatedSyncResult(t *testing.T) {
+	testDeleteAggregatedSyncResult(t, NewInMemoryRepository())
+}
+
+func TestInMemoryAggregatedMeasurementsKeepAdjustedOffset(t *testing.T) {
+	testAggregatedMeasurementsKeepAdjustedOffset(t, NewInMemoryRepository())
+}
+
+func TestInMemoryFilteredListings(t *testing.T) {
+	testFilteredListings(t, NewInMemoryRepository())
+}
+
+func TestInMemoryConfidenceRangeFilter(t *testing.T) {
+	testConfidenceRangeFilter(t, NewInMemoryRepository())
+}
+
+func TestInMemoryListSorting(t *testing.T) {
+	testListSorting(t, NewInMemoryRepository())
+}
+
+func TestInMemoryAggregatedOffsetBuckets(t *testing.T) {
+	testAggregatedOffsetBuckets(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeviceInfo(t *testing.T) {
+	testDeviceInfo(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeviceEvents(t *testing.T) {
+	testDeviceEvents(t, NewInMemoryRepository())
+}
+
+func TestInMemoryLatestAggregatedSyncResult(t *testing.T) {
+	testLatestAggregatedSyncResult(t, NewInMemoryRepository())
+}
+
+func TestInMemoryTimeSyncRecordsAfterCursor(t *testing.T) {
+	testTimeSyncRecordsAfterCursor(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeviceSyncStats(t *testing.T) {
+	testDeviceSyncStats(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeletePairingRecords(t *testing.T) {
+	testDeletePairingRecords(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeletePairingUnaggregatedRecords(t *testing.T) {
+	testDeletePairingUnaggregatedRecords(t, NewInMemoryRepository())
+}
+
+func TestInMemorySaveTimeSyncRecords(t *testing.T) {
+	testSaveTimeSyncRecords(t, NewInMemoryRepository())
+}
+
+func TestInMemoryOffsetOverrides(t *testing.T) {
+	testOffsetOverrides(t, NewInMemoryRepository())
+}
+
+func TestInMemoryUnaggregatedTimeSyncRecords(t *testing.T) {
+	testUnaggregatedTimeSyncRecords(t, NewInMemoryRepository())
+}
+
+func TestInMemoryLatestMemberAggregatedSyncResult(t *testing.T) {
+	testLatestMemberAggregatedSyncResult(t, NewInMemoryRepository())
+}
+
+func TestInMemoryDeviceTypeStats(t *testing.T) {
+	testDeviceTypeStats(t, NewInMemoryRepository())
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// migration upgrades the schema by one version. Each migration runs in its own transaction
+// together with the schema_migrations row recording it.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sqlTx) error
+}
+
+// migrate applies every migration newer than the version recorded in schema_migrations.
+// Versions must count up from 1 without gaps; append new migrations and never edit applied ones.
+// A database created before versioning has no rows and starts at version 0, so migration 1
+// must be idempotent to upgrade such databases in place.
+func (d *sqlDB) migrate(migrations []migration) error {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return fmt.Errorf("migration %q has version %d, expected %d", m.description, m.version, i+1)
+		}
+	}
+
+	if _, err := d.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := d.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d", current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		if err := d.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		log.Printf("Applied schema migration %d: %s", m.version, m.description)
+	}
+	return nil
+}
+
+// applyMigration runs a single migration and records its version in the same transaction
+func (d *sqlDB) applyMigration(m migration) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		m.version, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schemaVersion returns the latest applied migration version, 0 if none was applied
+func (d *sqlDB) schemaVersion() (int, error) {
+	var version int
+	if err := d.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
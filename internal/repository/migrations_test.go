@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+func TestMigrateUpgradesUnversionedDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// A database created before auto_sync_timeout_sec and schema versioning existed
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE pairings (
+		pairing_id TEXT PRIMARY KEY,
+		device1_id TEXT NOT NULL,
+		device2_id TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		auto_sync_interval_sec INTEGER,
+		auto_sync_sample_count INTEGER,
+		auto_sync_interval_ms INTEGER
+	)`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	legacy.Close()
+
+	for i := 0; i < 2; i++ {
+		repo, err := NewSQLiteRepository(dbPath, 0)
+		if err != nil {
+			t.Fatalf("NewSQLiteRepository() error = %v", err)
+		}
+		if version, err := repo.db.schemaVersion(); err != nil || version != len(sqliteMigrations) {
+			t.Errorf("schemaVersion() = %d, %v, expected %d", version, err, len(sqliteMigrations))
+		}
+		var rows int
+		if err := repo.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&rows); err != nil || rows != len(sqliteMigrations) {
+			t.Errorf("schema_migrations has %d rows (%v), expected every migration recorded once", rows, err)
+		}
+		repo.Close()
+	}
+
+	repo, err := NewSQLiteRepository(dbPath, 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository() error = %v", err)
+	}
+	defer repo.Close()
+	timeout := 7
+	pairing := &models.PersistentPairing{PairingID: "pair-1", Device1ID: "psg-001", Device2ID: "watch-001", CreatedAt: time.Now(), AutoSyncTimeoutSec: &timeout}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+	got, err := repo.GetPairingByID("pair-1")
+	if err != nil || got.AutoSyncTimeoutSec == nil || *got.AutoSyncTimeoutSec != timeout {
+		t.Errorf("GetPairingByID() = %+v, %v, expected the added column to round-trip", got, err)
+	}
+}
+
+func TestMigrateRejectsNewerDatabase(t *testing.T) {
+	repo := newTestRepository(t)
+	if _, err := repo.db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, len(sqliteMigrations)+1, time.Now().UnixMilli()); err != nil {
+		t.Fatalf("failed to record future migration: %v", err)
+	}
+
+	err := repo.db.migrate(sqliteMigrations)
+	if err == nil || !strings.Contains(err.Error(), "newer than the latest known version") {
+		t.Errorf("migrate() error = %v, expected the newer schema to be rejected", err)
+	}
+}
+
+func TestMigrateAppliesPendingMigrationsInOrder(t *testing.T) {
+	repo := newTestRepository(t)
+
+	var applied []int
+	migrations := append([]migration{}, sqliteMigrations...)
+	for _, version := range []int{len(migrations) + 1, len(migrations) + 2} {
+		version := version
+		migrations = append(migrations, migration{version, "test", func(tx *sqlTx) error {
+			applied = append(applied, version)
+			return nil
+		}})
+	}
+	if err := repo.db.migrate(migrations); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+	if len(applied) != 2 || applied[0] != len(sqliteMigrations)+1 {
+		t.Errorf("applied = %v, expected only the two new migrations in order", applied)
+	}
+
+	gap := append(migrations, migration{len(migrations) + 2, "gap", func(tx *sqlTx) error { return nil }})
+	if err := repo.db.migrate(gap); err == nil {
+		t.Error("migrate() accepted a gap in the migration versions")
+	}
+}
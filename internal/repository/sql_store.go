@@ -0,0 +1,2196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// ErrRecordNotFound is returned when a time sync record does not exist
+var ErrRecordNotFound = errors.New("sync record not found")
+
+// ErrAggregationNotFound is returned when an aggregated sync result does not exist
+var ErrAggregationNotFound = errors.New("aggregation not found")
+
+// ErrOffsetOverrideNotFound is returned when a pairing has no offset override
+var ErrOffsetOverrideNotFound = errors.New("offset override not found")
+
+// ErrPairingNotFound is returned when a pairing does not exist
+var ErrPairingNotFound = errors.New("pairing not found")
+
+// ErrInvalidSort is returned when a listing is asked for an unsupported sort key or order
+var ErrInvalidSort = errors.New("invalid sort")
+
+// dialect captures the SQL differences between the supported databases
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// rebind rewrites "?" placeholders to the dialect's style ("$1", "$2", ... for PostgreSQL).
+// Queries are written once with "?"; placeholders inside single-quoted literals are left alone.
+func (d dialect) rebind(query string) string {
+	if d != dialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	inLiteral := false
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			inLiteral = !inLiteral
+			b.WriteRune(c)
+		case c == '?' && !inLiteral:
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// sqlDB wraps *sql.DB so every query is rebound to the dialect's placeholder style
+type sqlDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (d *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(d.dialect.rebind(query), args...)
+}
+
+func (d *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.Query(d.dialect.rebind(query), args...)
+}
+
+func (d *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRow(d.dialect.rebind(query), args...)
+}
+
+func (d *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: d.dialect}, nil
+}
+
+// sqlTx wraps *sql.Tx with the same placeholder rebinding as sqlDB
+type sqlTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (t *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), args...)
+}
+
+func (t *sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), args...)
+}
+
+func (t *sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), args...)
+}
+
+// sqlExecutor runs single statements; implemented by sqlDB and sqlTx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqlStore implements the repository on top of database/sql. The queries are shared by
+// SQLiteRepository and PostgresRepository; only schema creation differs per database.
+type sqlStore struct {
+	db *sqlDB
+}
+
+// insertReturningID runs an INSERT and returns the generated id column
+func (r *sqlStore) insertReturningID(query string, args ...interface{}) (int64, error) {
+	return insertID(r.db, r.db.dialect, query, args...)
+}
+
+// insertID runs an INSERT on db, which may be a transaction, and returns the generated id column
+func insertID(db sqlExecutor, d dialect, query string, args ...interface{}) (int64, error) {
+	if d == dialectPostgres {
+		// lib/pq does not support LastInsertId
+		var id int64
+		if err := db.QueryRow(query+" RETURNING id", args...).Scan(&id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+const insertTimeSyncRecordQuery = `
+	INSERT INTO time_sync_records (
+		device1_id, device1_type, device1_timestamp,
+		device2_id, device2_type, device2_timestamp,
+		server_request_time, server_response_time,
+		device1_rtt, device2_rtt, time_difference, offset_compensated,
+		status, error_message, created_at, source
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+// timeSyncRecordArgs returns the values for insertTimeSyncRecordQuery
+func timeSyncRecordArgs(record *models.TimeSyncRecord) []interface{} {
+	return []interface{}{
+		record.Device1ID,
+		record.Device1Type,
+		record.Device1Timestamp,
+		record.Device2ID,
+		record.Device2Type,
+		record.Device2Timestamp,
+		record.ServerRequestTime,
+		record.ServerResponseTime,
+		record.Device1RTT,
+		record.Device2RTT,
+		record.TimeDifference,
+		record.OffsetCompensated,
+		record.Status,
+		record.ErrorMessage,
+		record.CreatedAt,
+		nullString(record.Source),
+	}
+}
+
+func (r *sqlStore) SaveTimeSyncRecord(record *models.TimeSyncRecord) error {
+	id, err := r.insertReturningID(insertTimeSyncRecordQuery, timeSyncRecordArgs(record)...)
+	if err != nil {
+		return fmt.Errorf("failed to save time sync record: %w", err)
+	}
+
+	record.ID = id
+	return nil
+}
+
+// SaveTimeSyncRecords saves several records in one transaction and sets their IDs.
+// Either all records are saved or none; on failure no ID is changed.
+func (r *sqlStore) SaveTimeSyncRecords(records []*models.TimeSyncRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, len(records))
+	for i, record := range records {
+		if ids[i], err = insertID(tx, tx.dialect, insertTimeSyncRecordQuery, timeSyncRecordArgs(record)...); err != nil {
+			return fmt.Errorf("failed to save time sync record %d of %d: %w", i+1, len(records), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit time sync records: %w", err)
+	}
+	for i, record := range records {
+		record.ID = ids[i]
+	}
+	return nil
+}
+
+// timeSyncRecordColumns is the column list used by time_sync_records queries (see scanTimeSyncRecord)
+const timeSyncRecordColumns = `id, device1_id, device1_type, device1_timestamp,
+	       device2_id, device2_type, device2_timestamp,
+	       server_request_time, server_response_time,
+	       device1_rtt, device2_rtt, time_difference, offset_compensated,
+	       status, error_message, created_at, source`
+
+// scanTimeSyncRecord scans a time_sync_records row selected with timeSyncRecordColumns.
+// extra receives any columns selected after them.
+func scanTimeSyncRecord(scanner rowScanner, extra ...any) (*models.TimeSyncRecord, error) {
+	record := &models.TimeSyncRecord{}
+	var source sql.NullString // NULL for records saved before the column existed
+	dest := []any{
+		&record.ID,
+		&record.Device1ID,
+		&record.Device1Type,
+		&record.Device1Timestamp,
+		&record.Device2ID,
+		&record.Device2Type,
+		&record.Device2Timestamp,
+		&record.ServerRequestTime,
+		&record.ServerResponseTime,
+		&record.Device1RTT,
+		&record.Device2RTT,
+		&record.TimeDifference,
+		&record.OffsetCompensated,
+		&record.Status,
+		&record.ErrorMessage,
+		&record.CreatedAt,
+		&source,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	record.Source = source.String
+	return record, nil
+}
+
+// GetTimeSyncRecord retrieves a single time sync record by ID.
+// Returns ErrRecordNotFound if the record does not exist.
+func (r *sqlStore) GetTimeSyncRecord(id int64) (*models.TimeSyncRecord, error) {
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records
+	WHERE id = ?
+	`
+
+	record, err := scanTimeSyncRecord(r.db.QueryRow(query, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrRecordNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to query sync record: %w", err)
+	}
+
+	return record, nil
+}
+
+// DeleteTimeSyncRecord deletes a time sync record and its links to aggregations.
+// Returns ErrRecordNotFound if the record does not exist.
+func (r *sqlStore) DeleteTimeSyncRecord(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM aggregation_measurements WHERE measurement_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete measurement links: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM time_sync_records WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete time sync record: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrRecordNotFound, id)
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlStore) GetTimeSyncRecords(limit, offset int) ([]*models.TimeSyncRecord, error) {
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time sync records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (r *sqlStore) GetTimeSyncRecordsByDeviceID(deviceID string, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records
+	WHERE device1_id = ? OR device2_id = ?
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, deviceID, deviceID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time sync records by device: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (r *sqlStore) GetTimeSyncRecordsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records
+	WHERE created_at BETWEEN ? AND ?
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	startMillis := startTime.UnixMilli()
+	endMillis := endTime.UnixMilli()
+
+	rows, err := r.db.Query(query, startMillis, endMillis, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time sync records by time range: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// GetUnaggregatedTimeSyncRecords retrieves single-sync records for a device pair created since the
+// given time that are not linked to any aggregation yet, oldest first. Multi-sync samples are left
+// out even before their aggregation links them, so they are never aggregated twice.
+func (r *sqlStore) GetUnaggregatedTimeSyncRecords(device1ID, device2ID string, since time.Time) ([]*models.TimeSyncRecord, error) {
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records
+	WHERE device1_id = ? AND device2_id = ? AND created_at >= ? AND source = ?
+	  AND id NOT IN (SELECT measurement_id FROM aggregation_measurements)
+	ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, device1ID, device2ID, since.UnixMilli(), models.RecordSourceSingle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unaggregated time sync records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// GetTimeSyncRecordsAfterCursor retrieves up to limit records matching the filter that come after cursor,
+// newest first by created_at, then id. A nil cursor starts at the newest record; filter.Sort is not applied.
+func (r *sqlStore) GetTimeSyncRecordsAfterCursor(filter models.RecordFilter, cursor *models.RecordCursor, limit int) ([]*models.TimeSyncRecord, error) {
+	where := recordFilterWhere(filter)
+	if cursor != nil {
+		where.add("(created_at < ? OR (created_at = ? AND id < ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records` + where.String() + `
+	ORDER BY created_at DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(where.args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time sync records after cursor: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// recordFilterWhere builds the conditions of a RecordFilter, without its sort
+func recordFilterWhere(filter models.RecordFilter) whereClause {
+	var where whereClause
+	if filter.DeviceID != "" {
+		where.add("(device1_id = ? OR device2_id = ?)", filter.DeviceID, filter.DeviceID)
+	}
+	if filter.Status != "" {
+		where.add("status = ?", filter.Status)
+	}
+	where.addTimeRange(filter.StartTime, filter.EndTime)
+	return where
+}
+
+// GetTimeSyncRecordsFiltered retrieves records matching all set fields of the filter in the order of filter.Sort
+func (r *sqlStore) GetTimeSyncRecordsFiltered(filter models.RecordFilter, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	orderBy, err := orderByClause(filter.Sort, recordSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	where := recordFilterWhere(filter)
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records` + where.String() + orderBy + `
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, append(where.args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filtered time sync records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ForEachTimeSyncRecord streams the records matching the filter to fn, oldest first,
+// without loading them all into memory. Iteration stops at the first error returned by fn.
+func (r *sqlStore) ForEachTimeSyncRecord(filter models.ExportFilter, fn func(*models.TimeSyncRecord) error) error {
+	where, args := exportWhereClause(filter, "(device1_id = ? OR device2_id = ?)", filter.DeviceID, filter.DeviceID)
+	query := `
+	SELECT ` + timeSyncRecordColumns + `
+	FROM time_sync_records` + where + `
+	ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query time sync records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanTimeSyncRecord(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan time sync record: %w", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// SaveAggregatedSyncResult saves an aggregated sync result with its measurements
+func (r *sqlStore) SaveAggregatedSyncResult(result *models.AggregatedSyncResult) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Insert aggregated result
+	query := `
+	INSERT INTO aggregated_sync_results (
+		aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
+		offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
+		total_samples, valid_samples, outlier_count, created_at,
+		reference_device_id, weighted_offset,
+		success_samples, partial_samples, failed_samples,
+		min_samples, outlier_threshold, top_percentile, outlier_method,
+		min_rtt_offset, retry_count,
+		rtt_p50, rtt_p90, rtt_p99, smoothed_offset, grade, member_device_id
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = tx.Exec(query,
+		result.AggregationID,
+		result.PairingID,
+		result.BestOffset,
+		result.MedianOffset,
+		result.MeanOffset,
+		result.OffsetStdDev,
+		result.MinRTT,
+		result.MaxRTT,
+		result.MeanRTT,
+		result.Confidence,
+		result.Jitter,
+		result.TotalSamples,
+		result.ValidSamples,
+		result.OutlierCount,
+		result.CreatedAt,
+		nullString(result.ReferenceDeviceID),
+		result.WeightedOffset,
+		result.SuccessSamples,
+		result.PartialSamples,
+		result.FailedSamples,
+		result.MinSamples,
+		result.OutlierThreshold,
+		result.TopPercentile,
+		nullString(result.OutlierMethod),
+		result.MinRTTOffset,
+		result.RetryCount,
+		result.RTTP50,
+		result.RTTP90,
+		result.RTTP99,
+		result.SmoothedOffset,
+		nullString(result.Grade),
+		nullString(result.MemberDeviceID),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert aggregated result: %w", err)
+	}
+
+	// Insert links to individual measurements; rejected outliers keep their deviation and reason on the link
+	rejected := make(map[int64]models.RejectedSample, len(result.RejectedSamples))
+	for _, sample := range result.RejectedSamples {
+		rejected[sample.RecordID] = sample
+	}
+	linkQuery := `INSERT INTO aggregation_measurements (aggregation_id, measurement_id, adjusted_offset, outlier_deviation, outlier_reason) VALUES (?, ?, ?, ?, ?)`
+	skipped := 0
+	for _, measurement := range result.Measurements {
+		if measurement.ID == 0 {
+			skipped++ // Measurement was never saved, nothing to link to
+			continue
+		}
+		var deviation *float64
+		var reason *string
+		if sample, ok := rejected[measurement.ID]; ok {
+			deviation, reason = &sample.Deviation, &sample.Reason
+		}
+		_, err = tx.Exec(linkQuery, result.AggregationID, measurement.ID, measurement.AdjustedOffset, deviation, reason)
+		if err != nil {
+			return fmt.Errorf("failed to link measurement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The aggregated result itself is saved; report that some links are missing
+	if skipped > 0 {
+		return &UnlinkedMeasurementsError{
+			AggregationID: result.AggregationID,
+			Skipped:       skipped,
+			Total:         len(result.Measurements),
+		}
+	}
+
+	return nil
+}
+
+// DeleteRecordsOlderThan deletes sync records created before t together with their aggregation
+// links, then removes aggregated results older than t that no longer reference any measurement.
+// Returns the total number of deleted rows.
+func (r *sqlStore) DeleteRecordsOlderThan(t time.Time) (int64, error) {
+	cutoff := t.UnixMilli()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{
+			"aggregation links",
+			`DELETE FROM aggregation_measurements
+			 WHERE measurement_id IN (SELECT id FROM time_sync_records WHERE created_at < ?)`,
+		},
+		{
+			"sync records",
+			`DELETE FROM time_sync_records WHERE created_at < ?`,
+		},
+		{
+			"aggregated results",
+			`DELETE FROM aggregated_sync_results
+			 WHERE created_at < ?
+			   AND aggregation_id NOT IN (SELECT aggregation_id FROM aggregation_measurements)`,
+		},
+	}
+
+	var total int64
+	for _, step := range steps {
+		result, err := tx.Exec(step.query, cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete old %s: %w", step.name, err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		total += rowsAffected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit cleanup: %w", err)
+	}
+
+	return total, nil
+}
+
+// DeletePairingRecords deletes every aggregated result of a pairing, their measurement links, the
+// linked sync records and the unaggregated records of the pairing's devices in one transaction.
+// Records that an aggregation of another pairing still links to are kept. The pairing itself is not touched.
+func (r *sqlStore) DeletePairingRecords(pairingID string) (*models.PairingRecordsDeletion, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletion := &models.PairingRecordsDeletion{PairingID: pairingID}
+
+	// The pairing's devices identify the records that were never aggregated. Without a saved
+	// pairing only the linked records can be found.
+	var device1ID, device2ID string
+	err = tx.QueryRow(`SELECT device1_id, device2_id FROM pairings WHERE pairing_id = ?`, pairingID).
+		Scan(&device1ID, &device2ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query pairing %s: %w", pairingID, err)
+	}
+	paired := err == nil
+
+	// Records go first, while the links that identify them still exist
+	steps := []struct {
+		name  string
+		query string
+		args  []interface{}
+		count *int64
+	}{
+		{
+			"sync records",
+			`DELETE FROM time_sync_records
+			 WHERE (id IN (SELECT am.measurement_id FROM aggregation_measurements am
+			               JOIN aggregated_sync_results a ON a.aggregation_id = am.aggregation_id
+			               WHERE a.pairing_id = ?)
+			        OR (? AND ((device1_id = ? AND device2_id = ?) OR (device1_id = ? AND device2_id = ?))))
+			   AND id NOT IN (SELECT am.measurement_id FROM aggregation_measurements am
+			                  JOIN aggregated_sync_results a ON a.aggregation_id = am.aggregation_id
+			                  WHERE a.pairing_id <> ?)`,
+			[]interface{}{pairingID, paired, device1ID, device2ID, device2ID, device1ID, pairingID},
+			&deletion.RecordsDeleted,
+		},
+		{
+			"aggregation links",
+			`DELETE FROM aggregation_measurements
+			 WHERE aggregation_id IN (SELECT aggregation_id FROM aggregated_sync_results WHERE pairing_id = ?)`,
+			[]interface{}{pairingID},
+			&deletion.LinksDeleted,
+		},
+		{
+			"aggregated results",
+			`DELETE FROM aggregated_sync_results WHERE pairing_id = ?`,
+			[]interface{}{pairingID},
+			&deletion.AggregationsDeleted,
+		},
+	}
+
+	for _, step := range steps {
+		result, err := tx.Exec(step.query, step.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s of pairing %s: %w", step.name, pairingID, err)
+		}
+		if *step.count, err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit deletion: %w", err)
+	}
+
+	return deletion, nil
+}
+
+// UnlinkedMeasurementsError is returned by SaveAggregatedSyncResult when the aggregated result
+// was saved but some of its measurements had no ID (e.g. their own save failed) and could not be linked
+type UnlinkedMeasurementsError struct {
+	AggregationID string
+	Skipped       int
+	Total         int
+}
+
+func (e *UnlinkedMeasurementsError) Error() string {
+	return fmt.Sprintf("aggregation %s saved with %d of %d measurement(s) unlinked (missing record ID)",
+		e.AggregationID, e.Skipped, e.Total)
+}
+
+// aggregatedResultColumns is the column list used by aggregated_sync_results queries (see scanAggregatedResult)
+const aggregatedResultColumns = `aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
+	       offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
+	       total_samples, valid_samples, outlier_count, created_at,
+	       reference_device_id, weighted_offset,
+	       success_samples, partial_samples, failed_samples,
+	       min_samples, outlier_threshold, top_percentile, outlier_method,
+	       min_rtt_offset, retry_count,
+	       rtt_p50, rtt_p90, rtt_p99, smoothed_offset, grade, member_device_id`
+
+// scanAggregatedResult scans an aggregated_sync_results row selected with aggregatedResultColumns
+func scanAggregatedResult(scanner rowScanner) (*models.AggregatedSyncResult, error) {
+	result := &models.AggregatedSyncResult{}
+	var referenceDeviceID sql.NullString
+	var weightedOffset sql.NullFloat64 // NULL for results saved before the column existed
+	var minSamples sql.NullInt64
+	var outlierThreshold, topPercentile sql.NullFloat64
+	var outlierMethod sql.NullString
+	var minRTTOffset sql.NullInt64
+	var rttP50, rttP90, rttP99 sql.NullInt64
+	var smoothedOffset sql.NullFloat64
+	var grade, memberDeviceID sql.NullString
+	err := scanner.Scan(
+		&result.AggregationID,
+		&result.PairingID,
+		&result.BestOffset,
+		&result.MedianOffset,
+		&result.MeanOffset,
+		&result.OffsetStdDev,
+		&result.MinRTT,
+		&result.MaxRTT,
+		&result.MeanRTT,
+		&result.Confidence,
+		&result.Jitter,
+		&result.TotalSamples,
+		&result.ValidSamples,
+		&result.OutlierCount,
+		&result.CreatedAt,
+		&referenceDeviceID,
+		&weightedOffset,
+		&result.SuccessSamples,
+		&result.PartialSamples,
+		&result.FailedSamples,
+		&minSamples,
+		&outlierThreshold,
+		&topPercentile,
+		&outlierMethod,
+		&minRTTOffset,
+		&result.RetryCount,
+		&rttP50,
+		&rttP90,
+		&rttP99,
+		&smoothedOffset,
+		&grade,
+		&memberDeviceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.ReferenceDeviceID = referenceDeviceID.String
+	result.WeightedOffset = weightedOffset.Float64
+	result.MinSamples = int(minSamples.Int64)
+	result.OutlierThreshold = outlierThreshold.Float64
+	result.TopPercentile = topPercentile.Float64
+	result.OutlierMethod = outlierMethod.String
+	result.MinRTTOffset = minRTTOffset.Int64
+	result.RTTP50 = rttP50.Int64
+	result.RTTP90 = rttP90.Int64
+	result.RTTP99 = rttP99.Int64
+	result.Grade = grade.String
+	result.MemberDeviceID = memberDeviceID.String
+	if smoothedOffset.Valid {
+		result.SmoothedOffset = &smoothedOffset.Float64
+	}
+
+	return result, nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// GetAggregatedSyncResult retrieves an aggregated sync result by ID
+func (r *sqlStore) GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE aggregation_id = ?
+	`
+
+	result, err := scanAggregatedResult(r.db.QueryRow(query, aggregationID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrAggregationNotFound, aggregationID)
+		}
+		return nil, fmt.Errorf("failed to query aggregated result: %w", err)
+	}
+
+	// Load associated measurements
+	measurements, err := r.getAggregationMeasurements(aggregationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load measurements: %w", err)
+	}
+	result.Measurements = measurements
+
+	rejected, err := r.getRejectedSamples(aggregationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rejected samples: %w", err)
+	}
+	result.RejectedSamples = rejected
+
+	return result, nil
+}
+
+// DeleteAggregatedSyncResult deletes an aggregated result and its measurement links in one transaction.
+// With deleteRecords the linked time sync records are deleted too, except those another aggregation
+// still links to. Returns ErrAggregationNotFound if the aggregation does not exist.
+func (r *sqlStore) DeleteAggregatedSyncResult(aggregationID string, deleteRecords bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var recordIDs []int64
+	if deleteRecords {
+		rows, err := tx.Query(`SELECT measurement_id FROM aggregation_measurements WHERE aggregation_id = ?`, aggregationID)
+		if err != nil {
+			return fmt.Errorf("failed to query measurement links: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan measurement link: %w", err)
+			}
+			recordIDs = append(recordIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM aggregation_measurements WHERE aggregation_id = ?`, aggregationID); err != nil {
+		return fmt.Errorf("failed to delete measurement links: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM aggregated_sync_results WHERE aggregation_id = ?`, aggregationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete aggregated result: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrAggregationNotFound, aggregationID)
+	}
+
+	for _, id := range recordIDs {
+		_, err := tx.Exec(`
+		DELETE FROM time_sync_records
+		WHERE id = ? AND NOT EXISTS (SELECT 1 FROM aggregation_measurements WHERE measurement_id = ?)
+		`, id, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete time sync record %d: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLatestAggregatedSyncResult retrieves the most recent aggregated result of a pairing, without measurements.
+// Returns ErrAggregationNotFound if the pairing has no aggregated results.
+func (r *sqlStore) GetLatestAggregatedSyncResult(pairingID string) (*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE pairing_id = ?
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	result, err := scanAggregatedResult(r.db.QueryRow(query, pairingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w for pairing %s", ErrAggregationNotFound, pairingID)
+		}
+		return nil, fmt.Errorf("failed to query latest aggregated result: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetLatestMemberAggregatedSyncResult retrieves the most recent aggregated result of one group member
+// of a pairing, without measurements. An empty memberDeviceID selects the results of a two-device pairing.
+func (r *sqlStore) GetLatestMemberAggregatedSyncResult(pairingID, memberDeviceID string) (*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE pairing_id = ? AND COALESCE(member_device_id, '') = ?
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+
+	result, err := scanAggregatedResult(r.db.QueryRow(query, pairingID, memberDeviceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w for pairing %s member %q", ErrAggregationNotFound, pairingID, memberDeviceID)
+		}
+		return nil, fmt.Errorf("failed to query latest aggregated result: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetLatestAggregatedSyncResults retrieves the most recent aggregated result of every pairing in one query,
+// without measurements, ordered by pairing ID. With pairingIDs only those pairings are considered;
+// pairings without results are left out.
+func (r *sqlStore) GetLatestAggregatedSyncResults(pairingIDs []string) ([]*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results a
+	WHERE a.created_at = (
+		SELECT MAX(b.created_at) FROM aggregated_sync_results b WHERE b.pairing_id = a.pairing_id
+	)`
+	var args []interface{}
+	if len(pairingIDs) > 0 {
+		query += ` AND a.pairing_id IN (?` + strings.Repeat(", ?", len(pairingIDs)-1) + `)`
+		for _, id := range pairingIDs {
+			args = append(args, id)
+		}
+	}
+	query += ` ORDER BY a.pairing_id, a.aggregation_id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		// Results created in the same millisecond tie on created_at; keep one per pairing
+		if n := len(results); n > 0 && results[n-1].PairingID == result.PairingID {
+			continue
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate latest aggregated results: %w", err)
+	}
+
+	return results, nil
+}
+
+// GetAggregatedSyncResultsByPairing retrieves aggregated results for a pairing
+func (r *sqlStore) GetAggregatedSyncResultsByPairing(pairingID string, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE pairing_id = ?
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, pairingID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetAggregatedSyncResultsByPairingSince retrieves a pairing's aggregated results created at or after since, oldest first
+func (r *sqlStore) GetAggregatedSyncResultsByPairingSince(pairingID string, since time.Time) ([]*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE pairing_id = ? AND created_at >= ?
+	ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, pairingID, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetAllAggregatedSyncResults retrieves all aggregated results
+func (r *sqlStore) GetAllAggregatedSyncResults(limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetAggregatedSyncResultsByTimeRange retrieves aggregated results within a time range
+func (r *sqlStore) GetAggregatedSyncResultsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results
+	WHERE created_at BETWEEN ? AND ?
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	startMillis := startTime.UnixMilli()
+	endMillis := endTime.UnixMilli()
+
+	rows, err := r.db.Query(query, startMillis, endMillis, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated results by time range: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetAggregatedSyncResultsFiltered retrieves aggregated results matching all set fields of the filter
+// in the order of filter.Sort. Measurements are not loaded.
+func (r *sqlStore) GetAggregatedSyncResultsFiltered(filter models.AggregatedResultFilter, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	orderBy, err := orderByClause(filter.Sort, aggregatedSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var where whereClause
+	if filter.PairingID != "" {
+		where.add("pairing_id = ?", filter.PairingID)
+	}
+	if filter.MinConfidence != nil {
+		where.add("confidence >= ?", *filter.MinConfidence)
+	}
+	if filter.MaxConfidence != nil {
+		where.add("confidence <= ?", *filter.MaxConfidence)
+	}
+	where.addTimeRange(filter.StartTime, filter.EndTime)
+
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results` + where.String() + orderBy + `
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, append(where.args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filtered aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.AggregatedSyncResult
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetAggregatedOffsetBuckets groups a pairing's aggregated results into buckets of bucketMs
+// milliseconds by created_at, oldest first. Buckets without results are not returned.
+func (r *sqlStore) GetAggregatedOffsetBuckets(pairingID string, startTime, endTime *time.Time, bucketMs int64) ([]*models.OffsetBucket, error) {
+	if bucketMs <= 0 {
+		return nil, fmt.Errorf("invalid bucket size %d ms", bucketMs)
+	}
+
+	var where whereClause
+	where.add("pairing_id = ?", pairingID)
+	where.addTimeRange(startTime, endTime)
+
+	// The bucket size is an integer, not user text, so it is inlined: PostgreSQL only
+	// matches the SELECT expression to the GROUP BY one when both are identical.
+	bucket := fmt.Sprintf("(created_at / %d)", bucketMs)
+	query := `
+	SELECT ` + bucket + ` * ` + fmt.Sprint(bucketMs) + ` AS bucket_start, COUNT(*),
+	       AVG(best_offset), MIN(best_offset), MAX(best_offset), AVG(confidence)
+	FROM aggregated_sync_results` + where.String() + `
+	GROUP BY ` + bucket + `
+	ORDER BY bucket_start ASC
+	`
+
+	rows, err := r.db.Query(query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query offset buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []*models.OffsetBucket
+	for rows.Next() {
+		var b models.OffsetBucket
+		if err := rows.Scan(&b.BucketStart, &b.Count, &b.MeanOffset, &b.MinOffset, &b.MaxOffset, &b.MeanConfidence); err != nil {
+			return nil, fmt.Errorf("failed to scan offset bucket: %w", err)
+		}
+		buckets = append(buckets, &b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// ForEachAggregatedSyncResult streams the aggregated results matching the filter to fn, oldest first.
+// Measurements are not loaded. Iteration stops at the first error returned by fn.
+func (r *sqlStore) ForEachAggregatedSyncResult(filter models.ExportFilter, fn func(*models.AggregatedSyncResult) error) error {
+	where, args := exportWhereClause(filter, "pairing_id = ?", filter.PairingID)
+	query := `
+	SELECT ` + aggregatedResultColumns + `
+	FROM aggregated_sync_results` + where + `
+	ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query aggregated results: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result, err := scanAggregatedResult(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan aggregated result: %w", err)
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// exportWhereClause builds the WHERE clause for an export filter. idCondition and idArgs
+// are applied when the table-specific ID filter is set (the first idArg is non-empty).
+func exportWhereClause(filter models.ExportFilter, idCondition string, idArgs ...interface{}) (string, []interface{}) {
+	var where whereClause
+	if len(idArgs) > 0 && idArgs[0] != "" {
+		where.add(idCondition, idArgs...)
+	}
+	where.addTimeRange(filter.StartTime, filter.EndTime)
+	return where.String(), where.args
+}
+
+// whereClause collects conditions that are combined with AND
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+// addTimeRange adds the optional created_at bounds
+func (w *whereClause) addTimeRange(start, end *time.Time) {
+	if start != nil {
+		w.add("created_at >= ?", start.UnixMilli())
+	}
+	if end != nil {
+		w.add("created_at <= ?", end.UnixMilli())
+	}
+}
+
+// String returns the WHERE clause, or an empty string without conditions
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return "\n\tWHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// SQL expressions for the sort keys of each listing. Only these are ever put into ORDER BY.
+var (
+	recordSortColumns = map[string]string{
+		models.SortByCreatedAt: "created_at",
+		models.SortByOffset:    "ABS(time_difference)",
+	}
+	aggregatedSortColumns = map[string]string{
+		models.SortByCreatedAt:  "created_at",
+		models.SortByConfidence: "confidence",
+		models.SortByOffset:     "ABS(best_offset)",
+	}
+)
+
+// resolveSort validates a ListSort against the supported keys and fills in the defaults
+func resolveSort(sort models.ListSort, keys map[string]string) (by string, ascending bool, err error) {
+	by = sort.By
+	if by == "" {
+		by = models.SortByCreatedAt
+	}
+	if _, ok := keys[by]; !ok {
+		return "", false, fmt.Errorf("%w: unsupported sort key %q", ErrInvalidSort, sort.By)
+	}
+	switch sort.Order {
+	case "", models.SortDesc:
+	case models.SortAsc:
+		ascending = true
+	default:
+		return "", false, fmt.Errorf("%w: unsupported order %q", ErrInvalidSort, sort.Order)
+	}
+	return by, ascending, nil
+}
+
+// orderByClause builds the ORDER BY clause for a ListSort. NULL keys are sorted last in
+// both directions, and ties are broken by created_at.
+func orderByClause(sort models.ListSort, columns map[string]string) (string, error) {
+	by, ascending, err := resolveSort(sort, columns)
+	if err != nil {
+		return "", err
+	}
+	direction := "DESC"
+	if ascending {
+		direction = "ASC"
+	}
+	if by == models.SortByCreatedAt {
+		return "\n\tORDER BY created_at " + direction, nil
+	}
+	column := columns[by]
+	return fmt.Sprintf("\n\tORDER BY %s IS NULL, %s %s, created_at %s", column, column, direction, direction), nil
+}
+
+// getAggregationMeasurements loads all measurements linked to an aggregation
+func (r *sqlStore) getAggregationMeasurements(aggregationID string) ([]*models.TimeSyncRecord, error) {
+	query := `
+	SELECT t.id, t.device1_id, t.device1_type, t.device1_timestamp,
+	       t.device2_id, t.device2_type, t.device2_timestamp,
+	       t.server_request_time, t.server_response_time,
+	       t.device1_rtt, t.device2_rtt, t.time_difference, t.offset_compensated,
+	       t.status, t.error_message, t.created_at, t.source, am.adjusted_offset
+	FROM time_sync_records t
+	INNER JOIN aggregation_measurements am ON t.id = am.measurement_id
+	WHERE am.aggregation_id = ?
+	ORDER BY t.created_at ASC
+	`
+
+	rows, err := r.db.Query(query, aggregationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.TimeSyncRecord
+	for rows.Next() {
+		var adjustedOffset *int64
+		record, err := scanTimeSyncRecord(rows, &adjustedOffset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		record.AdjustedOffset = adjustedOffset
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// getRejectedSamples loads the outliers recorded on an aggregation's measurement links, by record ID
+func (r *sqlStore) getRejectedSamples(aggregationID string) ([]models.RejectedSample, error) {
+	query := `
+	SELECT measurement_id, adjusted_offset, outlier_deviation, outlier_reason
+	FROM aggregation_measurements
+	WHERE aggregation_id = ? AND outlier_reason IS NOT NULL
+	ORDER BY measurement_id ASC
+	`
+
+	rows, err := r.db.Query(query, aggregationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejected samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []models.RejectedSample
+	for rows.Next() {
+		var sample models.RejectedSample
+		var offset sql.NullInt64
+		var deviation sql.NullFloat64
+		if err := rows.Scan(&sample.RecordID, &offset, &deviation, &sample.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan rejected sample: %w", err)
+		}
+		sample.Offset = offset.Int64
+		sample.Deviation = deviation.Float64
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// GetDeviceTypeStats computes sync reliability metrics grouped by device type.
+// A record or aggregation counts once for every distinct device type involved in it.
+func (r *sqlStore) GetDeviceTypeStats() ([]*models.DeviceTypeStats, error) {
+	// Both devices contribute their RTT; a record between two devices of the same type
+	// counts once towards that type's syncs
+	recordQuery := `
+	SELECT device_type,
+	       SUM(counted),
+	       SUM(CASE WHEN status = 'SUCCESS' THEN counted ELSE 0 END),
+	       COALESCE(AVG(rtt), 0)
+	FROM (
+		SELECT device1_type AS device_type, status, device1_rtt AS rtt, 1 AS counted FROM time_sync_records
+		UNION ALL
+		SELECT device2_type AS device_type, status, device2_rtt AS rtt,
+		       CASE WHEN device2_type != device1_type THEN 1 ELSE 0 END AS counted
+		FROM time_sync_records
+	) AS per_device
+	WHERE device_type != ''
+	GROUP BY device_type
+	ORDER BY device_type
+	`
+
+	rows, err := r.db.Query(recordQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device type record stats: %w", err)
+	}
+	defer rows.Close()
+
+	statsByType := make(map[models.DeviceType]*models.DeviceTypeStats)
+	var stats []*models.DeviceTypeStats
+	for rows.Next() {
+		stat := &models.DeviceTypeStats{}
+		if err := rows.Scan(&stat.DeviceType, &stat.TotalSyncs, &stat.SuccessfulSyncs, &stat.AvgRTT); err != nil {
+			return nil, fmt.Errorf("failed to scan device type record stats: %w", err)
+		}
+		if stat.TotalSyncs > 0 {
+			stat.SuccessRate = float64(stat.SuccessfulSyncs) / float64(stat.TotalSyncs)
+		}
+		statsByType[stat.DeviceType] = stat
+		stats = append(stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate device type record stats: %w", err)
+	}
+
+	// Aggregations are linked to device types through their measurements
+	aggregationQuery := `
+	SELECT t.device_type, COUNT(*), AVG(a.confidence)
+	FROM aggregated_sync_results a
+	INNER JOIN (
+		SELECT am.aggregation_id, r.device1_type AS device_type
+		FROM aggregation_measurements am
+		INNER JOIN time_sync_records r ON r.id = am.measurement_id
+		UNION
+		SELECT am.aggregation_id, r.device2_type AS device_type
+		FROM aggregation_measurements am
+		INNER JOIN time_sync_records r ON r.id = am.measurement_id
+	) t ON t.aggregation_id = a.aggregation_id
+	WHERE t.device_type != ''
+	GROUP BY t.device_type
+	`
+
+	aggRows, err := r.db.Query(aggregationQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device type aggregation stats: %w", err)
+	}
+	defer aggRows.Close()
+
+	for aggRows.Next() {
+		var deviceType models.DeviceType
+		var count int
+		var avgConfidence float64
+		if err := aggRows.Scan(&deviceType, &count, &avgConfidence); err != nil {
+			return nil, fmt.Errorf("failed to scan device type aggregation stats: %w", err)
+		}
+
+		stat, ok := statsByType[deviceType]
+		if !ok {
+			stat = &models.DeviceTypeStats{DeviceType: deviceType}
+			statsByType[deviceType] = stat
+			stats = append(stats, stat)
+		}
+		stat.Aggregations = count
+		stat.AvgConfidence = avgConfidence
+	}
+
+	return stats, nil
+}
+
+// GetDeviceSyncStats computes sync statistics over the records of a device with SQL aggregates.
+// The median needs a second query that reads only the one or two middle offsets.
+func (r *sqlStore) GetDeviceSyncStats(deviceID string, startTime, endTime *time.Time) (*models.DeviceSyncStats, error) {
+	var where whereClause
+	where.add("(device1_id = ? OR device2_id = ?)", deviceID, deviceID)
+	where.addTimeRange(startTime, endTime)
+
+	// time_difference of non-SUCCESS records is NULL, so AVG and COUNT skip them
+	query := `
+	SELECT COUNT(*),
+	       COALESCE(SUM(CASE WHEN status = 'SUCCESS' THEN 1 ELSE 0 END), 0),
+	       COUNT(time_difference),
+	       COALESCE(AVG(time_difference), 0),
+	       COALESCE(AVG(1.0 * time_difference * time_difference), 0),
+	       COALESCE(AVG(CASE WHEN device1_id = ? THEN device1_rtt ELSE device2_rtt END), 0)
+	FROM time_sync_records` + where.String()
+
+	stats := &models.DeviceSyncStats{DeviceID: deviceID, StartTime: startTime, EndTime: endTime}
+	var offsetCount int
+	var meanSquare float64
+	args := append([]interface{}{deviceID}, where.args...)
+	if err := r.db.QueryRow(query, args...).Scan(&stats.TotalSyncs, &stats.SuccessfulSyncs, &offsetCount,
+		&stats.MeanTimeDifference, &meanSquare, &stats.MeanRTT); err != nil {
+		return nil, fmt.Errorf("failed to query device sync stats: %w", err)
+	}
+	if stats.TotalSyncs > 0 {
+		stats.SuccessRate = float64(stats.SuccessfulSyncs) / float64(stats.TotalSyncs)
+	}
+	if offsetCount == 0 {
+		return stats, nil
+	}
+	stats.TimeDifferenceStdDev = math.Sqrt(math.Max(0, meanSquare-stats.MeanTimeDifference*stats.MeanTimeDifference))
+
+	// Middle offset(s): one for an odd count, two to average for an even count
+	limit, offset := 1, offsetCount/2
+	if offsetCount%2 == 0 {
+		limit, offset = 2, offsetCount/2-1
+	}
+	medianQuery := `
+	SELECT time_difference
+	FROM time_sync_records` + where.String() + ` AND time_difference IS NOT NULL
+	ORDER BY time_difference
+	LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.Query(medianQuery, append(where.args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device median time difference: %w", err)
+	}
+	defer rows.Close()
+
+	var sum int64
+	var n int
+	for rows.Next() {
+		var value int64
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan device median time difference: %w", err)
+		}
+		sum += value
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate device median time difference: %w", err)
+	}
+	if n > 0 {
+		stats.MedianTimeDifference = float64(sum) / float64(n)
+	}
+
+	return stats, nil
+}
+
+// pairingColumns is the column list used by every pairings query (see scanPairing)
+const pairingColumns = `pairing_id, device1_id, device2_id, created_at,
+	       auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms,
+	       auto_aggregate_enabled, auto_aggregate_window_sec, auto_aggregate_min_count,
+	       auto_sync_timeout_sec, enabled`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPairing scans a pairings row selected with pairingColumns
+func scanPairing(scanner rowScanner) (*models.PersistentPairing, error) {
+	pairing := &models.PersistentPairing{}
+	var createdAtMillis int64
+
+	err := scanner.Scan(
+		&pairing.PairingID,
+		&pairing.Device1ID,
+		&pairing.Device2ID,
+		&createdAtMillis,
+		&pairing.AutoSyncIntervalSec,
+		&pairing.AutoSyncSampleCount,
+		&pairing.AutoSyncIntervalMs,
+		&pairing.AutoAggregateEnabled,
+		&pairing.AutoAggregateWindowSec,
+		&pairing.AutoAggregateMinCount,
+		&pairing.AutoSyncTimeoutSec,
+		&pairing.Enabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pairing.CreatedAt = time.UnixMilli(createdAtMillis)
+	return pairing, nil
+}
+
+// SavePairing saves a pairing to the database
+func (r *sqlStore) SavePairing(pairing *models.PersistentPairing) error {
+	query := `
+	INSERT INTO pairings (
+		pairing_id, device1_id, device2_id, created_at,
+		auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms,
+		auto_aggregate_enabled, auto_aggregate_window_sec, auto_aggregate_min_count,
+		auto_sync_timeout_sec, enabled
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.Exec(query,
+		pairing.PairingID,
+		pairing.Device1ID,
+		pairing.Device2ID,
+		pairing.CreatedAt.UnixMilli(),
+		pairing.AutoSyncIntervalSec,
+		pairing.AutoSyncSampleCount,
+		pairing.AutoSyncIntervalMs,
+		pairing.AutoAggregateEnabled,
+		pairing.AutoAggregateWindowSec,
+		pairing.AutoAggregateMinCount,
+		pairing.AutoSyncTimeoutSec,
+		pairing.Enabled,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save pairing: %w", err)
+	}
+
+	return nil
+}
+
+// GetPairingByID retrieves a pairing by its ID
+func (r *sqlStore) GetPairingByID(pairingID string) (*models.PersistentPairing, error) {
+	query := `
+	SELECT ` + pairingColumns + `
+	FROM pairings
+	WHERE pairing_id = ?
+	`
+
+	pairing, err := scanPairing(r.db.QueryRow(query, pairingID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+		}
+		return nil, fmt.Errorf("failed to query pairing: %w", err)
+	}
+
+	return pairing, nil
+}
+
+// GetPairingsByDeviceID retrieves all pairings that include the specified device
+func (r *sqlStore) GetPairingsByDeviceID(deviceID string) ([]*models.PersistentPairing, error) {
+	query := `
+	SELECT ` + pairingColumns + `
+	FROM pairings
+	WHERE device1_id = ? OR device2_id = ?
+	ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, deviceID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pairings by device: %w", err)
+	}
+	defer rows.Close()
+
+	var pairings []*models.PersistentPairing
+	for rows.Next() {
+		pairing, err := scanPairing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pairing: %w", err)
+		}
+		pairings = append(pairings, pairing)
+	}
+
+	return pairings, nil
+}
+
+// GetPairingByDevices retrieves a pairing by device IDs (bidirectional check)
+func (r *sqlStore) GetPairingByDevices(device1ID, device2ID string) (*models.PersistentPairing, error) {
+	query := `
+	SELECT ` + pairingColumns + `
+	FROM pairings
+	WHERE (device1_id = ? AND device2_id = ?) OR (device1_id = ? AND device2_id = ?)
+	LIMIT 1
+	`
+
+	pairing, err := scanPairing(r.db.QueryRow(query, device1ID, device2ID, device2ID, device1ID))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w for devices: %s, %s", ErrPairingNotFound, device1ID, device2ID)
+		}
+		return nil, fmt.Errorf("failed to query pairing by devices: %w", err)
+	}
+
+	return pairing, nil
+}
+
+// DeletePairing deletes a pairing from the database
+func (r *sqlStore) DeletePairing(pairingID string) error {
+	query := `DELETE FROM pairings WHERE pairing_id = ?`
+
+	result, err := r.db.Exec(query, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to delete pairing: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	return nil
+}
+
+// UpdatePairingEnabled enables or disables syncing for a pairing
+func (r *sqlStore) UpdatePairingEnabled(pairingID string, enabled bool) error {
+	result, err := r.db.Exec(`UPDATE pairings SET enabled = ? WHERE pairing_id = ?`, enabled, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to update pairing enabled flag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	return nil
+}
+
+// UpdatePairingAutoAggregation updates the automatic single-sync aggregation settings of a pairing
+func (r *sqlStore) UpdatePairingAutoAggregation(pairingID string, enabled bool, windowSec, minCount *int) error {
+	query := `
+	UPDATE pairings
+	SET auto_aggregate_enabled = ?, auto_aggregate_window_sec = ?, auto_aggregate_min_count = ?
+	WHERE pairing_id = ?
+	`
+
+	result, err := r.db.Exec(query, enabled, windowSec, minCount, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to update pairing auto-aggregation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	return nil
+}
+
+// UpdatePairingAutoSync updates the persisted auto-sync configuration of a pairing
+func (r *sqlStore) UpdatePairingAutoSync(pairingID string, intervalSec, sampleCount, intervalMs, timeoutSec int) error {
+	query := `
+	UPDATE pairings
+	SET auto_sync_interval_sec = ?, auto_sync_sample_count = ?, auto_sync_interval_ms = ?, auto_sync_timeout_sec = ?
+	WHERE pairing_id = ?
+	`
+
+	result, err := r.db.Exec(query, intervalSec, sampleCount, intervalMs, timeoutSec, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to update pairing auto-sync: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrPairingNotFound, pairingID)
+	}
+
+	return nil
+}
+
+// GetAllPairings retrieves all pairings from the database
+func (r *sqlStore) GetAllPairings() ([]*models.PersistentPairing, error) {
+	query := `
+	SELECT ` + pairingColumns + `
+	FROM pairings
+	ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all pairings: %w", err)
+	}
+	defer rows.Close()
+
+	var pairings []*models.PersistentPairing
+	for rows.Next() {
+		pairing, err := scanPairing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pairing: %w", err)
+		}
+		pairings = append(pairings, pairing)
+	}
+
+	return pairings, nil
+}
+
+// SaveGroupPairing saves a group pairing and its device membership
+func (r *sqlStore) SaveGroupPairing(pairing *models.GroupPairing) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO group_pairings (pairing_id, reference_device_id, created_at) VALUES (?, ?, ?)`,
+		pairing.PairingID, pairing.ReferenceDeviceID, pairing.CreatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save group pairing: %w", err)
+	}
+
+	memberQuery := `INSERT INTO pairing_devices (pairing_id, device_id, position) VALUES (?, ?, ?)`
+	for i, deviceID := range pairing.DeviceIDs {
+		if _, err := tx.Exec(memberQuery, pairing.PairingID, deviceID, i); err != nil {
+			return fmt.Errorf("failed to save group pairing member: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetGroupPairingByID retrieves a group pairing with its members
+func (r *sqlStore) GetGroupPairingByID(pairingID string) (*models.GroupPairing, error) {
+	query := `
+	SELECT pairing_id, reference_device_id, created_at
+	FROM group_pairings
+	WHERE pairing_id = ?
+	`
+
+	pairing, err := r.scanGroupPairing(r.db.QueryRow(query, pairingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("group pairing not found: %s", pairingID)
+		}
+		return nil, fmt.Errorf("failed to query group pairing: %w", err)
+	}
+
+	return pairing, nil
+}
+
+// GetGroupPairingsByDeviceID retrieves all group pairings the device is a member of
+func (r *sqlStore) GetGroupPairingsByDeviceID(deviceID string) ([]*models.GroupPairing, error) {
+	query := `
+	SELECT g.pairing_id, g.reference_device_id, g.created_at
+	FROM group_pairings g
+	INNER JOIN pairing_devices d ON d.pairing_id = g.pairing_id
+	WHERE d.device_id = ?
+	ORDER BY g.created_at DESC
+	`
+
+	return r.queryGroupPairings(query, deviceID)
+}
+
+// GetAllGroupPairings retrieves all group pairings
+func (r *sqlStore) GetAllGroupPairings() ([]*models.GroupPairing, error) {
+	query := `
+	SELECT pairing_id, reference_device_id, created_at
+	FROM group_pairings
+	ORDER BY created_at DESC
+	`
+
+	return r.queryGroupPairings(query)
+}
+
+// DeleteGroupPairing deletes a group pairing and its membership
+func (r *sqlStore) DeleteGroupPairing(pairingID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM pairing_devices WHERE pairing_id = ?`, pairingID); err != nil {
+		return fmt.Errorf("failed to delete group pairing members: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM group_pairings WHERE pairing_id = ?`, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to delete group pairing: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("group pairing not found: %s", pairingID)
+	}
+
+	return tx.Commit()
+}
+
+// queryGroupPairings runs a group_pairings query and loads each pairing's members
+func (r *sqlStore) queryGroupPairings(query string, args ...interface{}) ([]*models.GroupPairing, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group pairings: %w", err)
+	}
+
+	var pairings []*models.GroupPairing
+	for rows.Next() {
+		pairing := &models.GroupPairing{}
+		var createdAtMillis int64
+		if err := rows.Scan(&pairing.PairingID, &pairing.ReferenceDeviceID, &createdAtMillis); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group pairing: %w", err)
+		}
+		pairing.CreatedAt = time.UnixMilli(createdAtMillis)
+		pairings = append(pairings, pairing)
+	}
+	rows.Close()
+
+	for _, pairing := range pairings {
+		if pairing.DeviceIDs, err = r.getGroupPairingDevices(pairing.PairingID); err != nil {
+			return nil, err
+		}
+	}
+
+	return pairings, nil
+}
+
+// scanGroupPairing scans a single group_pairings row and loads its members
+func (r *sqlStore) scanGroupPairing(row *sql.Row) (*models.GroupPairing, error) {
+	pairing := &models.GroupPairing{}
+	var createdAtMillis int64
+	if err := row.Scan(&pairing.PairingID, &pairing.ReferenceDeviceID, &createdAtMillis); err != nil {
+		return nil, err
+	}
+	pairing.CreatedAt = time.UnixMilli(createdAtMillis)
+
+	deviceIDs, err := r.getGroupPairingDevices(pairing.PairingID)
+	if err != nil {
+		return nil, err
+	}
+	pairing.DeviceIDs = deviceIDs
+
+	return pairing, nil
+}
+
+// getGroupPairingDevices loads the members of a group pairing in their original order
+func (r *sqlStore) getGroupPairingDevices(pairingID string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT device_id FROM pairing_devices WHERE pairing_id = ? ORDER BY position ASC`, pairingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group pairing members: %w", err)
+	}
+	defer rows.Close()
+
+	var deviceIDs []string
+	for rows.Next() {
+		var deviceID string
+		if err := rows.Scan(&deviceID); err != nil {
+			return nil, fmt.Errorf("failed to scan group pairing member: %w", err)
+		}
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+
+	return deviceIDs, nil
+}
+
+// SaveOffsetOverride inserts or replaces the offset override of a pairing
+func (r *sqlStore) SaveOffsetOverride(override *models.OffsetOverride) error {
+	query := `
+	INSERT INTO offset_overrides (pairing_id, reference_device_id, offset_ms, expires_at, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (pairing_id) DO UPDATE SET
+		reference_device_id = excluded.reference_device_id,
+		offset_ms = excluded.offset_ms,
+		expires_at = excluded.expires_at,
+		created_at = excluded.created_at
+	`
+
+	var expiresAt *int64
+	if override.ExpiresAt != nil {
+		ms := override.ExpiresAt.UnixMilli()
+		expiresAt = &ms
+	}
+
+	_, err := r.db.Exec(query,
+		override.PairingID,
+		override.ReferenceDeviceID,
+		override.OffsetMs,
+		expiresAt,
+		override.CreatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save offset override: %w", err)
+	}
+
+	return nil
+}
+
+// GetOffsetOverride retrieves the offset override of a pairing, whether or not it has expired
+func (r *sqlStore) GetOffsetOverride(pairingID string) (*models.OffsetOverride, error) {
+	query := `
+	SELECT pairing_id, reference_device_id, offset_ms, expires_at, created_at
+	FROM offset_overrides
+	WHERE pairing_id = ?
+	`
+
+	var override models.OffsetOverride
+	var expiresAt sql.NullInt64
+	var createdAt int64
+	err := r.db.QueryRow(query, pairingID).Scan(
+		&override.PairingID,
+		&override.ReferenceDeviceID,
+		&override.OffsetMs,
+		&expiresAt,
+		&createdAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrOffsetOverrideNotFound, pairingID)
+		}
+		return nil, fmt.Errorf("failed to query offset override: %w", err)
+	}
+
+	if expiresAt.Valid {
+		t := time.UnixMilli(expiresAt.Int64)
+		override.ExpiresAt = &t
+	}
+	override.CreatedAt = time.UnixMilli(createdAt)
+	return &override, nil
+}
+
+// DeleteOffsetOverride removes the offset override of a pairing
+func (r *sqlStore) DeleteOffsetOverride(pairingID string) error {
+	result, err := r.db.Exec(`DELETE FROM offset_overrides WHERE pairing_id = ?`, pairingID)
+	if err != nil {
+		return fmt.Errorf("failed to delete offset override: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrOffsetOverrideNotFound, pairingID)
+	}
+
+	return nil
+}
+
+// SaveDeviceInfo inserts or updates the metadata of a device.
+// An empty Model or Firmware keeps the stored value, and FirstSeenAt is only set on insert.
+func (r *sqlStore) SaveDeviceInfo(info *models.DeviceInfo) error {
+	query := `
+	INSERT INTO devices (device_id, device_type, model, firmware, first_seen_at, last_seen_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (device_id) DO UPDATE SET
+		device_type = excluded.device_type,
+		model = COALESCE(excluded.model, devices.model),
+		firmware = COALESCE(excluded.firmware, devices.firmware),
+		last_seen_at = excluded.last_seen_at
+	`
+
+	_, err := r.db.Exec(query,
+		info.DeviceID,
+		info.DeviceType,
+		nullString(info.Model),
+		nullString(info.Firmware),
+		info.LastSeenAt.UnixMilli(),
+		info.LastSeenAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save device: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeviceInfo retrieves the stored metadata of a device
+func (r *sqlStore) GetDeviceInfo(deviceID string) (*models.DeviceInfo, error) {
+	query := `
+	SELECT device_id, device_type, model, firmware, first_seen_at, last_seen_at
+	FROM devices
+	WHERE device_id = ?
+	`
+
+	var info models.DeviceInfo
+	var model, firmware sql.NullString
+	var firstSeenAt, lastSeenAt int64
+	err := r.db.QueryRow(query, deviceID).Scan(
+		&info.DeviceID,
+		&info.DeviceType,
+		&model,
+		&firmware,
+		&firstSeenAt,
+		&lastSeenAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device not found: %s", deviceID)
+		}
+		return nil, fmt.Errorf("failed to query device: %w", err)
+	}
+
+	info.Model = model.String
+	info.Firmware = firmware.String
+	info.FirstSeenAt = time.UnixMilli(firstSeenAt)
+	info.LastSeenAt = time.UnixMilli(lastSeenAt)
+	return &info, nil
+}
+
+// SaveDeviceEvent appends a connect/disconnect event to a device's audit trail
+func (r *sqlStore) SaveDeviceEvent(event *models.DeviceEvent) error {
+	query := `
+	INSERT INTO device_events (device_id, event_type, reason, created_at)
+	VALUES (?, ?, ?, ?)
+	`
+
+	id, err := r.insertReturningID(query, event.DeviceID, event.EventType, nullString(event.Reason), event.Timestamp.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert device event: %w", err)
+	}
+
+	event.ID = id
+	return nil
+}
+
+// GetDeviceEvents retrieves a device's connect/disconnect events, newest first.
+// startTime and endTime are optional bounds on the event time.
+func (r *sqlStore) GetDeviceEvents(deviceID string, startTime, endTime *time.Time, limit int) ([]*models.DeviceEvent, error) {
+	var where whereClause
+	where.add("device_id = ?", deviceID)
+	where.addTimeRange(startTime, endTime)
+
+	query := `
+	SELECT id, device_id, event_type, reason, created_at
+	FROM device_events` + where.String() + `
+	ORDER BY created_at DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, append(where.args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.DeviceEvent
+	for rows.Next() {
+		var event models.DeviceEvent
+		var reason sql.NullString
+		var createdAt int64
+		if err := rows.Scan(&event.ID, &event.DeviceID, &event.EventType, &reason, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan device event: %w", err)
+		}
+		event.Reason = reason.String
+		event.Timestamp = time.UnixMilli(createdAt)
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// pingTimeout bounds the database round trip of Ping
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the database is reachable
+func (r *sqlStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+func (r *sqlStore) Close() error {
+	return r.db.Close()
+}
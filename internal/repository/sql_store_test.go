@@ -0,0 +1,39 @@
+package repository
+
+import "testing"
+
+func TestDialectRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  dialect
+		query    string
+		expected string
+	}{
+		{
+			name:     "SQLite keeps question marks",
+			dialect:  dialectSQLite,
+			query:    "SELECT * FROM pairings WHERE device1_id = ? OR device2_id = ?",
+			expected: "SELECT * FROM pairings WHERE device1_id = ? OR device2_id = ?",
+		},
+		{
+			name:     "PostgreSQL numbers placeholders",
+			dialect:  dialectPostgres,
+			query:    "SELECT * FROM pairings WHERE device1_id = ? OR device2_id = ? LIMIT ? OFFSET ?",
+			expected: "SELECT * FROM pairings WHERE device1_id = $1 OR device2_id = $2 LIMIT $3 OFFSET $4",
+		},
+		{
+			name:     "PostgreSQL skips string literals",
+			dialect:  dialectPostgres,
+			query:    "SELECT * FROM time_sync_records WHERE error_message = 'why?' AND id = ?",
+			expected: "SELECT * FROM time_sync_records WHERE error_message = 'why?' AND id = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.rebind(tt.query); got != tt.expected {
+				t.Errorf("rebind() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -3,27 +3,47 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
-	"time-sync-server/internal/models"
 )
 
+const (
+	// DefaultSQLiteBusyTimeout is how long a connection waits for a lock before failing with "database is locked"
+	DefaultSQLiteBusyTimeout = 5 * time.Second
+
+	// Connection pool size. WAL allows concurrent readers next to the single writer;
+	// writers queue on the busy timeout.
+	sqliteMaxOpenConns = 4
+	sqliteMaxIdleConns = 4
+)
+
+// SQLiteRepository stores data in a local SQLite database file
 type SQLiteRepository struct {
-	db *sql.DB
+	sqlStore
 }
 
-func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewSQLiteRepository opens the database in WAL mode with synchronous=NORMAL and the given
+// busy timeout (DefaultSQLiteBusyTimeout if <= 0), so concurrent writers wait instead of failing
+func NewSQLiteRepository(dbPath string, busyTimeout time.Duration) (*SQLiteRepository, error) {
+	if busyTimeout <= 0 {
+		busyTimeout = DefaultSQLiteBusyTimeout
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath, busyTimeout))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+	db.SetMaxIdleConns(sqliteMaxIdleConns)
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	repo := &SQLiteRepository{db: db}
+	repo := &SQLiteRepository{sqlStore{db: &sqlDB{DB: db, dialect: dialectSQLite}}}
 	if err := repo.initSchema(); err != nil {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
@@ -31,7 +51,77 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 	return repo, nil
 }
 
+// sqliteDSN adds the connection PRAGMAs to the path. They are passed through the DSN rather than
+// executed once after opening because busy_timeout and synchronous are per connection, and
+// database/sql may open several. _txlock=immediate takes the write lock at BEGIN so a transaction
+// never has to upgrade from a read lock, which would fail with SQLITE_BUSY without waiting.
+func sqliteDSN(dbPath string, busyTimeout time.Duration) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_journal_mode=WAL&_busy_timeout=%d&_synchronous=NORMAL&_txlock=immediate",
+		dbPath, separator, busyTimeout.Milliseconds())
+}
+
+// sqliteMigrations upgrade the SQLite schema in order; see migrate
+var sqliteMigrations = []migration{
+	{1, "initial schema", sqliteInitialSchema},
+	{2, "offset overrides", func(tx *sqlTx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS offset_overrides (
+			pairing_id TEXT PRIMARY KEY,
+			reference_device_id TEXT NOT NULL,
+			offset_ms INTEGER NOT NULL,
+			expires_at INTEGER,
+			created_at INTEGER NOT NULL
+		)`)
+		return err
+	}},
+	{3, "aggregated RTT percentiles", func(tx *sqlTx) error {
+		for _, column := range []string{"rtt_p50", "rtt_p90", "rtt_p99"} {
+			if _, err := tx.Exec("ALTER TABLE aggregated_sync_results ADD COLUMN " + column + " INTEGER"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{4, "pairing enabled flag", func(tx *sqlTx) error {
+		_, err := tx.Exec(`ALTER TABLE pairings ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1`)
+		return err
+	}},
+	{5, "smoothed offsets", func(tx *sqlTx) error {
+		_, err := tx.Exec(`ALTER TABLE aggregated_sync_results ADD COLUMN smoothed_offset REAL`)
+		return err
+	}},
+	{6, "rejected outlier details", func(tx *sqlTx) error {
+		if _, err := tx.Exec(`ALTER TABLE aggregation_measurements ADD COLUMN outlier_deviation REAL`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`ALTER TABLE aggregation_measurements ADD COLUMN outlier_reason TEXT`)
+		return err
+	}},
+	{7, "aggregation grades", func(tx *sqlTx) error {
+		_, err := tx.Exec(`ALTER TABLE aggregated_sync_results ADD COLUMN grade TEXT`)
+		return err
+	}},
+	{8, "record sources", func(tx *sqlTx) error {
+		_, err := tx.Exec(`ALTER TABLE time_sync_records ADD COLUMN source TEXT`)
+		return err
+	}},
+	{9, "group member results", func(tx *sqlTx) error {
+		_, err := tx.Exec(`ALTER TABLE aggregated_sync_results ADD COLUMN member_device_id TEXT`)
+		return err
+	}},
+}
+
 func (r *SQLiteRepository) initSchema() error {
+	return r.db.migrate(sqliteMigrations)
+}
+
+// sqliteInitialSchema creates the schema as it was when versioning was introduced. Databases
+// created before then may lack columns added over time, which are added here.
+func sqliteInitialSchema(tx *sqlTx) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS time_sync_records (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -46,6 +136,7 @@ func (r *SQLiteRepository) initSchema() error {
 		device1_rtt INTEGER,
 		device2_rtt INTEGER,
 		time_difference INTEGER,
+		offset_compensated INTEGER NOT NULL DEFAULT 0,
 		status TEXT NOT NULL,
 		error_message TEXT,
 		created_at INTEGER NOT NULL
@@ -70,15 +161,28 @@ func (r *SQLiteRepository) initSchema() error {
 		total_samples INTEGER NOT NULL,
 		valid_samples INTEGER NOT NULL,
 		outlier_count INTEGER NOT NULL,
-		created_at INTEGER NOT NULL
+		created_at INTEGER NOT NULL,
+		reference_device_id TEXT,
+		weighted_offset REAL,
+		success_samples INTEGER NOT NULL DEFAULT 0,
+		partial_samples INTEGER NOT NULL DEFAULT 0,
+		failed_samples INTEGER NOT NULL DEFAULT 0,
+		min_samples INTEGER,
+		outlier_threshold REAL,
+		top_percentile REAL,
+		outlier_method TEXT,
+		min_rtt_offset INTEGER,
+		retry_count INTEGER NOT NULL DEFAULT 0
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_agg_pairing ON aggregated_sync_results(pairing_id);
 	CREATE INDEX IF NOT EXISTS idx_agg_created ON aggregated_sync_results(created_at);
+	CREATE INDEX IF NOT EXISTS idx_agg_confidence ON aggregated_sync_results(confidence);
 
 	CREATE TABLE IF NOT EXISTS aggregation_measurements (
 		aggregation_id TEXT NOT NULL,
 		measurement_id INTEGER NOT NULL,
+		adjusted_offset INTEGER,
 		FOREIGN KEY (aggregation_id) REFERENCES aggregated_sync_results(aggregation_id),
 		FOREIGN KEY (measurement_id) REFERENCES time_sync_records(id)
 	);
@@ -93,733 +197,117 @@ func (r *SQLiteRepository) initSchema() error {
 		created_at INTEGER NOT NULL,
 		auto_sync_interval_sec INTEGER,
 		auto_sync_sample_count INTEGER,
-		auto_sync_interval_ms INTEGER
+		auto_sync_interval_ms INTEGER,
+		auto_aggregate_enabled INTEGER NOT NULL DEFAULT 0,
+		auto_aggregate_window_sec INTEGER,
+		auto_aggregate_min_count INTEGER,
+		auto_sync_timeout_sec INTEGER
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_pairing_device1 ON pairings(device1_id);
 	CREATE INDEX IF NOT EXISTS idx_pairing_device2 ON pairings(device2_id);
 	CREATE UNIQUE INDEX IF NOT EXISTS idx_pairing_devices ON pairings(device1_id, device2_id);
-	`
-
-	_, err := r.db.Exec(schema)
-	return err
-}
-
-func (r *SQLiteRepository) SaveTimeSyncRecord(record *models.TimeSyncRecord) error {
-	query := `
-	INSERT INTO time_sync_records (
-		device1_id, device1_type, device1_timestamp,
-		device2_id, device2_type, device2_timestamp,
-		server_request_time, server_response_time,
-		device1_rtt, device2_rtt, time_difference,
-		status, error_message, created_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	result, err := r.db.Exec(query,
-		record.Device1ID,
-		record.Device1Type,
-		record.Device1Timestamp,
-		record.Device2ID,
-		record.Device2Type,
-		record.Device2Timestamp,
-		record.ServerRequestTime,
-		record.ServerResponseTime,
-		record.Device1RTT,
-		record.Device2RTT,
-		record.TimeDifference,
-		record.Status,
-		record.ErrorMessage,
-		record.CreatedAt,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to save time sync record: %w", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
-	record.ID = id
-	return nil
-}
-
-// GetTimeSyncRecord retrieves a single time sync record by ID
-func (r *SQLiteRepository) GetTimeSyncRecord(id int64) (*models.TimeSyncRecord, error) {
-	query := `
-	SELECT id, device1_id, device1_type, device1_timestamp,
-	       device2_id, device2_type, device2_timestamp,
-	       server_request_time, server_response_time,
-	       device1_rtt, device2_rtt, time_difference,
-	       status, error_message, created_at
-	FROM time_sync_records
-	WHERE id = ?
-	`
-
-	record := &models.TimeSyncRecord{}
-	err := r.db.QueryRow(query, id).Scan(
-		&record.ID,
-		&record.Device1ID,
-		&record.Device1Type,
-		&record.Device1Timestamp,
-		&record.Device2ID,
-		&record.Device2Type,
-		&record.Device2Timestamp,
-		&record.ServerRequestTime,
-		&record.ServerResponseTime,
-		&record.Device1RTT,
-		&record.Device2RTT,
-		&record.TimeDifference,
-		&record.Status,
-		&record.ErrorMessage,
-		&record.CreatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("sync record not found: %d", id)
-		}
-		return nil, fmt.Errorf("failed to query sync record: %w", err)
-	}
-
-	return record, nil
-}
-
-func (r *SQLiteRepository) GetTimeSyncRecords(limit, offset int) ([]*models.TimeSyncRecord, error) {
-	query := `
-	SELECT id, device1_id, device1_type, device1_timestamp,
-	       device2_id, device2_type, device2_timestamp,
-	       server_request_time, server_response_time,
-	       device1_rtt, device2_rtt, time_difference,
-	       status, error_message, created_at
-	FROM time_sync_records
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
-
-	rows, err := r.db.Query(query, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query time sync records: %w", err)
-	}
-	defer rows.Close()
-
-	var records []*models.TimeSyncRecord
-	for rows.Next() {
-		record := &models.TimeSyncRecord{}
-		err := rows.Scan(
-			&record.ID,
-			&record.Device1ID,
-			&record.Device1Type,
-			&record.Device1Timestamp,
-			&record.Device2ID,
-			&record.Device2Type,
-			&record.Device2Timestamp,
-			&record.ServerRequestTime,
-			&record.ServerResponseTime,
-			&record.Device1RTT,
-			&record.Device2RTT,
-			&record.TimeDifference,
-			&record.Status,
-			&record.ErrorMessage,
-			&record.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	return records, nil
-}
-
-func (r *SQLiteRepository) GetTimeSyncRecordsByDeviceID(deviceID string, limit, offset int) ([]*models.TimeSyncRecord, error) {
-	query := `
-	SELECT id, device1_id, device1_type, device1_timestamp,
-	       device2_id, device2_type, device2_timestamp,
-	       server_request_time, server_response_time,
-	       device1_rtt, device2_rtt, time_difference,
-	       status, error_message, created_at
-	FROM time_sync_records
-	WHERE device1_id = ? OR device2_id = ?
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
-
-	rows, err := r.db.Query(query, deviceID, deviceID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query time sync records by device: %w", err)
-	}
-	defer rows.Close()
-
-	var records []*models.TimeSyncRecord
-	for rows.Next() {
-		record := &models.TimeSyncRecord{}
-		err := rows.Scan(
-			&record.ID,
-			&record.Device1ID,
-			&record.Device1Type,
-			&record.Device1Timestamp,
-			&record.Device2ID,
-			&record.Device2Type,
-			&record.Device2Timestamp,
-			&record.ServerRequestTime,
-			&record.ServerResponseTime,
-			&record.Device1RTT,
-			&record.Device2RTT,
-			&record.TimeDifference,
-			&record.Status,
-			&record.ErrorMessage,
-			&record.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	return records, nil
-}
-
-func (r *SQLiteRepository) GetTimeSyncRecordsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.TimeSyncRecord, error) {
-	query := `
-	SELECT id, device1_id, device1_type, device1_timestamp,
-	       device2_id, device2_type, device2_timestamp,
-	       server_request_time, server_response_time,
-	       device1_rtt, device2_rtt, time_difference,
-	       status, error_message, created_at
-	FROM time_sync_records
-	WHERE created_at BETWEEN ? AND ?
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
-
-	startMillis := startTime.UnixMilli()
-	endMillis := endTime.UnixMilli()
-
-	rows, err := r.db.Query(query, startMillis, endMillis, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query time sync records by time range: %w", err)
-	}
-	defer rows.Close()
-
-	var records []*models.TimeSyncRecord
-	for rows.Next() {
-		record := &models.TimeSyncRecord{}
-		err := rows.Scan(
-			&record.ID,
-			&record.Device1ID,
-			&record.Device1Type,
-			&record.Device1Timestamp,
-			&record.Device2ID,
-			&record.Device2Type,
-			&record.Device2Timestamp,
-			&record.ServerRequestTime,
-			&record.ServerResponseTime,
-			&record.Device1RTT,
-			&record.Device2RTT,
-			&record.TimeDifference,
-			&record.Status,
-			&record.ErrorMessage,
-			&record.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan time sync record: %w", err)
-		}
-		records = append(records, record)
-	}
-
-	return records, nil
-}
-
-// SaveAggregatedSyncResult saves an aggregated sync result with its measurements
-func (r *SQLiteRepository) SaveAggregatedSyncResult(result *models.AggregatedSyncResult) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Insert aggregated result
-	query := `
-	INSERT INTO aggregated_sync_results (
-		aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
-		offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
-		total_samples, valid_samples, outlier_count, created_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err = tx.Exec(query,
-		result.AggregationID,
-		result.PairingID,
-		result.BestOffset,
-		result.MedianOffset,
-		result.MeanOffset,
-		result.OffsetStdDev,
-		result.MinRTT,
-		result.MaxRTT,
-		result.MeanRTT,
-		result.Confidence,
-		result.Jitter,
-		result.TotalSamples,
-		result.ValidSamples,
-		result.OutlierCount,
-		result.CreatedAt,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to insert aggregated result: %w", err)
-	}
-
-	// Insert links to individual measurements
-	linkQuery := `INSERT INTO aggregation_measurements (aggregation_id, measurement_id) VALUES (?, ?)`
-	for _, measurement := range result.Measurements {
-		if measurement.ID == 0 {
-			continue // Skip measurements without ID
-		}
-		_, err = tx.Exec(linkQuery, result.AggregationID, measurement.ID)
-		if err != nil {
-			return fmt.Errorf("failed to link measurement: %w", err)
-		}
-	}
-
-	return tx.Commit()
-}
-
-// GetAggregatedSyncResult retrieves an aggregated sync result by ID
-func (r *SQLiteRepository) GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error) {
-	query := `
-	SELECT aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
-	       offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
-	       total_samples, valid_samples, outlier_count, created_at
-	FROM aggregated_sync_results
-	WHERE aggregation_id = ?
-	`
-
-	result := &models.AggregatedSyncResult{}
-	err := r.db.QueryRow(query, aggregationID).Scan(
-		&result.AggregationID,
-		&result.PairingID,
-		&result.BestOffset,
-		&result.MedianOffset,
-		&result.MeanOffset,
-		&result.OffsetStdDev,
-		&result.MinRTT,
-		&result.MaxRTT,
-		&result.MeanRTT,
-		&result.Confidence,
-		&result.Jitter,
-		&result.TotalSamples,
-		&result.ValidSamples,
-		&result.OutlierCount,
-		&result.CreatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("aggregation not found: %s", aggregationID)
-		}
-		return nil, fmt.Errorf("failed to query aggregated result: %w", err)
-	}
-
-	// Load associated measurements
-	measurements, err := r.getAggregationMeasurements(aggregationID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load measurements: %w", err)
-	}
-	result.Measurements = measurements
-
-	return result, nil
-}
 
-// GetAggregatedSyncResultsByPairing retrieves aggregated results for a pairing
-func (r *SQLiteRepository) GetAggregatedSyncResultsByPairing(pairingID string, limit, offset int) ([]*models.AggregatedSyncResult, error) {
-	query := `
-	SELECT aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
-	       offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
-	       total_samples, valid_samples, outlier_count, created_at
-	FROM aggregated_sync_results
-	WHERE pairing_id = ?
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
-
-	rows, err := r.db.Query(query, pairingID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query aggregated results: %w", err)
-	}
-	defer rows.Close()
-
-	var results []*models.AggregatedSyncResult
-	for rows.Next() {
-		result := &models.AggregatedSyncResult{}
-		err := rows.Scan(
-			&result.AggregationID,
-			&result.PairingID,
-			&result.BestOffset,
-			&result.MedianOffset,
-			&result.MeanOffset,
-			&result.OffsetStdDev,
-			&result.MinRTT,
-			&result.MaxRTT,
-			&result.MeanRTT,
-			&result.Confidence,
-			&result.Jitter,
-			&result.TotalSamples,
-			&result.ValidSamples,
-			&result.OutlierCount,
-			&result.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
-		}
-		results = append(results, result)
-	}
-
-	return results, nil
-}
-
-// GetAllAggregatedSyncResults retrieves all aggregated results
-func (r *SQLiteRepository) GetAllAggregatedSyncResults(limit, offset int) ([]*models.AggregatedSyncResult, error) {
-	query := `
-	SELECT aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
-	       offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
-	       total_samples, valid_samples, outlier_count, created_at
-	FROM aggregated_sync_results
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
-
-	rows, err := r.db.Query(query, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query all aggregated results: %w", err)
-	}
-	defer rows.Close()
-
-	var results []*models.AggregatedSyncResult
-	for rows.Next() {
-		result := &models.AggregatedSyncResult{}
-		err := rows.Scan(
-			&result.AggregationID,
-			&result.PairingID,
-			&result.BestOffset,
-			&result.MedianOffset,
-			&result.MeanOffset,
-			&result.OffsetStdDev,
-			&result.MinRTT,
-			&result.MaxRTT,
-			&result.MeanRTT,
-			&result.Confidence,
-			&result.Jitter,
-			&result.TotalSamples,
-			&result.ValidSamples,
-			&result.OutlierCount,
-			&result.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
-		}
-		results = append(results, result)
-	}
-
-	return results, nil
-}
-
-// GetAggregatedSyncResultsByTimeRange retrieves aggregated results within a time range
-func (r *SQLiteRepository) GetAggregatedSyncResultsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.AggregatedSyncResult, error) {
-	query := `
-	SELECT aggregation_id, pairing_id, best_offset, median_offset, mean_offset,
-	       offset_std_dev, min_rtt, max_rtt, mean_rtt, confidence, jitter,
-	       total_samples, valid_samples, outlier_count, created_at
-	FROM aggregated_sync_results
-	WHERE created_at BETWEEN ? AND ?
-	ORDER BY created_at DESC
-	LIMIT ? OFFSET ?
-	`
+	CREATE TABLE IF NOT EXISTS group_pairings (
+		pairing_id TEXT PRIMARY KEY,
+		reference_device_id TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
 
-	startMillis := startTime.UnixMilli()
-	endMillis := endTime.UnixMilli()
+	CREATE TABLE IF NOT EXISTS pairing_devices (
+		pairing_id TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (pairing_id, device_id),
+		FOREIGN KEY (pairing_id) REFERENCES group_pairings(pairing_id)
+	);
 
-	rows, err := r.db.Query(query, startMillis, endMillis, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query aggregated results by time range: %w", err)
-	}
-	defer rows.Close()
+	CREATE INDEX IF NOT EXISTS idx_pairing_devices_device ON pairing_devices(device_id);
 
-	var results []*models.AggregatedSyncResult
-	for rows.Next() {
-		result := &models.AggregatedSyncResult{}
-		err := rows.Scan(
-			&result.AggregationID,
-			&result.PairingID,
-			&result.BestOffset,
-			&result.MedianOffset,
-			&result.MeanOffset,
-			&result.OffsetStdDev,
-			&result.MinRTT,
-			&result.MaxRTT,
-			&result.MeanRTT,
-			&result.Confidence,
-			&result.Jitter,
-			&result.TotalSamples,
-			&result.ValidSamples,
-			&result.OutlierCount,
-			&result.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan aggregated result: %w", err)
-		}
-		results = append(results, result)
-	}
+	CREATE TABLE IF NOT EXISTS device_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		reason TEXT,
+		created_at INTEGER NOT NULL
+	);
 
-	return results, nil
-}
+	CREATE INDEX IF NOT EXISTS idx_device_events_device ON device_events(device_id, created_at);
 
-// getAggregationMeasurements loads all measurements linked to an aggregation
-func (r *SQLiteRepository) getAggregationMeasurements(aggregationID string) ([]*models.TimeSyncRecord, error) {
-	query := `
-	SELECT t.id, t.device1_id, t.device1_type, t.device1_timestamp,
-	       t.device2_id, t.device2_type, t.device2_timestamp,
-	       t.server_request_time, t.server_response_time,
-	       t.device1_rtt, t.device2_rtt, t.time_difference,
-	       t.status, t.error_message, t.created_at
-	FROM time_sync_records t
-	INNER JOIN aggregation_measurements am ON t.id = am.measurement_id
-	WHERE am.aggregation_id = ?
-	ORDER BY t.created_at ASC
+	CREATE TABLE IF NOT EXISTS devices (
+		device_id TEXT PRIMARY KEY,
+		device_type TEXT NOT NULL,
+		model TEXT,
+		firmware TEXT,
+		first_seen_at INTEGER NOT NULL,
+		last_seen_at INTEGER NOT NULL
+	);
 	`
 
-	rows, err := r.db.Query(query, aggregationID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query measurements: %w", err)
-	}
-	defer rows.Close()
-
-	var records []*models.TimeSyncRecord
-	for rows.Next() {
-		record := &models.TimeSyncRecord{}
-		err := rows.Scan(
-			&record.ID,
-			&record.Device1ID,
-			&record.Device1Type,
-			&record.Device1Timestamp,
-			&record.Device2ID,
-			&record.Device2Type,
-			&record.Device2Timestamp,
-			&record.ServerRequestTime,
-			&record.ServerResponseTime,
-			&record.Device1RTT,
-			&record.Device2RTT,
-			&record.TimeDifference,
-			&record.Status,
-			&record.ErrorMessage,
-			&record.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema (CREATE TABLE IF NOT EXISTS does not add them to existing DBs)
+	columns := []struct{ table, column, definition string }{
+		{"pairings", "auto_aggregate_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		{"pairings", "auto_aggregate_window_sec", "INTEGER"},
+		{"pairings", "auto_aggregate_min_count", "INTEGER"},
+		{"aggregated_sync_results", "reference_device_id", "TEXT"},
+		{"aggregated_sync_results", "weighted_offset", "REAL"},
+		{"aggregated_sync_results", "success_samples", "INTEGER NOT NULL DEFAULT 0"},
+		{"aggregated_sync_results", "partial_samples", "INTEGER NOT NULL DEFAULT 0"},
+		{"aggregated_sync_results", "failed_samples", "INTEGER NOT NULL DEFAULT 0"},
+		{"pairings", "auto_sync_timeout_sec", "INTEGER"},
+		{"aggregation_measurements", "adjusted_offset", "INTEGER"},
+		{"time_sync_records", "offset_compensated", "INTEGER NOT NULL DEFAULT 0"},
+		{"aggregated_sync_results", "min_samples", "INTEGER"},
+		{"aggregated_sync_results", "outlier_threshold", "REAL"},
+		{"aggregated_sync_results", "top_percentile", "REAL"},
+		{"aggregated_sync_results", "outlier_method", "TEXT"},
+		{"aggregated_sync_results", "min_rtt_offset", "INTEGER"},
+		{"aggregated_sync_results", "retry_count", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(tx, c.table, c.column, c.definition); err != nil {
+			return err
 		}
-		records = append(records, record)
-	}
-
-	return records, nil
-}
-
-// SavePairing saves a pairing to the database
-func (r *SQLiteRepository) SavePairing(pairing *models.PersistentPairing) error {
-	query := `
-	INSERT INTO pairings (
-		pairing_id, device1_id, device2_id, created_at,
-		auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms
-	) VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err := r.db.Exec(query,
-		pairing.PairingID,
-		pairing.Device1ID,
-		pairing.Device2ID,
-		pairing.CreatedAt.UnixMilli(),
-		pairing.AutoSyncIntervalSec,
-		pairing.AutoSyncSampleCount,
-		pairing.AutoSyncIntervalMs,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to save pairing: %w", err)
 	}
 
 	return nil
 }
 
-// GetPairingByID retrieves a pairing by its ID
-func (r *SQLiteRepository) GetPairingByID(pairingID string) (*models.PersistentPairing, error) {
-	query := `
-	SELECT pairing_id, device1_id, device2_id, created_at,
-	       auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms
-	FROM pairings
-	WHERE pairing_id = ?
-	`
-
-	pairing := &models.PersistentPairing{}
-	var createdAtMillis int64
-
-	err := r.db.QueryRow(query, pairingID).Scan(
-		&pairing.PairingID,
-		&pairing.Device1ID,
-		&pairing.Device2ID,
-		&createdAtMillis,
-		&pairing.AutoSyncIntervalSec,
-		&pairing.AutoSyncSampleCount,
-		&pairing.AutoSyncIntervalMs,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("pairing not found: %s", pairingID)
-		}
-		return nil, fmt.Errorf("failed to query pairing: %w", err)
-	}
-
-	pairing.CreatedAt = time.UnixMilli(createdAtMillis)
-	return pairing, nil
-}
-
-// GetPairingsByDeviceID retrieves all pairings that include the specified device
-func (r *SQLiteRepository) GetPairingsByDeviceID(deviceID string) ([]*models.PersistentPairing, error) {
-	query := `
-	SELECT pairing_id, device1_id, device2_id, created_at,
-	       auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms
-	FROM pairings
-	WHERE device1_id = ? OR device2_id = ?
-	ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.Query(query, deviceID, deviceID)
+// addColumnIfMissing adds a column to an existing table if it is not there yet
+func addColumnIfMissing(tx *sqlTx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pairings by device: %w", err)
+		return fmt.Errorf("failed to read table info for %s: %w", table, err)
 	}
 	defer rows.Close()
 
-	var pairings []*models.PersistentPairing
 	for rows.Next() {
-		pairing := &models.PersistentPairing{}
-		var createdAtMillis int64
-
-		err := rows.Scan(
-			&pairing.PairingID,
-			&pairing.Device1ID,
-			&pairing.Device2ID,
-			&createdAtMillis,
-			&pairing.AutoSyncIntervalSec,
-			&pairing.AutoSyncSampleCount,
-			&pairing.AutoSyncIntervalMs,
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
 		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan pairing: %w", err)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan table info for %s: %w", table, err)
 		}
-
-		pairing.CreatedAt = time.UnixMilli(createdAtMillis)
-		pairings = append(pairings, pairing)
-	}
-
-	return pairings, nil
-}
-
-// GetPairingByDevices retrieves a pairing by device IDs (bidirectional check)
-func (r *SQLiteRepository) GetPairingByDevices(device1ID, device2ID string) (*models.PersistentPairing, error) {
-	query := `
-	SELECT pairing_id, device1_id, device2_id, created_at,
-	       auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms
-	FROM pairings
-	WHERE (device1_id = ? AND device2_id = ?) OR (device1_id = ? AND device2_id = ?)
-	LIMIT 1
-	`
-
-	pairing := &models.PersistentPairing{}
-	var createdAtMillis int64
-
-	err := r.db.QueryRow(query, device1ID, device2ID, device2ID, device1ID).Scan(
-		&pairing.PairingID,
-		&pairing.Device1ID,
-		&pairing.Device2ID,
-		&createdAtMillis,
-		&pairing.AutoSyncIntervalSec,
-		&pairing.AutoSyncSampleCount,
-		&pairing.AutoSyncIntervalMs,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("pairing not found for devices: %s, %s", device1ID, device2ID)
+		if name == column {
+			return nil
 		}
-		return nil, fmt.Errorf("failed to query pairing by devices: %w", err)
 	}
-
-	pairing.CreatedAt = time.UnixMilli(createdAtMillis)
-	return pairing, nil
-}
-
-// DeletePairing deletes a pairing from the database
-func (r *SQLiteRepository) DeletePairing(pairingID string) error {
-	query := `DELETE FROM pairings WHERE pairing_id = ?`
-
-	result, err := r.db.Exec(query, pairingID)
-	if err != nil {
-		return fmt.Errorf("failed to delete pairing: %w", err)
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("pairing not found: %s", pairingID)
-	}
-
 	return nil
 }
-
-// GetAllPairings retrieves all pairings from the database
-func (r *SQLiteRepository) GetAllPairings() ([]*models.PersistentPairing, error) {
-	query := `
-	SELECT pairing_id, device1_id, device2_id, created_at,
-	       auto_sync_interval_sec, auto_sync_sample_count, auto_sync_interval_ms
-	FROM pairings
-	ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query all pairings: %w", err)
-	}
-	defer rows.Close()
-
-	var pairings []*models.PersistentPairing
-	for rows.Next() {
-		pairing := &models.PersistentPairing{}
-		var createdAtMillis int64
-
-		err := rows.Scan(
-			&pairing.PairingID,
-			&pairing.Device1ID,
-			&pairing.Device2ID,
-			&createdAtMillis,
-			&pairing.AutoSyncIntervalSec,
-			&pairing.AutoSyncSampleCount,
-			&pairing.AutoSyncIntervalMs,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan pairing: %w", err)
-		}
-
-		pairing.CreatedAt = time.UnixMilli(createdAtMillis)
-		pairings = append(pairings, pairing)
-	}
-
-	return pairings, nil
-}
-
-func (r *SQLiteRepository) Close() error {
-	return r.db.Close()
-}
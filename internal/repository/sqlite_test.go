@@ -0,0 +1,1494 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+func newTestRepository(t testing.TB) *SQLiteRepository {
+	t.Helper()
+
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository() error = %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func newTestRecord(offset int64) *models.TimeSyncRecord {
+	ts1 := int64(1000) + offset
+	ts2 := int64(1000)
+	rtt := int64(10000)
+	return &models.TimeSyncRecord{
+		Device1ID:         "psg-001",
+		Device1Type:       models.DeviceTypePSG,
+		Device1Timestamp:  &ts1,
+		Device2ID:         "watch-001",
+		Device2Type:       models.DeviceTypeWatch,
+		Device2Timestamp:  &ts2,
+		ServerRequestTime: time.Now().UnixMilli(),
+		Device1RTT:        &rtt,
+		Device2RTT:        &rtt,
+		TimeDifference:    &offset,
+		Status:            models.SyncStatusSuccess,
+		CreatedAt:         time.Now().UnixMilli(),
+	}
+}
+
+func TestSaveAggregatedSyncResultReportsUnlinkedMeasurements(t *testing.T) {
+	repo := newTestRepository(t)
+
+	saved1 := newTestRecord(100)
+	saved2 := newTestRecord(110)
+	for _, record := range []*models.TimeSyncRecord{saved1, saved2} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+	unsaved := newTestRecord(120) // ID stays 0, as if its own save had failed
+
+	result := &models.AggregatedSyncResult{
+		AggregationID: "agg-unlinked",
+		PairingID:     "pair-123",
+		BestOffset:    110,
+		TotalSamples:  3,
+		ValidSamples:  3,
+		Measurements:  []*models.TimeSyncRecord{saved1, unsaved, saved2},
+		CreatedAt:     time.Now().UnixMilli(),
+	}
+
+	err := repo.SaveAggregatedSyncResult(result)
+
+	var unlinked *UnlinkedMeasurementsError
+	if !errors.As(err, &unlinked) {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v, expected *UnlinkedMeasurementsError", err)
+	}
+	if unlinked.Skipped != 1 || unlinked.Total != 3 {
+		t.Errorf("Skipped/Total = %d/%d, expected 1/3", unlinked.Skipped, unlinked.Total)
+	}
+	if unlinked.AggregationID != result.AggregationID {
+		t.Errorf("AggregationID = %s, expected %s", unlinked.AggregationID, result.AggregationID)
+	}
+
+	// The aggregated result and the links that could be made are still persisted
+	stored, err := repo.GetAggregatedSyncResult(result.AggregationID)
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(stored.Measurements) != 2 {
+		t.Errorf("linked measurements = %d, expected 2", len(stored.Measurements))
+	}
+}
+
+func TestSaveAggregatedSyncResultAllLinked(t *testing.T) {
+	repo := newTestRepository(t)
+
+	record := newTestRecord(100)
+	if err := repo.SaveTimeSyncRecord(record); err != nil {
+		t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+	}
+
+	result := &models.AggregatedSyncResult{
+		AggregationID:    "agg-linked",
+		PairingID:        "pair-123",
+		Measurements:     []*models.TimeSyncRecord{record},
+		CreatedAt:        time.Now().UnixMilli(),
+		MinSamples:       5,
+		OutlierThreshold: 2.5,
+		TopPercentile:    0.3,
+		OutlierMethod:    models.OutlierMethodMAD,
+		MinRTTOffset:     -97,
+		RTTP50:           6000,
+		RTTP90:           9500,
+		RTTP99:           12000,
+		Grade:            models.GradeB,
+		MemberDeviceID:   "psg-001",
+	}
+
+	if err := repo.SaveAggregatedSyncResult(result); err != nil {
+		t.Errorf("SaveAggregatedSyncResult() error = %v, expected nil", err)
+	}
+
+	stored, err := repo.GetAggregatedSyncResult(result.AggregationID)
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if stored.MinSamples != 5 || stored.OutlierThreshold != 2.5 || stored.TopPercentile != 0.3 || stored.OutlierMethod != models.OutlierMethodMAD {
+		t.Errorf("filter config = %d/%v/%v/%q, expected 5/2.5/0.3/%q",
+			stored.MinSamples, stored.OutlierThreshold, stored.TopPercentile, stored.OutlierMethod, models.OutlierMethodMAD)
+	}
+	if stored.MinRTTOffset != -97 {
+		t.Errorf("MinRTTOffset = %d, expected -97", stored.MinRTTOffset)
+	}
+	if stored.RTTP50 != 6000 || stored.RTTP90 != 9500 || stored.RTTP99 != 12000 {
+		t.Errorf("RTT p50/p90/p99 = %d/%d/%d, expected 6000/9500/12000", stored.RTTP50, stored.RTTP90, stored.RTTP99)
+	}
+	if stored.Grade != models.GradeB {
+		t.Errorf("Grade = %q, expected %q", stored.Grade, models.GradeB)
+	}
+	if stored.MemberDeviceID != "psg-001" {
+		t.Errorf("MemberDeviceID = %q, expected psg-001", stored.MemberDeviceID)
+	}
+}
+
+func TestDeleteRecordsOlderThan(t *testing.T) {
+	repo := newTestRepository(t)
+
+	now := time.Now()
+	old := newTestRecord(100)
+	old.CreatedAt = now.Add(-48 * time.Hour).UnixMilli()
+	recent := newTestRecord(110)
+	for _, record := range []*models.TimeSyncRecord{old, recent} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	oldAggregation := &models.AggregatedSyncResult{
+		AggregationID: "agg-old",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{old},
+		CreatedAt:     old.CreatedAt,
+	}
+	recentAggregation := &models.AggregatedSyncResult{
+		AggregationID: "agg-recent",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{recent},
+		CreatedAt:     recent.CreatedAt,
+	}
+	for _, result := range []*models.AggregatedSyncResult{oldAggregation, recentAggregation} {
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	deleted, err := repo.DeleteRecordsOlderThan(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteRecordsOlderThan() error = %v", err)
+	}
+
+	// 1 link + 1 record + 1 aggregated result
+	if deleted != 3 {
+		t.Errorf("deleted = %d, expected 3", deleted)
+	}
+	if _, err := repo.GetTimeSyncRecord(old.ID); err == nil {
+		t.Errorf("Expected old record to be deleted")
+	}
+	if _, err := repo.GetAggregatedSyncResult(oldAggregation.AggregationID); err == nil {
+		t.Errorf("Expected old aggregation to be deleted")
+	}
+	stored, err := repo.GetAggregatedSyncResult(recentAggregation.AggregationID)
+	if err != nil {
+		t.Fatalf("Expected recent aggregation to be kept, got error %v", err)
+	}
+	if len(stored.Measurements) != 1 {
+		t.Errorf("recent aggregation measurements = %d, expected 1", len(stored.Measurements))
+	}
+}
+
+func TestForEachTimeSyncRecordFilters(t *testing.T) {
+	repo := newTestRepository(t)
+
+	now := time.Now()
+	old := newTestRecord(100)
+	old.CreatedAt = now.Add(-2 * time.Hour).UnixMilli()
+	recent := newTestRecord(110)
+	other := newTestRecord(120)
+	other.Device1ID = "psg-002"
+	other.Device2ID = "watch-002"
+	for _, record := range []*models.TimeSyncRecord{recent, old, other} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	var streamed []int64
+	collect := func(record *models.TimeSyncRecord) error {
+		streamed = append(streamed, record.ID)
+		return nil
+	}
+
+	// Device filter matches either side, oldest first
+	if err := repo.ForEachTimeSyncRecord(models.ExportFilter{DeviceID: "watch-001"}, collect); err != nil {
+		t.Fatalf("ForEachTimeSyncRecord() error = %v", err)
+	}
+	if len(streamed) != 2 || streamed[0] != old.ID || streamed[1] != recent.ID {
+		t.Errorf("streamed = %v, expected [%d %d]", streamed, old.ID, recent.ID)
+	}
+
+	// Device and time filters are combined
+	streamed = nil
+	since := now.Add(-time.Hour)
+	if err := repo.ForEachTimeSyncRecord(models.ExportFilter{DeviceID: "watch-001", StartTime: &since}, collect); err != nil {
+		t.Fatalf("ForEachTimeSyncRecord() error = %v", err)
+	}
+	if len(streamed) != 1 || streamed[0] != recent.ID {
+		t.Errorf("streamed = %v, expected [%d]", streamed, recent.ID)
+	}
+
+	// An error from the callback stops the iteration
+	stop := errors.New("stop")
+	calls := 0
+	err := repo.ForEachTimeSyncRecord(models.ExportFilter{}, func(*models.TimeSyncRecord) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v, calls = %d, expected stop after 1 call", err, calls)
+	}
+}
+
+func TestSQLiteUsesWALMode(t *testing.T) {
+	repo := newTestRepository(t)
+
+	var journalMode string
+	if err := repo.db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("PRAGMA journal_mode error = %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("journal_mode = %s, expected wal", journalMode)
+	}
+}
+
+func TestConcurrentSaveTimeSyncRecord(t *testing.T) {
+	repo := newTestRepository(t)
+
+	const writers = 20
+	const recordsPerWriter = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*recordsPerWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < recordsPerWriter; i++ {
+				if err := repo.SaveTimeSyncRecord(newTestRecord(int64(w*recordsPerWriter + i))); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("SaveTimeSyncRecord() error = %v", err)
+	}
+
+	records, err := repo.GetTimeSyncRecords(writers*recordsPerWriter+1, 0)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecords() error = %v", err)
+	}
+	if len(records) != writers*recordsPerWriter {
+		t.Errorf("saved records = %d, expected %d", len(records), writers*recordsPerWriter)
+	}
+}
+
+// aggregationStore is the part of the repositories exercised by the shared aggregation tests
+type aggregationStore interface {
+	SaveTimeSyncRecord(record *models.TimeSyncRecord) error
+	SaveTimeSyncRecords(records []*models.TimeSyncRecord) error
+	GetTimeSyncRecord(id int64) (*models.TimeSyncRecord, error)
+	DeleteTimeSyncRecord(id int64) error
+	GetUnaggregatedTimeSyncRecords(device1ID, device2ID string, since time.Time) ([]*models.TimeSyncRecord, error)
+	GetDeviceTypeStats() ([]*models.DeviceTypeStats, error)
+	SaveAggregatedSyncResult(result *models.AggregatedSyncResult) error
+	GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error)
+	DeleteAggregatedSyncResult(aggregationID string, deleteRecords bool) error
+	DeletePairingRecords(pairingID string) (*models.PairingRecordsDeletion, error)
+	SavePairing(pairing *models.PersistentPairing) error
+	GetTimeSyncRecordsFiltered(filter models.RecordFilter, limit, offset int) ([]*models.TimeSyncRecord, error)
+	GetTimeSyncRecordsAfterCursor(filter models.RecordFilter, cursor *models.RecordCursor, limit int) ([]*models.TimeSyncRecord, error)
+	GetAggregatedSyncResultsFiltered(filter models.AggregatedResultFilter, limit, offset int) ([]*models.AggregatedSyncResult, error)
+	GetAggregatedOffsetBuckets(pairingID string, startTime, endTime *time.Time, bucketMs int64) ([]*models.OffsetBucket, error)
+	GetLatestAggregatedSyncResult(pairingID string) (*models.AggregatedSyncResult, error)
+	GetLatestMemberAggregatedSyncResult(pairingID, memberDeviceID string) (*models.AggregatedSyncResult, error)
+	GetLatestAggregatedSyncResults(pairingIDs []string) ([]*models.AggregatedSyncResult, error)
+	GetDeviceSyncStats(deviceID string, startTime, endTime *time.Time) (*models.DeviceSyncStats, error)
+	SaveDeviceInfo(info *models.DeviceInfo) error
+	GetDeviceInfo(deviceID string) (*models.DeviceInfo, error)
+	SaveDeviceEvent(event *models.DeviceEvent) error
+	GetDeviceEvents(deviceID string, startTime, endTime *time.Time, limit int) ([]*models.DeviceEvent, error)
+	SaveOffsetOverride(override *models.OffsetOverride) error
+	GetOffsetOverride(pairingID string) (*models.OffsetOverride, error)
+	DeleteOffsetOverride(pairingID string) error
+}
+
+func testDeleteAggregatedSyncResult(t *testing.T, repo aggregationStore) {
+	shared := newTestRecord(100)
+	own := newTestRecord(110)
+	kept := newTestRecord(120)
+	for _, record := range []*models.TimeSyncRecord{shared, own, kept} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	aggregations := []*models.AggregatedSyncResult{
+		{AggregationID: "agg-keep-records", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{kept}},
+		{AggregationID: "agg-delete-records", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{shared, own}},
+		{AggregationID: "agg-other", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{shared}},
+	}
+	for _, result := range aggregations {
+		result.CreatedAt = time.Now().UnixMilli()
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	// Without deleteRecords the measurements stay
+	if err := repo.DeleteAggregatedSyncResult("agg-keep-records", false); err != nil {
+		t.Fatalf("DeleteAggregatedSyncResult() error = %v", err)
+	}
+	if _, err := repo.GetAggregatedSyncResult("agg-keep-records"); !errors.Is(err, ErrAggregationNotFound) {
+		t.Errorf("GetAggregatedSyncResult() error = %v, expected ErrAggregationNotFound", err)
+	}
+	if _, err := repo.GetTimeSyncRecord(kept.ID); err != nil {
+		t.Errorf("Expected record %d to be kept, got error %v", kept.ID, err)
+	}
+
+	// With deleteRecords only records no other aggregation links to are deleted
+	if err := repo.DeleteAggregatedSyncResult("agg-delete-records", true); err != nil {
+		t.Fatalf("DeleteAggregatedSyncResult() error = %v", err)
+	}
+	if _, err := repo.GetTimeSyncRecord(own.ID); err == nil {
+		t.Errorf("Expected record %d to be deleted", own.ID)
+	}
+	if _, err := repo.GetTimeSyncRecord(shared.ID); err != nil {
+		t.Errorf("Expected shared record %d to be kept, got error %v", shared.ID, err)
+	}
+	other, err := repo.GetAggregatedSyncResult("agg-other")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(other.Measurements) != 1 {
+		t.Errorf("agg-other measurements = %d, expected 1", len(other.Measurements))
+	}
+
+	if err := repo.DeleteAggregatedSyncResult("agg-missing", true); !errors.Is(err, ErrAggregationNotFound) {
+		t.Errorf("DeleteAggregatedSyncResult() error = %v, expected ErrAggregationNotFound", err)
+	}
+}
+
+func TestDeleteAggregatedSyncResult(t *testing.T) {
+	testDeleteAggregatedSyncResult(t, newTestRepository(t))
+}
+
+func testDeleteTimeSyncRecord(t *testing.T, repo aggregationStore) {
+	deleted := newTestRecord(100)
+	kept := newTestRecord(110)
+	for _, record := range []*models.TimeSyncRecord{deleted, kept} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+		AggregationID: "agg-123",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{deleted, kept},
+		CreatedAt:     time.Now().UnixMilli(),
+	}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	if err := repo.DeleteTimeSyncRecord(deleted.ID); err != nil {
+		t.Fatalf("DeleteTimeSyncRecord() error = %v", err)
+	}
+	if _, err := repo.GetTimeSyncRecord(deleted.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetTimeSyncRecord(%d) error = %v, expected ErrRecordNotFound", deleted.ID, err)
+	}
+
+	// The aggregation keeps its other measurement
+	result, err := repo.GetAggregatedSyncResult("agg-123")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(result.Measurements) != 1 || result.Measurements[0].ID != kept.ID {
+		t.Errorf("agg-123 measurements = %v, expected only record %d", result.Measurements, kept.ID)
+	}
+
+	if err := repo.DeleteTimeSyncRecord(deleted.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteTimeSyncRecord() of a deleted record error = %v, expected ErrRecordNotFound", err)
+	}
+}
+
+func TestDeleteTimeSyncRecord(t *testing.T) {
+	repo := newTestRepository(t)
+	testDeleteTimeSyncRecord(t, repo)
+
+	// Only the link of the kept record is left
+	var links int
+	if err := repo.db.QueryRow(`SELECT COUNT(*) FROM aggregation_measurements WHERE aggregation_id = ?`, "agg-123").Scan(&links); err != nil {
+		t.Fatalf("failed to count measurement links: %v", err)
+	}
+	if links != 1 {
+		t.Errorf("aggregation_measurements rows = %d, expected the deleted record's link to be removed", links)
+	}
+}
+
+func testDeletePairingRecords(t *testing.T, repo aggregationStore) {
+	own1, own2 := newTestRecord(100), newTestRecord(110)
+	shared := newTestRecord(120)
+	other := newTestRecord(130)
+	for _, record := range []*models.TimeSyncRecord{own1, own2, shared, other} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	aggregations := []*models.AggregatedSyncResult{
+		{AggregationID: "agg-1", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{own1, shared}},
+		{AggregationID: "agg-2", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{own1, own2}},
+		{AggregationID: "agg-other", PairingID: "pair-456", Measurements: []*models.TimeSyncRecord{shared, other}},
+	}
+	for _, result := range aggregations {
+		result.CreatedAt = time.Now().UnixMilli()
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	deletion, err := repo.DeletePairingRecords("pair-123")
+	if err != nil {
+		t.Fatalf("DeletePairingRecords() error = %v", err)
+	}
+	expected := models.PairingRecordsDeletion{PairingID: "pair-123", RecordsDeleted: 2, AggregationsDeleted: 2, LinksDeleted: 4}
+	if *deletion != expected {
+		t.Errorf("DeletePairingRecords() = %+v, expected %+v", *deletion, expected)
+	}
+
+	for _, aggregationID := range []string{"agg-1", "agg-2"} {
+		if _, err := repo.GetAggregatedSyncResult(aggregationID); !errors.Is(err, ErrAggregationNotFound) {
+			t.Errorf("GetAggregatedSyncResult(%s) error = %v, expected ErrAggregationNotFound", aggregationID, err)
+		}
+	}
+	for _, record := range []*models.TimeSyncRecord{own1, own2} {
+		if _, err := repo.GetTimeSyncRecord(record.ID); err == nil {
+			t.Errorf("Expected record %d to be deleted", record.ID)
+		}
+	}
+
+	// The other pairing keeps its aggregation and every record it links to
+	kept, err := repo.GetAggregatedSyncResult("agg-other")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(kept.Measurements) != 2 {
+		t.Errorf("agg-other measurements = %d, expected 2", len(kept.Measurements))
+	}
+
+	// Purging again finds nothing left
+	deletion, err = repo.DeletePairingRecords("pair-123")
+	if err != nil {
+		t.Fatalf("DeletePairingRecords() error = %v", err)
+	}
+	if deletion.RecordsDeleted != 0 || deletion.AggregationsDeleted != 0 || deletion.LinksDeleted != 0 {
+		t.Errorf("second DeletePairingRecords() = %+v, expected nothing deleted", *deletion)
+	}
+}
+
+func TestDeletePairingRecords(t *testing.T) {
+	testDeletePairingRecords(t, newTestRepository(t))
+}
+
+func testDeletePairingUnaggregatedRecords(t *testing.T, repo aggregationStore) {
+	pairing := &models.PersistentPairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001", CreatedAt: time.Now(), Enabled: true}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	aggregated := newTestRecord(100)
+	single := newTestRecord(110)
+	failed := newTestRecord(120)
+	failed.Device1ID, failed.Device2ID = "watch-001", "psg-001"
+	failed.Status = models.SyncStatusFailed
+	shared := newTestRecord(130)
+	unrelated := newTestRecord(140)
+	unrelated.Device2ID = "watch-002"
+	for _, record := range []*models.TimeSyncRecord{aggregated, single, failed, shared, unrelated} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	aggregations := []*models.AggregatedSyncResult{
+		{AggregationID: "agg-1", PairingID: "pair-123", Measurements: []*models.TimeSyncRecord{aggregated}},
+		{AggregationID: "agg-other", PairingID: "pair-456", Measurements: []*models.TimeSyncRecord{shared}},
+	}
+	for _, result := range aggregations {
+		result.CreatedAt = time.Now().UnixMilli()
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	deletion, err := repo.DeletePairingRecords("pair-123")
+	if err != nil {
+		t.Fatalf("DeletePairingRecords() error = %v", err)
+	}
+	expected := models.PairingRecordsDeletion{PairingID: "pair-123", RecordsDeleted: 3, AggregationsDeleted: 1, LinksDeleted: 1}
+	if *deletion != expected {
+		t.Errorf("DeletePairingRecords() = %+v, expected %+v", *deletion, expected)
+	}
+
+	// Unaggregated records of the pairing's devices go in either device order
+	for _, record := range []*models.TimeSyncRecord{aggregated, single, failed} {
+		if _, err := repo.GetTimeSyncRecord(record.ID); err == nil {
+			t.Errorf("Expected record %d to be deleted", record.ID)
+		}
+	}
+
+	// Records of other devices and records another pairing still links to are kept
+	for _, record := range []*models.TimeSyncRecord{shared, unrelated} {
+		if _, err := repo.GetTimeSyncRecord(record.ID); err != nil {
+			t.Errorf("GetTimeSyncRecord(%d) error = %v, expected the record to be kept", record.ID, err)
+		}
+	}
+}
+
+func TestDeletePairingUnaggregatedRecords(t *testing.T) {
+	testDeletePairingUnaggregatedRecords(t, newTestRepository(t))
+}
+
+func testSaveTimeSyncRecords(t *testing.T, repo aggregationStore) {
+	if err := repo.SaveTimeSyncRecords(nil); err != nil {
+		t.Fatalf("SaveTimeSyncRecords(nil) error = %v", err)
+	}
+
+	records := []*models.TimeSyncRecord{newTestRecord(100), newTestRecord(110), newTestRecord(120)}
+	if err := repo.SaveTimeSyncRecords(records); err != nil {
+		t.Fatalf("SaveTimeSyncRecords() error = %v", err)
+	}
+
+	seen := map[int64]bool{}
+	for i, record := range records {
+		if record.ID == 0 || seen[record.ID] {
+			t.Fatalf("record %d has ID %d, expected a new unique ID", i, record.ID)
+		}
+		seen[record.ID] = true
+
+		stored, err := repo.GetTimeSyncRecord(record.ID)
+		if err != nil {
+			t.Fatalf("GetTimeSyncRecord(%d) error = %v", record.ID, err)
+		}
+		if *stored.TimeDifference != *record.TimeDifference {
+			t.Errorf("record %d TimeDifference = %d, expected %d", record.ID, *stored.TimeDifference, *record.TimeDifference)
+		}
+	}
+
+	// The back-filled IDs link the records to an aggregation
+	result := &models.AggregatedSyncResult{AggregationID: "agg-batch", PairingID: "pair-123", Measurements: records}
+	if err := repo.SaveAggregatedSyncResult(result); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+	stored, err := repo.GetAggregatedSyncResult("agg-batch")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(stored.Measurements) != len(records) {
+		t.Errorf("aggregation has %d measurements, expected %d", len(stored.Measurements), len(records))
+	}
+}
+
+func TestSaveTimeSyncRecords(t *testing.T) {
+	testSaveTimeSyncRecords(t, newTestRepository(t))
+}
+
+// BenchmarkSaveTimeSyncRecords compares saving the samples of a 15-sample multi-sync one by one
+// with saving them in one transaction
+func BenchmarkSaveTimeSyncRecords(b *testing.B) {
+	const samples = 15
+	newRecords := func() []*models.TimeSyncRecord {
+		records := make([]*models.TimeSyncRecord, samples)
+		for i := range records {
+			records[i] = newTestRecord(int64(100 + i))
+		}
+		return records
+	}
+
+	b.Run("single", func(b *testing.B) {
+		repo := newTestRepository(b)
+		for i := 0; i < b.N; i++ {
+			for _, record := range newRecords() {
+				if err := repo.SaveTimeSyncRecord(record); err != nil {
+					b.Fatalf("SaveTimeSyncRecord() error = %v", err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		repo := newTestRepository(b)
+		for i := 0; i < b.N; i++ {
+			if err := repo.SaveTimeSyncRecords(newRecords()); err != nil {
+				b.Fatalf("SaveTimeSyncRecords() error = %v", err)
+			}
+		}
+	})
+}
+
+func testAggregatedMeasurementsKeepAdjustedOffset(t *testing.T, repo aggregationStore) {
+	adjusted := newTestRecord(100)
+	raw := newTestRecord(110)
+	for _, record := range []*models.TimeSyncRecord{adjusted, raw} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	offset := int64(97)
+	adjusted.AdjustedOffset = &offset
+	outlier := models.RejectedSample{RecordID: adjusted.ID, Offset: offset, Deviation: -40.5, Reason: "4.2 standard deviations from the mean (threshold 2.0)"}
+	result := &models.AggregatedSyncResult{
+		AggregationID: "agg-adjusted",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{adjusted, raw},
+		// A rejected sample that was never saved has nothing to be recorded on
+		RejectedSamples: []models.RejectedSample{outlier, {Offset: 300, Deviation: 160, Reason: "unsaved"}},
+		CreatedAt:       time.Now().UnixMilli(),
+	}
+	if err := repo.SaveAggregatedSyncResult(result); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	loaded, err := repo.GetAggregatedSyncResult("agg-adjusted")
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() error = %v", err)
+	}
+	if len(loaded.Measurements) != 2 {
+		t.Fatalf("measurements = %d, expected 2", len(loaded.Measurements))
+	}
+	if len(loaded.RejectedSamples) != 1 || loaded.RejectedSamples[0] != outlier {
+		t.Errorf("RejectedSamples = %+v, expected only %+v", loaded.RejectedSamples, outlier)
+	}
+	for _, m := range loaded.Measurements {
+		switch m.ID {
+		case adjusted.ID:
+			if m.AdjustedOffset == nil || *m.AdjustedOffset != offset {
+				t.Errorf("AdjustedOffset = %v, expected %d", m.AdjustedOffset, offset)
+			}
+		case raw.ID:
+			if m.AdjustedOffset != nil {
+				t.Errorf("AdjustedOffset = %d, expected nil for a sample without one", *m.AdjustedOffset)
+			}
+		}
+	}
+
+	// The adjusted offset belongs to the aggregation, not to the record itself
+	record, err := repo.GetTimeSyncRecord(adjusted.ID)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecord() error = %v", err)
+	}
+	if record.AdjustedOffset != nil {
+		t.Errorf("GetTimeSyncRecord() AdjustedOffset = %d, expected nil", *record.AdjustedOffset)
+	}
+}
+
+func TestAggregatedMeasurementsKeepAdjustedOffset(t *testing.T) {
+	testAggregatedMeasurementsKeepAdjustedOffset(t, newTestRepository(t))
+}
+
+func TestSavePairingPersistsAutoSyncTimeout(t *testing.T) {
+	repo := newTestRepository(t)
+
+	timeoutSec := 15
+	pairing := &models.PersistentPairing{
+		PairingID:          "pair-timeout",
+		Device1ID:          "psg-001",
+		Device2ID:          "watch-001",
+		CreatedAt:          time.Now(),
+		AutoSyncTimeoutSec: &timeoutSec,
+	}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	stored, err := repo.GetPairingByID(pairing.PairingID)
+	if err != nil {
+		t.Fatalf("GetPairingByID() error = %v", err)
+	}
+	if stored.AutoSyncTimeoutSec == nil || *stored.AutoSyncTimeoutSec != timeoutSec {
+		t.Errorf("AutoSyncTimeoutSec = %v, expected %d", stored.AutoSyncTimeoutSec, timeoutSec)
+	}
+}
+
+func TestUpdatePairingAutoSync(t *testing.T) {
+	repo := newTestRepository(t)
+
+	pairing := &models.PersistentPairing{
+		PairingID: "pair-auto-sync",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+	}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	if err := repo.UpdatePairingAutoSync(pairing.PairingID, 30, 16, 100, 3); err != nil {
+		t.Fatalf("UpdatePairingAutoSync() error = %v", err)
+	}
+	stored, err := repo.GetPairingByID(pairing.PairingID)
+	if err != nil {
+		t.Fatalf("GetPairingByID() error = %v", err)
+	}
+	if stored.AutoSyncIntervalSec == nil || stored.AutoSyncSampleCount == nil || stored.AutoSyncIntervalMs == nil || stored.AutoSyncTimeoutSec == nil {
+		t.Fatalf("auto-sync config not persisted: %+v", stored)
+	}
+	if *stored.AutoSyncIntervalSec != 30 || *stored.AutoSyncSampleCount != 16 || *stored.AutoSyncIntervalMs != 100 || *stored.AutoSyncTimeoutSec != 3 {
+		t.Errorf("auto-sync config = %d/%d/%d/%d, expected 30/16/100/3",
+			*stored.AutoSyncIntervalSec, *stored.AutoSyncSampleCount, *stored.AutoSyncIntervalMs, *stored.AutoSyncTimeoutSec)
+	}
+
+	if err := repo.UpdatePairingAutoSync("pair-missing", 30, 16, 100, 3); !errors.Is(err, ErrPairingNotFound) {
+		t.Errorf("UpdatePairingAutoSync() on missing pairing error = %v, expected ErrPairingNotFound", err)
+	}
+}
+
+func TestUpdatePairingEnabled(t *testing.T) {
+	repo := newTestRepository(t)
+
+	pairing := &models.PersistentPairing{
+		PairingID: "pair-enabled",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	if err := repo.SavePairing(pairing); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		if err := repo.UpdatePairingEnabled(pairing.PairingID, enabled); err != nil {
+			t.Fatalf("UpdatePairingEnabled(%v) error = %v", enabled, err)
+		}
+		stored, err := repo.GetPairingByID(pairing.PairingID)
+		if err != nil {
+			t.Fatalf("GetPairingByID() error = %v", err)
+		}
+		if stored.Enabled != enabled {
+			t.Errorf("Enabled = %v, expected %v", stored.Enabled, enabled)
+		}
+	}
+
+	// Pairings saved before the flag existed default to enabled
+	if _, err := repo.db.Exec(`INSERT INTO pairings (pairing_id, device1_id, device2_id, created_at) VALUES (?, ?, ?, ?)`,
+		"pair-legacy", "psg-002", "watch-002", time.Now().UnixMilli()); err != nil {
+		t.Fatalf("failed to insert legacy pairing: %v", err)
+	}
+	if stored, err := repo.GetPairingByID("pair-legacy"); err != nil || !stored.Enabled {
+		t.Errorf("GetPairingByID() = %+v, %v, expected legacy pairing to be enabled", stored, err)
+	}
+
+	if err := repo.UpdatePairingEnabled("pair-missing", false); !errors.Is(err, ErrPairingNotFound) {
+		t.Errorf("UpdatePairingEnabled() on missing pairing error = %v, expected ErrPairingNotFound", err)
+	}
+}
+
+func testFilteredListings(t *testing.T, repo aggregationStore) {
+	now := time.Now()
+	oldFailed := newTestRecord(100)
+	oldFailed.Status = models.SyncStatusFailed
+	oldFailed.CreatedAt = now.Add(-time.Hour).UnixMilli()
+	failed := newTestRecord(110)
+	failed.Status = models.SyncStatusFailed
+	partial := newTestRecord(120)
+	partial.Status = models.SyncStatusPartial
+	otherDevice := newTestRecord(130)
+	otherDevice.Status = models.SyncStatusFailed
+	otherDevice.Device2ID = "watch-002"
+	for _, record := range []*models.TimeSyncRecord{oldFailed, failed, partial, otherDevice} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	since := now.Add(-time.Minute)
+	records, err := repo.GetTimeSyncRecordsFiltered(models.RecordFilter{
+		DeviceID:  "watch-001",
+		Status:    models.SyncStatusFailed,
+		StartTime: &since,
+	}, 50, 0)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecordsFiltered() error = %v", err)
+	}
+	if len(records) != 1 || records[0].ID != failed.ID {
+		t.Errorf("GetTimeSyncRecordsFiltered() = %d records, expected only record %d", len(records), failed.ID)
+	}
+
+	records, err = repo.GetTimeSyncRecordsFiltered(models.RecordFilter{Status: models.SyncStatusFailed}, 50, 0)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecordsFiltered() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("FAILED records = %d, expected 3", len(records))
+	}
+
+	for i, confidence := range []float64{0.5, 0.8, 0.95} {
+		result := &models.AggregatedSyncResult{
+			AggregationID: fmt.Sprintf("agg-confidence-%d", i),
+			PairingID:     "pair-123",
+			Confidence:    confidence,
+			CreatedAt:     now.Add(time.Duration(i) * time.Second).UnixMilli(),
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+	other := &models.AggregatedSyncResult{AggregationID: "agg-other-pairing", PairingID: "pair-456", Confidence: 0.99, CreatedAt: now.UnixMilli()}
+	if err := repo.SaveAggregatedSyncResult(other); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	minConfidence := 0.8
+	results, err := repo.GetAggregatedSyncResultsFiltered(models.AggregatedResultFilter{
+		PairingID:     "pair-123",
+		MinConfidence: &minConfidence,
+	}, 50, 0)
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResultsFiltered() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %d, expected 2", len(results))
+	}
+	if results[0].AggregationID != "agg-confidence-2" || results[1].AggregationID != "agg-confidence-1" {
+		t.Errorf("results = [%s %s], expected newest first", results[0].AggregationID, results[1].AggregationID)
+	}
+}
+
+func TestFilteredListings(t *testing.T) {
+	testFilteredListings(t, newTestRepository(t))
+}
+
+func testConfidenceRangeFilter(t *testing.T, repo aggregationStore) {
+	now := time.Now()
+	for i, result := range []*models.AggregatedSyncResult{
+		{AggregationID: "agg-low", PairingID: "pair-123", Confidence: 0.3},
+		{AggregationID: "agg-lower-bound", PairingID: "pair-123", Confidence: 0.5},
+		{AggregationID: "agg-upper-bound", PairingID: "pair-123", Confidence: 0.7},
+		{AggregationID: "agg-high", PairingID: "pair-123", Confidence: 0.9},
+		{AggregationID: "agg-other-pairing", PairingID: "pair-456", Confidence: 0.6},
+		{AggregationID: "agg-old", PairingID: "pair-123", Confidence: 0.6},
+	} {
+		result.CreatedAt = now.Add(time.Duration(i) * time.Second).UnixMilli()
+		if result.AggregationID == "agg-old" {
+			result.CreatedAt = now.Add(-time.Hour).UnixMilli()
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	minConfidence, maxConfidence := 0.5, 0.7
+	start := now.Add(-time.Minute)
+	tests := []struct {
+		name     string
+		filter   models.AggregatedResultFilter
+		expected []string
+	}{
+		{
+			"both bounds are inclusive",
+			models.AggregatedResultFilter{MinConfidence: &minConfidence, MaxConfidence: &maxConfidence},
+			[]string{"agg-other-pairing", "agg-upper-bound", "agg-lower-bound", "agg-old"},
+		},
+		{
+			"max only",
+			models.AggregatedResultFilter{MaxConfidence: &minConfidence},
+			[]string{"agg-lower-bound", "agg-low"},
+		},
+		{
+			"combined with pairing and time range",
+			models.AggregatedResultFilter{PairingID: "pair-123", StartTime: &start, MinConfidence: &minConfidence, MaxConfidence: &maxConfidence},
+			[]string{"agg-upper-bound", "agg-lower-bound"},
+		},
+	}
+	for _, tt := range tests {
+		results, err := repo.GetAggregatedSyncResultsFiltered(tt.filter, 50, 0)
+		if err != nil {
+			t.Fatalf("%s: GetAggregatedSyncResultsFiltered() error = %v", tt.name, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.AggregationID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: results = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestConfidenceRangeFilter(t *testing.T) {
+	testConfidenceRangeFilter(t, newTestRepository(t))
+}
+
+func testListSorting(t *testing.T, repo aggregationStore) {
+	now := time.Now()
+	var saved []*models.TimeSyncRecord
+	for i, offset := range []int64{-300, 100, 200} {
+		record := newTestRecord(offset)
+		record.CreatedAt = now.Add(time.Duration(i) * time.Minute).UnixMilli()
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		saved = append(saved, record)
+	}
+	failed := newTestRecord(0)
+	failed.TimeDifference = nil
+	failed.Status = models.SyncStatusFailed
+	failed.CreatedAt = now.Add(3 * time.Minute).UnixMilli()
+	if err := repo.SaveTimeSyncRecord(failed); err != nil {
+		t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+	}
+
+	recordIDs := func(sort models.ListSort) []int64 {
+		t.Helper()
+		records, err := repo.GetTimeSyncRecordsFiltered(models.RecordFilter{Sort: sort}, 50, 0)
+		if err != nil {
+			t.Fatalf("GetTimeSyncRecordsFiltered(%+v) error = %v", sort, err)
+		}
+		var ids []int64
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+	recordTests := []struct {
+		sort     models.ListSort
+		expected []int64
+	}{
+		{models.ListSort{}, []int64{failed.ID, saved[2].ID, saved[1].ID, saved[0].ID}},
+		{models.ListSort{Order: models.SortAsc}, []int64{saved[0].ID, saved[1].ID, saved[2].ID, failed.ID}},
+		// Records without an offset go last in both directions
+		{models.ListSort{By: models.SortByOffset, Order: models.SortAsc}, []int64{saved[1].ID, saved[2].ID, saved[0].ID, failed.ID}},
+		{models.ListSort{By: models.SortByOffset, Order: models.SortDesc}, []int64{saved[0].ID, saved[2].ID, saved[1].ID, failed.ID}},
+	}
+	for _, tt := range recordTests {
+		if got := recordIDs(tt.sort); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("records sorted by %+v = %v, expected %v", tt.sort, got, tt.expected)
+		}
+	}
+
+	for i, r := range []struct {
+		offset     int64
+		confidence float64
+	}{{-500, 0.9}, {50, 0.6}, {200, 0.75}} {
+		result := &models.AggregatedSyncResult{
+			AggregationID: fmt.Sprintf("agg-sort-%d", i),
+			PairingID:     "pair-123",
+			BestOffset:    r.offset,
+			Confidence:    r.confidence,
+			CreatedAt:     now.Add(time.Duration(i) * time.Minute).UnixMilli(),
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	aggregatedTests := []struct {
+		sort     models.ListSort
+		expected []string
+	}{
+		{models.ListSort{}, []string{"agg-sort-2", "agg-sort-1", "agg-sort-0"}},
+		{models.ListSort{By: models.SortByCreatedAt, Order: models.SortAsc}, []string{"agg-sort-0", "agg-sort-1", "agg-sort-2"}},
+		{models.ListSort{By: models.SortByConfidence, Order: models.SortDesc}, []string{"agg-sort-0", "agg-sort-2", "agg-sort-1"}},
+		{models.ListSort{By: models.SortByOffset, Order: models.SortAsc}, []string{"agg-sort-1", "agg-sort-2", "agg-sort-0"}},
+	}
+	for _, tt := range aggregatedTests {
+		results, err := repo.GetAggregatedSyncResultsFiltered(models.AggregatedResultFilter{Sort: tt.sort}, 50, 0)
+		if err != nil {
+			t.Fatalf("GetAggregatedSyncResultsFiltered(%+v) error = %v", tt.sort, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.AggregationID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("aggregated results sorted by %+v = %v, expected %v", tt.sort, got, tt.expected)
+		}
+	}
+
+	// Sort keys are whitelisted; anything else is rejected rather than put into the query
+	if _, err := repo.GetTimeSyncRecordsFiltered(models.RecordFilter{Sort: models.ListSort{By: "created_at; DROP TABLE time_sync_records"}}, 50, 0); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("unknown record sort key error = %v, expected ErrInvalidSort", err)
+	}
+	if _, err := repo.GetTimeSyncRecordsFiltered(models.RecordFilter{Sort: models.ListSort{By: models.SortByConfidence}}, 50, 0); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("confidence sort on records error = %v, expected ErrInvalidSort", err)
+	}
+	if _, err := repo.GetAggregatedSyncResultsFiltered(models.AggregatedResultFilter{Sort: models.ListSort{Order: "sideways"}}, 50, 0); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("unknown order error = %v, expected ErrInvalidSort", err)
+	}
+}
+
+func TestListSorting(t *testing.T) {
+	testListSorting(t, newTestRepository(t))
+}
+
+func testAggregatedOffsetBuckets(t *testing.T, repo aggregationStore) {
+	const hour = int64(time.Hour / time.Millisecond)
+	base := time.Now().Add(-24*time.Hour).UnixMilli() / hour * hour
+	for i, r := range []struct {
+		pairingID  string
+		createdAt  int64
+		offset     int64
+		confidence float64
+	}{
+		{"pair-123", base + 10, -100, 0.9},
+		{"pair-123", base + hour - 1, -200, 0.7},
+		{"pair-123", base + 2*hour, 50, 0.8},
+		{"pair-456", base + 20, 999, 0.1},
+	} {
+		result := &models.AggregatedSyncResult{
+			AggregationID: fmt.Sprintf("agg-bucket-%d", i),
+			PairingID:     r.pairingID,
+			BestOffset:    r.offset,
+			Confidence:    r.confidence,
+			CreatedAt:     r.createdAt,
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	buckets, err := repo.GetAggregatedOffsetBuckets("pair-123", nil, nil, hour)
+	if err != nil {
+		t.Fatalf("GetAggregatedOffsetBuckets() error = %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("buckets = %d, expected 2 (the empty hour is omitted)", len(buckets))
+	}
+	first := buckets[0]
+	if first.BucketStart != base || first.Count != 2 || first.MinOffset != -200 || first.MaxOffset != -100 {
+		t.Errorf("first bucket = %+v, expected start %d with 2 results between -200 and -100", first, base)
+	}
+	if math.Abs(first.MeanOffset-(-150)) > 1e-9 || math.Abs(first.MeanConfidence-0.8) > 1e-9 {
+		t.Errorf("first bucket means = %v/%v, expected -150/0.8", first.MeanOffset, first.MeanConfidence)
+	}
+	if buckets[1].BucketStart != base+2*hour || buckets[1].Count != 1 || buckets[1].MeanOffset != 50 {
+		t.Errorf("second bucket = %+v, expected one result at %d", buckets[1], base+2*hour)
+	}
+
+	// A range without results yields no buckets
+	start := time.UnixMilli(base + 3*hour)
+	buckets, err = repo.GetAggregatedOffsetBuckets("pair-123", &start, nil, hour)
+	if err != nil {
+		t.Fatalf("GetAggregatedOffsetBuckets() error = %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Errorf("buckets = %d, expected none after the last result", len(buckets))
+	}
+}
+
+func TestAggregatedOffsetBuckets(t *testing.T) {
+	testAggregatedOffsetBuckets(t, newTestRepository(t))
+}
+
+func testDeviceInfo(t *testing.T, repo aggregationStore) {
+	firstSeen := time.UnixMilli(time.Now().Add(-time.Hour).UnixMilli())
+	if err := repo.SaveDeviceInfo(&models.DeviceInfo{
+		DeviceID:   "watch-1",
+		DeviceType: models.DeviceTypeWatch,
+		Model:      "GW5",
+		Firmware:   "1.0.0",
+		LastSeenAt: firstSeen,
+	}); err != nil {
+		t.Fatalf("SaveDeviceInfo() error = %v", err)
+	}
+
+	// Reconnect with a new firmware but no model
+	lastSeen := firstSeen.Add(30 * time.Minute)
+	if err := repo.SaveDeviceInfo(&models.DeviceInfo{
+		DeviceID:   "watch-1",
+		DeviceType: models.DeviceTypeWatch,
+		Firmware:   "1.1.0",
+		LastSeenAt: lastSeen,
+	}); err != nil {
+		t.Fatalf("SaveDeviceInfo() error = %v", err)
+	}
+
+	info, err := repo.GetDeviceInfo("watch-1")
+	if err != nil {
+		t.Fatalf("GetDeviceInfo() error = %v", err)
+	}
+	if info.Model != "GW5" || info.Firmware != "1.1.0" {
+		t.Errorf("Model, Firmware = %q, %q, expected GW5, 1.1.0", info.Model, info.Firmware)
+	}
+	if !info.FirstSeenAt.Equal(firstSeen) || !info.LastSeenAt.Equal(lastSeen) {
+		t.Errorf("FirstSeenAt, LastSeenAt = %v, %v, expected %v, %v", info.FirstSeenAt, info.LastSeenAt, firstSeen, lastSeen)
+	}
+
+	if _, err := repo.GetDeviceInfo("unknown"); err == nil {
+		t.Error("GetDeviceInfo() of an unknown device should fail")
+	}
+}
+
+func TestDeviceInfo(t *testing.T) {
+	testDeviceInfo(t, newTestRepository(t))
+}
+
+func testDeviceEvents(t *testing.T, repo aggregationStore) {
+	base := time.UnixMilli(time.Now().Add(-time.Hour).UnixMilli())
+	for _, event := range []*models.DeviceEvent{
+		{DeviceID: "watch-1", EventType: models.DeviceEventConnected, Timestamp: base},
+		{DeviceID: "watch-1", EventType: models.DeviceEventDisconnected, Reason: models.DisconnectReasonDeadConnection, Timestamp: base.Add(10 * time.Minute)},
+		{DeviceID: "watch-2", EventType: models.DeviceEventConnected, Timestamp: base.Add(15 * time.Minute)},
+		{DeviceID: "watch-1", EventType: models.DeviceEventConnected, Timestamp: base.Add(20 * time.Minute)},
+	} {
+		if err := repo.SaveDeviceEvent(event); err != nil {
+			t.Fatalf("SaveDeviceEvent() error = %v", err)
+		}
+		if event.ID == 0 {
+			t.Fatal("SaveDeviceEvent() did not assign an ID")
+		}
+	}
+
+	events, err := repo.GetDeviceEvents("watch-1", nil, nil, 100)
+	if err != nil {
+		t.Fatalf("GetDeviceEvents() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("GetDeviceEvents() returned %d events, expected 3", len(events))
+	}
+	if !events[0].Timestamp.Equal(base.Add(20*time.Minute)) || !events[2].Timestamp.Equal(base) {
+		t.Errorf("GetDeviceEvents() = %v .. %v, expected newest first", events[0].Timestamp, events[2].Timestamp)
+	}
+	if events[1].EventType != models.DeviceEventDisconnected || events[1].Reason != models.DisconnectReasonDeadConnection {
+		t.Errorf("events[1] = %s %q, expected DISCONNECTED %q", events[1].EventType, events[1].Reason, models.DisconnectReasonDeadConnection)
+	}
+
+	// Both bounds are inclusive
+	start, end := base.Add(10*time.Minute), base.Add(20*time.Minute)
+	events, err = repo.GetDeviceEvents("watch-1", &start, &end, 100)
+	if err != nil {
+		t.Fatalf("GetDeviceEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("GetDeviceEvents(%v, %v) returned %d events, expected 2", start, end, len(events))
+	}
+
+	events, err = repo.GetDeviceEvents("watch-1", nil, nil, 1)
+	if err != nil {
+		t.Fatalf("GetDeviceEvents() error = %v", err)
+	}
+	if len(events) != 1 || events[0].EventType != models.DeviceEventConnected {
+		t.Errorf("GetDeviceEvents(limit 1) = %+v, expected the latest CONNECTED event", events)
+	}
+}
+
+func TestDeviceEvents(t *testing.T) {
+	testDeviceEvents(t, newTestRepository(t))
+}
+
+func testLatestAggregatedSyncResult(t *testing.T, repo aggregationStore) {
+	if _, err := repo.GetLatestAggregatedSyncResult("pair-123"); !errors.Is(err, ErrAggregationNotFound) {
+		t.Fatalf("GetLatestAggregatedSyncResult() error = %v, expected ErrAggregationNotFound", err)
+	}
+
+	now := time.Now().UnixMilli()
+	for _, result := range []*models.AggregatedSyncResult{
+		{AggregationID: "agg-old", PairingID: "pair-123", BestOffset: -10, CreatedAt: now - 2000},
+		{AggregationID: "agg-new", PairingID: "pair-123", BestOffset: 20, CreatedAt: now - 1000},
+		{AggregationID: "agg-other", PairingID: "pair-456", BestOffset: 30, CreatedAt: now},
+	} {
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	latest, err := repo.GetLatestAggregatedSyncResult("pair-123")
+	if err != nil {
+		t.Fatalf("GetLatestAggregatedSyncResult() error = %v", err)
+	}
+	if latest.AggregationID != "agg-new" || latest.BestOffset != 20 {
+		t.Errorf("latest = %s (%dms), expected agg-new (20ms)", latest.AggregationID, latest.BestOffset)
+	}
+
+	tests := []struct {
+		pairingIDs []string
+		expected   []string
+	}{
+		{nil, []string{"agg-new", "agg-other"}},
+		{[]string{"pair-456", "pair-unknown"}, []string{"agg-other"}},
+		{[]string{"pair-unknown"}, nil},
+	}
+	for _, tt := range tests {
+		results, err := repo.GetLatestAggregatedSyncResults(tt.pairingIDs)
+		if err != nil {
+			t.Fatalf("GetLatestAggregatedSyncResults(%v) error = %v", tt.pairingIDs, err)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.AggregationID)
+		}
+		if !slices.Equal(ids, tt.expected) {
+			t.Errorf("GetLatestAggregatedSyncResults(%v) = %v, expected %v", tt.pairingIDs, ids, tt.expected)
+		}
+	}
+}
+
+func TestLatestAggregatedSyncResult(t *testing.T) {
+	testLatestAggregatedSyncResult(t, newTestRepository(t))
+}
+
+func testTimeSyncRecordsAfterCursor(t *testing.T, repo aggregationStore) {
+	// Two records share a created_at, so the id breaks the tie
+	now := time.Now().UnixMilli()
+	var saved []*models.TimeSyncRecord
+	for i, createdAt := range []int64{now - 2000, now - 1000, now - 1000, now} {
+		record := newTestRecord(int64(100 + i))
+		record.CreatedAt = createdAt
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		saved = append(saved, record)
+	}
+	expected := []int64{saved[3].ID, saved[2].ID, saved[1].ID, saved[0].ID}
+
+	var got []int64
+	var cursor *models.RecordCursor
+	for page := 0; page < 3; page++ {
+		records, err := repo.GetTimeSyncRecordsAfterCursor(models.RecordFilter{}, cursor, 3)
+		if err != nil {
+			t.Fatalf("GetTimeSyncRecordsAfterCursor() error = %v", err)
+		}
+		for _, record := range records {
+			got = append(got, record.ID)
+		}
+		if len(records) < 3 {
+			break
+		}
+		last := records[len(records)-1]
+		cursor = &models.RecordCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("record IDs across pages = %v, expected %v", got, expected)
+	}
+
+	// Filters apply together with the cursor
+	records, err := repo.GetTimeSyncRecordsAfterCursor(models.RecordFilter{Status: models.SyncStatusFailed}, nil, 10)
+	if err != nil {
+		t.Fatalf("GetTimeSyncRecordsAfterCursor() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("FAILED records = %d, expected 0", len(records))
+	}
+}
+
+func TestTimeSyncRecordsAfterCursor(t *testing.T) {
+	testTimeSyncRecordsAfterCursor(t, newTestRepository(t))
+}
+
+func testDeviceSyncStats(t *testing.T, repo aggregationStore) {
+	now := time.Now()
+	old := newTestRecord(500)
+	old.CreatedAt = now.Add(-48 * time.Hour).UnixMilli()
+	failed := newTestRecord(0)
+	failed.TimeDifference = nil
+	failed.Device2RTT = nil
+	failed.Status = models.SyncStatusFailed
+	other := newTestRecord(900)
+	other.Device2ID = "watch-002"
+	for _, record := range []*models.TimeSyncRecord{old, newTestRecord(100), newTestRecord(110), newTestRecord(130), failed, other} {
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	since := now.Add(-time.Hour)
+	stats, err := repo.GetDeviceSyncStats("watch-001", &since, nil)
+	if err != nil {
+		t.Fatalf("GetDeviceSyncStats() error = %v", err)
+	}
+	if stats.TotalSyncs != 4 || stats.SuccessfulSyncs != 3 || stats.SuccessRate != 0.75 {
+		t.Errorf("total, successful, rate = %d, %d, %v, expected 4, 3, 0.75", stats.TotalSyncs, stats.SuccessfulSyncs, stats.SuccessRate)
+	}
+	if math.Abs(stats.MeanTimeDifference-113.333) > 0.01 || stats.MedianTimeDifference != 110 {
+		t.Errorf("mean, median = %v, %v, expected 113.33, 110", stats.MeanTimeDifference, stats.MedianTimeDifference)
+	}
+	if math.Abs(stats.TimeDifferenceStdDev-12.472) > 0.01 {
+		t.Errorf("std dev = %v, expected 12.47", stats.TimeDifferenceStdDev)
+	}
+	if stats.MeanRTT != 10000 {
+		t.Errorf("mean RTT = %v, expected 10000", stats.MeanRTT)
+	}
+
+	// Even count: the median averages the two middle offsets
+	stats, err = repo.GetDeviceSyncStats("psg-001", &since, nil)
+	if err != nil {
+		t.Fatalf("GetDeviceSyncStats() error = %v", err)
+	}
+	if stats.TotalSyncs != 5 || stats.MedianTimeDifference != 120 {
+		t.Errorf("total, median = %d, %v, expected 5, 120", stats.TotalSyncs, stats.MedianTimeDifference)
+	}
+
+	stats, err = repo.GetDeviceSyncStats("unknown", nil, nil)
+	if err != nil {
+		t.Fatalf("GetDeviceSyncStats() error = %v", err)
+	}
+	if stats.TotalSyncs != 0 || stats.MeanRTT != 0 {
+		t.Errorf("stats of a device without records = %+v, expected zeros", stats)
+	}
+}
+
+func TestDeviceSyncStats(t *testing.T) {
+	testDeviceSyncStats(t, newTestRepository(t))
+}
+
+func testOffsetOverrides(t *testing.T, repo aggregationStore) {
+	if _, err := repo.GetOffsetOverride("pair-123"); !errors.Is(err, ErrOffsetOverrideNotFound) {
+		t.Errorf("GetOffsetOverride() error = %v, expected ErrOffsetOverrideNotFound", err)
+	}
+
+	createdAt := time.UnixMilli(1727870400000)
+	expiresAt := createdAt.Add(time.Hour)
+	for _, offset := range []int64{-120, 35} {
+		override := &models.OffsetOverride{PairingID: "pair-123", ReferenceDeviceID: "psg-001", OffsetMs: offset, ExpiresAt: &expiresAt, CreatedAt: createdAt}
+		if err := repo.SaveOffsetOverride(override); err != nil {
+			t.Fatalf("SaveOffsetOverride() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetOffsetOverride("pair-123")
+	if err != nil {
+		t.Fatalf("GetOffsetOverride() error = %v", err)
+	}
+	if got.OffsetMs != 35 || got.ReferenceDeviceID != "psg-001" || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) || !got.CreatedAt.Equal(createdAt) {
+		t.Errorf("GetOffsetOverride() = %+v, expected the replacing override", got)
+	}
+
+	if err := repo.DeleteOffsetOverride("pair-123"); err != nil {
+		t.Fatalf("DeleteOffsetOverride() error = %v", err)
+	}
+	if err := repo.DeleteOffsetOverride("pair-123"); !errors.Is(err, ErrOffsetOverrideNotFound) {
+		t.Errorf("second DeleteOffsetOverride() error = %v, expected ErrOffsetOverrideNotFound", err)
+	}
+}
+
+func TestOffsetOverrides(t *testing.T) {
+	testOffsetOverrides(t, newTestRepository(t))
+}
+
+func testUnaggregatedTimeSyncRecords(t *testing.T, repo aggregationStore) {
+	records := map[string]*models.TimeSyncRecord{}
+	for _, name := range []string{"single", "linked single", "multi", "legacy", "other pair"} {
+		record := newTestRecord(100)
+		switch name {
+		case "single", "linked single":
+			record.Source = models.RecordSourceSingle
+		case "multi":
+			// A multi-sync sample saved before its aggregation links it
+			record.Source = models.RecordSourceMulti
+		case "other pair":
+			record.Source = models.RecordSourceSingle
+			record.Device2ID = "watch-002"
+		}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord(%s) error = %v", name, err)
+		}
+		records[name] = record
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+		AggregationID: "agg-single",
+		PairingID:     "pair-123",
+		Measurements:  []*models.TimeSyncRecord{records["linked single"]},
+		CreatedAt:     time.Now().UnixMilli(),
+	}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+
+	pending, err := repo.GetUnaggregatedTimeSyncRecords("psg-001", "watch-001", time.Time{})
+	if err != nil {
+		t.Fatalf("GetUnaggregatedTimeSyncRecords() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != records["single"].ID || pending[0].Source != models.RecordSourceSingle {
+		t.Errorf("unaggregated records = %+v, expected only the unlinked single-sync record %d", pending, records["single"].ID)
+	}
+
+	stored, err := repo.GetTimeSyncRecord(records["multi"].ID)
+	if err != nil || stored.Source != models.RecordSourceMulti {
+		t.Errorf("GetTimeSyncRecord() = %+v, %v, expected the source to round-trip", stored, err)
+	}
+}
+
+func TestUnaggregatedTimeSyncRecords(t *testing.T) {
+	testUnaggregatedTimeSyncRecords(t, newTestRepository(t))
+}
+
+func testLatestMemberAggregatedSyncResult(t *testing.T, repo aggregationStore) {
+	now := time.Now().UnixMilli()
+	for i, result := range []*models.AggregatedSyncResult{
+		{AggregationID: "agg-pair", PairingID: "group-1", CreatedAt: now - 3},
+		{AggregationID: "agg-watch-1", PairingID: "group-1", MemberDeviceID: "watch-001", CreatedAt: now - 2},
+		{AggregationID: "agg-watch-2", PairingID: "group-1", MemberDeviceID: "watch-002", CreatedAt: now - 1},
+	} {
+		result.BestOffset = int64(i)
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult(%s) error = %v", result.AggregationID, err)
+		}
+	}
+
+	for member, expected := range map[string]string{"": "agg-pair", "watch-001": "agg-watch-1", "watch-002": "agg-watch-2"} {
+		latest, err := repo.GetLatestMemberAggregatedSyncResult("group-1", member)
+		if err != nil || latest.AggregationID != expected {
+			t.Errorf("GetLatestMemberAggregatedSyncResult(%q) = %v, %v, expected %s", member, latest, err, expected)
+		}
+	}
+	if _, err := repo.GetLatestMemberAggregatedSyncResult("group-1", "watch-003"); !errors.Is(err, ErrAggregationNotFound) {
+		t.Errorf("GetLatestMemberAggregatedSyncResult() of a member without results error = %v, expected ErrAggregationNotFound", err)
+	}
+}
+
+func TestLatestMemberAggregatedSyncResult(t *testing.T) {
+	testLatestMemberAggregatedSyncResult(t, newTestRepository(t))
+}
+
+func testDeviceTypeStats(t *testing.T, repo aggregationStore) {
+	rtt := func(micros int64) *int64 { return &micros }
+	for _, record := range []*models.TimeSyncRecord{
+		{Device1ID: "psg-001", Device1Type: models.DeviceTypePSG, Device1RTT: rtt(10000),
+			Device2ID: "watch-001", Device2Type: models.DeviceTypeWatch, Device2RTT: rtt(20000), Status: models.SyncStatusSuccess},
+		// A pairing of two watches: both RTTs count, the record once
+		{Device1ID: "watch-002", Device1Type: models.DeviceTypeWatch, Device1RTT: rtt(4000),
+			Device2ID: "watch-001", Device2Type: models.DeviceTypeWatch, Device2RTT: rtt(9000), Status: models.SyncStatusSuccess},
+		{Device1ID: "watch-002", Device1Type: models.DeviceTypeWatch,
+			Device2ID: "watch-001", Device2Type: models.DeviceTypeWatch, Status: models.SyncStatusFailed},
+	} {
+		record.CreatedAt = time.Now().UnixMilli()
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	stats, err := repo.GetDeviceTypeStats()
+	if err != nil {
+		t.Fatalf("GetDeviceTypeStats() error = %v", err)
+	}
+	expected := map[models.DeviceType]models.DeviceTypeStats{
+		models.DeviceTypePSG:   {TotalSyncs: 1, SuccessfulSyncs: 1, AvgRTT: 10000},
+		models.DeviceTypeWatch: {TotalSyncs: 3, SuccessfulSyncs: 2, AvgRTT: 11000},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("GetDeviceTypeStats() returned %d types, expected %d", len(stats), len(expected))
+	}
+	for _, stat := range stats {
+		want := expected[stat.DeviceType]
+		if stat.TotalSyncs != want.TotalSyncs || stat.SuccessfulSyncs != want.SuccessfulSyncs || stat.AvgRTT != want.AvgRTT {
+			t.Errorf("%s: total, successful, avg RTT = %d, %d, %g, expected %d, %d, %g", stat.DeviceType,
+				stat.TotalSyncs, stat.SuccessfulSyncs, stat.AvgRTT, want.TotalSyncs, want.SuccessfulSyncs, want.AvgRTT)
+		}
+	}
+}
+
+func TestDeviceTypeStats(t *testing.T) {
+	testDeviceTypeStats(t, newTestRepository(t))
+}
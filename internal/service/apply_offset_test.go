@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestApplyOffset(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "watch-001",
+		Device2ID: "psg-001",
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+		AggregationID:     "agg-123",
+		PairingID:         "pair-123",
+		ReferenceDeviceID: "psg-001",
+		BestOffset:        -150, // watch-001 is 150ms behind psg-001
+	}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+	s := NewSyncService(websocket.NewHub(), repo)
+
+	result, err := s.ApplyOffset(&models.ApplyOffsetRequest{
+		AggregationID: "agg-123",
+		DeviceID:      "watch-001",
+		Timestamps:    []int64{1727870400000, 1727870401000},
+	})
+	if err != nil {
+		t.Fatalf("ApplyOffset() error = %v", err)
+	}
+	if result.OffsetMs != -150 || result.Timestamps[0] != 1727870400150 || result.Timestamps[1] != 1727870401150 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// The reference device is already on the reference clock
+	result, err = s.ApplyOffset(&models.ApplyOffsetRequest{AggregationID: "agg-123", DeviceID: "psg-001", Timestamps: []int64{1000}})
+	if err != nil {
+		t.Fatalf("ApplyOffset() error = %v", err)
+	}
+	if result.Timestamps[0] != 1000 {
+		t.Errorf("reference timestamp = %d, expected 1000", result.Timestamps[0])
+	}
+
+	if _, err := s.ApplyOffset(&models.ApplyOffsetRequest{AggregationID: "agg-123", DeviceID: "watch-999", Timestamps: []int64{1000}}); err == nil {
+		t.Error("ApplyOffset() for a device outside the pairing should fail")
+	}
+	if _, err := s.ApplyOffset(&models.ApplyOffsetRequest{AggregationID: "agg-missing", DeviceID: "watch-001"}); !errors.Is(err, repository.ErrAggregationNotFound) {
+		t.Errorf("ApplyOffset() error = %v, expected ErrAggregationNotFound", err)
+	}
+}
+
+func TestOffsetOverrideTakesPrecedence(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	if err := repo.SavePairing(&models.PersistentPairing{PairingID: "pair-123", Device1ID: "watch-001", Device2ID: "psg-001", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+		AggregationID:     "agg-123",
+		PairingID:         "pair-123",
+		ReferenceDeviceID: "psg-001",
+		BestOffset:        -150,
+		CreatedAt:         time.Now().UnixMilli(),
+	}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+	s := NewSyncService(websocket.NewHub(), repo)
+
+	offsetMs := int64(-120)
+	expiresAt := time.Now().Add(time.Hour)
+	override, err := s.SetOffsetOverride("pair-123", &models.OffsetOverrideRequest{OffsetMs: &offsetMs, ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("SetOffsetOverride() error = %v", err)
+	}
+	if override.ReferenceDeviceID != "psg-001" {
+		t.Errorf("ReferenceDeviceID = %s, expected the pairing's reference psg-001", override.ReferenceDeviceID)
+	}
+
+	applied, err := s.ApplyOffset(&models.ApplyOffsetRequest{AggregationID: "agg-123", DeviceID: "watch-001", Timestamps: []int64{1000}})
+	if err != nil {
+		t.Fatalf("ApplyOffset() error = %v", err)
+	}
+	if !applied.Overridden || applied.OffsetMs != -120 || applied.Timestamps[0] != 1120 {
+		t.Errorf("ApplyOffset() = %+v, expected the pinned -120ms", applied)
+	}
+
+	latest, err := s.GetLatestAggregatedSyncResult("pair-123")
+	if err != nil {
+		t.Fatalf("GetLatestAggregatedSyncResult() error = %v", err)
+	}
+	if latest.BestOffset != -120 || latest.MeasuredOffset == nil || *latest.MeasuredOffset != -150 || latest.OffsetOverride == nil {
+		t.Errorf("latest best, measured offset = %d, %v, expected -120 and -150", latest.BestOffset, latest.MeasuredOffset)
+	}
+
+	series, err := s.GetOffsetTimeSeries("pair-123", nil, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("GetOffsetTimeSeries() error = %v", err)
+	}
+	if series.Override == nil || series.Override.OffsetMs != -120 {
+		t.Errorf("series override = %+v, expected the active override", series.Override)
+	}
+
+	// An expired override is ignored
+	expired := time.Now().Add(-time.Minute)
+	if err := repo.SaveOffsetOverride(&models.OffsetOverride{PairingID: "pair-123", ReferenceDeviceID: "psg-001", OffsetMs: -120, ExpiresAt: &expired, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOffsetOverride() error = %v", err)
+	}
+	applied, err = s.ApplyOffset(&models.ApplyOffsetRequest{AggregationID: "agg-123", DeviceID: "watch-001", Timestamps: []int64{1000}})
+	if err != nil || applied.Overridden || applied.OffsetMs != -150 {
+		t.Errorf("ApplyOffset() with an expired override = %+v, %v, expected the measured -150ms", applied, err)
+	}
+
+	if _, err := s.SetOffsetOverride("pair-missing", &models.OffsetOverrideRequest{OffsetMs: &offsetMs}); !errors.Is(err, repository.ErrPairingNotFound) {
+		t.Errorf("SetOffsetOverride() error = %v, expected ErrPairingNotFound", err)
+	}
+}
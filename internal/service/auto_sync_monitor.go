@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
 	"sync"
 	"time"
 
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/metrics"
 	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
 )
 
 // AutoSyncMonitor manages automatic periodic synchronization for pairings
@@ -15,6 +21,30 @@ type AutoSyncMonitor struct {
 	syncService *SyncService
 	jobs        map[string]*autoSyncJobContext
 	mu          sync.RWMutex
+
+	// Exponential backoff applied to the interval after consecutive failures
+	backoffMultiplier float64
+	maxBackoff        time.Duration
+
+	// Random jitter spreading out the syncs of jobs started together (0 = disabled)
+	jitterPercent float64 // Each interval is randomly adjusted by up to ±jitterPercent
+	initialJitter bool    // Delay the first sync by a random fraction of the interval
+	randFloat     func() float64
+
+	// Syncs whose offset differs from an active offset override by more than this are flagged
+	overrideThresholdMs int64
+
+	// Optional, nil disables publishing of job state changes
+	events *events.EventBus
+
+	// Stops the stale job reaper, nil while it is not running
+	reaperCancel context.CancelFunc
+
+	// In-progress performSync calls, drained by Shutdown
+	syncMu   sync.Mutex
+	syncWG   sync.WaitGroup
+	inFlight int
+	draining bool // Set by Shutdown, no new syncs are started
 }
 
 // autoSyncJobContext holds the context and control for a single auto-sync job
@@ -22,16 +52,73 @@ type autoSyncJobContext struct {
 	job        *models.AutoSyncJob
 	cancelFunc context.CancelFunc
 	mu         sync.RWMutex
+
+	// Signals the goroutine to re-arm its timer after UpdateAutoSync
+	reconfigured chan struct{}
 }
 
+// DefaultOverrideThresholdMs is how far a measured offset may differ from an active offset override
+// before the sync is flagged
+const DefaultOverrideThresholdMs = 20
+
 // NewAutoSyncMonitor creates a new AutoSyncMonitor instance
 func NewAutoSyncMonitor(syncService *SyncService) *AutoSyncMonitor {
 	return &AutoSyncMonitor{
-		syncService: syncService,
-		jobs:        make(map[string]*autoSyncJobContext),
+		syncService:         syncService,
+		jobs:                make(map[string]*autoSyncJobContext),
+		backoffMultiplier:   2.0,
+		maxBackoff:          time.Hour,
+		randFloat:           rand.Float64,
+		overrideThresholdMs: DefaultOverrideThresholdMs,
 	}
 }
 
+// SetOverrideThreshold sets how many milliseconds a measured offset may differ from the pairing's
+// active offset override before the sync is counted as a disagreement
+func (m *AutoSyncMonitor) SetOverrideThreshold(thresholdMs int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.overrideThresholdMs = thresholdMs
+}
+
+// SetEventBus sets the bus job state changes are published to (call before starting jobs)
+func (m *AutoSyncMonitor) SetEventBus(bus *events.EventBus) {
+	m.events = bus
+}
+
+// publishState publishes a snapshot of the job after a state change.
+// Must not be called with jobCtx.mu held.
+func (m *AutoSyncMonitor) publishState(jobCtx *autoSyncJobContext) {
+	jobCtx.mu.RLock()
+	jobCopy := *jobCtx.job
+	jobCtx.mu.RUnlock()
+
+	m.events.Publish(models.EventTypeAutoSyncState, &jobCopy)
+}
+
+// SetBackoff configures the exponential backoff for failing jobs.
+// A multiplier <= 1 disables backoff. Applies to syncs performed after the call.
+func (m *AutoSyncMonitor) SetBackoff(multiplier float64, maxBackoff time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.backoffMultiplier = multiplier
+	m.maxBackoff = maxBackoff
+}
+
+// SetJitter configures random jitter for job scheduling so jobs started together do not
+// keep firing at the same moment. Every interval is adjusted by a random amount of up to
+// ±percent (0 disables); with initialDelay the first sync is delayed by a random fraction
+// of the interval instead of running immediately. Applies to jobs started after the call.
+func (m *AutoSyncMonitor) SetJitter(percent float64, initialDelay bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jitterPercent = percent
+	m.initialJitter = initialDelay
+}
+
 // StartAutoSync starts automatic synchronization for a pairing
 func (m *AutoSyncMonitor) StartAutoSync(config models.AutoSyncConfig) error {
 	// Apply default values
@@ -44,6 +131,15 @@ func (m *AutoSyncMonitor) StartAutoSync(config models.AutoSyncConfig) error {
 	if config.IntervalMs <= 0 {
 		config.IntervalMs = 200
 	}
+	if config.TimeoutSec <= 0 {
+		config.TimeoutSec = 5
+	}
+	if config.MinConfidence < 0 || config.MinConfidence > 1 {
+		return fmt.Errorf("min_confidence must be between 0 and 1, got %v", config.MinConfidence)
+	}
+	if config.InitialSyncDelaySec < models.InitialSyncSkip {
+		return fmt.Errorf("initial_sync_delay_sec must be %d (skip) or at least 0, got %d", models.InitialSyncSkip, config.InitialSyncDelaySec)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -76,17 +172,22 @@ func (m *AutoSyncMonitor) StartAutoSync(config models.AutoSyncConfig) error {
 		LastSyncSuccess: true,
 		TotalSyncs:      0,
 		FailedSyncs:     0,
+
+		CurrentIntervalSec: config.IntervalSec,
 	}
 
 	jobCtx := &autoSyncJobContext{
-		job:        job,
-		cancelFunc: cancel,
+		job:          job,
+		cancelFunc:   cancel,
+		reconfigured: make(chan struct{}, 1),
 	}
 
 	m.jobs[config.PairingID] = jobCtx
+	metrics.AutoSyncJobsRunning.Set(float64(m.runningCountLocked()))
 
 	// Start background goroutine
 	go m.runAutoSync(ctx, jobCtx)
+	m.publishState(jobCtx)
 
 	log.Printf("Auto-sync started for pairing %s (interval: %ds, samples: %d)",
 		config.PairingID, config.IntervalSec, config.SampleCount)
@@ -114,12 +215,102 @@ func (m *AutoSyncMonitor) StopAutoSync(pairingID string) error {
 
 	// Remove from active jobs
 	delete(m.jobs, pairingID)
+	metrics.AutoSyncJobsRunning.Set(float64(m.runningCountLocked()))
+	m.publishState(jobCtx)
 
 	log.Printf("Auto-sync stopped for pairing %s", pairingID)
 
 	return nil
 }
 
+// PauseAutoSync pauses a running auto-sync job; its counters are kept until it is resumed or stopped
+func (m *AutoSyncMonitor) PauseAutoSync(pairingID string) error {
+	m.mu.RLock()
+	jobCtx, exists := m.jobs[pairingID]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("auto-sync not running for pairing: %s", pairingID)
+	}
+
+	jobCtx.mu.Lock()
+	if jobCtx.job.Status == models.AutoSyncStatusPaused {
+		jobCtx.mu.Unlock()
+		return fmt.Errorf("auto-sync already paused for pairing: %s", pairingID)
+	}
+
+	now := time.Now()
+	jobCtx.job.Status = models.AutoSyncStatusPaused
+	jobCtx.job.PausedAt = &now
+	jobCtx.mu.Unlock()
+	metrics.AutoSyncJobsRunning.Set(float64(m.RunningCount()))
+
+	log.Printf("Auto-sync paused for pairing %s", pairingID)
+	m.publishState(jobCtx)
+
+	return nil
+}
+
+// ResumeAutoSync resumes a paused auto-sync job at its next tick
+func (m *AutoSyncMonitor) ResumeAutoSync(pairingID string) error {
+	m.mu.RLock()
+	jobCtx, exists := m.jobs[pairingID]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("auto-sync not running for pairing: %s", pairingID)
+	}
+
+	jobCtx.mu.Lock()
+	if jobCtx.job.Status != models.AutoSyncStatusPaused {
+		jobCtx.mu.Unlock()
+		return fmt.Errorf("auto-sync not paused for pairing: %s", pairingID)
+	}
+
+	jobCtx.job.Status = models.AutoSyncStatusRunning
+	jobCtx.job.PausedAt = nil
+	jobCtx.mu.Unlock()
+	metrics.AutoSyncJobsRunning.Set(float64(m.RunningCount()))
+
+	log.Printf("Auto-sync resumed for pairing %s", pairingID)
+	m.publishState(jobCtx)
+
+	return nil
+}
+
+// UpdateAutoSync reconfigures a running or paused job in place, keeping its goroutine and counters.
+// The new configuration is persisted to the pairing, any backoff is reset and the next sync is
+// scheduled using the new interval.
+func (m *AutoSyncMonitor) UpdateAutoSync(pairingID string, update *models.AutoSyncUpdateRequest) (*models.AutoSyncJob, error) {
+	m.mu.RLock()
+	jobCtx, exists := m.jobs[pairingID]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("auto-sync not running for pairing: %s", pairingID)
+	}
+
+	jobCtx.mu.Lock()
+	config := update.Apply(jobCtx.job.Config)
+	if err := m.syncService.repo.UpdatePairingAutoSync(pairingID, config.IntervalSec, config.SampleCount, config.IntervalMs, config.TimeoutSec); err != nil {
+		jobCtx.mu.Unlock()
+		return nil, fmt.Errorf("failed to persist auto-sync config: %w", err)
+	}
+	jobCtx.job.Config = config
+	jobCtx.job.CurrentIntervalSec = config.IntervalSec
+	jobCopy := *jobCtx.job
+	jobCtx.mu.Unlock()
+
+	// The goroutine picks this up at its next select; a pending signal already covers this update
+	select {
+	case jobCtx.reconfigured <- struct{}{}:
+	default:
+	}
+
+	log.Printf("Auto-sync reconfigured for pairing %s (interval: %ds, samples: %d, interval_ms: %dms, timeout: %ds)",
+		pairingID, config.IntervalSec, config.SampleCount, config.IntervalMs, config.TimeoutSec)
+	m.publishState(jobCtx)
+
+	return &jobCopy, nil
+}
+
 // GetStatus returns the status of a specific auto-sync job
 func (m *AutoSyncMonitor) GetStatus(pairingID string) (*models.AutoSyncJob, error) {
 	m.mu.RLock()
@@ -154,12 +345,46 @@ func (m *AutoSyncMonitor) GetAllStatuses() []*models.AutoSyncJob {
 	return statuses
 }
 
-// Shutdown stops all auto-sync jobs gracefully
-func (m *AutoSyncMonitor) Shutdown() {
+// Shutdown stops all auto-sync jobs gracefully: no new syncs are started and syncs already in
+// progress may finish until ctx expires, after which the rest are cancelled. Returns ctx.Err()
+// if syncs had to be cancelled.
+func (m *AutoSyncMonitor) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	log.Printf("Shutting down auto-sync monitor (%d jobs)", len(m.jobs))
+	if m.reaperCancel != nil {
+		m.reaperCancel()
+		m.reaperCancel = nil
+	}
+	m.mu.Unlock()
+
+	// Stop starting syncs, then wait for the running ones; performSync takes m.mu, so it must not be held here
+	m.syncMu.Lock()
+	m.draining = true
+	running := m.inFlight
+	m.syncMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		m.syncWG.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	log.Printf("Shutting down auto-sync monitor (%d jobs)", len(m.jobs))
+	m.syncMu.Lock()
+	cancelled := m.inFlight
+	m.syncMu.Unlock()
+	if running > 0 {
+		log.Printf("Auto-sync shutdown: %d in-progress sync(s) completed, %d cancelled", running-cancelled, cancelled)
+	}
 
 	for pairingID, jobCtx := range m.jobs {
 		jobCtx.cancelFunc()
@@ -167,10 +392,76 @@ func (m *AutoSyncMonitor) Shutdown() {
 		jobCtx.job.Status = models.AutoSyncStatusStopped
 		jobCtx.mu.Unlock()
 		log.Printf("Stopped auto-sync for pairing %s", pairingID)
+		m.publishState(jobCtx)
 	}
 
 	// Clear all jobs
 	m.jobs = make(map[string]*autoSyncJobContext)
+	metrics.AutoSyncJobsRunning.Set(0)
+
+	return err
+}
+
+// StartReaper periodically stops jobs whose pairing no longer exists in the repository,
+// e.g. after it was deleted directly in the database. StartAutoSync only checks the pairing
+// once, so such jobs would otherwise keep failing forever. An interval <= 0 disables the reaper.
+func (m *AutoSyncMonitor) StartReaper(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reaperCancel != nil {
+		return // Already running
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.reaperCancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.ReapStaleJobs()
+			}
+		}
+	}()
+
+	log.Printf("Auto-sync stale job reaper started (interval: %v)", interval)
+}
+
+// ReapStaleJobs stops every job whose pairing is no longer in the repository and returns
+// how many were stopped. Jobs are kept when the lookup fails for another reason.
+func (m *AutoSyncMonitor) ReapStaleJobs() int {
+	m.mu.RLock()
+	pairingIDs := make([]string, 0, len(m.jobs))
+	for pairingID := range m.jobs {
+		pairingIDs = append(pairingIDs, pairingID)
+	}
+	m.mu.RUnlock()
+
+	reaped := 0
+	for _, pairingID := range pairingIDs {
+		_, err := m.syncService.repo.GetPairingByID(pairingID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrPairingNotFound) {
+			log.Printf("Auto-sync reaper could not check pairing %s: %v", pairingID, err)
+			continue
+		}
+		if err := m.StopAutoSync(pairingID); err != nil {
+			continue // Stopped in the meantime
+		}
+		log.Printf("Auto-sync reaper stopped job for pairing %s: pairing no longer exists in the repository", pairingID)
+		reaped++
+	}
+	return reaped
 }
 
 // runAutoSync is the background goroutine that performs periodic synchronization
@@ -179,15 +470,46 @@ func (m *AutoSyncMonitor) runAutoSync(ctx context.Context, jobCtx *autoSyncJobCo
 	config := jobCtx.job.Config
 	jobCtx.mu.RUnlock()
 
+	m.mu.RLock()
+	jitterPercent := m.jitterPercent
+	initialJitter := m.initialJitter
+	randFloat := m.randFloat
+	m.mu.RUnlock()
+
 	log.Printf("Auto-sync goroutine started for pairing %s", config.PairingID)
 
-	// Perform initial synchronization immediately
-	log.Printf("Auto-sync performing initial sync for pairing %s", config.PairingID)
-	m.performSync(jobCtx)
+	if config.InitialSyncDelaySec == models.InitialSyncSkip {
+		log.Printf("Auto-sync skipping initial sync for pairing %s", config.PairingID)
+	} else {
+		// Delay the first sync as configured, optionally spreading out the first syncs of jobs
+		// started together by a random fraction of the interval on top
+		delay := time.Duration(config.InitialSyncDelaySec) * time.Second
+		if initialJitter {
+			delay += time.Duration(randFloat() * float64(jobCtx.currentInterval()))
+		}
+		if delay > 0 {
+			log.Printf("Auto-sync delaying initial sync for pairing %s by %v", config.PairingID, delay.Round(time.Millisecond))
+			initialTimer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				initialTimer.Stop()
+				log.Printf("Auto-sync goroutine stopped for pairing %s", config.PairingID)
+				return
+			case <-initialTimer.C:
+			}
+		}
+
+		// Perform initial synchronization, unless the job was paused while it waited
+		if !jobCtx.isPaused() {
+			log.Printf("Auto-sync performing initial sync for pairing %s", config.PairingID)
+			m.runSync(ctx, jobCtx)
+		}
+	}
 
-	// Setup ticker for periodic synchronization
-	ticker := time.NewTicker(time.Duration(config.IntervalSec) * time.Second)
-	defer ticker.Stop()
+	// Setup timer for periodic synchronization; it is re-armed with the
+	// current (possibly backed-off) interval, jittered, after every tick
+	timer := time.NewTimer(applyJitter(jobCtx.currentInterval(), jitterPercent, randFloat()))
+	defer timer.Stop()
 
 	for {
 		select {
@@ -195,15 +517,45 @@ func (m *AutoSyncMonitor) runAutoSync(ctx context.Context, jobCtx *autoSyncJobCo
 			log.Printf("Auto-sync goroutine stopped for pairing %s", config.PairingID)
 			return
 
-		case <-ticker.C:
-			// Perform periodic synchronization
-			m.performSync(jobCtx)
+		case <-timer.C:
+			// Skip ticks while paused
+			if !jobCtx.isPaused() {
+				// Perform periodic synchronization
+				m.runSync(ctx, jobCtx)
+			}
+			timer.Reset(applyJitter(jobCtx.currentInterval(), jitterPercent, randFloat()))
+
+		case <-jobCtx.reconfigured:
+			// Restart the wait with the new interval
+			timer.Reset(applyJitter(jobCtx.currentInterval(), jitterPercent, randFloat()))
 		}
 	}
 }
 
-// performSync executes a single synchronization attempt
-func (m *AutoSyncMonitor) performSync(jobCtx *autoSyncJobContext) {
+// runSync performs a sync tracked for Shutdown, unless the monitor is already draining
+func (m *AutoSyncMonitor) runSync(ctx context.Context, jobCtx *autoSyncJobContext) {
+	m.syncMu.Lock()
+	if m.draining {
+		m.syncMu.Unlock()
+		return
+	}
+	m.inFlight++
+	m.syncWG.Add(1)
+	m.syncMu.Unlock()
+
+	defer func() {
+		m.syncMu.Lock()
+		m.inFlight--
+		m.syncMu.Unlock()
+		m.syncWG.Done()
+	}()
+
+	m.performSync(ctx, jobCtx)
+}
+
+// performSync executes a single synchronization attempt.
+// Stopping the job cancels ctx, which aborts the attempt without counting it as a failure.
+func (m *AutoSyncMonitor) performSync(ctx context.Context, jobCtx *autoSyncJobContext) {
 	jobCtx.mu.RLock()
 	config := jobCtx.job.Config
 	pairingID := jobCtx.job.PairingID
@@ -216,11 +568,33 @@ func (m *AutoSyncMonitor) performSync(jobCtx *autoSyncJobContext) {
 		PairingID:   config.PairingID,
 		SampleCount: config.SampleCount,
 		IntervalMs:  config.IntervalMs,
-		TimeoutSec:  5, // Fixed 5 second timeout per sample
+		TimeoutSec:  config.TimeoutSec,
 	}
 
 	// Execute synchronization
-	result, err := m.syncService.RequestMultipleTimeSyncs(req)
+	result, err := m.syncService.RequestMultipleTimeSyncs(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Auto-sync for pairing %s aborted: %v", pairingID, err)
+		return
+	}
+
+	m.mu.RLock()
+	multiplier := m.backoffMultiplier
+	maxBackoff := m.maxBackoff
+	overrideThresholdMs := m.overrideThresholdMs
+	m.mu.RUnlock()
+
+	// Measurements are still recorded while an override pins the pairing's offset
+	var overrideDelta *int64
+	if err == nil {
+		override, overrideErr := m.syncService.GetActiveOffsetOverride(pairingID)
+		if overrideErr != nil {
+			log.Printf("Auto-sync could not check the offset override of pairing %s: %v", pairingID, overrideErr)
+		} else if override != nil {
+			delta := result.BestOffset - override.OffsetAgainst(result.ReferenceDeviceID)
+			overrideDelta = &delta
+		}
+	}
 
 	// Update job status
 	jobCtx.mu.Lock()
@@ -234,13 +608,107 @@ func (m *AutoSyncMonitor) performSync(jobCtx *autoSyncJobContext) {
 		jobCtx.job.LastSyncSuccess = false
 		jobCtx.job.LastError = err.Error()
 		jobCtx.job.FailedSyncs++
-		log.Printf("Auto-sync failed for pairing %s: %v", pairingID, err)
+		jobCtx.job.ConsecutiveFailures++
+		jobCtx.job.CurrentIntervalSec = nextBackoffInterval(jobCtx.job.CurrentIntervalSec, config.IntervalSec, multiplier, maxBackoff)
+		log.Printf("Auto-sync failed for pairing %s (%d in a row, next attempt in %ds): %v",
+			pairingID, jobCtx.job.ConsecutiveFailures, jobCtx.job.CurrentIntervalSec, err)
+	} else if result.Confidence < config.MinConfidence {
+		// The devices answered, so there is no backoff, but a poor link should not look healthy
+		jobCtx.job.LastSyncSuccess = false
+		jobCtx.job.LastError = fmt.Sprintf("low confidence: %.2f is below the minimum of %.2f (offset=%dms)",
+			result.Confidence, config.MinConfidence, result.BestOffset)
+		jobCtx.job.LowConfidenceSyncs++
+		jobCtx.job.ConsecutiveFailures = 0
+		jobCtx.job.CurrentIntervalSec = config.IntervalSec
+		log.Printf("Auto-sync for pairing %s produced a low-confidence result: offset=%dms, confidence=%.2f (minimum %.2f)",
+			pairingID, result.BestOffset, result.Confidence, config.MinConfidence)
 	} else {
 		jobCtx.job.LastSyncSuccess = true
 		jobCtx.job.LastError = ""
+		jobCtx.job.ConsecutiveFailures = 0
+		jobCtx.job.CurrentIntervalSec = config.IntervalSec
 		log.Printf("Auto-sync succeeded for pairing %s: offset=%dms, confidence=%.2f",
 			pairingID, result.BestOffset, result.Confidence)
 	}
+
+	jobCtx.job.LastOverrideDeltaMs = overrideDelta
+	if overrideDelta != nil && abs64(*overrideDelta) > overrideThresholdMs {
+		jobCtx.job.OverrideDisagreements++
+		log.Printf("Auto-sync for pairing %s measured offset=%dms, %dms away from its offset override (threshold %dms)",
+			pairingID, result.BestOffset, *overrideDelta, overrideThresholdMs)
+	}
+}
+
+// nextBackoffInterval returns the interval to wait after another failure, in seconds.
+// The result never drops below the configured interval, even if maxBackoff is smaller.
+func nextBackoffInterval(currentSec, configuredSec int, multiplier float64, maxBackoff time.Duration) int {
+	if multiplier <= 1 {
+		return configuredSec
+	}
+
+	next := int(math.Ceil(float64(currentSec) * multiplier))
+	if maxSec := int(maxBackoff / time.Second); next > maxSec {
+		next = maxSec
+	}
+	if next < configuredSec {
+		next = configuredSec
+	}
+	return next
+}
+
+// applyJitter adjusts interval by up to ±percent. r is a random number in [0, 1):
+// 0 gives the shortest interval, 0.5 the unchanged one.
+func applyJitter(interval time.Duration, percent, r float64) time.Duration {
+	if percent <= 0 {
+		return interval
+	}
+	factor := 1 + percent/100*(2*r-1)
+	return time.Duration(float64(interval) * factor)
+}
+
+// currentInterval returns the job's effective interval including backoff
+func (jc *autoSyncJobContext) currentInterval() time.Duration {
+	jc.mu.RLock()
+	defer jc.mu.RUnlock()
+
+	return time.Duration(jc.job.CurrentIntervalSec) * time.Second
+}
+
+// isPaused reports whether the job is currently paused
+func (jc *autoSyncJobContext) isPaused() bool {
+	jc.mu.RLock()
+	defer jc.mu.RUnlock()
+
+	return jc.job.Status == models.AutoSyncStatusPaused
+}
+
+// HasJob checks if an auto-sync job exists for a pairing, whether running or paused
+func (m *AutoSyncMonitor) HasJob(pairingID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, exists := m.jobs[pairingID]
+	return exists
+}
+
+// RunningCount returns the number of jobs that are currently running (not paused)
+func (m *AutoSyncMonitor) RunningCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.runningCountLocked()
+}
+
+// runningCountLocked counts the running jobs, the caller must hold m.mu
+func (m *AutoSyncMonitor) runningCountLocked() int {
+	count := 0
+	for _, jobCtx := range m.jobs {
+		jobCtx.mu.RLock()
+		if jobCtx.job.Status == models.AutoSyncStatusRunning {
+			count++
+		}
+		jobCtx.mu.RUnlock()
+	}
+	return count
 }
 
 // IsRunning checks if an auto-sync job is currently running for a pairing
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestApplyJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  float64
+		r        float64
+		expected time.Duration
+	}{
+		{"disabled", 0, 0.9, 60 * time.Second},
+		{"lower bound", 10, 0, 54 * time.Second},
+		{"unchanged at midpoint", 10, 0.5, 60 * time.Second},
+		{"near upper bound", 10, 0.75, 63 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyJitter(60*time.Second, tt.percent, tt.r)
+			if got != tt.expected {
+				t.Errorf("applyJitter() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReapStaleJobs(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	hub := websocket.NewHub()
+	for _, pairingID := range []string{"pair-kept", "pair-deleted"} {
+		hub.Pairings[pairingID] = &models.Pairing{PairingID: pairingID, Device1ID: "psg-" + pairingID, Device2ID: "watch-" + pairingID}
+	}
+	if err := repo.SavePairing(&models.PersistentPairing{PairingID: "pair-kept", Device1ID: "psg-pair-kept", Device2ID: "watch-pair-kept", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+
+	m := NewAutoSyncMonitor(NewSyncService(hub, repo))
+	defer m.Shutdown(context.Background())
+	m.SetJitter(0, true) // Keep the first sync from running during the test
+	for _, pairingID := range []string{"pair-kept", "pair-deleted"} {
+		if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: pairingID, IntervalSec: 3600}); err != nil {
+			t.Fatalf("StartAutoSync(%s) error = %v", pairingID, err)
+		}
+	}
+
+	if reaped := m.ReapStaleJobs(); reaped != 1 {
+		t.Errorf("ReapStaleJobs() = %d, expected 1", reaped)
+	}
+	if m.HasJob("pair-deleted") {
+		t.Error("job of the deleted pairing is still running")
+	}
+	if !m.HasJob("pair-kept") {
+		t.Error("job of the existing pairing was stopped")
+	}
+}
+
+func TestShutdownDrainsInProgressSyncs(t *testing.T) {
+	tests := []struct {
+		name        string
+		deadline    time.Duration
+		expectedErr error
+	}{
+		{"sync finishes before deadline", 5 * time.Second, nil},
+		{"sync cancelled at deadline", 50 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewInMemoryRepository()
+			hub := websocket.NewHub()
+			device1 := &websocket.Client{Hub: hub, DeviceID: "psg-001", Send: make(chan []byte, 4)}
+			hub.Clients[device1.DeviceID] = device1
+			hub.Clients["watch-001"] = &websocket.Client{Hub: hub, DeviceID: "watch-001", Send: make(chan []byte, 4)}
+			hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+			// The devices never answer, so the single sample runs until its 1s timeout
+			m := NewAutoSyncMonitor(NewSyncService(hub, repo))
+			if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 3600, SampleCount: 1, TimeoutSec: 1}); err != nil {
+				t.Fatalf("StartAutoSync() error = %v", err)
+			}
+			select {
+			case <-device1.Send:
+			case <-time.After(2 * time.Second):
+				t.Fatal("initial sync did not send a TIME_REQUEST")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+			defer cancel()
+			start := time.Now()
+			err := m.Shutdown(ctx)
+			elapsed := time.Since(start)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Shutdown() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr == nil {
+				// The sample timed out rather than being cancelled, so it was persisted as FAILED
+				records, _ := repo.GetTimeSyncRecords(10, 0)
+				if len(records) != 1 || records[0].Status != models.SyncStatusFailed {
+					t.Errorf("records = %+v, expected the timed-out sample", records)
+				}
+			} else if elapsed > 500*time.Millisecond {
+				t.Errorf("Shutdown() took %v, expected it to cancel the sync at the deadline", elapsed)
+			}
+			if m.HasJob("pair-123") {
+				t.Error("job still registered after Shutdown()")
+			}
+		})
+	}
+}
+
+func TestInitialSyncDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delaySec int
+		wait     time.Duration
+		expected bool // A TIME_REQUEST is sent within wait
+	}{
+		{"immediate by default", 0, 500 * time.Millisecond, true},
+		{"delayed", 1, 500 * time.Millisecond, false},
+		{"delayed sync runs after the delay", 1, 2 * time.Second, true},
+		{"skipped until the first interval", models.InitialSyncSkip, 700 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := websocket.NewHub()
+			device1 := &websocket.Client{Hub: hub, DeviceID: "psg-001", Send: make(chan []byte, 4)}
+			hub.Clients[device1.DeviceID] = device1
+			hub.Clients["watch-001"] = &websocket.Client{Hub: hub, DeviceID: "watch-001", Send: make(chan []byte, 4)}
+			hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+			m := NewAutoSyncMonitor(NewSyncService(hub, repository.NewInMemoryRepository()))
+			defer m.Shutdown(context.Background())
+			config := models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 3600, SampleCount: 1, TimeoutSec: 1, InitialSyncDelaySec: tt.delaySec}
+			if err := m.StartAutoSync(config); err != nil {
+				t.Fatalf("StartAutoSync() error = %v", err)
+			}
+
+			select {
+			case <-device1.Send:
+				if !tt.expected {
+					t.Error("initial sync sent a TIME_REQUEST, expected it to wait")
+				}
+			case <-time.After(tt.wait):
+				if tt.expected {
+					t.Errorf("no TIME_REQUEST within %v, expected the initial sync", tt.wait)
+				}
+			}
+		})
+	}
+
+	m := NewAutoSyncMonitor(NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository()))
+	if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", InitialSyncDelaySec: -2}); err == nil {
+		t.Error("StartAutoSync() accepted initial_sync_delay_sec -2")
+	}
+}
+
+// newAnsweringPairHub connects psg-001 and watch-001, both answering every TIME_REQUEST,
+// and pairs them as pair-123
+func newAnsweringPairHub(t *testing.T) *websocket.Hub {
+	hub := websocket.NewHub()
+	for _, id := range []string{"psg-001", "watch-001"} {
+		client := &websocket.Client{Hub: hub, DeviceID: id, DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 16)}
+		hub.Clients[id] = client
+		answerTimeRequests(t, hub, client, 0, 0)
+	}
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+	return hub
+}
+
+// awaitTotalSyncs waits until the job has completed the given number of syncs
+func awaitTotalSyncs(t *testing.T, m *AutoSyncMonitor, pairingID string, total int) *models.AutoSyncJob {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		job, err := m.GetStatus(pairingID)
+		if err != nil {
+			t.Fatalf("GetStatus() error = %v", err)
+		}
+		if job.TotalSyncs >= total {
+			return job
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("TotalSyncs = %d, expected %d", job.TotalSyncs, total)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestPauseResumeAutoSync(t *testing.T) {
+	m := NewAutoSyncMonitor(NewSyncService(newAnsweringPairHub(t), repository.NewInMemoryRepository()))
+	defer m.Shutdown(context.Background())
+	if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 1, SampleCount: 3, IntervalMs: 1, TimeoutSec: 1}); err != nil {
+		t.Fatalf("StartAutoSync() error = %v", err)
+	}
+	awaitTotalSyncs(t, m, "pair-123", 1)
+
+	if err := m.PauseAutoSync("pair-123"); err != nil {
+		t.Fatalf("PauseAutoSync() error = %v", err)
+	}
+	if err := m.PauseAutoSync("pair-123"); err == nil {
+		t.Error("PauseAutoSync() of an already paused job should fail")
+	}
+
+	// The ticks of the next 1.5 intervals are skipped
+	time.Sleep(1500 * time.Millisecond)
+	job, err := m.GetStatus("pair-123")
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if job.Status != models.AutoSyncStatusPaused || job.PausedAt == nil {
+		t.Errorf("Status, PausedAt = %s, %v, expected a paused job", job.Status, job.PausedAt)
+	}
+	if job.TotalSyncs != 1 {
+		t.Errorf("TotalSyncs = %d while paused, expected 1", job.TotalSyncs)
+	}
+	if m.RunningCount() != 0 || !m.HasJob("pair-123") {
+		t.Errorf("RunningCount(), HasJob() = %d, %v, expected the paused job to be kept but not counted as running", m.RunningCount(), m.HasJob("pair-123"))
+	}
+
+	if err := m.ResumeAutoSync("pair-123"); err != nil {
+		t.Fatalf("ResumeAutoSync() error = %v", err)
+	}
+	if err := m.ResumeAutoSync("pair-123"); err == nil {
+		t.Error("ResumeAutoSync() of a running job should fail")
+	}
+
+	// The counters continue from before the pause
+	job = awaitTotalSyncs(t, m, "pair-123", 2)
+	if job.Status != models.AutoSyncStatusRunning || job.PausedAt != nil {
+		t.Errorf("Status, PausedAt = %s, %v, expected a running job", job.Status, job.PausedAt)
+	}
+	if job.TotalSyncs != 2 || job.FailedSyncs != 0 {
+		t.Errorf("TotalSyncs, FailedSyncs = %d, %d, expected 2, 0", job.TotalSyncs, job.FailedSyncs)
+	}
+}
+
+func TestNextBackoffInterval(t *testing.T) {
+	tests := []struct {
+		name         string
+		currentSec   int
+		multiplier   float64
+		maxBackoff   time.Duration
+		configured   int
+		expectedNext int
+	}{
+		{"doubles the interval", 60, 2, time.Hour, 60, 120},
+		{"rounds up", 45, 1.5, time.Hour, 45, 68},
+		{"capped at max backoff", 2400, 2, time.Hour, 60, 3600},
+		{"multiplier 1 disables backoff", 240, 1, time.Hour, 60, 60},
+		{"multiplier below 1 disables backoff", 240, 0.5, time.Hour, 60, 60},
+		{"max backoff below the interval keeps the interval", 600, 2, 5 * time.Minute, 600, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextBackoffInterval(tt.currentSec, tt.configured, tt.multiplier, tt.maxBackoff)
+			if got != tt.expectedNext {
+				t.Errorf("nextBackoffInterval() = %d, expected %d", got, tt.expectedNext)
+			}
+		})
+	}
+}
+
+// newTestJobContext returns a job context for calling performSync directly, without its goroutine
+func newTestJobContext(config models.AutoSyncConfig, currentIntervalSec, consecutiveFailures int) *autoSyncJobContext {
+	return &autoSyncJobContext{
+		job: &models.AutoSyncJob{
+			PairingID:           config.PairingID,
+			Status:              models.AutoSyncStatusRunning,
+			Config:              config,
+			LastSyncSuccess:     true,
+			ConsecutiveFailures: consecutiveFailures,
+			CurrentIntervalSec:  currentIntervalSec,
+		},
+		reconfigured: make(chan struct{}, 1),
+	}
+}
+
+func TestPerformSyncBackoff(t *testing.T) {
+	config := models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 60, SampleCount: 3, IntervalMs: 1, TimeoutSec: 1}
+
+	t.Run("failure backs off", func(t *testing.T) {
+		// Without the pairing every sync fails right away
+		m := NewAutoSyncMonitor(NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository()))
+		jobCtx := newTestJobContext(config, 120, 1)
+		m.performSync(context.Background(), jobCtx)
+
+		if jobCtx.job.CurrentIntervalSec != 240 || jobCtx.job.ConsecutiveFailures != 2 || jobCtx.job.LastSyncSuccess {
+			t.Errorf("CurrentIntervalSec, ConsecutiveFailures, LastSyncSuccess = %d, %d, %v, expected 240, 2, false",
+				jobCtx.job.CurrentIntervalSec, jobCtx.job.ConsecutiveFailures, jobCtx.job.LastSyncSuccess)
+		}
+	})
+
+	t.Run("success resets", func(t *testing.T) {
+		m := NewAutoSyncMonitor(NewSyncService(newAnsweringPairHub(t), repository.NewInMemoryRepository()))
+		jobCtx := newTestJobContext(config, 240, 2)
+		m.performSync(context.Background(), jobCtx)
+
+		if jobCtx.job.CurrentIntervalSec != 60 || jobCtx.job.ConsecutiveFailures != 0 || !jobCtx.job.LastSyncSuccess {
+			t.Errorf("CurrentIntervalSec, ConsecutiveFailures, LastSyncSuccess = %d, %d, %v, expected 60, 0, true (error: %s)",
+				jobCtx.job.CurrentIntervalSec, jobCtx.job.ConsecutiveFailures, jobCtx.job.LastSyncSuccess, jobCtx.job.LastError)
+		}
+	})
+}
+
+func TestPerformSyncLowConfidence(t *testing.T) {
+	// Three samples stay below the full sample count, so the confidence is always below 1
+	config := models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 60, SampleCount: 3, IntervalMs: 1, TimeoutSec: 1, MinConfidence: 1}
+	m := NewAutoSyncMonitor(NewSyncService(newAnsweringPairHub(t), repository.NewInMemoryRepository()))
+	jobCtx := newTestJobContext(config, 240, 2)
+	m.performSync(context.Background(), jobCtx)
+
+	job := jobCtx.job
+	if job.LowConfidenceSyncs != 1 || job.LastSyncSuccess || !strings.Contains(job.LastError, "low confidence") {
+		t.Errorf("LowConfidenceSyncs, LastSyncSuccess, LastError = %d, %v, %q, expected a low-confidence sync",
+			job.LowConfidenceSyncs, job.LastSyncSuccess, job.LastError)
+	}
+	// The devices answered, so it is not a failure and does not back off
+	if job.FailedSyncs != 0 || job.ConsecutiveFailures != 0 || job.CurrentIntervalSec != 60 {
+		t.Errorf("FailedSyncs, ConsecutiveFailures, CurrentIntervalSec = %d, %d, %d, expected 0, 0, 60",
+			job.FailedSyncs, job.ConsecutiveFailures, job.CurrentIntervalSec)
+	}
+}
+
+func TestStartAutoSyncValidatesMinConfidence(t *testing.T) {
+	tests := []struct {
+		minConfidence float64
+		valid         bool
+	}{
+		{-0.1, false},
+		{0, true},
+		{1, true},
+		{1.1, false},
+	}
+
+	hub := websocket.NewHub()
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+	m := NewAutoSyncMonitor(NewSyncService(hub, repository.NewInMemoryRepository()))
+	defer m.Shutdown(context.Background())
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.minConfidence), func(t *testing.T) {
+			config := models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 3600, MinConfidence: tt.minConfidence, InitialSyncDelaySec: models.InitialSyncSkip}
+			err := m.StartAutoSync(config)
+			if (err == nil) != tt.valid {
+				t.Fatalf("StartAutoSync(min_confidence=%v) error = %v, expected valid = %v", tt.minConfidence, err, tt.valid)
+			}
+			if err == nil {
+				m.StopAutoSync("pair-123")
+			}
+		})
+	}
+}
+
+func TestAutoSyncJobsRunningGauge(t *testing.T) {
+	m := NewAutoSyncMonitor(NewSyncService(newAnsweringPairHub(t), repository.NewInMemoryRepository()))
+	defer m.Shutdown(context.Background())
+
+	expectGauge := func(step string, expected float64) {
+		t.Helper()
+		families, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatalf("Gather() error = %v", err)
+		}
+		for _, family := range families {
+			if family.GetName() != "timesync_auto_sync_jobs_running" {
+				continue
+			}
+			if value := family.GetMetric()[0].GetGauge().GetValue(); value != expected {
+				t.Errorf("auto_sync_jobs_running after %s = %v, expected %v", step, value, expected)
+			}
+			return
+		}
+		t.Fatal("auto_sync_jobs_running is not registered")
+	}
+
+	if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 60, InitialSyncDelaySec: 3600}); err != nil {
+		t.Fatalf("StartAutoSync() error = %v", err)
+	}
+	expectGauge("start", 1)
+
+	// A paused job is kept but no longer counted as running
+	if err := m.PauseAutoSync("pair-123"); err != nil {
+		t.Fatalf("PauseAutoSync() error = %v", err)
+	}
+	expectGauge("pause", 0)
+
+	if err := m.ResumeAutoSync("pair-123"); err != nil {
+		t.Fatalf("ResumeAutoSync() error = %v", err)
+	}
+	expectGauge("resume", 1)
+
+	if err := m.PauseAutoSync("pair-123"); err != nil {
+		t.Fatalf("PauseAutoSync() error = %v", err)
+	}
+	if err := m.StopAutoSync("pair-123"); err != nil {
+		t.Fatalf("StopAutoSync() error = %v", err)
+	}
+	expectGauge("stop", 0)
+}
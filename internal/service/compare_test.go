@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestCompareAggregations(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	for _, result := range []*models.AggregatedSyncResult{
+		{AggregationID: "agg-before", PairingID: "pair-123", BestOffset: -80, Confidence: 0.75, Jitter: 400},
+		{AggregationID: "agg-after", PairingID: "pair-123", BestOffset: 50, Confidence: 0.87, Jitter: 250},
+	} {
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+	s := NewSyncService(websocket.NewHub(), repo)
+
+	comparison, err := s.CompareAggregations("agg-before", "agg-after")
+	if err != nil {
+		t.Fatalf("CompareAggregations() error = %v", err)
+	}
+	if comparison.OffsetDelta != 130 || comparison.AbsOffsetDelta != -30 || comparison.JitterDelta != -150 {
+		t.Errorf("unexpected deltas: %+v", comparison)
+	}
+	if expected := "B is 30ms closer, 0.12 more confident, 150μs less jitter"; comparison.Verdict != expected {
+		t.Errorf("Verdict = %q, expected %q", comparison.Verdict, expected)
+	}
+
+	if _, err := s.CompareAggregations("agg-before", "agg-missing"); !errors.Is(err, repository.ErrAggregationNotFound) {
+		t.Errorf("CompareAggregations() error = %v, expected ErrAggregationNotFound", err)
+	}
+}
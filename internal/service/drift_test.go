@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestEstimateClockDriftNormalizesReference(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+
+	// The offset against psg-001 grows 10ms per hour from 100ms; every other result was
+	// measured against watch-001 and stored with the opposite sign
+	start := time.Now().Add(-5 * time.Hour)
+	for i := 0; i < 6; i++ {
+		offset := int64(100 + 10*i)
+		reference := "psg-001"
+		if i%2 == 1 {
+			offset, reference = -offset, "watch-001"
+		}
+		result := &models.AggregatedSyncResult{
+			AggregationID:     fmt.Sprintf("agg-%d", i),
+			PairingID:         "pair-123",
+			BestOffset:        offset,
+			ReferenceDeviceID: reference,
+			CreatedAt:         start.Add(time.Duration(i) * time.Hour).UnixMilli(),
+		}
+		if err := repo.SaveAggregatedSyncResult(result); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	estimate, err := s.EstimateClockDrift("pair-123", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("EstimateClockDrift() error = %v", err)
+	}
+	// The newest result was measured against watch-001, so the fit is in its sign
+	if estimate.ReferenceDeviceID != "watch-001" {
+		t.Errorf("ReferenceDeviceID = %q, expected the newest result's reference", estimate.ReferenceDeviceID)
+	}
+	if math.Abs(estimate.SlopeMsPerHour+10) > 1e-6 || math.Abs(estimate.InterceptMs+100) > 1e-6 {
+		t.Errorf("slope, intercept = %.3f, %.3f, expected -10, -100", estimate.SlopeMsPerHour, estimate.InterceptMs)
+	}
+	if math.Abs(estimate.RSquared-1) > 1e-9 {
+		t.Errorf("RSquared = %.3f, expected a perfect fit once the signs agree", estimate.RSquared)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+// answerTimeRequests answers every TIME_REQUEST queued for client with its clock running
+// offsetMs ahead, except the first skip ones, until the test ends
+func answerTimeRequests(t *testing.T, hub *websocket.Hub, client *websocket.Client, offsetMs int64, skip int32) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	var seen atomic.Int32
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case data := <-client.Send:
+				var req models.TimeRequestMessage
+				if json.Unmarshal(data, &req) != nil || req.Type != models.MessageTypeTimeRequest {
+					continue
+				}
+				if seen.Add(1) <= skip {
+					continue
+				}
+				hub.HandleMessage(client, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`,
+					models.MessageTypeTimeResponse, req.RequestID, time.Now().UnixMilli()+offsetMs)))
+			}
+		}
+	}()
+}
+
+// newTestGroupHub connects a responding client per device (device ID -> clock offset in ms,
+// first responses skipped) and creates a group pairing with psg-001 as the reference
+func newTestGroupHub(t *testing.T, members map[string][2]int64) *websocket.Hub {
+	hub := websocket.NewHub()
+	deviceIDs := []string{"psg-001"}
+	for id := range members {
+		if id != "psg-001" {
+			deviceIDs = append(deviceIDs, id)
+		}
+	}
+	for id, member := range members {
+		client := &websocket.Client{Hub: hub, DeviceID: id, DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 16)}
+		hub.Clients[id] = client
+		answerTimeRequests(t, hub, client, member[0], int32(member[1]))
+	}
+	hub.GroupPairings["group-1"] = &models.GroupPairing{PairingID: "group-1", DeviceIDs: deviceIDs, ReferenceDeviceID: "psg-001"}
+	return hub
+}
+
+func TestRequestGroupMultipleTimeSyncs(t *testing.T) {
+	hub := newTestGroupHub(t, map[string][2]int64{"psg-001": {0, 0}, "watch-001": {400, 0}, "watch-002": {-300, 0}})
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(hub, repo)
+
+	result, err := s.RequestGroupMultipleTimeSyncs(context.Background(), &models.MultiSyncRequest{
+		PairingID:        "group-1",
+		SampleCount:      4,
+		IntervalStrategy: models.IntervalStrategyAdaptive,
+		MinIntervalMs:    1,
+		MaxIntervalMs:    20,
+	})
+	if err != nil {
+		t.Fatalf("RequestGroupMultipleTimeSyncs() error = %v", err)
+	}
+	if result.ReferenceDeviceID != "psg-001" || len(result.Results) != 2 || len(result.FailedDevices) != 0 {
+		t.Fatalf("result = %+v, expected one aggregation per non-reference member", result)
+	}
+
+	for deviceID, expected := range map[string]int64{"watch-001": 400, "watch-002": -300} {
+		member := result.Results[deviceID]
+		if member == nil {
+			t.Errorf("no result for %s", deviceID)
+			continue
+		}
+		if member.MemberDeviceID != deviceID || member.ReferenceDeviceID != "psg-001" {
+			t.Errorf("%s: MemberDeviceID, ReferenceDeviceID = %q, %q", deviceID, member.MemberDeviceID, member.ReferenceDeviceID)
+		}
+		if diff := member.BestOffset - expected; diff < -20 || diff > 20 {
+			t.Errorf("%s: BestOffset = %d, expected about %d", deviceID, member.BestOffset, expected)
+		}
+
+		// The saved results of one run are told apart by their member
+		saved, err := repo.GetAggregatedSyncResult(member.AggregationID)
+		if err != nil {
+			t.Fatalf("GetAggregatedSyncResult(%s) error = %v", deviceID, err)
+		}
+		if saved.MemberDeviceID != deviceID || len(saved.Measurements) != 4 {
+			t.Errorf("%s: saved member, measurements = %q, %d, expected %q, 4", deviceID, saved.MemberDeviceID, len(saved.Measurements), deviceID)
+		}
+	}
+}
+
+func TestRequestGroupMultipleTimeSyncsRetriesIncompleteSamples(t *testing.T) {
+	// watch-001 misses the first TIME_REQUEST, so the first sample is partial
+	hub := newTestGroupHub(t, map[string][2]int64{"psg-001": {0, 0}, "watch-001": {400, 1}})
+	s := NewSyncService(hub, repository.NewInMemoryRepository())
+
+	result, err := s.RequestGroupMultipleTimeSyncs(context.Background(), &models.MultiSyncRequest{
+		PairingID:           "group-1",
+		SampleCount:         3,
+		IntervalMs:          1,
+		TimeoutSec:          1,
+		MaxRetriesPerSample: 1,
+		DryRun:              true,
+	})
+	if err != nil {
+		t.Fatalf("RequestGroupMultipleTimeSyncs() error = %v", err)
+	}
+	member := result.Results["watch-001"]
+	if member == nil {
+		t.Fatalf("no result for watch-001: %+v", result)
+	}
+	if member.RetryCount != 1 || member.ValidSamples != 3 {
+		t.Errorf("RetryCount, ValidSamples = %d, %d, expected 1, 3", member.RetryCount, member.ValidSamples)
+	}
+	if member.AggregationID != "" {
+		t.Errorf("dry run saved the result as %q", member.AggregationID)
+	}
+}
+
+func TestRequestGroupMultipleTimeSyncsRejectsInvertedIntervalBounds(t *testing.T) {
+	s := NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository())
+
+	// The unset max defaults to 1000ms, below the requested min
+	_, err := s.RequestGroupMultipleTimeSyncs(context.Background(), &models.MultiSyncRequest{
+		PairingID:        "group-1",
+		IntervalStrategy: models.IntervalStrategyAdaptive,
+		MinIntervalMs:    2000,
+	})
+	if err == nil {
+		t.Error("RequestGroupMultipleTimeSyncs() with min_interval_ms above the default max should fail")
+	}
+}
+
+func TestShouldRetryGroupSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   *models.GroupSyncRecord
+		err      error
+		expected bool
+	}{
+		{"every member answered", &models.GroupSyncRecord{Status: models.SyncStatusSuccess}, nil, false},
+		{"a member did not answer", &models.GroupSyncRecord{Status: models.SyncStatusPartial}, nil, true},
+		{"server busy", nil, &websocket.ServerBusyError{MaxPendingRequests: 1}, true},
+		{"pairing deleted", nil, &websocket.PairingNotFoundError{PairingID: "group-1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetryGroupSample(tt.sample, tt.err); got != tt.expected {
+				t.Errorf("shouldRetryGroupSample() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
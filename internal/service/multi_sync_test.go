@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestNoUsableSamplesError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *NoUsableSamplesError
+		expected string
+	}{
+		{"all timed out", &NoUsableSamplesError{Total: 8, Failed: 8}, "no usable samples: all 8 samples timed out or failed"},
+		{"all partial", &NoUsableSamplesError{Total: 8, Partial: 8}, "no usable samples: all 8 samples were partial (only one device responded)"},
+		{"mixed", &NoUsableSamplesError{Total: 8, Partial: 3, Failed: 5}, "no usable samples: 3 partial and 5 failed of 8 samples"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.expected {
+				t.Errorf("Error() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAggregateRecordsCountsSampleStatuses(t *testing.T) {
+	var records []*models.TimeSyncRecord
+	for _, offset := range []int64{100, 102, 98, 101} {
+		rtt := int64(2000)
+		records = append(records, &models.TimeSyncRecord{
+			Device1ID:      "psg-001",
+			Device2ID:      "watch-001",
+			TimeDifference: &offset,
+			Device1RTT:     &rtt,
+			Device2RTT:     &rtt,
+			Status:         models.SyncStatusSuccess,
+		})
+	}
+	records = append(records,
+		&models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusPartial},
+		&models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusPartial},
+		&models.TimeSyncRecord{Device1ID: "psg-001", Device2ID: "watch-001", Status: models.SyncStatusFailed},
+	)
+
+	s := NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository())
+	result, err := s.AggregateRecords("pair-123", records, models.NTPFilterConfig{})
+	if err != nil {
+		t.Fatalf("AggregateRecords() error = %v", err)
+	}
+	if result.SuccessSamples != 4 || result.PartialSamples != 2 || result.FailedSamples != 1 {
+		t.Errorf("success, partial, failed = %d, %d, %d, expected 4, 2, 1",
+			result.SuccessSamples, result.PartialSamples, result.FailedSamples)
+	}
+}
+
+func TestAggregateRecordsSmoothsOffset(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+	s.SetOffsetSmoothingAlpha(0.5)
+
+	// member is set for the per-member results of a group pairing
+	aggregate := func(pairingID, member, device1, device2 string, offset int64) *models.AggregatedSyncResult {
+		t.Helper()
+		var records []*models.TimeSyncRecord
+		for i := 0; i < 4; i++ {
+			offset, rtt := offset, int64(2000)
+			records = append(records, &models.TimeSyncRecord{
+				Device1ID:      device1,
+				Device2ID:      device2,
+				TimeDifference: &offset,
+				Device1RTT:     &rtt,
+				Device2RTT:     &rtt,
+				Status:         models.SyncStatusSuccess,
+			})
+		}
+		result, err := s.selectBestOffset(pairingID, records, models.NTPFilterConfig{})
+		if err != nil {
+			t.Fatalf("selectBestOffset() error = %v", err)
+		}
+		result.MemberDeviceID = member
+		if err := s.saveAggregatedResult(result, records); err != nil {
+			t.Fatalf("saveAggregatedResult() error = %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // Keep CreatedAt apart so the next call finds this result as the latest
+		return result
+	}
+
+	tests := []struct {
+		pairingID, member string
+		device1, device2  string
+		offset            int64
+		expected          float64
+	}{
+		{"pair-123", "", "psg-001", "watch-001", 100, 100},   // The first result starts the average
+		{"pair-123", "", "psg-001", "watch-001", 200, 150},   // 0.5 × 200 + 0.5 × 100
+		{"pair-123", "", "watch-001", "psg-001", -250, -200}, // Measured against the PSG now, so the previous 150 counts as -150
+		// A group multi-sync saves one result per member; each member is averaged on its own
+		{"group-1", "watch-001", "watch-001", "psg-001", 100, 100},
+		{"group-1", "watch-002", "watch-002", "psg-001", -300, -300},
+		{"group-1", "watch-001", "watch-001", "psg-001", 200, 150},
+		{"group-1", "watch-002", "watch-002", "psg-001", -200, -250},
+	}
+	for _, tt := range tests {
+		result := aggregate(tt.pairingID, tt.member, tt.device1, tt.device2, tt.offset)
+		if result.SmoothedOffset == nil || *result.SmoothedOffset != tt.expected {
+			t.Errorf("%s %s: SmoothedOffset after offset %d = %v, expected %g", tt.pairingID, tt.member, tt.offset, result.SmoothedOffset, tt.expected)
+			continue
+		}
+		stored, err := repo.GetAggregatedSyncResult(result.AggregationID)
+		if err != nil || stored.SmoothedOffset == nil || *stored.SmoothedOffset != tt.expected {
+			t.Errorf("stored SmoothedOffset = %v, %v, expected %g to be persisted", stored, err, tt.expected)
+		}
+	}
+}
+
+func TestCountUnpersisted(t *testing.T) {
+	records := []*models.TimeSyncRecord{
+		{ID: 1, Status: models.SyncStatusSuccess},
+		{Status: models.SyncStatusSuccess}, // Its save failed, so a missing link is worth a warning
+		{Status: models.SyncStatusPartial},
+		{Status: models.SyncStatusFailed},
+	}
+
+	s := NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository())
+	if got := s.countUnpersisted(records); got != 0 {
+		t.Errorf("countUnpersisted() = %d with failed samples persisted, expected 0", got)
+	}
+
+	s.SetPersistFailedSamples(false)
+	if got := s.countUnpersisted(records); got != 2 {
+		t.Errorf("countUnpersisted() = %d, expected the PARTIAL and FAILED records", got)
+	}
+}
+
+func TestAdaptiveSampleInterval(t *testing.T) {
+	rtt := func(micros int64) *int64 { return &micros }
+	tests := []struct {
+		name       string
+		device1RTT *int64
+		device2RTT *int64
+		expected   time.Duration
+	}{
+		{"scaled from slower device", rtt(20000), rtt(60000), 240 * time.Millisecond},
+		{"clamped to min on fast links", rtt(2000), rtt(3000), 50 * time.Millisecond},
+		{"clamped to max on slow links", rtt(400000), rtt(100000), time.Second},
+		{"backs off without RTT", rtt(2000), nil, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &models.TimeSyncRecord{Device1RTT: tt.device1RTT, Device2RTT: tt.device2RTT}
+			if got := adaptiveSampleInterval(record, 50*time.Millisecond, time.Second); got != tt.expected {
+				t.Errorf("adaptiveSampleInterval() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShouldRetrySample(t *testing.T) {
+	offset := int64(100)
+	tests := []struct {
+		name     string
+		record   *models.TimeSyncRecord
+		err      error
+		expected bool
+	}{
+		{"success", &models.TimeSyncRecord{Status: models.SyncStatusSuccess, TimeDifference: &offset}, nil, false},
+		{"timed out", &models.TimeSyncRecord{Status: models.SyncStatusFailed}, nil, true},
+		{"one device answered", &models.TimeSyncRecord{Status: models.SyncStatusPartial}, nil, true},
+		{"device offline", nil, &websocket.DeviceNotConnectedError{DeviceID: "watch-001"}, true},
+		{"pairing deleted", nil, &websocket.PairingNotFoundError{PairingID: "pair-123"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetrySample(tt.record, tt.err); got != tt.expected {
+				t.Errorf("shouldRetrySample() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMultiSyncTimeBudget(t *testing.T) {
+	// 8 samples timing out after 5s with 200ms between them
+	if got := multiSyncTimeBudget(8, 5*time.Second, 200*time.Millisecond, time.Second, models.IntervalStrategyFixed); got != 41400*time.Millisecond {
+		t.Errorf("fixed budget = %v, expected 41.4s", got)
+	}
+	// Adaptive intervals may grow up to the max interval
+	if got := multiSyncTimeBudget(8, 5*time.Second, 200*time.Millisecond, time.Second, models.IntervalStrategyAdaptive); got != 47*time.Second {
+		t.Errorf("adaptive budget = %v, expected 47s", got)
+	}
+}
+
+func TestApplyIntervalStrategyDefaults(t *testing.T) {
+	req := &models.MultiSyncRequest{PairingID: "pair-123"}
+	if err := applyIntervalStrategyDefaults(req); err != nil {
+		t.Fatalf("applyIntervalStrategyDefaults() error = %v", err)
+	}
+	if req.IntervalStrategy != models.IntervalStrategyFixed || req.MinIntervalMs != 50 || req.MaxIntervalMs != 1000 {
+		t.Errorf("unexpected defaults: %+v", req)
+	}
+
+	// The unset max defaults to 1000ms, below the requested min
+	invalid := &models.MultiSyncRequest{IntervalStrategy: models.IntervalStrategyAdaptive, MinIntervalMs: 2000}
+	if err := applyIntervalStrategyDefaults(invalid); err == nil {
+		t.Errorf("applyIntervalStrategyDefaults(%+v) should fail", invalid)
+	}
+}
+
+func TestSelectBestOffsetDoesNotPersist(t *testing.T) {
+	var records []*models.TimeSyncRecord
+	for _, offset := range []int64{100, 102, 98} {
+		rtt := int64(2000)
+		records = append(records, &models.TimeSyncRecord{
+			Device1ID:      "psg-001",
+			Device2ID:      "watch-001",
+			TimeDifference: &offset,
+			Device1RTT:     &rtt,
+			Device2RTT:     &rtt,
+			Status:         models.SyncStatusSuccess,
+		})
+	}
+
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+	result, err := s.selectBestOffset("pair-123", records, models.NTPFilterConfig{})
+	if err != nil {
+		t.Fatalf("selectBestOffset() error = %v", err)
+	}
+	if result.AggregationID != "" || result.PairingID != "pair-123" || result.ValidSamples != 3 {
+		t.Errorf("unexpected dry-run result: %+v", result)
+	}
+	if result.MinSamples != 3 || result.OutlierThreshold != 2.0 || result.TopPercentile != 0.5 || result.OutlierMethod != models.OutlierMethodStdDev {
+		t.Errorf("filter config = %d/%v/%v/%q, expected the defaults", result.MinSamples, result.OutlierThreshold, result.TopPercentile, result.OutlierMethod)
+	}
+
+	saved, err := repo.GetAllAggregatedSyncResults(10, 0)
+	if err != nil {
+		t.Fatalf("GetAllAggregatedSyncResults() error = %v", err)
+	}
+	if len(saved) != 0 {
+		t.Errorf("dry run saved %d aggregated results, expected none", len(saved))
+	}
+}
+
+func TestRequestMultipleTimeSyncsCancelled(t *testing.T) {
+	s := NewSyncService(websocket.NewHub(), repository.NewInMemoryRepository())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.RequestMultipleTimeSyncs(ctx, &models.MultiSyncRequest{PairingID: "pair-123"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RequestMultipleTimeSyncs() error = %v, expected context.Canceled", err)
+	}
+}
+
+func TestInFlightSyncsCancel(t *testing.T) {
+	var syncs inFlightSyncs
+	ctx, release := syncs.track(context.Background(), "pair-123")
+	other, releaseOther := syncs.track(context.Background(), "pair-456")
+	defer releaseOther()
+
+	cause := errors.New("pairing deleted")
+	if n := syncs.cancel("pair-123", cause); n != 1 {
+		t.Errorf("cancel() = %d, expected 1", n)
+	}
+	if !errors.Is(context.Cause(ctx), cause) {
+		t.Errorf("context.Cause() = %v, expected %v", context.Cause(ctx), cause)
+	}
+	if other.Err() != nil {
+		t.Errorf("sync of another pairing was cancelled: %v", other.Err())
+	}
+
+	release()
+	if n := syncs.cancel("pair-123", cause); n != 0 {
+		t.Errorf("cancel() after release = %d, expected 0", n)
+	}
+}
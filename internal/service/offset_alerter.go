@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/logging"
+	"time-sync-server/internal/models"
+)
+
+const (
+	// alertRequestTimeout bounds a single webhook POST
+	alertRequestTimeout = 10 * time.Second
+
+	// alertRetryBaseDelay is the wait before the first retry; it doubles on every further retry
+	alertRetryBaseDelay = time.Second
+)
+
+// OffsetAlerter POSTs an OffsetAlert to a webhook when an aggregated offset exceeds the
+// configured threshold. Delivery runs in the background with retries and exponential backoff,
+// so callers never wait for the webhook.
+type OffsetAlerter struct {
+	url         string
+	thresholdMs int64
+	maxAttempts int
+	retryDelay  time.Duration
+
+	client *http.Client
+	logger *slog.Logger // nil = slog.Default()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewOffsetAlerter creates an alerter from the configuration.
+// It returns nil (alerting disabled) when no webhook URL or no positive threshold is configured.
+func NewOffsetAlerter(cfg *config.Config) *OffsetAlerter {
+	if cfg.AlertWebhookURL == "" || cfg.AlertOffsetThresholdMs <= 0 {
+		return nil
+	}
+
+	maxAttempts := cfg.AlertWebhookMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OffsetAlerter{
+		url:         cfg.AlertWebhookURL,
+		thresholdMs: int64(cfg.AlertOffsetThresholdMs),
+		maxAttempts: maxAttempts,
+		retryDelay:  alertRetryBaseDelay,
+		client:      &http.Client{Timeout: alertRequestTimeout},
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// SetLogger sets the structured logger for delivery failures. nil uses slog.Default().
+func (a *OffsetAlerter) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+func (a *OffsetAlerter) log() *slog.Logger {
+	if a.logger != nil {
+		return a.logger
+	}
+	return slog.Default()
+}
+
+// Check sends an alert if |BestOffset| of the result exceeds the threshold.
+// It returns immediately and is safe to call on a nil OffsetAlerter.
+func (a *OffsetAlerter) Check(result *models.AggregatedSyncResult) {
+	if a == nil || abs64(result.BestOffset) <= a.thresholdMs {
+		return
+	}
+
+	alert := &models.OffsetAlert{
+		PairingID:     result.PairingID,
+		AggregationID: result.AggregationID,
+		Offset:        result.BestOffset,
+		ThresholdMs:   a.thresholdMs,
+		Confidence:    result.Confidence,
+		Timestamp:     result.CreatedAt,
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.deliver(alert)
+	}()
+}
+
+// Shutdown cancels pending retries and waits for in-flight deliveries to return
+func (a *OffsetAlerter) Shutdown() {
+	if a == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+// deliver POSTs the alert, retrying with exponential backoff until it is accepted,
+// the attempts are used up or the alerter is shut down
+func (a *OffsetAlerter) deliver(alert *models.OffsetAlert) {
+	logger := a.log().With(logging.KeyPairingID, alert.PairingID, "aggregation_id", alert.AggregationID)
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		logger.Error("failed to encode offset alert", "error", err)
+		return
+	}
+
+	delay := a.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := a.post(body)
+		if err == nil {
+			logger.Info("offset alert delivered", "offset_ms", alert.Offset, "attempt", attempt)
+			return
+		}
+		if attempt >= a.maxAttempts {
+			logger.Error("offset alert delivery failed", "error", err, "attempts", attempt)
+			return
+		}
+		logger.Warn("offset alert delivery failed, retrying", "error", err, "attempt", attempt, "retry_in", delay)
+
+		select {
+		case <-a.ctx.Done():
+			logger.Warn("offset alert dropped on shutdown", "attempts", attempt)
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends one webhook request; any non-2xx status counts as a failure
+func (a *OffsetAlerter) post(body []byte) error {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
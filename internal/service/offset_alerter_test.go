@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/models"
+)
+
+func newTestAlerter(t *testing.T, url string) *OffsetAlerter {
+	t.Helper()
+	alerter := NewOffsetAlerter(&config.Config{
+		AlertWebhookURL:         url,
+		AlertOffsetThresholdMs:  500,
+		AlertWebhookMaxAttempts: 3,
+	})
+	if alerter == nil {
+		t.Fatal("NewOffsetAlerter() = nil, expected an enabled alerter")
+	}
+	alerter.retryDelay = time.Millisecond
+	t.Cleanup(alerter.Shutdown)
+	return alerter
+}
+
+func TestOffsetAlerterRetriesUntilDelivered(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan models.OffsetAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var alert models.OffsetAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	alerter := newTestAlerter(t, server.URL)
+	alerter.Check(&models.AggregatedSyncResult{
+		AggregationID: "agg-123",
+		PairingID:     "pair-123",
+		BestOffset:    -620,
+		Confidence:    0.9,
+		CreatedAt:     1727870400000,
+	})
+
+	select {
+	case alert := <-received:
+		if alert.PairingID != "pair-123" || alert.Offset != -620 || alert.Confidence != 0.9 || alert.Timestamp != 1727870400000 {
+			t.Errorf("unexpected alert payload: %+v", alert)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("alert was not delivered")
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, expected 2 (one failure, one success)", got)
+	}
+}
+
+func TestOffsetAlerterIgnoresOffsetsWithinThreshold(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+	}))
+	defer server.Close()
+
+	alerter := newTestAlerter(t, server.URL)
+	alerter.Check(&models.AggregatedSyncResult{PairingID: "pair-123", BestOffset: 500})
+	alerter.Check(&models.AggregatedSyncResult{PairingID: "pair-123", BestOffset: -120})
+	alerter.Shutdown()
+
+	if got := attempts.Load(); got != 0 {
+		t.Errorf("webhook called %d times, expected none", got)
+	}
+}
+
+func TestOffsetAlerterGivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	alerter := newTestAlerter(t, server.URL)
+	alerter.Check(&models.AggregatedSyncResult{PairingID: "pair-123", BestOffset: 800})
+	alerter.wg.Wait()
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("attempts = %d, expected 3", got)
+	}
+}
+
+func TestNewOffsetAlerterDisabledWithoutURL(t *testing.T) {
+	alerter := NewOffsetAlerter(&config.Config{AlertOffsetThresholdMs: 500})
+	if alerter != nil {
+		t.Fatal("expected nil alerter without a webhook URL")
+	}
+	// A nil alerter is a no-op
+	alerter.Check(&models.AggregatedSyncResult{BestOffset: 1000})
+	alerter.Shutdown()
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"log"
 
+	"time-sync-server/config"
 	"time-sync-server/internal/models"
 	"time-sync-server/internal/repository"
 	"time-sync-server/internal/websocket"
@@ -11,16 +14,18 @@ import (
 // PairingOperator manages automatic pairing restoration when devices reconnect
 type PairingOperator struct {
 	hub        *websocket.Hub
-	repository *repository.SQLiteRepository
+	repository Repository
 	autoSync   *AutoSyncMonitor
+	config     *config.Config
 }
 
 // NewPairingOperator creates a new PairingOperator instance
-func NewPairingOperator(hub *websocket.Hub, repo *repository.SQLiteRepository, autoSync *AutoSyncMonitor) *PairingOperator {
+func NewPairingOperator(hub *websocket.Hub, repo Repository, autoSync *AutoSyncMonitor, cfg *config.Config) *PairingOperator {
 	return &PairingOperator{
 		hub:        hub,
 		repository: repo,
 		autoSync:   autoSync,
+		config:     cfg,
 	}
 }
 
@@ -33,6 +38,9 @@ func (op *PairingOperator) OnDeviceConnected(deviceID string) {
 		return
 	}
 
+	// Group pairings are restored independently of two-device pairings
+	op.restoreGroupPairings(deviceID)
+
 	if len(pairings) == 0 {
 		log.Printf("No pairings found for device %s", deviceID)
 		return
@@ -78,26 +86,100 @@ func (op *PairingOperator) OnDeviceConnected(deviceID string) {
 	}
 }
 
+// restoreGroupPairings restores every group pairing of the device whose members are all connected
+func (op *PairingOperator) restoreGroupPairings(deviceID string) {
+	groups, err := op.repository.GetGroupPairingsByDeviceID(deviceID)
+	if err != nil {
+		log.Printf("Failed to get group pairings for device %s: %v", deviceID, err)
+		return
+	}
+
+	for _, group := range groups {
+		if op.hub.IsGroupPairingRestored(group.PairingID) {
+			continue
+		}
+
+		if err := op.hub.RestoreGroupPairing(group); err != nil {
+			log.Printf("Group pairing %s cannot be restored yet: %v", group.PairingID, err)
+			continue
+		}
+
+		log.Printf("✓ Group pairing restored: %s (%v, reference: %s)",
+			group.PairingID, group.DeviceIDs, group.ReferenceDeviceID)
+	}
+}
+
+// CreatePairing creates a pairing requested by the devices themselves (PAIR_REQUEST). Like POST
+// /api/pairings, devices that are already paired get their saved pairing back. A new pairing is
+// persisted with the server's default Auto-Sync settings for the two device types and Auto-Sync is started.
+// If it cannot be saved, it is removed from the hub again and no Auto-Sync is started.
+func (op *PairingOperator) CreatePairing(device1ID, device2ID string) (*models.Pairing, error) {
+	existing, err := restoreSavedPairing(op.hub, op.repository, device1ID, device2ID)
+	if !errors.Is(err, repository.ErrPairingNotFound) {
+		return existing, err
+	}
+
+	pairing, err := op.hub.CreatePairing(device1ID, device2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	device1Type, _ := op.hub.GetDeviceType(pairing.Device1ID)
+	device2Type, _ := op.hub.GetDeviceType(pairing.Device2ID)
+	defaults := op.config.AutoSyncDefaultsFor(string(device1Type), string(device2Type))
+
+	intervalSec := defaults.IntervalSec
+	sampleCount := defaults.SampleCount
+	intervalMs := defaults.IntervalMs
+	timeoutSec := defaults.TimeoutSec
+
+	persistentPairing := &models.PersistentPairing{
+		PairingID:           pairing.PairingID,
+		Device1ID:           pairing.Device1ID,
+		Device2ID:           pairing.Device2ID,
+		CreatedAt:           pairing.CreatedAt,
+		Enabled:             true,
+		AutoSyncIntervalSec: &intervalSec,
+		AutoSyncSampleCount: &sampleCount,
+		AutoSyncIntervalMs:  &intervalMs,
+		AutoSyncTimeoutSec:  &timeoutSec,
+	}
+
+	if err := op.repository.SavePairing(persistentPairing); err != nil {
+		log.Printf("Failed to save device-initiated pairing %s to DB: %v", pairing.PairingID, err)
+		// Don't keep an in-memory pairing whose ID diverges from the DB
+		if deleteErr := op.hub.DeletePairing(pairing.PairingID); deleteErr != nil {
+			log.Printf("Failed to roll back in-memory pairing %s: %v", pairing.PairingID, deleteErr)
+		}
+		// A concurrent request may have created the pairing in the meantime
+		if existing, lookupErr := restoreSavedPairing(op.hub, op.repository, device1ID, device2ID); lookupErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to save pairing: %w", err)
+	}
+
+	op.restartAutoSync(persistentPairing)
+	return pairing, nil
+}
+
 // restartAutoSync restarts Auto-Sync for a restored pairing
 func (op *PairingOperator) restartAutoSync(pp *models.PersistentPairing) {
-	// Check if Auto-Sync configuration exists
-	if pp.AutoSyncIntervalSec == nil || pp.AutoSyncSampleCount == nil || pp.AutoSyncIntervalMs == nil {
-		log.Printf("No Auto-Sync configuration found for pairing %s, skipping auto-start", pp.PairingID)
+	if !pp.Enabled {
+		log.Printf("Pairing %s is disabled, skipping Auto-Sync auto-start", pp.PairingID)
 		return
 	}
 
-	// Check if Auto-Sync is already running (avoid duplicate start)
-	if op.autoSync.IsRunning(pp.PairingID) {
-		log.Printf("Auto-Sync already running for pairing %s, skipping", pp.PairingID)
+	// Check if Auto-Sync configuration exists
+	config, ok := PersistedAutoSyncConfig(pp, op.config)
+	if !ok {
+		log.Printf("No Auto-Sync configuration found for pairing %s, skipping auto-start", pp.PairingID)
 		return
 	}
 
-	// Create Auto-Sync config from persisted settings
-	config := models.AutoSyncConfig{
-		PairingID:   pp.PairingID,
-		IntervalSec: *pp.AutoSyncIntervalSec,
-		SampleCount: *pp.AutoSyncSampleCount,
-		IntervalMs:  *pp.AutoSyncIntervalMs,
+	// Check if an Auto-Sync job already exists (avoid duplicate start; a paused job stays paused)
+	if op.autoSync.HasJob(pp.PairingID) {
+		log.Printf("Auto-Sync job already exists for pairing %s, skipping", pp.PairingID)
+		return
 	}
 
 	// Start Auto-Sync
@@ -110,6 +192,29 @@ func (op *PairingOperator) restartAutoSync(pp *models.PersistentPairing) {
 		pp.PairingID, config.IntervalSec, config.SampleCount)
 }
 
+// PersistedAutoSyncConfig builds the Auto-Sync config saved with a pairing, falling back to the
+// server defaults for settings older pairings lack. ok is false if no Auto-Sync config was saved.
+func PersistedAutoSyncConfig(pp *models.PersistentPairing, cfg *config.Config) (models.AutoSyncConfig, bool) {
+	if pp.AutoSyncIntervalSec == nil || pp.AutoSyncSampleCount == nil || pp.AutoSyncIntervalMs == nil {
+		return models.AutoSyncConfig{}, false
+	}
+
+	config := models.AutoSyncConfig{
+		PairingID:     pp.PairingID,
+		IntervalSec:   *pp.AutoSyncIntervalSec,
+		SampleCount:   *pp.AutoSyncSampleCount,
+		IntervalMs:    *pp.AutoSyncIntervalMs,
+		TimeoutSec:    cfg.AutoSyncTimeoutSec,
+		MinConfidence: cfg.AutoSyncMinConfidence,
+
+		InitialSyncDelaySec: cfg.AutoSyncInitialSyncDelaySec,
+	}
+	if pp.AutoSyncTimeoutSec != nil {
+		config.TimeoutSec = *pp.AutoSyncTimeoutSec
+	}
+	return config, true
+}
+
 // getOtherDeviceID returns the other device ID in the pairing
 func (op *PairingOperator) getOtherDeviceID(pairing *models.PersistentPairing, myDeviceID string) string {
 	if pairing.Device1ID == myDeviceID {
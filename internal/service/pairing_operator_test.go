@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+// newTestPairingOperator connects psg-001 and watch-001 to a hub without pairing them
+func newTestPairingOperator(t *testing.T, repo Repository) (*PairingOperator, *websocket.Hub, *AutoSyncMonitor) {
+	t.Helper()
+	hub := websocket.NewHub()
+	for _, id := range []string{"psg-001", "watch-001"} {
+		hub.Clients[id] = &websocket.Client{Hub: hub, DeviceID: id, DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 16)}
+	}
+	autoSync := NewAutoSyncMonitor(NewSyncService(hub, repo))
+	t.Cleanup(func() { autoSync.Shutdown(context.Background()) })
+
+	cfg := &config.Config{
+		AutoSyncIntervalSec:         60,
+		AutoSyncSampleCount:         1,
+		AutoSyncIntervalMs:          100,
+		AutoSyncTimeoutSec:          5,
+		AutoSyncInitialSyncDelaySec: 3600,
+	}
+	return NewPairingOperator(hub, repo, autoSync, cfg), hub, autoSync
+}
+
+func TestPairingOperatorCreatePairing(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	op, hub, autoSync := newTestPairingOperator(t, repo)
+
+	pairing, err := op.CreatePairing("psg-001", "watch-001")
+	if err != nil {
+		t.Fatalf("CreatePairing() error = %v", err)
+	}
+
+	saved, err := repo.GetPairingByID(pairing.PairingID)
+	if err != nil {
+		t.Fatalf("GetPairingByID() error = %v", err)
+	}
+	if saved.AutoSyncIntervalSec == nil || *saved.AutoSyncIntervalSec != 60 || !saved.Enabled {
+		t.Errorf("saved pairing = %+v, expected it enabled with the default Auto-Sync settings", saved)
+	}
+	if pairings := hub.GetPairings(); len(pairings) != 1 || pairings[0].PairingID != pairing.PairingID {
+		t.Errorf("GetPairings() = %v, expected only %s", pairings, pairing.PairingID)
+	}
+	if !autoSync.HasJob(pairing.PairingID) {
+		t.Errorf("no Auto-Sync job for %s", pairing.PairingID)
+	}
+}
+
+func TestPairingOperatorCreatePairingReturnsSavedPairing(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	if err := repo.SavePairing(&models.PersistentPairing{
+		PairingID: "pair-123",
+		Device1ID: "psg-001",
+		Device2ID: "watch-001",
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}); err != nil {
+		t.Fatalf("SavePairing() error = %v", err)
+	}
+	op, hub, _ := newTestPairingOperator(t, repo)
+
+	// Both directions resolve to the saved pairing, no second pairing is created
+	for _, devices := range [][2]string{{"watch-001", "psg-001"}, {"psg-001", "watch-001"}} {
+		pairing, err := op.CreatePairing(devices[0], devices[1])
+		if err != nil {
+			t.Fatalf("CreatePairing(%s, %s) error = %v", devices[0], devices[1], err)
+		}
+		if pairing.PairingID != "pair-123" {
+			t.Errorf("CreatePairing(%s, %s) = %s, expected pair-123", devices[0], devices[1], pairing.PairingID)
+		}
+	}
+
+	if pairings := hub.GetPairings(); len(pairings) != 1 || pairings[0].PairingID != "pair-123" {
+		t.Errorf("GetPairings() = %v, expected only pair-123", pairings)
+	}
+	if saved, err := repo.GetAllPairings(); err != nil || len(saved) != 1 {
+		t.Errorf("GetAllPairings() = %d pairings, %v, expected only pair-123", len(saved), err)
+	}
+}
+
+// failingPairingRepository fails every SavePairing, after calling onSave
+type failingPairingRepository struct {
+	*repository.InMemoryRepository
+	onSave func()
+}
+
+func (r *failingPairingRepository) SavePairing(*models.PersistentPairing) error {
+	if r.onSave != nil {
+		r.onSave()
+	}
+	return errors.New("database is locked")
+}
+
+func TestPairingOperatorCreatePairingRollsBackFailedSave(t *testing.T) {
+	repo := &failingPairingRepository{InMemoryRepository: repository.NewInMemoryRepository()}
+	op, hub, autoSync := newTestPairingOperator(t, repo)
+
+	if pairing, err := op.CreatePairing("psg-001", "watch-001"); err == nil {
+		t.Fatalf("CreatePairing() = %s, expected the save error", pairing.PairingID)
+	}
+	if pairings := hub.GetPairings(); len(pairings) != 0 {
+		t.Errorf("GetPairings() = %v, expected the unsaved pairing to be removed", pairings)
+	}
+	if jobs := autoSync.RunningCount(); jobs != 0 {
+		t.Errorf("RunningCount() = %d, expected no Auto-Sync for an unsaved pairing", jobs)
+	}
+}
+
+func TestPairingOperatorCreatePairingReturnsConcurrentlySavedPairing(t *testing.T) {
+	repo := &failingPairingRepository{InMemoryRepository: repository.NewInMemoryRepository()}
+	// Another request saves the pairing of the two devices first
+	repo.onSave = func() {
+		repo.InMemoryRepository.SavePairing(&models.PersistentPairing{
+			PairingID: "pair-123",
+			Device1ID: "watch-001",
+			Device2ID: "psg-001",
+			CreatedAt: time.Now(),
+			Enabled:   true,
+		})
+	}
+	op, hub, autoSync := newTestPairingOperator(t, repo)
+
+	pairing, err := op.CreatePairing("psg-001", "watch-001")
+	if err != nil {
+		t.Fatalf("CreatePairing() error = %v", err)
+	}
+	if pairing.PairingID != "pair-123" {
+		t.Errorf("CreatePairing() = %s, expected the saved pair-123", pairing.PairingID)
+	}
+	if pairings := hub.GetPairings(); len(pairings) != 1 || pairings[0].PairingID != "pair-123" {
+		t.Errorf("GetPairings() = %v, expected only pair-123", pairings)
+	}
+	if jobs := autoSync.RunningCount(); jobs != 0 {
+		t.Errorf("RunningCount() = %d, expected no Auto-Sync started for the unsaved pairing", jobs)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+// newOverviewFixture saves pair-123, connected and restored with two aggregated results, and
+// pair-456, disconnected and never aggregated
+func newOverviewFixture(t *testing.T) *SyncService {
+	repo := repository.NewInMemoryRepository()
+	now := time.Now()
+	for _, pairing := range []*models.PersistentPairing{
+		{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001", CreatedAt: now.Add(-time.Hour), Enabled: true},
+		{PairingID: "pair-456", Device1ID: "psg-002", Device2ID: "watch-002", CreatedAt: now},
+	} {
+		if err := repo.SavePairing(pairing); err != nil {
+			t.Fatalf("SavePairing(%s) error = %v", pairing.PairingID, err)
+		}
+	}
+	for i, offset := range []int64{30, 42} {
+		if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{
+			AggregationID: fmt.Sprintf("agg-%d", i),
+			PairingID:     "pair-123",
+			BestOffset:    offset,
+			Confidence:    0.5 + float64(i)/4,
+			CreatedAt:     now.Add(time.Duration(i-2) * time.Minute).UnixMilli(),
+		}); err != nil {
+			t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+		}
+	}
+
+	hub := websocket.NewHub()
+	for _, id := range []string{"psg-001", "watch-001"} {
+		hub.Clients[id] = &websocket.Client{Hub: hub, DeviceID: id, Send: make(chan []byte, 4)}
+	}
+	hub.Pairings["pair-123"] = &models.Pairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+	return NewSyncService(hub, repo)
+}
+
+// overviewsByID lists the overviews indexed by pairing, failing unless exactly the expected ones are listed
+func overviewsByID(t *testing.T, s *SyncService, autoSync *AutoSyncMonitor) map[string]*models.PairingOverview {
+	t.Helper()
+	overviews, err := s.GetPairingOverviews(autoSync)
+	if err != nil {
+		t.Fatalf("GetPairingOverviews() error = %v", err)
+	}
+	byID := make(map[string]*models.PairingOverview, len(overviews))
+	for _, overview := range overviews {
+		byID[overview.PairingID] = overview
+	}
+	if len(overviews) != 2 || byID["pair-123"] == nil || byID["pair-456"] == nil {
+		t.Fatalf("GetPairingOverviews() = %d overviews, expected pair-123 and pair-456", len(overviews))
+	}
+	return byID
+}
+
+func TestGetPairingOverviews(t *testing.T) {
+	s := newOverviewFixture(t)
+	m := NewAutoSyncMonitor(s)
+	defer m.Shutdown(context.Background())
+	if err := m.StartAutoSync(models.AutoSyncConfig{PairingID: "pair-123", IntervalSec: 3600, InitialSyncDelaySec: models.InitialSyncSkip}); err != nil {
+		t.Fatalf("StartAutoSync() error = %v", err)
+	}
+
+	overviews := overviewsByID(t, s, m)
+
+	synced := overviews["pair-123"]
+	if !synced.Device1Connected || !synced.Device2Connected || !synced.Active || !synced.Enabled {
+		t.Errorf("pair-123 connected, active, enabled = %v/%v, %v, %v, expected all true",
+			synced.Device1Connected, synced.Device2Connected, synced.Active, synced.Enabled)
+	}
+	if synced.AutoSyncStatus != models.AutoSyncStatusRunning || !synced.AutoSyncRunning {
+		t.Errorf("pair-123 AutoSyncStatus, AutoSyncRunning = %q, %v, expected a running job", synced.AutoSyncStatus, synced.AutoSyncRunning)
+	}
+	// Only the latest aggregated result is reported
+	if synced.LastBestOffset == nil || *synced.LastBestOffset != 42 || synced.LastConfidence == nil || *synced.LastConfidence != 0.75 || synced.LastSyncAt == nil {
+		t.Errorf("pair-123 LastBestOffset, LastConfidence, LastSyncAt = %v, %v, %v, expected the newer result", synced.LastBestOffset, synced.LastConfidence, synced.LastSyncAt)
+	}
+
+	unsynced := overviews["pair-456"]
+	if unsynced.Device1Connected || unsynced.Device2Connected || unsynced.Active || unsynced.Enabled {
+		t.Errorf("pair-456 connected, active, enabled = %v/%v, %v, %v, expected all false",
+			unsynced.Device1Connected, unsynced.Device2Connected, unsynced.Active, unsynced.Enabled)
+	}
+	if unsynced.AutoSyncStatus != "" || unsynced.AutoSyncRunning {
+		t.Errorf("pair-456 AutoSyncStatus, AutoSyncRunning = %q, %v, expected no job", unsynced.AutoSyncStatus, unsynced.AutoSyncRunning)
+	}
+	if unsynced.LastSyncAt != nil || unsynced.LastBestOffset != nil || unsynced.LastConfidence != nil {
+		t.Errorf("pair-456 last sync = %v, %v, %v, expected none without aggregations", unsynced.LastSyncAt, unsynced.LastBestOffset, unsynced.LastConfidence)
+	}
+
+	// A paused job is reported, but not as running
+	if err := m.PauseAutoSync("pair-123"); err != nil {
+		t.Fatalf("PauseAutoSync() error = %v", err)
+	}
+	paused := overviewsByID(t, s, m)["pair-123"]
+	if paused.AutoSyncStatus != models.AutoSyncStatusPaused || paused.AutoSyncRunning {
+		t.Errorf("paused AutoSyncStatus, AutoSyncRunning = %q, %v, expected a paused job", paused.AutoSyncStatus, paused.AutoSyncRunning)
+	}
+}
+
+func TestGetPairingOverviewsWithoutAutoSync(t *testing.T) {
+	s := newOverviewFixture(t)
+
+	overviews := overviewsByID(t, s, nil)
+	for id, overview := range overviews {
+		if overview.AutoSyncStatus != "" || overview.AutoSyncRunning {
+			t.Errorf("%s AutoSyncStatus, AutoSyncRunning = %q, %v, expected no auto-sync status", id, overview.AutoSyncStatus, overview.AutoSyncRunning)
+		}
+	}
+	if offset := overviews["pair-123"].LastBestOffset; offset == nil || *offset != 42 {
+		t.Errorf("pair-123 LastBestOffset = %v, expected the latest result without a monitor too", offset)
+	}
+}
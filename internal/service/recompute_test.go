@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestRecomputeAggregation(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+
+	var records []*models.TimeSyncRecord
+	for _, offset := range []int64{100, 102, 98, 101, 99, 160} {
+		rtt := int64(2000)
+		record := &models.TimeSyncRecord{
+			Device1ID:      "psg-001",
+			Device2ID:      "watch-001",
+			TimeDifference: &offset,
+			Device1RTT:     &rtt,
+			Device2RTT:     &rtt,
+			Status:         models.SyncStatusSuccess,
+		}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		records = append(records, record)
+	}
+	source, err := s.AggregateRecords("pair-123", records, models.NTPFilterConfig{TopPercentile: 1})
+	if err != nil {
+		t.Fatalf("AggregateRecords() error = %v", err)
+	}
+
+	// Without a filter the recorded settings are reused, so the result is reproduced
+	recomputed, err := s.RecomputeAggregation(source.AggregationID, &models.RecomputeRequest{})
+	if err != nil {
+		t.Fatalf("RecomputeAggregation() error = %v", err)
+	}
+	if recomputed.Saved || recomputed.Result.AggregationID != "" {
+		t.Errorf("result was saved as %q without save", recomputed.Result.AggregationID)
+	}
+	if recomputed.Result.BestOffset != source.BestOffset || recomputed.Result.TopPercentile != 1 {
+		t.Errorf("BestOffset, TopPercentile = %d, %g, expected %d, 1", recomputed.Result.BestOffset, recomputed.Result.TopPercentile, source.BestOffset)
+	}
+
+	// A new filter saved as a new aggregation leaves the source untouched
+	recomputed, err = s.RecomputeAggregation(source.AggregationID, &models.RecomputeRequest{
+		Filter: &models.NTPFilterConfig{TopPercentile: 1, OutlierMethod: models.OutlierMethodMAD},
+		Save:   true,
+	})
+	if err != nil {
+		t.Fatalf("RecomputeAggregation() error = %v", err)
+	}
+	saved, err := repo.GetAggregatedSyncResult(recomputed.Result.AggregationID)
+	if err != nil {
+		t.Fatalf("GetAggregatedSyncResult() of the saved result error = %v", err)
+	}
+	if saved.OutlierMethod != models.OutlierMethodMAD || len(saved.Measurements) != len(records) {
+		t.Errorf("saved OutlierMethod = %q with %d measurements, expected mad with %d", saved.OutlierMethod, len(saved.Measurements), len(records))
+	}
+	if saved.SmoothedOffset == nil {
+		t.Error("saved result has no SmoothedOffset, expected it to be smoothed like other saved results")
+	}
+	if stored, _ := repo.GetAggregatedSyncResult(source.AggregationID); stored.OutlierMethod != models.OutlierMethodStdDev {
+		t.Errorf("source OutlierMethod = %q, expected it to be unchanged", stored.OutlierMethod)
+	}
+
+	if _, err := s.RecomputeAggregation("agg-missing", &models.RecomputeRequest{}); !errors.Is(err, repository.ErrAggregationNotFound) {
+		t.Errorf("RecomputeAggregation() error = %v, expected ErrAggregationNotFound", err)
+	}
+
+	if err := repo.SaveAggregatedSyncResult(&models.AggregatedSyncResult{AggregationID: "agg-empty", PairingID: "pair-123"}); err != nil {
+		t.Fatalf("SaveAggregatedSyncResult() error = %v", err)
+	}
+	if _, err := s.RecomputeAggregation("agg-empty", &models.RecomputeRequest{}); !errors.Is(err, ErrNoStoredMeasurements) {
+		t.Errorf("RecomputeAggregation() error = %v, expected ErrNoStoredMeasurements", err)
+	}
+}
+
+func TestRecomputeAggregationSavePublishesResult(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+	bus := events.NewEventBus()
+	s.SetEventBus(bus)
+
+	var records []*models.TimeSyncRecord
+	for _, offset := range []int64{100, 102, 98, 101} {
+		rtt := int64(2000)
+		record := &models.TimeSyncRecord{
+			Device1ID:      "watch-001",
+			Device2ID:      "psg-001",
+			TimeDifference: &offset,
+			Device1RTT:     &rtt,
+			Device2RTT:     &rtt,
+			Status:         models.SyncStatusSuccess,
+		}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+		records = append(records, record)
+	}
+	source, err := s.selectBestOffset("group-1", records, models.NTPFilterConfig{})
+	if err != nil {
+		t.Fatalf("selectBestOffset() error = %v", err)
+	}
+	source.MemberDeviceID = "watch-001"
+	if err := s.saveAggregatedResult(source, records); err != nil {
+		t.Fatalf("saveAggregatedResult() error = %v", err)
+	}
+
+	id, ch := bus.Subscribe()
+	defer bus.Unsubscribe(id)
+	recomputed, err := s.RecomputeAggregation(source.AggregationID, &models.RecomputeRequest{Save: true})
+	if err != nil {
+		t.Fatalf("RecomputeAggregation() error = %v", err)
+	}
+	result := recomputed.Result
+	if result.MemberDeviceID != "watch-001" || result.SmoothedOffset == nil {
+		t.Errorf("MemberDeviceID, SmoothedOffset = %q, %v, expected the source member and a smoothed offset", result.MemberDeviceID, result.SmoothedOffset)
+	}
+
+	select {
+	case event := <-ch:
+		published, ok := event.Data.(*models.AggregatedSyncResult)
+		if event.Type != models.EventTypeAggregatedResult || !ok || published.AggregationID != result.AggregationID {
+			t.Errorf("event = %s %+v, expected the saved result %s", event.Type, event.Data, result.AggregationID)
+		}
+	case <-time.After(time.Second):
+		t.Error("no aggregated result event published for the saved recomputation")
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/repository"
+)
+
+// NewRepository opens the storage backend selected by cfg.DBDriver
+func NewRepository(cfg *config.Config) (Repository, error) {
+	switch cfg.DBDriver {
+	case config.DBDriverSQLite, "":
+		repo, err := repository.NewSQLiteRepository(cfg.DBPath, time.Duration(cfg.SQLiteBusyTimeoutMs)*time.Millisecond)
+		if err != nil {
+			return nil, err // Avoid returning a typed nil inside the interface
+		}
+		return repo, nil
+	case config.DBDriverPostgres:
+		repo, err := repository.NewPostgresRepository(cfg.DBDSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case config.DBDriverMemory:
+		log.Printf("Using in-memory storage: data will be lost on restart")
+		return repository.NewInMemoryRepository(), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER: %s", cfg.DBDriver)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"time-sync-server/config"
+)
+
+// RetentionCleaner periodically deletes sync records (and the aggregations that only
+// referenced them) older than the configured retention window
+type RetentionCleaner struct {
+	repository Repository
+	config     *config.Config
+	cancelFunc context.CancelFunc
+	mu         sync.Mutex
+}
+
+// NewRetentionCleaner creates a new RetentionCleaner instance
+func NewRetentionCleaner(repo Repository, cfg *config.Config) *RetentionCleaner {
+	return &RetentionCleaner{
+		repository: repo,
+		config:     cfg,
+	}
+}
+
+// Start starts the background cleanup loop. It does nothing when retention is disabled.
+func (c *RetentionCleaner) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancelFunc != nil {
+		return // Already running
+	}
+
+	if c.config.RetentionDays <= 0 {
+		log.Printf("Retention cleanup disabled (records are kept forever)")
+		return
+	}
+
+	interval := time.Duration(c.config.RetentionCheckIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancelFunc = cancel
+
+	go c.run(ctx, interval)
+
+	log.Printf("Retention cleanup started (retention: %d days, check interval: %v)", c.config.RetentionDays, interval)
+}
+
+// Shutdown stops the background cleanup loop
+func (c *RetentionCleaner) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancelFunc != nil {
+		c.cancelFunc()
+		c.cancelFunc = nil
+		log.Printf("Retention cleanup stopped")
+	}
+}
+
+// run is the background goroutine that runs a cleanup on start and on every tick
+func (c *RetentionCleaner) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c.Cleanup()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Cleanup()
+		}
+	}
+}
+
+// Cleanup deletes everything older than the retention window once
+func (c *RetentionCleaner) Cleanup() {
+	cutoff := time.Now().AddDate(0, 0, -c.config.RetentionDays)
+
+	deleted, err := c.repository.DeleteRecordsOlderThan(cutoff)
+	if err != nil {
+		log.Printf("Retention cleanup failed: %v", err)
+		return
+	}
+
+	log.Printf("Retention cleanup deleted %d row(s) older than %s", deleted, cutoff.Format(time.RFC3339))
+}
@@ -0,0 +1,47 @@
+package service
+
+import "sync"
+
+// flightCall is an in-flight or completed singleFlight call
+type flightCall[T any] struct {
+	done  chan struct{}
+	dups  int // Callers waiting on this call
+	value T
+	err   error
+}
+
+// singleFlight collapses concurrent calls with the same key into one execution.
+// The zero value is ready to use.
+type singleFlight[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// Do runs fn for key unless a call for key is already running, in which case it waits for
+// that call and returns its result. shared reports whether the result came from another caller's call.
+func (g *singleFlight[T]) Do(key string, fn func() (T, error)) (value T, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		<-call.done
+		return call.value, true, call.err
+	}
+
+	call := &flightCall[T]{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+	return call.value, false, call.err
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlightSharesConcurrentCalls(t *testing.T) {
+	var g singleFlight[int]
+	var executions int32
+	release := make(chan struct{})
+
+	fn := func() (int, error) {
+		atomic.AddInt32(&executions, 1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	var sharedCount int32
+	results := make([]int, callers)
+
+	// First caller starts the call; the rest join while it is blocked
+	started := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		results[0], _, _ = g.Do("pair-1", fn)
+	}()
+	<-started
+	waitForCall(t, &g, "pair-1")
+
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var shared bool
+			results[i], shared, _ = g.Do("pair-1", fn)
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}(i)
+	}
+
+	// Release only once every joiner is waiting on the in-flight call
+	waitForDups(t, &g, "pair-1", callers-1)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&executions); got != 1 {
+		t.Errorf("expected fn to run once, ran %d times", got)
+	}
+	if got := atomic.LoadInt32(&sharedCount); got != callers-1 {
+		t.Errorf("expected %d shared results, got %d", callers-1, got)
+	}
+	for i, r := range results {
+		if r != 42 {
+			t.Errorf("caller %d: expected 42, got %d", i, r)
+		}
+	}
+}
+
+func TestSingleFlightSeparatesKeysAndSequentialCalls(t *testing.T) {
+	var g singleFlight[string]
+	errSync := errors.New("sync failed")
+
+	if _, shared, err := g.Do("pair-1", func() (string, error) { return "", errSync }); !errors.Is(err, errSync) || shared {
+		t.Fatalf("expected unshared errSync, got shared=%v err=%v", shared, err)
+	}
+
+	// A completed call is not reused
+	v, shared, err := g.Do("pair-1", func() (string, error) { return "second", nil })
+	if err != nil || shared || v != "second" {
+		t.Fatalf("expected fresh call, got v=%q shared=%v err=%v", v, shared, err)
+	}
+
+	// Different keys run independently even while one is in flight
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		g.Do("pair-1", func() (string, error) { <-release; return "", nil })
+		close(done)
+	}()
+	waitForCall(t, &g, "pair-1")
+
+	v, shared, _ = g.Do("pair-2", func() (string, error) { return "other", nil })
+	if shared || v != "other" {
+		t.Errorf("expected independent call for pair-2, got v=%q shared=%v", v, shared)
+	}
+	close(release)
+	<-done
+}
+
+// waitForCall blocks until a call for key is in flight
+func waitForCall[T any](t *testing.T, g *singleFlight[T], key string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		_, ok := g.calls[key]
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("call for %s never started", key)
+}
+
+// waitForDups blocks until n callers are waiting on the in-flight call for key
+func waitForDups[T any](t *testing.T, g *singleFlight[T], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		call, ok := g.calls[key]
+		dups := 0
+		if ok {
+			dups = call.dups
+		}
+		g.mu.Unlock()
+		if dups >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d callers waiting on %s", n, key)
+}
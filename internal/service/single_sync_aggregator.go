@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/models"
+)
+
+// SingleSyncAggregator periodically combines single-sync records (POST /api/sync/:pairingId)
+// into aggregated results using the NTP selector. It only processes pairings that opted in.
+type SingleSyncAggregator struct {
+	syncService *SyncService
+	config      *config.Config
+	cancelFunc  context.CancelFunc
+	mu          sync.Mutex
+}
+
+// NewSingleSyncAggregator creates a new SingleSyncAggregator instance
+func NewSingleSyncAggregator(syncService *SyncService, cfg *config.Config) *SingleSyncAggregator {
+	return &SingleSyncAggregator{
+		syncService: syncService,
+		config:      cfg,
+	}
+}
+
+// Start starts the background aggregation loop
+func (a *SingleSyncAggregator) Start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelFunc != nil {
+		return // Already running
+	}
+
+	interval := time.Duration(a.config.AutoAggregateCheckIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = 60 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelFunc = cancel
+
+	go a.run(ctx, interval)
+
+	log.Printf("Single-sync aggregator started (check interval: %v)", interval)
+}
+
+// Shutdown stops the background aggregation loop
+func (a *SingleSyncAggregator) Shutdown() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelFunc != nil {
+		a.cancelFunc()
+		a.cancelFunc = nil
+		log.Printf("Single-sync aggregator stopped")
+	}
+}
+
+// run is the background goroutine that aggregates opted-in pairings on every tick
+func (a *SingleSyncAggregator) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.aggregateAll()
+		}
+	}
+}
+
+// aggregateAll aggregates pending single-sync records for every opted-in pairing
+func (a *SingleSyncAggregator) aggregateAll() {
+	pairings, err := a.syncService.repo.GetAllPairings()
+	if err != nil {
+		log.Printf("Single-sync aggregator failed to load pairings: %v", err)
+		return
+	}
+
+	for _, pairing := range pairings {
+		if !pairing.AutoAggregateEnabled {
+			continue
+		}
+		if _, err := a.AggregatePairing(pairing); err != nil {
+			log.Printf("Single-sync aggregation failed for pairing %s: %v", pairing.PairingID, err)
+		}
+	}
+}
+
+// AggregatePairing aggregates the pairing's single-sync records within its window.
+// Returns nil without error when fewer than the minimum number of records are available.
+func (a *SingleSyncAggregator) AggregatePairing(pairing *models.PersistentPairing) (*models.AggregatedSyncResult, error) {
+	windowSec := a.config.AutoAggregateWindowSec
+	if pairing.AutoAggregateWindowSec != nil {
+		windowSec = *pairing.AutoAggregateWindowSec
+	}
+	minCount := a.config.AutoAggregateMinCount
+	if pairing.AutoAggregateMinCount != nil {
+		minCount = *pairing.AutoAggregateMinCount
+	}
+
+	since := time.Now().Add(-time.Duration(windowSec) * time.Second)
+	// Records list the devices in the order the hub oriented them against the reference device type
+	device1ID, device2ID := a.syncService.hub.OrientPairing(pairing.Device1ID, pairing.Device2ID)
+	records, err := a.syncService.repo.GetUnaggregatedTimeSyncRecords(device1ID, device2ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 || len(records) < minCount {
+		return nil, nil
+	}
+
+	log.Printf("Aggregating %d single-sync record(s) for pairing %s", len(records), pairing.PairingID)
+
+	return a.syncService.AggregateRecords(pairing.PairingID, records, models.NTPFilterConfig{})
+}
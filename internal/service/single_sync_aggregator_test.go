@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"time-sync-server/config"
+	"time-sync-server/internal/models"
+	"time-sync-server/internal/repository"
+	"time-sync-server/internal/websocket"
+)
+
+func TestSingleSyncAggregatorAggregatePairing(t *testing.T) {
+	repo := repository.NewInMemoryRepository()
+	s := NewSyncService(websocket.NewHub(), repo)
+	a := NewSingleSyncAggregator(s, &config.Config{AutoAggregateWindowSec: 300, AutoAggregateMinCount: 3})
+	pairing := &models.PersistentPairing{PairingID: "pair-123", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+	save := func(source string, offset int64) {
+		t.Helper()
+		rtt := int64(2000)
+		record := &models.TimeSyncRecord{
+			Device1ID:      "psg-001",
+			Device2ID:      "watch-001",
+			TimeDifference: &offset,
+			Device1RTT:     &rtt,
+			Device2RTT:     &rtt,
+			Status:         models.SyncStatusSuccess,
+			Source:         source,
+			CreatedAt:      time.Now().UnixMilli(),
+		}
+		if err := repo.SaveTimeSyncRecord(record); err != nil {
+			t.Fatalf("SaveTimeSyncRecord() error = %v", err)
+		}
+	}
+
+	// Multi-sync samples awaiting their own aggregation do not count towards the minimum
+	save(models.RecordSourceSingle, 100)
+	save(models.RecordSourceSingle, 102)
+	for i := 0; i < 5; i++ {
+		save(models.RecordSourceMulti, 500)
+	}
+	result, err := a.AggregatePairing(pairing)
+	if err != nil || result != nil {
+		t.Fatalf("AggregatePairing() = %v, %v, expected nothing below the minimum count", result, err)
+	}
+
+	save(models.RecordSourceSingle, 101)
+	result, err = a.AggregatePairing(pairing)
+	if err != nil || result == nil {
+		t.Fatalf("AggregatePairing() = %v, %v, expected an aggregation", result, err)
+	}
+	if len(result.Measurements) != 3 || result.BestOffset < 100 || result.BestOffset > 102 {
+		t.Errorf("TotalMeasurements, BestOffset = %d, %d, expected 3 single-sync records around 101", len(result.Measurements), result.BestOffset)
+	}
+
+	// Aggregated records are linked and not picked up again
+	result, err = a.AggregatePairing(pairing)
+	if err != nil || result != nil {
+		t.Errorf("second AggregatePairing() = %v, %v, expected nothing left to aggregate", result, err)
+	}
+
+	// The pairing's own settings override the server defaults
+	minCount := 1
+	pairing.AutoAggregateMinCount = &minCount
+	save(models.RecordSourceSingle, 99)
+	result, err = a.AggregatePairing(pairing)
+	if err != nil || result == nil || len(result.Measurements) != 1 {
+		t.Errorf("AggregatePairing() with min_count 1 = %+v, %v, expected one record aggregated", result, err)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// inFlightSyncs tracks the cancel functions of running multi-syncs by pairing ID,
+// so deleting a pairing can stop the syncs still sampling it. The zero value is ready to use.
+type inFlightSyncs struct {
+	mu      sync.Mutex
+	nextID  uint64
+	cancels map[string]map[uint64]context.CancelCauseFunc
+}
+
+// track derives a cancellable context for a sync of pairingID.
+// The returned release func must be called when the sync finishes.
+func (f *inFlightSyncs) track(ctx context.Context, pairingID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	f.mu.Lock()
+	if f.cancels == nil {
+		f.cancels = make(map[string]map[uint64]context.CancelCauseFunc)
+	}
+	if f.cancels[pairingID] == nil {
+		f.cancels[pairingID] = make(map[uint64]context.CancelCauseFunc)
+	}
+	f.nextID++
+	id := f.nextID
+	f.cancels[pairingID][id] = cancel
+	f.mu.Unlock()
+
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.cancels[pairingID], id)
+		if len(f.cancels[pairingID]) == 0 {
+			delete(f.cancels, pairingID)
+		}
+		f.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel cancels every running sync of pairingID with cause and returns how many there were
+func (f *inFlightSyncs) cancel(pairingID string, cause error) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cancels := f.cancels[pairingID]
+	for _, cancel := range cancels {
+		cancel(cause)
+	}
+	return len(cancels)
+}
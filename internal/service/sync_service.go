@@ -1,39 +1,172 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"time-sync-server/internal/algorithms"
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/logging"
 	"time-sync-server/internal/models"
 	"time-sync-server/internal/repository"
 	"time-sync-server/internal/websocket"
 )
 
-// Repository interface for database operations
+// Repository interface for database operations.
+// Implemented by repository.SQLiteRepository, repository.PostgresRepository and repository.InMemoryRepository.
 type Repository interface {
+	// Pairings
 	SavePairing(pairing *models.PersistentPairing) error
 	GetPairingByID(pairingID string) (*models.PersistentPairing, error)
 	GetPairingsByDeviceID(deviceID string) ([]*models.PersistentPairing, error)
 	GetPairingByDevices(device1ID, device2ID string) (*models.PersistentPairing, error)
 	DeletePairing(pairingID string) error
 	GetAllPairings() ([]*models.PersistentPairing, error)
+	UpdatePairingAutoAggregation(pairingID string, enabled bool, windowSec, minCount *int) error
+	UpdatePairingAutoSync(pairingID string, intervalSec, sampleCount, intervalMs, timeoutSec int) error
+	UpdatePairingEnabled(pairingID string, enabled bool) error
+	SaveOffsetOverride(override *models.OffsetOverride) error
+	GetOffsetOverride(pairingID string) (*models.OffsetOverride, error)
+	DeleteOffsetOverride(pairingID string) error
+
+	// Group pairings
+	SaveGroupPairing(pairing *models.GroupPairing) error
+	GetGroupPairingByID(pairingID string) (*models.GroupPairing, error)
+	GetGroupPairingsByDeviceID(deviceID string) ([]*models.GroupPairing, error)
+	GetAllGroupPairings() ([]*models.GroupPairing, error)
+	DeleteGroupPairing(pairingID string) error
+
+	// Devices
+	SaveDeviceInfo(info *models.DeviceInfo) error
+	GetDeviceInfo(deviceID string) (*models.DeviceInfo, error)
+	SaveDeviceEvent(event *models.DeviceEvent) error
+	GetDeviceEvents(deviceID string, startTime, endTime *time.Time, limit int) ([]*models.DeviceEvent, error)
+
+	// Time sync records
 	SaveTimeSyncRecord(record *models.TimeSyncRecord) error
+	SaveTimeSyncRecords(records []*models.TimeSyncRecord) error
+	GetTimeSyncRecord(id int64) (*models.TimeSyncRecord, error)
+	DeleteTimeSyncRecord(id int64) error
+	GetTimeSyncRecords(limit, offset int) ([]*models.TimeSyncRecord, error)
+	GetTimeSyncRecordsByDeviceID(deviceID string, limit, offset int) ([]*models.TimeSyncRecord, error)
+	GetTimeSyncRecordsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.TimeSyncRecord, error)
+	GetTimeSyncRecordsFiltered(filter models.RecordFilter, limit, offset int) ([]*models.TimeSyncRecord, error)
+	GetTimeSyncRecordsAfterCursor(filter models.RecordFilter, cursor *models.RecordCursor, limit int) ([]*models.TimeSyncRecord, error)
+	GetUnaggregatedTimeSyncRecords(device1ID, device2ID string, since time.Time) ([]*models.TimeSyncRecord, error)
+	ForEachTimeSyncRecord(filter models.ExportFilter, fn func(*models.TimeSyncRecord) error) error
+	DeleteRecordsOlderThan(t time.Time) (int64, error)
+
+	// Aggregated results
 	SaveAggregatedSyncResult(result *models.AggregatedSyncResult) error
+	GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error)
+	DeleteAggregatedSyncResult(aggregationID string, deleteRecords bool) error
+	DeletePairingRecords(pairingID string) (*models.PairingRecordsDeletion, error)
+	GetLatestAggregatedSyncResult(pairingID string) (*models.AggregatedSyncResult, error)
+	GetLatestMemberAggregatedSyncResult(pairingID, memberDeviceID string) (*models.AggregatedSyncResult, error)
+	GetLatestAggregatedSyncResults(pairingIDs []string) ([]*models.AggregatedSyncResult, error)
+	GetAggregatedSyncResultsByPairing(pairingID string, limit, offset int) ([]*models.AggregatedSyncResult, error)
+	GetAggregatedSyncResultsByPairingSince(pairingID string, since time.Time) ([]*models.AggregatedSyncResult, error)
+	GetAllAggregatedSyncResults(limit, offset int) ([]*models.AggregatedSyncResult, error)
+	GetAggregatedSyncResultsByTimeRange(startTime, endTime time.Time, limit, offset int) ([]*models.AggregatedSyncResult, error)
+	GetAggregatedSyncResultsFiltered(filter models.AggregatedResultFilter, limit, offset int) ([]*models.AggregatedSyncResult, error)
+	ForEachAggregatedSyncResult(filter models.ExportFilter, fn func(*models.AggregatedSyncResult) error) error
+	GetAggregatedOffsetBuckets(pairingID string, startTime, endTime *time.Time, bucketMs int64) ([]*models.OffsetBucket, error)
+
+	// Statistics
+	GetDeviceTypeStats() ([]*models.DeviceTypeStats, error)
+	GetDeviceSyncStats(deviceID string, startTime, endTime *time.Time) (*models.DeviceSyncStats, error)
+
+	Ping() error // Checks that the storage backend is reachable
+	Close() error
 }
 
+var (
+	_ Repository = (*repository.SQLiteRepository)(nil)
+	_ Repository = (*repository.PostgresRepository)(nil)
+	_ Repository = (*repository.InMemoryRepository)(nil)
+)
+
 type SyncService struct {
-	hub  *websocket.Hub
-	repo *repository.SQLiteRepository
+	hub    *websocket.Hub
+	repo   Repository
+	events *events.EventBus // Optional, nil disables event publishing
+	alerts *OffsetAlerter   // Optional, nil disables offset alerts
+
+	// In-flight single syncs by pairing ID, see RequestTimeSync
+	syncFlight singleFlight[*models.TimeSyncRecord]
+
+	// Cancel functions of running multi-syncs, see DeletePairing
+	multiSyncs inFlightSyncs
+
+	// Whether PARTIAL and FAILED records are saved, see SetPersistFailedSamples
+	persistFailedSamples bool
+
+	// Weight of a new BestOffset in the pairing's smoothed offset, see SetOffsetSmoothingAlpha
+	smoothingAlpha float64
+
+	logger *slog.Logger // nil = slog.Default()
 }
 
-func NewSyncService(hub *websocket.Hub, repo *repository.SQLiteRepository) *SyncService {
+func NewSyncService(hub *websocket.Hub, repo Repository) *SyncService {
 	return &SyncService{
-		hub:  hub,
-		repo: repo,
+		hub:                  hub,
+		repo:                 repo,
+		persistFailedSamples: true,
+		smoothingAlpha:       DefaultOffsetSmoothingAlpha,
+	}
+}
+
+// DefaultOffsetSmoothingAlpha is the weight of a new BestOffset in SmoothedOffset when none is configured
+const DefaultOffsetSmoothingAlpha = 0.3
+
+// SetEventBus sets the bus new aggregated results are published to
+func (s *SyncService) SetEventBus(bus *events.EventBus) {
+	s.events = bus
+}
+
+// SetOffsetAlerter sets the alerter checked after every saved aggregated result
+// (multi-sync, group multi-sync, auto-sync and auto-aggregation)
+func (s *SyncService) SetOffsetAlerter(alerter *OffsetAlerter) {
+	s.alerts = alerter
+}
+
+// SetPersistFailedSamples sets whether PARTIAL and FAILED records are saved (default true).
+// When disabled they are still returned and counted in aggregated results, but never stored,
+// so they have no record ID and are not linked to their aggregation.
+func (s *SyncService) SetPersistFailedSamples(persist bool) {
+	s.persistFailedSamples = persist
+}
+
+// SetOffsetSmoothingAlpha sets the weight (0, 1] of a new aggregation's BestOffset in the
+// pairing's SmoothedOffset; 1 disables smoothing. 0 uses DefaultOffsetSmoothingAlpha.
+func (s *SyncService) SetOffsetSmoothingAlpha(alpha float64) {
+	if alpha <= 0 {
+		alpha = DefaultOffsetSmoothingAlpha
+	}
+	s.smoothingAlpha = alpha
+}
+
+// shouldPersist reports whether record is saved to the database
+func (s *SyncService) shouldPersist(record *models.TimeSyncRecord) bool {
+	return s.persistFailedSamples || record.Status == models.SyncStatusSuccess
+}
+
+// SetLogger sets the structured logger for sync flows. nil uses slog.Default().
+func (s *SyncService) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+func (s *SyncService) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
 	}
+	return slog.Default()
 }
 
 // Device Management
@@ -41,11 +174,62 @@ func (s *SyncService) GetConnectedDevices() []*models.Device {
 	return s.hub.GetConnectedDevices()
 }
 
+// ProbeRTT measures a device's round trip time on demand (microseconds)
+func (s *SyncService) ProbeRTT(deviceID string) (int64, error) {
+	return s.hub.ProbeRTT(deviceID)
+}
+
 // Pairing Management
 func (s *SyncService) GetPairings() []*models.Pairing {
 	return s.hub.GetPairings()
 }
 
+// GetPairingOverviews lists all persistent pairings with their connection state, auto-sync status
+// and latest aggregated result, so clients do not need one call per pairing for each.
+// autoSync may be nil, in which case no auto-sync status is reported.
+func (s *SyncService) GetPairingOverviews(autoSync *AutoSyncMonitor) ([]*models.PairingOverview, error) {
+	pairings, err := s.repo.GetAllPairings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pairings: %w", err)
+	}
+
+	overviews := make([]*models.PairingOverview, 0, len(pairings))
+	for _, pairing := range pairings {
+		overview := &models.PairingOverview{
+			PairingID:        pairing.PairingID,
+			Device1ID:        pairing.Device1ID,
+			Device2ID:        pairing.Device2ID,
+			CreatedAt:        pairing.CreatedAt,
+			Device1Connected: s.hub.IsDeviceConnected(pairing.Device1ID),
+			Device2Connected: s.hub.IsDeviceConnected(pairing.Device2ID),
+			Active:           s.hub.IsPairingRestored(pairing.PairingID),
+			Enabled:          pairing.Enabled,
+		}
+
+		if autoSync != nil {
+			if job, err := autoSync.GetStatus(pairing.PairingID); err == nil {
+				overview.AutoSyncStatus = job.Status
+				overview.AutoSyncRunning = job.Status == models.AutoSyncStatusRunning
+			}
+		}
+
+		latest, err := s.repo.GetAggregatedSyncResultsByPairing(pairing.PairingID, 1, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest aggregated result for pairing %s: %w", pairing.PairingID, err)
+		}
+		if len(latest) > 0 {
+			lastSyncAt := time.UnixMilli(latest[0].CreatedAt)
+			overview.LastSyncAt = &lastSyncAt
+			overview.LastBestOffset = &latest[0].BestOffset
+			overview.LastConfidence = &latest[0].Confidence
+		}
+
+		overviews = append(overviews, overview)
+	}
+
+	return overviews, nil
+}
+
 func (s *SyncService) CreatePairing(device1ID, device2ID string) (*models.Pairing, error) {
 	if device1ID == device2ID {
 		return nil, fmt.Errorf("cannot pair device with itself")
@@ -53,24 +237,140 @@ func (s *SyncService) CreatePairing(device1ID, device2ID string) (*models.Pairin
 	return s.hub.CreatePairing(device1ID, device2ID)
 }
 
+// RestoreSavedPairing returns the saved pairing of two devices (in either order), restored in the hub
+// under its persisted ID. Returns ErrPairingNotFound if the devices are not paired yet.
+func (s *SyncService) RestoreSavedPairing(device1ID, device2ID string) (*models.Pairing, error) {
+	return restoreSavedPairing(s.hub, s.repo, device1ID, device2ID)
+}
+
+// restoreSavedPairing is the duplicate check shared by POST /api/pairings and PAIR_REQUEST. The DB is
+// authoritative, so a new pairing is only created when this returns ErrPairingNotFound.
+func restoreSavedPairing(hub *websocket.Hub, repo Repository, device1ID, device2ID string) (*models.Pairing, error) {
+	saved, err := repo.GetPairingByDevices(device1ID, device2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pairing := &models.Pairing{
+		PairingID: saved.PairingID,
+		Device1ID: saved.Device1ID,
+		Device2ID: saved.Device2ID,
+		CreatedAt: saved.CreatedAt,
+	}
+	if err := hub.RestorePairing(pairing); err != nil {
+		return nil, err
+	}
+	return pairing, nil
+}
+
+// DeletePairing deletes a pairing from the hub and cancels its running multi-syncs
 func (s *SyncService) DeletePairing(pairingID string) error {
-	return s.hub.DeletePairing(pairingID)
+	if err := s.hub.DeletePairing(pairingID); err != nil {
+		return err
+	}
+	s.cancelMultiSyncs(pairingID, "deleted")
+	return nil
+}
+
+// Group Pairing Management
+func (s *SyncService) CreateGroupPairing(deviceIDs []string, referenceDeviceID string) (*models.GroupPairing, error) {
+	return s.hub.CreateGroupPairing(deviceIDs, referenceDeviceID)
+}
+
+// DeleteGroupPairing deletes a group pairing from the hub and cancels its running multi-syncs
+func (s *SyncService) DeleteGroupPairing(pairingID string) error {
+	if err := s.hub.DeleteGroupPairing(pairingID); err != nil {
+		return err
+	}
+	s.cancelMultiSyncs(pairingID, "deleted")
+	return nil
+}
+
+// cancelMultiSyncs stops the multi-syncs still sampling a pairing that was deleted or disabled
+func (s *SyncService) cancelMultiSyncs(pairingID, reason string) {
+	cause := fmt.Errorf("%w: pairing %s was %s", context.Canceled, pairingID, reason)
+	if n := s.multiSyncs.cancel(pairingID, cause); n > 0 {
+		log.Printf("Cancelled %d in-flight multi-sync(s) for %s pairing %s", n, reason, pairingID)
+	}
+}
+
+// SetPairingEnabled enables or disables syncing for a pairing and returns the updated pairing.
+// A disabled pairing keeps its configuration and history, but single syncs, multi-syncs and
+// auto-sync fail with PairingDisabledError until it is enabled again.
+func (s *SyncService) SetPairingEnabled(pairingID string, enabled bool) (*models.PersistentPairing, error) {
+	if err := s.repo.UpdatePairingEnabled(pairingID, enabled); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		s.cancelMultiSyncs(pairingID, "disabled")
+	}
+	return s.repo.GetPairingByID(pairingID)
+}
+
+// PairingDisabledError is returned when a sync is requested for a disabled pairing
+type PairingDisabledError struct {
+	PairingID string
+}
+
+func (e *PairingDisabledError) Error() string {
+	return "pairing disabled: " + e.PairingID
+}
+
+// checkPairingEnabled fails with PairingDisabledError if syncing is disabled for the pairing.
+// Pairings that are not persisted are left to the hub to accept or reject.
+func (s *SyncService) checkPairingEnabled(pairingID string) error {
+	pairing, err := s.repo.GetPairingByID(pairingID)
+	if errors.Is(err, repository.ErrPairingNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check pairing %s: %w", pairingID, err)
+	}
+	if !pairing.Enabled {
+		return &PairingDisabledError{PairingID: pairingID}
+	}
+	return nil
 }
 
 // Time Synchronization
+
+// RequestTimeSync performs a single time sync for a pairing and saves the record.
+// Only one sync per pairing runs at a time: a call made while another is in flight for the
+// same pairing does not send its own TIME_REQUESTs but waits and returns the same record
+// (saved once) or the same error.
 func (s *SyncService) RequestTimeSync(pairingID string) (*models.TimeSyncRecord, error) {
-	// Request time sync with 5 second timeout
-	record, err := s.hub.RequestTimeSync(pairingID, 5*time.Second)
-	if err != nil {
+	if err := s.checkPairingEnabled(pairingID); err != nil {
 		return nil, err
 	}
 
-	// Save to database
-	if err := s.repo.SaveTimeSyncRecord(record); err != nil {
-		return nil, fmt.Errorf("failed to save sync record: %w", err)
-	}
+	record, shared, err := s.syncFlight.Do(pairingID, func() (*models.TimeSyncRecord, error) {
+		correlationID := logging.NewCorrelationID()
+		logger := s.log().With(logging.KeyCorrelationID, correlationID, logging.KeyPairingID, pairingID)
+		logger.Info("time sync requested")
 
-	return record, nil
+		// Request time sync with 5 second timeout
+		record, err := s.hub.RequestTimeSync(pairingID, 5*time.Second, correlationID)
+		if err != nil {
+			logger.Warn("time sync failed", "error", err)
+			return nil, err
+		}
+
+		// Save to database, as a single-sync record that auto-aggregation may pick up
+		record.Source = models.RecordSourceSingle
+		if s.shouldPersist(record) {
+			if err := s.repo.SaveTimeSyncRecord(record); err != nil {
+				logger.Error("failed to save sync record", "error", err)
+				return nil, fmt.Errorf("failed to save sync record: %w", err)
+			}
+			logger.Debug("sync record saved", "record_id", record.ID)
+		}
+
+		return record, nil
+	})
+	if shared {
+		s.log().Info("time sync joined an in-flight sync", logging.KeyPairingID, pairingID)
+	}
+	return record, err
 }
 
 // Sync History
@@ -78,6 +378,60 @@ func (s *SyncService) GetSyncRecord(id int64) (*models.TimeSyncRecord, error) {
 	return s.repo.GetTimeSyncRecord(id)
 }
 
+// AnalyzeSyncRecord loads a record and runs the per-sample math of the NTP selector on it, so
+// the raw and adjusted offsets can be compared with their intermediate values
+func (s *SyncService) AnalyzeSyncRecord(id int64) (*models.SyncRecordAnalysis, error) {
+	record, err := s.repo.GetTimeSyncRecord(id)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &models.SyncRecordAnalysis{
+		RecordID:           record.ID,
+		Status:             record.Status,
+		ServerRequestTime:  record.ServerRequestTime,
+		ServerResponseTime: record.ServerResponseTime,
+		Device1ID:          record.Device1ID,
+		Device1Timestamp:   record.Device1Timestamp,
+		Device1RTT:         record.Device1RTT,
+		Device2ID:          record.Device2ID,
+		Device2Timestamp:   record.Device2Timestamp,
+		Device2RTT:         record.Device2RTT,
+		RawOffset:          record.TimeDifference,
+		OffsetCompensated:  record.OffsetCompensated,
+	}
+	if record.Device1RTT != nil {
+		delay := algorithms.OneWayDelay(*record.Device1RTT)
+		analysis.Device1OneWayDelay = &delay
+	}
+	if record.Device2RTT != nil {
+		delay := algorithms.OneWayDelay(*record.Device2RTT)
+		analysis.Device2OneWayDelay = &delay
+	}
+
+	// Both devices answered with RTTs: the record is a sample the selector can use
+	if m := models.NewSyncMeasurementFromRecord(record); m != nil && record.TimeDifference != nil {
+		totalRTT := m.ResponseTime1 + m.ResponseTime2
+		rttDifference := m.ResponseTime1 - m.ResponseTime2
+		if rttDifference < 0 {
+			rttDifference = -rttDifference
+		}
+		analysis.TotalRTT, analysis.RTTDifference = &totalRTT, &rttDifference
+
+		if !record.OffsetCompensated {
+			correction := *analysis.Device1OneWayDelay - *analysis.Device2OneWayDelay
+			analysis.DelayCorrection = &correction
+		}
+		adjusted := algorithms.AdjustOffset(record)
+		analysis.AdjustedOffset = &adjusted
+	}
+	return analysis, nil
+}
+
+func (s *SyncService) DeleteSyncRecord(id int64) error {
+	return s.repo.DeleteTimeSyncRecord(id)
+}
+
 func (s *SyncService) GetSyncRecords(limit, offset int) ([]*models.TimeSyncRecord, error) {
 	if limit <= 0 {
 		limit = 50
@@ -108,11 +462,58 @@ func (s *SyncService) GetSyncRecordsByTimeRange(startTime, endTime time.Time, li
 	return s.repo.GetTimeSyncRecordsByTimeRange(startTime, endTime, limit, offset)
 }
 
+// GetSyncRecordsPage retrieves the page of sync records matching the filter after cursor (nil for the first page).
+// Unlike offset pagination, the cost of a page does not grow with its depth.
+func (s *SyncService) GetSyncRecordsPage(filter models.RecordFilter, cursor *models.RecordCursor, limit int) (*models.RecordPage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	records, err := s.repo.GetTimeSyncRecordsAfterCursor(filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.RecordPage{Records: records}
+	if page.Records == nil {
+		page.Records = []*models.TimeSyncRecord{}
+	}
+	if len(records) == limit {
+		last := records[len(records)-1]
+		page.NextCursor = models.RecordCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+// GetSyncRecordsFiltered retrieves sync records matching all set fields of the filter
+func (s *SyncService) GetSyncRecordsFiltered(filter models.RecordFilter, limit, offset int) ([]*models.TimeSyncRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	return s.repo.GetTimeSyncRecordsFiltered(filter, limit, offset)
+}
+
 // RequestMultipleTimeSyncs performs NTP-style multi-sampling synchronization
-// It takes multiple measurements and applies NTP selection algorithm to find the best offset
-func (s *SyncService) RequestMultipleTimeSyncs(req *models.MultiSyncRequest) (*models.AggregatedSyncResult, error) {
-	// Apply default values
-	if req.SampleCount == 0 || req.SampleCount > 20 {
+// It takes multiple measurements and applies NTP selection algorithm to find the best offset.
+// With req.DryRun nothing is saved, published or alerted and the result has no AggregationID.
+// ctx is checked between samples; once it is done, or the pairing is deleted, the sync stops
+// and returns an error wrapping context.Canceled (or context.DeadlineExceeded).
+func (s *SyncService) RequestMultipleTimeSyncs(ctx context.Context, req *models.MultiSyncRequest) (*models.AggregatedSyncResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkPairingEnabled(req.PairingID); err != nil {
+		return nil, err
+	}
+
+	// Apply default values for unset fields
+	if req.SampleCount == 0 {
 		req.SampleCount = 8 // NTP standard: 8 samples
 	}
 	if req.IntervalMs == 0 {
@@ -121,55 +522,358 @@ func (s *SyncService) RequestMultipleTimeSyncs(req *models.MultiSyncRequest) (*m
 	if req.TimeoutSec == 0 {
 		req.TimeoutSec = 5 // 5 seconds timeout per sample
 	}
+	if err := applyIntervalStrategyDefaults(req); err != nil {
+		return nil, err
+	}
+
+	ctx, release := s.multiSyncs.track(ctx, req.PairingID)
+	defer release()
 
 	timeout := time.Duration(req.TimeoutSec) * time.Second
 	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	minInterval := time.Duration(req.MinIntervalMs) * time.Millisecond
+	maxInterval := time.Duration(req.MaxIntervalMs) * time.Millisecond
 
-	log.Printf("Starting multi-sync for pairing %s: %d samples, %dms interval",
-		req.PairingID, req.SampleCount, req.IntervalMs)
+	// One correlation ID for all samples of this multi-sync
+	correlationID := logging.NewCorrelationID()
+	logger := s.log().With(logging.KeyCorrelationID, correlationID, logging.KeyPairingID, req.PairingID)
+	logger.Info("starting multi-sync", "samples", req.SampleCount, "interval_ms", req.IntervalMs,
+		"interval_strategy", req.IntervalStrategy)
+
+	// Retries may only use the time a multi-sync without retries could take at most
+	retryDelay := interval
+	if req.IntervalStrategy == models.IntervalStrategyAdaptive {
+		retryDelay = minInterval
+	}
+	retryDeadline := time.Now().Add(multiSyncTimeBudget(req.SampleCount, timeout, interval, maxInterval, req.IntervalStrategy))
 
 	// Perform multiple measurements
 	measurements := make([]*models.TimeSyncRecord, 0, req.SampleCount)
+	// Samples are saved in one batch once collected, including when the multi-sync is
+	// cancelled or unusable (a dry run keeps them in memory only)
+	persist := func() {
+		if req.DryRun {
+			return
+		}
+		toSave := make([]*models.TimeSyncRecord, 0, len(measurements))
+		for _, record := range measurements {
+			record.Source = models.RecordSourceMulti
+			if s.shouldPersist(record) {
+				toSave = append(toSave, record)
+			}
+		}
+		if err := s.repo.SaveTimeSyncRecords(toSave); err != nil {
+			logger.Error("failed to save sync records", "samples", len(toSave), "error", err)
+			// Continue even if DB save fails
+		}
+	}
+	var lastErr error
+	retries := 0
 	for i := 0; i < req.SampleCount; i++ {
-		record, err := s.hub.RequestTimeSync(req.PairingID, timeout)
-		if err != nil {
-			log.Printf("Sample %d/%d failed: %v", i+1, req.SampleCount, err)
-			continue // Skip failed samples
+		if err := multiSyncCancelled(ctx, i, req.SampleCount); err != nil {
+			logger.Info("multi-sync cancelled", "error", err)
+			persist()
+			return nil, err
 		}
 
-		// Save individual measurement to database
-		if err := s.repo.SaveTimeSyncRecord(record); err != nil {
-			log.Printf("Failed to save sync record: %v", err)
-			// Continue even if DB save fails
+		record, err := s.hub.RequestTimeSync(req.PairingID, timeout, correlationID)
+		for attempt := 1; attempt <= req.MaxRetriesPerSample && shouldRetrySample(record, err); attempt++ {
+			if time.Until(retryDeadline) < retryDelay+timeout {
+				logger.Warn("sample not retried, multi-sync time budget used up", "sample", i+1)
+				break
+			}
+			sleepContext(ctx, retryDelay)
+			if ctx.Err() != nil {
+				break
+			}
+			logger.Info("retrying sample", "sample", i+1, "attempt", attempt, "error", sampleFailure(record, err))
+			retries++
+			record, err = s.hub.RequestTimeSync(req.PairingID, timeout, correlationID)
+		}
+		if err != nil {
+			logger.Warn("sample failed", "sample", i+1, "error", err)
+			lastErr = err
+			continue // Skip failed samples
 		}
 
 		measurements = append(measurements, record)
-		log.Printf("Sample %d/%d completed: offset=%dms, rtt1=%dμs, rtt2=%dμs",
-			i+1, req.SampleCount,
-			getValueOrZero(record.TimeDifference),
-			getValueOrZero(record.Device1RTT),
-			getValueOrZero(record.Device2RTT))
+		logger.Debug("sample completed", "sample", i+1,
+			"offset_ms", getValueOrZero(record.TimeDifference),
+			"device1_rtt_us", getValueOrZero(record.Device1RTT),
+			"device2_rtt_us", getValueOrZero(record.Device2RTT))
 
 		// Wait between samples (except for last sample)
 		if i < req.SampleCount-1 {
-			time.Sleep(interval)
+			wait := interval
+			if req.IntervalStrategy == models.IntervalStrategyAdaptive {
+				wait = adaptiveSampleInterval(record, minInterval, maxInterval)
+			}
+			sleepContext(ctx, wait)
 		}
 	}
 
+	// Saved before aggregation so the records have IDs to link to
+	persist()
+
 	// Check if we have any valid measurements
 	if len(measurements) == 0 {
+		logger.Warn("multi-sync failed, all samples failed", "samples", req.SampleCount)
+		if lastErr != nil {
+			return nil, fmt.Errorf("all %d samples failed: %w", req.SampleCount, lastErr)
+		}
 		return nil, fmt.Errorf("all %d samples failed", req.SampleCount)
 	}
 
-	log.Printf("Collected %d/%d valid samples, applying NTP selection algorithm",
-		len(measurements), req.SampleCount)
+	// PARTIAL and FAILED records carry no offset and are skipped by the selector
+	success, partial, failed := countSampleStatuses(measurements)
+	if success == 0 {
+		err := &NoUsableSamplesError{Total: req.SampleCount, Partial: partial, Failed: failed + req.SampleCount - len(measurements)}
+		logger.Warn("multi-sync failed", "error", err)
+		return nil, err
+	}
 
-	// Apply NTP selection algorithm
-	selector := algorithms.NewNTPSelector(models.NTPFilterConfig{
-		MinSamples:       3,
-		OutlierThreshold: 2.0, // 2 standard deviations
-		TopPercentile:    0.5, // Top 50% by RTT
-	})
+	logger.Info("collected samples, applying NTP selection algorithm",
+		"success", success, "partial", partial, "failed", failed, "samples", req.SampleCount, "retries", retries)
+
+	result, err := s.selectBestOffset(req.PairingID, measurements, req.FilterConfig())
+	if err != nil {
+		logger.Warn("multi-sync selection failed", "error", err)
+		return nil, err
+	}
+	result.RetryCount = retries
+
+	if req.DryRun {
+		logger.Info("dry-run multi-sync completed, nothing persisted",
+			"best_offset_ms", result.BestOffset, "confidence", result.Confidence)
+		return result, nil
+	}
+
+	if err := s.saveAggregatedResult(result, measurements); err != nil {
+		logger.Warn("multi-sync aggregation failed", "error", err)
+		return nil, err
+	}
+	logger.Info("multi-sync completed", "aggregation_id", result.AggregationID,
+		"best_offset_ms", result.BestOffset, "confidence", result.Confidence)
+	return result, nil
+}
+
+// multiSyncCancelled returns an error if ctx is done before sample i+1 of total is taken
+func multiSyncCancelled(ctx context.Context, i, total int) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("multi-sync cancelled after %d of %d samples: %w", i, total, context.Cause(ctx))
+}
+
+// shouldRetrySample reports whether a multi-sync sample is worth another attempt: the request
+// failed, or it produced no usable offset (timed out or only one device answered).
+// A deleted pairing is not retried.
+func shouldRetrySample(record *models.TimeSyncRecord, err error) bool {
+	if err != nil {
+		var pairingNotFound *websocket.PairingNotFoundError
+		return !errors.As(err, &pairingNotFound)
+	}
+	return record.Status != models.SyncStatusSuccess || record.TimeDifference == nil
+}
+
+// sampleFailure describes why a sample is retried, for logging
+func sampleFailure(record *models.TimeSyncRecord, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if record.ErrorMessage != nil {
+		return fmt.Sprintf("%s: %s", record.Status, *record.ErrorMessage)
+	}
+	return string(record.Status)
+}
+
+// multiSyncTimeBudget returns the longest a multi-sync of sampleCount samples takes without retries:
+// every sample times out and the longest interval is waited between them
+func multiSyncTimeBudget(sampleCount int, timeout, interval, maxInterval time.Duration, strategy string) time.Duration {
+	wait := interval
+	if strategy == models.IntervalStrategyAdaptive {
+		wait = maxInterval
+	}
+	return time.Duration(sampleCount)*timeout + time.Duration(sampleCount-1)*wait
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+const (
+	defaultMinIntervalMs = 50
+	defaultMaxIntervalMs = 1000
+
+	// adaptiveIntervalRTTFactor is the multiple of the previous sample's RTT waited before the next sample
+	adaptiveIntervalRTTFactor = 4
+)
+
+// applyIntervalStrategyDefaults fills in the interval strategy defaults of a validated multi-sync request.
+// A bound left unset can still end up below the other one's default, which is rejected.
+func applyIntervalStrategyDefaults(req *models.MultiSyncRequest) error {
+	if req.IntervalStrategy == "" {
+		req.IntervalStrategy = models.IntervalStrategyFixed
+	}
+	if req.MinIntervalMs == 0 {
+		req.MinIntervalMs = defaultMinIntervalMs
+	}
+	if req.MaxIntervalMs == 0 {
+		req.MaxIntervalMs = defaultMaxIntervalMs
+	}
+	if req.MaxIntervalMs < req.MinIntervalMs {
+		return fmt.Errorf("invalid interval bounds: min_interval_ms %d, max_interval_ms %d", req.MinIntervalMs, req.MaxIntervalMs)
+	}
+	return nil
+}
+
+// adaptiveSampleInterval scales the wait before the next sample with the slower device's RTT
+// in the previous sample, clamped to [lower, upper]. Without both RTTs (partial or failed sample) it backs off to upper.
+func adaptiveSampleInterval(previous *models.TimeSyncRecord, lower, upper time.Duration) time.Duration {
+	var rttMicros int64
+	for _, rtt := range []*int64{previous.Device1RTT, previous.Device2RTT} {
+		if rtt == nil {
+			return upper
+		}
+		if *rtt > rttMicros {
+			rttMicros = *rtt
+		}
+	}
+
+	interval := time.Duration(rttMicros) * time.Microsecond * adaptiveIntervalRTTFactor
+	if interval < lower {
+		return lower
+	}
+	if interval > upper {
+		return upper
+	}
+	return interval
+}
+
+// NoUsableSamplesError is returned by RequestMultipleTimeSyncs when no sample has an offset from both devices
+type NoUsableSamplesError struct {
+	Total   int // Samples requested
+	Partial int // Only one device responded
+	Failed  int // Neither device responded, or the request itself failed
+}
+
+func (e *NoUsableSamplesError) Error() string {
+	switch {
+	case e.Partial == 0:
+		return fmt.Sprintf("no usable samples: all %d samples timed out or failed", e.Total)
+	case e.Failed == 0:
+		return fmt.Sprintf("no usable samples: all %d samples were partial (only one device responded)", e.Total)
+	default:
+		return fmt.Sprintf("no usable samples: %d partial and %d failed of %d samples", e.Partial, e.Failed, e.Total)
+	}
+}
+
+// countSampleStatuses counts records by status; only successful records with an offset count as success
+func countSampleStatuses(records []*models.TimeSyncRecord) (success, partial, failed int) {
+	for _, record := range records {
+		switch {
+		case record.Status == models.SyncStatusSuccess && record.TimeDifference != nil:
+			success++
+		case record.Status == models.SyncStatusPartial:
+			partial++
+		default:
+			failed++
+		}
+	}
+	return success, partial, failed
+}
+
+// AggregateRecords applies the NTP selection algorithm to already collected records
+// of a pairing and saves the aggregated result. Zero fields of filter use the selector defaults.
+func (s *SyncService) AggregateRecords(pairingID string, measurements []*models.TimeSyncRecord, filter models.NTPFilterConfig) (*models.AggregatedSyncResult, error) {
+	result, err := s.selectBestOffset(pairingID, measurements, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveAggregatedResult(result, measurements); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveAggregatedResult assigns the result an AggregationID, saves it with its measurement links
+// and publishes it to dashboards and the offset alerter
+func (s *SyncService) saveAggregatedResult(result *models.AggregatedSyncResult, measurements []*models.TimeSyncRecord) error {
+	result.AggregationID = uuid.New().String()
+	s.smoothOffset(result)
+
+	// Save aggregated result to database
+	if err := s.repo.SaveAggregatedSyncResult(result); err != nil {
+		var unlinked *repository.UnlinkedMeasurementsError
+		if !errors.As(err, &unlinked) {
+			return fmt.Errorf("failed to save aggregated result: %w", err)
+		}
+		// The result is stored, only some measurement links are missing.
+		// Failed samples deliberately not persisted are expected to be unlinked.
+		if unlinked.Skipped > s.countUnpersisted(measurements) {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	// Dashboards get the summary; measurements are available via GET /api/sync/aggregated/:aggregationId
+	summary := *result
+	summary.Measurements = nil
+	s.events.Publish(models.EventTypeAggregatedResult, &summary)
+	s.alerts.Check(result)
+
+	return nil
+}
+
+// smoothOffset sets result.SmoothedOffset to the exponentially weighted moving average of the
+// pairing's BestOffsets: alpha × BestOffset + (1 - alpha) × the previous result's smoothed offset.
+// Each member of a group pairing is averaged on its own. The first result of a pairing (or member)
+// starts the average at its own BestOffset. If the previous result cannot be loaded the result is
+// saved without a smoothed offset.
+func (s *SyncService) smoothOffset(result *models.AggregatedSyncResult) {
+	prev, err := s.repo.GetLatestMemberAggregatedSyncResult(result.PairingID, result.MemberDeviceID)
+	if err != nil && !errors.Is(err, repository.ErrAggregationNotFound) {
+		log.Printf("Warning: no smoothed offset for pairing %s, failed to load the previous result: %v", result.PairingID, err)
+		return
+	}
+
+	smoothed := float64(result.BestOffset)
+	if prev != nil {
+		prevSmoothed := float64(prev.BestOffset) // Results saved before smoothing start from their own offset
+		if prev.SmoothedOffset != nil {
+			prevSmoothed = *prev.SmoothedOffset
+		}
+		// An offset measured against the other device has the opposite sign
+		if prev.ReferenceDeviceID != "" && result.ReferenceDeviceID != "" && prev.ReferenceDeviceID != result.ReferenceDeviceID {
+			prevSmoothed = -prevSmoothed
+		}
+		smoothed = s.smoothingAlpha*smoothed + (1-s.smoothingAlpha)*prevSmoothed
+	}
+	result.SmoothedOffset = &smoothed
+}
+
+// countUnpersisted returns how many measurements were deliberately not saved (see SetPersistFailedSamples)
+func (s *SyncService) countUnpersisted(measurements []*models.TimeSyncRecord) int {
+	count := 0
+	for _, m := range measurements {
+		if m.ID == 0 && !s.shouldPersist(m) {
+			count++
+		}
+	}
+	return count
+}
+
+// selectBestOffset applies the NTP selection algorithm to a pairing's records without saving
+// anything. The result has no AggregationID.
+func (s *SyncService) selectBestOffset(pairingID string, measurements []*models.TimeSyncRecord, filter models.NTPFilterConfig) (*models.AggregatedSyncResult, error) {
+	// Apply NTP selection algorithm; unset fields default to 3 samples, 2 standard deviations
+	// and the top 50% by RTT
+	selector := algorithms.NewNTPSelector(filter)
 
 	result, err := selector.SelectBestMeasurements(measurements)
 	if err != nil {
@@ -177,26 +881,377 @@ func (s *SyncService) RequestMultipleTimeSyncs(req *models.MultiSyncRequest) (*m
 	}
 
 	// Populate metadata
-	result.AggregationID = uuid.New().String()
-	result.PairingID = req.PairingID
+	result.PairingID = pairingID
+	// Offsets are Device1 - Device2, so Device2 is the device they are measured against
+	result.ReferenceDeviceID = measurements[0].Device2ID
 	result.CreatedAt = time.Now().UnixMilli()
+	result.SuccessSamples, result.PartialSamples, result.FailedSamples = countSampleStatuses(measurements)
+
+	// Record the effective filter so the result can be reproduced
+	applied := selector.Config()
+	result.MinSamples = applied.MinSamples
+	result.OutlierThreshold = applied.OutlierThreshold
+	result.TopPercentile = applied.TopPercentile
+	result.OutlierMethod = applied.OutlierMethod
 
 	log.Printf("NTP algorithm completed: best_offset=%dms, confidence=%.2f, valid=%d/%d",
 		result.BestOffset, result.Confidence, result.ValidSamples, result.TotalSamples)
 
-	// Save aggregated result to database
-	if err := s.repo.SaveAggregatedSyncResult(result); err != nil {
-		return nil, fmt.Errorf("failed to save aggregated result: %w", err)
+	return result, nil
+}
+
+// RequestGroupTimeSync performs one time synchronization across every member of a group pairing.
+// Each member is measured against the group's reference device.
+func (s *SyncService) RequestGroupTimeSync(pairingID string) (*models.GroupSyncRecord, error) {
+	if err := s.checkPairingEnabled(pairingID); err != nil {
+		return nil, err
+	}
+
+	// Request time sync with 5 second timeout
+	result, err := s.hub.RequestGroupTimeSync(pairingID, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save each member record to database
+	for _, record := range result.Records {
+		record.Source = models.RecordSourceSingle
+		if !s.shouldPersist(record) {
+			continue
+		}
+		if err := s.repo.SaveTimeSyncRecord(record); err != nil {
+			return nil, fmt.Errorf("failed to save sync record: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// RequestGroupMultipleTimeSyncs performs NTP-style multi-sampling over a group pairing.
+// Every sample is one fan-out to all members; the NTP selection algorithm is then applied
+// per member, yielding each member's offset relative to the reference device.
+// Interval strategy, retries and cancellation work as in RequestMultipleTimeSyncs; a sample
+// is retried unless every member answered.
+func (s *SyncService) RequestGroupMultipleTimeSyncs(ctx context.Context, req *models.MultiSyncRequest) (*models.GroupAggregatedSyncResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkPairingEnabled(req.PairingID); err != nil {
+		return nil, err
+	}
+
+	// Apply default values for unset fields
+	if req.SampleCount == 0 {
+		req.SampleCount = 8 // NTP standard: 8 samples
+	}
+	if req.IntervalMs == 0 {
+		req.IntervalMs = 200 // 200ms between samples
+	}
+	if req.TimeoutSec == 0 {
+		req.TimeoutSec = 5 // 5 seconds timeout per sample
+	}
+	if err := applyIntervalStrategyDefaults(req); err != nil {
+		return nil, err
+	}
+
+	ctx, release := s.multiSyncs.track(ctx, req.PairingID)
+	defer release()
+
+	timeout := time.Duration(req.TimeoutSec) * time.Second
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	minInterval := time.Duration(req.MinIntervalMs) * time.Millisecond
+	maxInterval := time.Duration(req.MaxIntervalMs) * time.Millisecond
+
+	log.Printf("Starting group multi-sync for pairing %s: %d samples, %dms interval (%s)",
+		req.PairingID, req.SampleCount, req.IntervalMs, req.IntervalStrategy)
+
+	// Retries may only use the time a multi-sync without retries could take at most
+	retryDelay := interval
+	if req.IntervalStrategy == models.IntervalStrategyAdaptive {
+		retryDelay = minInterval
+	}
+	retryDeadline := time.Now().Add(multiSyncTimeBudget(req.SampleCount, timeout, interval, maxInterval, req.IntervalStrategy))
+
+	// Collect measurements per member (member device ID -> records)
+	referenceDeviceID := ""
+	measurements := make(map[string][]*models.TimeSyncRecord)
+	var lastErr error
+	retries := 0
+	for i := 0; i < req.SampleCount; i++ {
+		if err := multiSyncCancelled(ctx, i, req.SampleCount); err != nil {
+			log.Printf("Group multi-sync for pairing %s: %v", req.PairingID, err)
+			return nil, err
+		}
+
+		sample, err := s.hub.RequestGroupTimeSync(req.PairingID, timeout)
+		for attempt := 1; attempt <= req.MaxRetriesPerSample && shouldRetryGroupSample(sample, err); attempt++ {
+			if time.Until(retryDeadline) < retryDelay+timeout {
+				log.Printf("Sample %d/%d not retried, group multi-sync time budget used up", i+1, req.SampleCount)
+				break
+			}
+			sleepContext(ctx, retryDelay)
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("Retrying sample %d/%d (attempt %d)", i+1, req.SampleCount, attempt)
+			retries++
+			sample, err = s.hub.RequestGroupTimeSync(req.PairingID, timeout)
+		}
+		if err != nil {
+			log.Printf("Sample %d/%d failed: %v", i+1, req.SampleCount, err)
+			lastErr = err
+			continue // Skip failed samples
+		}
+		referenceDeviceID = sample.ReferenceDeviceID
+
+		for _, record := range sample.Records {
+			// Save individual measurement to database (a dry run keeps it in memory only)
+			record.Source = models.RecordSourceMulti
+			if !req.DryRun && s.shouldPersist(record) {
+				if err := s.repo.SaveTimeSyncRecord(record); err != nil {
+					log.Printf("Failed to save sync record: %v", err)
+					// Continue even if DB save fails
+				}
+			}
+
+			// Register every member so ones without valid samples are reported as failed
+			if _, ok := measurements[record.Device1ID]; !ok {
+				measurements[record.Device1ID] = nil
+			}
+			if record.Status == models.SyncStatusSuccess {
+				measurements[record.Device1ID] = append(measurements[record.Device1ID], record)
+			}
+		}
+
+		log.Printf("Sample %d/%d completed: status=%s, members=%d",
+			i+1, req.SampleCount, sample.Status, len(sample.Records))
+
+		// Wait between samples (except for last sample)
+		if i < req.SampleCount-1 {
+			wait := interval
+			if req.IntervalStrategy == models.IntervalStrategyAdaptive {
+				wait = adaptiveGroupSampleInterval(sample, minInterval, maxInterval)
+			}
+			sleepContext(ctx, wait)
+		}
+	}
+
+	// Check if we have any valid measurements
+	if len(measurements) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("all %d samples failed: %w", req.SampleCount, lastErr)
+		}
+		return nil, fmt.Errorf("all %d samples failed", req.SampleCount)
+	}
+
+	result := &models.GroupAggregatedSyncResult{
+		PairingID:         req.PairingID,
+		ReferenceDeviceID: referenceDeviceID,
+		Results:           make(map[string]*models.AggregatedSyncResult),
+		FailedDevices:     make(map[string]string),
+		CreatedAt:         time.Now().UnixMilli(),
+	}
+
+	// Apply NTP selection per member
+	for deviceID, records := range measurements {
+		aggregated, err := s.selectBestOffset(req.PairingID, records, req.FilterConfig())
+		if err == nil {
+			aggregated.MemberDeviceID = deviceID
+			aggregated.RetryCount = retries
+			if !req.DryRun {
+				err = s.saveAggregatedResult(aggregated, records)
+			}
+		}
+		if err != nil {
+			log.Printf("Group aggregation failed for device %s: %v", deviceID, err)
+			result.FailedDevices[deviceID] = err.Error()
+			continue
+		}
+		result.Results[deviceID] = aggregated
+	}
+
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("aggregation failed for every member of group pairing %s", req.PairingID)
 	}
 
 	return result, nil
 }
 
+// shouldRetryGroupSample reports whether a group multi-sync sample is worth another attempt:
+// the request failed, or not every member answered. A deleted pairing is not retried.
+func shouldRetryGroupSample(sample *models.GroupSyncRecord, err error) bool {
+	if err != nil {
+		var pairingNotFound *websocket.PairingNotFoundError
+		return !errors.As(err, &pairingNotFound)
+	}
+	return sample.Status != models.SyncStatusSuccess
+}
+
+// adaptiveGroupSampleInterval is adaptiveSampleInterval over the slowest member of a group sample
+func adaptiveGroupSampleInterval(previous *models.GroupSyncRecord, lower, upper time.Duration) time.Duration {
+	wait := lower
+	for _, record := range previous.Records {
+		if w := adaptiveSampleInterval(record, lower, upper); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
 // GetAggregatedSyncResult retrieves a single aggregated sync result by ID
 func (s *SyncService) GetAggregatedSyncResult(aggregationID string) (*models.AggregatedSyncResult, error) {
 	return s.repo.GetAggregatedSyncResult(aggregationID)
 }
 
+// ErrNoStoredMeasurements is returned by RecomputeAggregation when none of the aggregation's
+// measurements are stored anymore (e.g. removed by retention cleanup)
+var ErrNoStoredMeasurements = errors.New("aggregation has no stored measurements")
+
+// RecomputeAggregation re-runs the NTP selection over the stored measurements of an aggregation,
+// e.g. to compare algorithm versions on historical data without measuring the devices again.
+// With req.Save the result is saved as a new aggregation, smoothed, published and alerted like any
+// other aggregated result; the source aggregation is never changed.
+// Fails with ErrAggregationNotFound if the aggregation does not exist.
+func (s *SyncService) RecomputeAggregation(aggregationID string, req *models.RecomputeRequest) (*models.RecomputeResult, error) {
+	source, err := s.repo.GetAggregatedSyncResult(aggregationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(source.Measurements) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoStoredMeasurements, aggregationID)
+	}
+
+	filter := models.NTPFilterConfig{
+		MinSamples:       source.MinSamples,
+		OutlierThreshold: source.OutlierThreshold,
+		TopPercentile:    source.TopPercentile,
+		OutlierMethod:    source.OutlierMethod,
+	}
+	if req.Filter != nil {
+		filter = *req.Filter
+	}
+
+	result, err := s.selectBestOffset(source.PairingID, source.Measurements, filter)
+	if err != nil {
+		return nil, err
+	}
+	// Offsets stay relative to the device (and group member) the source was reported for
+	result.ReferenceDeviceID = source.ReferenceDeviceID
+	result.MemberDeviceID = source.MemberDeviceID
+
+	if req.Save {
+		if err := s.saveAggregatedResult(result, source.Measurements); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.RecomputeResult{
+		SourceAggregationID: aggregationID,
+		Saved:               req.Save,
+		Result:              result,
+	}, nil
+}
+
+// GetLatestAggregatedSyncResult retrieves the most recent aggregated result of a pairing, without measurements.
+// While the pairing has an active offset override, BestOffset is the pinned offset.
+func (s *SyncService) GetLatestAggregatedSyncResult(pairingID string) (*models.AggregatedSyncResult, error) {
+	result, err := s.repo.GetLatestAggregatedSyncResult(pairingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyOffsetOverride(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetLatestAggregatedSyncResults retrieves the most recent aggregated result of every pairing (or only of
+// pairingIDs) in one query, ordered by pairing ID. Offset overrides apply as in GetLatestAggregatedSyncResult.
+func (s *SyncService) GetLatestAggregatedSyncResults(pairingIDs []string) ([]*models.AggregatedSyncResult, error) {
+	results, err := s.repo.GetLatestAggregatedSyncResults(pairingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if err := s.applyOffsetOverride(result); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// applyOffsetOverride replaces BestOffset with the pinned offset while the result's pairing has an
+// active override, keeping the measured value in MeasuredOffset
+func (s *SyncService) applyOffsetOverride(result *models.AggregatedSyncResult) error {
+	override, err := s.GetActiveOffsetOverride(result.PairingID)
+	if err != nil {
+		return err
+	}
+	if override != nil {
+		measured := result.BestOffset
+		result.MeasuredOffset = &measured
+		result.BestOffset = override.OffsetAgainst(result.ReferenceDeviceID)
+		result.OffsetOverride = override
+	}
+	return nil
+}
+
+// SetOffsetOverride pins the offset of a two-device pairing, replacing any existing override.
+// The offset is taken against the device the pairing's measurements use as reference.
+func (s *SyncService) SetOffsetOverride(pairingID string, req *models.OffsetOverrideRequest) (*models.OffsetOverride, error) {
+	pairing, err := s.repo.GetPairingByID(pairingID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, referenceDeviceID := s.hub.OrientPairing(pairing.Device1ID, pairing.Device2ID)
+	override := &models.OffsetOverride{
+		PairingID:         pairingID,
+		ReferenceDeviceID: referenceDeviceID,
+		OffsetMs:          *req.OffsetMs,
+		ExpiresAt:         req.ExpiresAt,
+		CreatedAt:         time.Now(),
+	}
+	if err := s.repo.SaveOffsetOverride(override); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Offset override set for pairing %s: %dms against %s", pairingID, override.OffsetMs, referenceDeviceID)
+	return override, nil
+}
+
+// GetActiveOffsetOverride returns the pairing's offset override, or nil if it has none or it expired
+func (s *SyncService) GetActiveOffsetOverride(pairingID string) (*models.OffsetOverride, error) {
+	override, err := s.repo.GetOffsetOverride(pairingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOffsetOverrideNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !override.Active(time.Now()) {
+		return nil, nil
+	}
+	return override, nil
+}
+
+// DeleteOffsetOverride removes the pairing's offset override, so measurements are used again
+func (s *SyncService) DeleteOffsetOverride(pairingID string) error {
+	return s.repo.DeleteOffsetOverride(pairingID)
+}
+
+// DeletePairingRecords purges the aggregated results of a pairing and the records of its devices.
+// The pairing itself is kept, see DeletePairing.
+func (s *SyncService) DeletePairingRecords(pairingID string) (*models.PairingRecordsDeletion, error) {
+	return s.repo.DeletePairingRecords(pairingID)
+}
+
+// DeleteAggregatedSyncResult deletes an aggregated result. The measurements it was computed from
+// are kept unless deleteRecords is set.
+func (s *SyncService) DeleteAggregatedSyncResult(aggregationID string, deleteRecords bool) error {
+	return s.repo.DeleteAggregatedSyncResult(aggregationID, deleteRecords)
+}
+
 // GetAggregatedSyncResults retrieves aggregated sync results for a pairing
 func (s *SyncService) GetAggregatedSyncResults(pairingID string, limit, offset int) ([]*models.AggregatedSyncResult, error) {
 	if limit <= 0 {
@@ -230,6 +1285,282 @@ func (s *SyncService) GetAggregatedSyncResultsByTimeRange(startTime, endTime tim
 	return s.repo.GetAggregatedSyncResultsByTimeRange(startTime, endTime, limit, offset)
 }
 
+// GetAggregatedSyncResultsFiltered retrieves aggregated sync results matching all set fields of the filter
+func (s *SyncService) GetAggregatedSyncResultsFiltered(filter models.AggregatedResultFilter, limit, offset int) ([]*models.AggregatedSyncResult, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	return s.repo.GetAggregatedSyncResultsFiltered(filter, limit, offset)
+}
+
+// ExportSyncRecords streams sync records matching the filter to fn, oldest first
+func (s *SyncService) ExportSyncRecords(filter models.ExportFilter, fn func(*models.TimeSyncRecord) error) error {
+	return s.repo.ForEachTimeSyncRecord(filter, fn)
+}
+
+// ExportAggregatedResults streams aggregated results matching the filter to fn, oldest first
+func (s *SyncService) ExportAggregatedResults(filter models.ExportFilter, fn func(*models.AggregatedSyncResult) error) error {
+	return s.repo.ForEachAggregatedSyncResult(filter, fn)
+}
+
+// GetPairingExport returns the header of a pairing's export bundle, see ExportPairingAggregations
+func (s *SyncService) GetPairingExport(pairingID string) (*models.PairingExport, error) {
+	pairing, err := s.repo.GetPairingByID(pairingID)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.GetActiveOffsetOverride(pairingID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PairingExport{
+		SchemaVersion:  models.PairingExportSchemaVersion,
+		ExportedAt:     time.Now().UTC(),
+		Pairing:        pairing,
+		OffsetOverride: override,
+	}, nil
+}
+
+// ExportPairingAggregations streams the pairing's aggregated results to fn, oldest first, each
+// loaded with its measurements and rejected samples. Results are stored values, without the
+// offset override applied. Only the IDs are collected up front, so a single aggregation is held
+// in memory at a time; aggregations deleted in the meantime are skipped.
+func (s *SyncService) ExportPairingAggregations(pairingID string, fn func(*models.AggregatedSyncResult) error) error {
+	var aggregationIDs []string
+	if err := s.repo.ForEachAggregatedSyncResult(models.ExportFilter{PairingID: pairingID}, func(result *models.AggregatedSyncResult) error {
+		aggregationIDs = append(aggregationIDs, result.AggregationID)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, aggregationID := range aggregationIDs {
+		result, err := s.repo.GetAggregatedSyncResult(aggregationID)
+		if errors.Is(err, repository.ErrAggregationNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyOffset converts device timestamps to the reference device's clock using an aggregation's offset.
+// The device must belong to the aggregation's pairing; the reference device's own timestamps are unchanged.
+func (s *SyncService) ApplyOffset(req *models.ApplyOffsetRequest) (*models.ApplyOffsetResult, error) {
+	result, err := s.repo.GetAggregatedSyncResult(req.AggregationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPairingMember(result.PairingID, req.DeviceID); err != nil {
+		return nil, err
+	}
+
+	offset, err := result.OffsetForDevice(req.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	referenceDeviceID := result.ReferenceDeviceID
+
+	// An active override of the pairing takes precedence over the aggregation's measured offset
+	override, err := s.GetActiveOffsetOverride(result.PairingID)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		offset = override.OffsetForDevice(req.DeviceID)
+		referenceDeviceID = override.ReferenceDeviceID
+	}
+
+	// offset = device time - reference time
+	corrected := make([]int64, len(req.Timestamps))
+	for i, ts := range req.Timestamps {
+		corrected[i] = ts - offset
+	}
+
+	return &models.ApplyOffsetResult{
+		AggregationID:     result.AggregationID,
+		DeviceID:          req.DeviceID,
+		ReferenceDeviceID: referenceDeviceID,
+		OffsetMs:          offset,
+		Timestamps:        corrected,
+		Overridden:        override != nil,
+	}, nil
+}
+
+// checkPairingMember rejects devices that are not part of the pairing or group pairing.
+// A pairing that no longer exists is not checked here; OffsetForDevice still checks the measurements.
+func (s *SyncService) checkPairingMember(pairingID, deviceID string) error {
+	if pairing, err := s.repo.GetPairingByID(pairingID); err == nil {
+		if deviceID != pairing.Device1ID && deviceID != pairing.Device2ID {
+			return fmt.Errorf("device %s is not part of pairing %s", deviceID, pairingID)
+		}
+		return nil
+	}
+	if group, err := s.repo.GetGroupPairingByID(pairingID); err == nil {
+		if !group.HasDevice(deviceID) {
+			return fmt.Errorf("device %s is not part of group pairing %s", deviceID, pairingID)
+		}
+	}
+	return nil
+}
+
+// CompareAggregations loads two aggregated results and reports how B differs from A.
+// It fails with ErrAggregationNotFound if either does not exist.
+func (s *SyncService) CompareAggregations(aggregationIDA, aggregationIDB string) (*models.AggregationComparison, error) {
+	a, err := s.repo.GetAggregatedSyncResult(aggregationIDA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.repo.GetAggregatedSyncResult(aggregationIDB)
+	if err != nil {
+		return nil, err
+	}
+	a.Measurements, b.Measurements = nil, nil
+
+	comparison := &models.AggregationComparison{
+		A:               a,
+		B:               b,
+		OffsetDelta:     b.BestOffset - a.BestOffset,
+		AbsOffsetDelta:  abs64(b.BestOffset) - abs64(a.BestOffset),
+		ConfidenceDelta: b.Confidence - a.Confidence,
+		JitterDelta:     b.Jitter - a.Jitter,
+	}
+	comparison.Verdict = comparisonVerdict(comparison)
+	return comparison, nil
+}
+
+// comparisonVerdict summarizes a comparison in words, e.g. "B is 30ms closer, 0.12 more confident"
+func comparisonVerdict(c *models.AggregationComparison) string {
+	var parts []string
+
+	switch {
+	case c.AbsOffsetDelta < 0:
+		parts = append(parts, fmt.Sprintf("B is %dms closer", -c.AbsOffsetDelta))
+	case c.AbsOffsetDelta > 0:
+		parts = append(parts, fmt.Sprintf("B is %dms further", c.AbsOffsetDelta))
+	default:
+		parts = append(parts, "B is equally close")
+	}
+
+	// Differences below the displayed precision count as equal
+	switch {
+	case c.ConfidenceDelta >= 0.005:
+		parts = append(parts, fmt.Sprintf("%.2f more confident", c.ConfidenceDelta))
+	case c.ConfidenceDelta <= -0.005:
+		parts = append(parts, fmt.Sprintf("%.2f less confident", -c.ConfidenceDelta))
+	default:
+		parts = append(parts, "equally confident")
+	}
+
+	switch {
+	case c.JitterDelta <= -0.5:
+		parts = append(parts, fmt.Sprintf("%.0fμs less jitter", -c.JitterDelta))
+	case c.JitterDelta >= 0.5:
+		parts = append(parts, fmt.Sprintf("%.0fμs more jitter", c.JitterDelta))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// GetOffsetTimeSeries returns the pairing's best offset downsampled into buckets of the given size.
+// An empty range yields a series without buckets.
+func (s *SyncService) GetOffsetTimeSeries(pairingID string, startTime, endTime *time.Time, bucket time.Duration) (*models.OffsetTimeSeries, error) {
+	buckets, err := s.repo.GetAggregatedOffsetBuckets(pairingID, startTime, endTime, bucket.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	if buckets == nil {
+		buckets = []*models.OffsetBucket{}
+	}
+	override, err := s.GetActiveOffsetOverride(pairingID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OffsetTimeSeries{
+		PairingID: pairingID,
+		BucketMs:  bucket.Milliseconds(),
+		Buckets:   buckets,
+		Override:  override,
+	}, nil
+}
+
+// InsufficientDriftDataError is returned when a pairing has too few aggregated results to estimate drift
+type InsufficientDriftDataError struct {
+	PairingID string
+	Found     int
+}
+
+func (e *InsufficientDriftDataError) Error() string {
+	return fmt.Sprintf("pairing %s has %d aggregated result(s) in the window, at least 2 are required to estimate drift", e.PairingID, e.Found)
+}
+
+// EstimateClockDrift fits a line through BestOffset over CreatedAt for the pairing's
+// aggregated results within the window and reports the slope as the clock drift.
+// Offsets are normalized to the reference device of the newest result first.
+func (s *SyncService) EstimateClockDrift(pairingID string, window time.Duration) (*models.DriftEstimate, error) {
+	results, err := s.repo.GetAggregatedSyncResultsByPairingSince(pairingID, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	if len(results) < 2 {
+		return nil, &InsufficientDriftDataError{PairingID: pairingID, Found: len(results)}
+	}
+
+	// x: hours since the oldest result, y: best offset in ms
+	start := results[0].CreatedAt
+	reference := results[len(results)-1].ReferenceDeviceID
+	xs := make([]float64, len(results))
+	ys := make([]float64, len(results))
+	for i, result := range results {
+		xs[i] = float64(result.CreatedAt-start) / float64(time.Hour/time.Millisecond)
+		ys[i] = float64(result.BestOffset)
+		// An offset measured against the other device has the opposite sign
+		if result.ReferenceDeviceID != "" && reference != "" && result.ReferenceDeviceID != reference {
+			ys[i] = -ys[i]
+		}
+	}
+
+	slope, intercept, rSquared, err := algorithms.LinearRegression(xs, ys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate drift: %w", err)
+	}
+
+	return &models.DriftEstimate{
+		PairingID:         pairingID,
+		ReferenceDeviceID: reference,
+		SampleCount:       len(results),
+		StartTime:         start,
+		EndTime:           results[len(results)-1].CreatedAt,
+		SlopeMsPerHour:    slope,
+		DriftPPM:          slope / 3.6, // ms per hour -> ms per 3.6e6 ms -> ppm
+		InterceptMs:       intercept,
+		RSquared:          rSquared,
+	}, nil
+}
+
+// GetDeviceSyncStats returns sync statistics of one device's records, optionally within a time range
+func (s *SyncService) GetDeviceSyncStats(deviceID string, startTime, endTime *time.Time) (*models.DeviceSyncStats, error) {
+	return s.repo.GetDeviceSyncStats(deviceID, startTime, endTime)
+}
+
+// GetDeviceEvents returns a device's connect/disconnect events, newest first
+func (s *SyncService) GetDeviceEvents(deviceID string, startTime, endTime *time.Time, limit int) ([]*models.DeviceEvent, error) {
+	return s.repo.GetDeviceEvents(deviceID, startTime, endTime, limit)
+}
+
+// GetDeviceTypeStats returns sync reliability metrics grouped by device type
+func (s *SyncService) GetDeviceTypeStats() ([]*models.DeviceTypeStats, error) {
+	return s.repo.GetDeviceTypeStats()
+}
+
 // Helper function to get value or zero for nullable int64 pointers
 func getValueOrZero(ptr *int64) int64 {
 	if ptr == nil {
@@ -2,18 +2,20 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"time-sync-server/internal/logging"
 	"time-sync-server/internal/models"
 
 	"github.com/gorilla/websocket"
 )
 
 const (
-	// Time allowed to write a message to the peer
-	writeWait = 10 * time.Second
-
 	// Time allowed to read the next pong message from the peer
 	pongWait = 60 * time.Second
 
@@ -23,46 +25,113 @@ const (
 	// Application-level PING period (20 seconds as recommended)
 	appPingPeriod = 40 * time.Second
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512 // Increased to 8KB to handle longer JSON messages
+	// DefaultHealthThreshold is how long a device may go without a PONG and still be reported
+	// healthy: two missed application-level PINGs plus slack for the round trip
+	DefaultHealthThreshold = appPingPeriod * 5 / 2
+)
+
+// DefaultWriteWait is how long a write to the peer may take when none is configured
+const DefaultWriteWait = 10 * time.Second
+
+// DefaultSendBufferSize is how many outgoing messages a client queues when no size is configured
+const DefaultSendBufferSize = 256
+
+// DefaultMaxMessageSize is the largest message in bytes a client may send when none is configured.
+// A TIME_RESPONSE with all four timestamps plus metadata can exceed 512 bytes; larger messages
+// fail the read and drop the connection.
+const DefaultMaxMessageSize = 4096
+
+// SendPolicy decides what SendMessage does when a client's send buffer is full
+type SendPolicy string
+
+const (
+	SendPolicyDrop       SendPolicy = "drop"       // Drop the message right away (default)
+	SendPolicyBlock      SendPolicy = "block"      // Wait up to the send timeout for room, then drop
+	SendPolicyDisconnect SendPolicy = "disconnect" // Drop the message and close the connection
 )
 
+// ErrSendBufferFull is returned by SendMessage when a message is dropped because the client's send buffer is full
+var ErrSendBufferFull = errors.New("send buffer full")
+
+// ErrClientClosed is returned by SendMessage once the hub is done with the client
+var ErrClientClosed = errors.New("client closed")
+
 type Client struct {
 	Hub          *Hub
 	Conn         *websocket.Conn
 	Send         chan []byte
 	DeviceID     string
 	DeviceType   models.DeviceType
-	ConnectedAt  time.Time // Connection establishment time
-	LastPingSent time.Time // Last application-level PING sent time
-	LastPongRecv time.Time // Last application-level PONG received time
-	LastRTT      int64     // Last measured RTT in milliseconds
+	Identity     string        // Authenticated identity from the handshake token (empty if auth is disabled)
+	Model        string        // Device model reported on connect (empty if unknown)
+	Firmware     string        // Firmware version reported on connect (empty if unknown)
+	ConnectedAt  time.Time     // Connection establishment time
+	LastPingSent time.Time     // Last application-level PING sent time (guarded by Hub.mu)
+	LastPongRecv time.Time     // Last application-level PONG received time (guarded by Hub.mu)
+	LastRTT      int64         // Last measured RTT in milliseconds (guarded by Hub.mu)
+	lastPingMono time.Duration // Hub monotonic reading when LastPingSent was taken, for the RTT (guarded by Hub.mu)
+
+	// Largest message in bytes accepted from the peer
+	maxMessageSize int64
+
+	// Backpressure handling when Send is full, copied from the hub at creation
+	sendPolicy  SendPolicy
+	sendTimeout time.Duration
+	// Time allowed to write a message to the peer, copied from the hub at creation (0 = DefaultWriteWait)
+	writeWait time.Duration
+	// Messages dropped because Send was full
+	droppedMessages atomic.Int64
+	closeOnce       sync.Once
+	// Closed by stop once the hub is done with the client. Send itself is never closed, since
+	// SendMessage may still be waiting on it.
+	done     chan struct{}
+	doneOnce sync.Once
+	// Why the connection ended, see models.DisconnectReason*; the first cause recorded wins
+	disconnectReason atomic.Pointer[string]
 }
 
-func NewClient(hub *Hub, conn *websocket.Conn, deviceID string, deviceType models.DeviceType) *Client {
+// NewClient creates a client for an upgraded connection. maxMessageSize bounds the messages
+// read from the peer (<= 0 uses DefaultMaxMessageSize) and sendBufferSize the messages queued
+// for it (<= 0 uses DefaultSendBufferSize).
+func NewClient(hub *Hub, conn *websocket.Conn, deviceID string, deviceType models.DeviceType, maxMessageSize int64, sendBufferSize int) *Client {
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	if sendBufferSize <= 0 {
+		sendBufferSize = DefaultSendBufferSize
+	}
 	now := time.Now()
 	return &Client{
-		Hub:          hub,
-		Conn:         conn,
-		Send:         make(chan []byte, 256),
-		DeviceID:     deviceID,
-		DeviceType:   deviceType,
-		ConnectedAt:  now,
-		LastPingSent: now,
-		LastPongRecv: now,
-		LastRTT:      0,
+		Hub:            hub,
+		Conn:           conn,
+		Send:           make(chan []byte, sendBufferSize),
+		DeviceID:       deviceID,
+		DeviceType:     deviceType,
+		ConnectedAt:    now,
+		LastPingSent:   now,
+		LastPongRecv:   now,
+		LastRTT:        0,
+		maxMessageSize: maxMessageSize,
+		sendPolicy:     hub.sendPolicy,
+		sendTimeout:    hub.sendTimeout,
+		writeWait:      hub.writeWait,
+		done:           make(chan struct{}),
 	}
 }
 
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
-		c.Hub.Unregister <- c
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.stopped:
+			// Hub is no longer running
+		}
 		c.Conn.Close()
 	}()
 
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.Conn.SetReadLimit(maxMessageSize)
+	c.Conn.SetReadLimit(c.maxMessageSize)
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
@@ -71,8 +140,13 @@ func (c *Client) ReadPump() {
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.setDisconnectReason(models.DisconnectReasonCleanClose)
+			} else {
+				c.setDisconnectReason(models.DisconnectReasonReadError)
+			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("websocket error: %v", err)
+				c.log().Warn("websocket error", "error", err)
 			}
 			break
 		}
@@ -94,16 +168,17 @@ func (c *Client) WritePump() {
 
 	for {
 		select {
-		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+		case <-c.done:
+			// The hub is done with the client
+			c.Conn.SetWriteDeadline(c.writeDeadline())
+			c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
 
+		case message := <-c.Send:
+			c.Conn.SetWriteDeadline(c.writeDeadline())
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
+				c.handleWriteError(err)
 				return
 			}
 			w.Write(message)
@@ -116,13 +191,15 @@ func (c *Client) WritePump() {
 			}
 
 			if err := w.Close(); err != nil {
+				c.handleWriteError(err)
 				return
 			}
 
 		case <-protocolPingTicker.C:
 			// WebSocket protocol-level ping
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.Conn.SetWriteDeadline(c.writeDeadline())
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.handleWriteError(err)
 				return
 			}
 
@@ -133,31 +210,121 @@ func (c *Client) WritePump() {
 	}
 }
 
-// SendMessage sends a JSON message to the client
+// writeDeadline returns the deadline for a write started now
+func (c *Client) writeDeadline() time.Time {
+	wait := c.writeWait
+	if wait <= 0 {
+		wait = DefaultWriteWait
+	}
+	return time.Now().Add(wait)
+}
+
+// handleWriteError records why WritePump is giving up on the connection. A write that hits its
+// deadline leaves the connection unusable, so a stalled client is disconnected right away and the
+// timeout is counted against the device. Closing the connection makes ReadPump unregister the client.
+func (c *Client) handleWriteError(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		timeouts := c.Hub.recordWriteTimeout(c.DeviceID)
+		c.log().Warn("write deadline exceeded, disconnecting slow client", "write_timeouts", timeouts)
+		c.setDisconnectReason(models.DisconnectReasonWriteTimeout)
+		return
+	}
+	c.setDisconnectReason(models.DisconnectReasonWriteError)
+}
+
+// SendMessage queues a JSON message for the client. If the send buffer is full the
+// message is handled according to the client's SendPolicy; a dropped message is counted
+// and reported as ErrSendBufferFull. Once the hub is done with the client nothing is
+// queued and ErrClientClosed is returned.
 func (c *Client) SendMessage(msg interface{}) error {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
 	select {
 	case c.Send <- data:
 		return nil
 	default:
-		return websocket.ErrCloseSent
 	}
+
+	if c.sendPolicy == SendPolicyBlock && c.sendTimeout > 0 {
+		timer := time.NewTimer(c.sendTimeout)
+		defer timer.Stop()
+		select {
+		case c.Send <- data:
+			return nil
+		case <-c.done:
+			return ErrClientClosed
+		case <-timer.C:
+		}
+	}
+
+	dropped := c.droppedMessages.Add(1)
+	if c.sendPolicy == SendPolicyDisconnect {
+		c.log().Warn("send buffer full, disconnecting slow client", "dropped_messages", dropped)
+		c.setDisconnectReason(models.DisconnectReasonSendBufferFull)
+		// ReadPump exits and unregisters the client
+		c.closeOnce.Do(func() { c.Conn.Close() })
+	} else {
+		c.log().Warn("send buffer full, message dropped", "dropped_messages", dropped)
+	}
+	return ErrSendBufferFull
+}
+
+// stop tells WritePump to send a close frame and exit, and makes senders waiting for room
+// in Send give up. Safe to call more than once.
+func (c *Client) stop() {
+	c.doneOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
+}
+
+// DroppedMessages returns how many messages were dropped because the send buffer was full
+func (c *Client) DroppedMessages() int64 {
+	return c.droppedMessages.Load()
+}
+
+// setDisconnectReason records why the connection is ending unless a cause was already recorded
+func (c *Client) setDisconnectReason(reason string) {
+	c.disconnectReason.CompareAndSwap(nil, &reason)
+}
+
+// DisconnectReason returns why the connection ended (empty while it is open)
+func (c *Client) DisconnectReason() string {
+	if reason := c.disconnectReason.Load(); reason != nil {
+		return *reason
+	}
+	return ""
 }
 
-// sendAppPing sends an application-level PING message to the client
+// sendAppPing sends an application-level PING message to the client.
+// The ping times are read by handlePong on the ReadPump goroutine, so they are set under the hub lock.
 func (c *Client) sendAppPing() {
+	c.Hub.mu.Lock()
 	c.LastPingSent = time.Now()
+	c.lastPingMono = c.Hub.monotonic()
 	pingMsg := models.PingMessage{
 		Type:      models.MessageTypePing,
 		Timestamp: c.LastPingSent.UnixMilli(),
 	}
+	c.Hub.mu.Unlock()
 
 	if err := c.SendMessage(pingMsg); err != nil {
-		log.Printf("Failed to send PING to device %s: %v", c.DeviceID, err)
-		// 에러는 중요하므로 유지
+		c.log().Warn("failed to send PING", "error", err)
 	}
 }
+
+// log returns the hub's logger with this client's device ID attached
+func (c *Client) log() *slog.Logger {
+	return c.Hub.log().With(logging.KeyDeviceID, c.DeviceID)
+}
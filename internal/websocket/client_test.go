@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWritePumpDisconnectsStalledClient(t *testing.T) {
+	h := NewHub()
+	h.SetWriteWait(100 * time.Millisecond)
+	go h.Run()
+
+	upgraded := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		upgraded <- conn
+	}))
+	defer server.Close()
+
+	// The peer never reads, so once the socket buffers fill every write blocks
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer peer.Close()
+
+	client := NewClient(h, <-upgraded, "watch-001", models.DeviceTypeWatch, 0, 0)
+	// Queue far more than the socket buffers hold; WritePump sends it as one frame
+	payload := []byte(strings.Repeat("x", 128*1024))
+	for len(client.Send) < cap(client.Send) {
+		client.Send <- payload
+	}
+	h.mu.Lock()
+	h.Clients[client.DeviceID] = client
+	h.mu.Unlock()
+	go client.ReadPump()
+	start := time.Now()
+	go client.WritePump()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, err := h.GetDeviceHealthByID("watch-001"); err != nil {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("stalled client is still registered")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("client unregistered after %s, expected the write deadline to fail fast", elapsed)
+	}
+	if reason := client.DisconnectReason(); reason != models.DisconnectReasonWriteTimeout {
+		t.Errorf("DisconnectReason() = %q, expected %q", reason, models.DisconnectReasonWriteTimeout)
+	}
+
+	// The count survives the reconnect so a device that keeps stalling shows up in its health
+	h.mu.Lock()
+	h.Clients["watch-001"] = &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, LastPongRecv: time.Now()}
+	h.mu.Unlock()
+	if health, err := h.GetDeviceHealthByID("watch-001"); err != nil || health.WriteTimeouts != 1 {
+		t.Errorf("GetDeviceHealthByID() = %+v, %v, expected WriteTimeouts = 1", health, err)
+	}
+}
+
+func TestNewClientSendBufferSize(t *testing.T) {
+	h := NewHub()
+
+	if client := NewClient(h, nil, "psg-001", models.DeviceTypePSG, 0, 0); cap(client.Send) != DefaultSendBufferSize {
+		t.Errorf("cap(Send) = %d, expected the default %d", cap(client.Send), DefaultSendBufferSize)
+	}
+
+	client := NewClient(h, nil, "watch-001", models.DeviceTypeWatch, 0, 4)
+	client.Send <- []byte("queued")
+	h.mu.Lock()
+	h.Clients[client.DeviceID] = client
+	h.mu.Unlock()
+
+	health, err := h.GetDeviceHealthByID("watch-001")
+	if err != nil {
+		t.Fatalf("GetDeviceHealthByID() error = %v", err)
+	}
+	if health.SendBufferDepth != 1 || health.SendBufferSize != 4 {
+		t.Errorf("health send buffer = %d/%d, expected 1/4", health.SendBufferDepth, health.SendBufferSize)
+	}
+}
+
+// newFullClient returns a client whose one-message send buffer is already full
+func newFullClient(h *Hub, policy SendPolicy, timeout time.Duration) *Client {
+	client := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 1), sendPolicy: policy, sendTimeout: timeout}
+	client.Send <- []byte("queued")
+	return client
+}
+
+func TestSendMessageDropPolicy(t *testing.T) {
+	client := newFullClient(NewHub(), SendPolicyDrop, time.Second)
+
+	// The send timeout only applies to the block policy
+	start := time.Now()
+	if err := client.SendMessage(models.PingMessage{Type: models.MessageTypePing}); !errors.Is(err, ErrSendBufferFull) {
+		t.Errorf("SendMessage() error = %v, expected ErrSendBufferFull", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("SendMessage() took %s, expected the message to be dropped right away", elapsed)
+	}
+	if dropped := client.DroppedMessages(); dropped != 1 {
+		t.Errorf("DroppedMessages() = %d, expected 1", dropped)
+	}
+	if reason := client.DisconnectReason(); reason != "" {
+		t.Errorf("DisconnectReason() = %q, expected the client to stay connected", reason)
+	}
+}
+
+func TestSendMessageBlockPolicy(t *testing.T) {
+	t.Run("room frees up in time", func(t *testing.T) {
+		client := newFullClient(NewHub(), SendPolicyBlock, 2*time.Second)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			<-client.Send
+		}()
+
+		if err := client.SendMessage(models.PingMessage{Type: models.MessageTypePing}); err != nil {
+			t.Fatalf("SendMessage() error = %v, expected it to wait for room", err)
+		}
+		if len(client.Send) != 1 || client.DroppedMessages() != 0 {
+			t.Errorf("queued, dropped = %d, %d, expected the message queued and none dropped", len(client.Send), client.DroppedMessages())
+		}
+	})
+
+	t.Run("dropped after the timeout", func(t *testing.T) {
+		client := newFullClient(NewHub(), SendPolicyBlock, 100*time.Millisecond)
+
+		start := time.Now()
+		if err := client.SendMessage(models.PingMessage{Type: models.MessageTypePing}); !errors.Is(err, ErrSendBufferFull) {
+			t.Errorf("SendMessage() error = %v, expected ErrSendBufferFull", err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("SendMessage() gave up after %s, expected it to wait for the send timeout", elapsed)
+		}
+		if dropped := client.DroppedMessages(); dropped != 1 {
+			t.Errorf("DroppedMessages() = %d, expected 1", dropped)
+		}
+	})
+}
+
+func TestSendMessageDisconnectPolicy(t *testing.T) {
+	h := NewHub()
+	h.SetSendPolicy(SendPolicyDisconnect, 0)
+	client, peer := dialTestClient(t, h, "watch-001")
+	for len(client.Send) < cap(client.Send) {
+		client.Send <- []byte("queued")
+	}
+
+	if err := client.SendMessage(models.PingMessage{Type: models.MessageTypePing}); !errors.Is(err, ErrSendBufferFull) {
+		t.Errorf("SendMessage() error = %v, expected ErrSendBufferFull", err)
+	}
+	if dropped := client.DroppedMessages(); dropped != 1 {
+		t.Errorf("DroppedMessages() = %d, expected 1", dropped)
+	}
+	if reason := client.DisconnectReason(); reason != models.DisconnectReasonSendBufferFull {
+		t.Errorf("DisconnectReason() = %q, expected %q", reason, models.DisconnectReasonSendBufferFull)
+	}
+
+	// The connection is closed, so the peer's read fails
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var netErr net.Error
+	if _, _, err := peer.ReadMessage(); err == nil {
+		t.Error("peer ReadMessage() succeeded, expected the connection to be closed")
+	} else if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Errorf("peer ReadMessage() error = %v, expected the connection to be closed before the deadline", err)
+	}
+}
+
+func TestSendMessageBlockedDuringUnregister(t *testing.T) {
+	h := NewHub()
+	h.SetSendPolicy(SendPolicyBlock, 5*time.Second)
+	go h.Run()
+	client := NewClient(h, nil, "watch-001", models.DeviceTypeWatch, 0, 1)
+	client.Send <- []byte("queued")
+	h.mu.Lock()
+	h.Clients[client.DeviceID] = client
+	h.mu.Unlock()
+
+	// A sender is parked on the full buffer while the client unregisters
+	sent := make(chan error, 1)
+	go func() {
+		sent <- client.SendMessage(models.PingMessage{Type: models.MessageTypePing})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	h.Unregister <- client
+
+	select {
+	case err := <-sent:
+		if !errors.Is(err, ErrClientClosed) {
+			t.Errorf("SendMessage() error = %v, expected ErrClientClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SendMessage() still blocked after the client unregistered")
+	}
+
+	// Later sends give up right away instead of queuing for nobody
+	if err := client.SendMessage(models.PingMessage{Type: models.MessageTypePing}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("SendMessage() after unregister error = %v, expected ErrClientClosed", err)
+	}
+}
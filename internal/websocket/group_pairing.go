@@ -0,0 +1,391 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"time-sync-server/internal/metrics"
+	"time-sync-server/internal/models"
+)
+
+// PendingGroupRequest is a TIME_REQUEST fanned out to every member of a group pairing
+type PendingGroupRequest struct {
+	RequestID         string
+	PairingID         string
+	DeviceIDs         []string
+	ReferenceDeviceID string
+	ServerRequestTime int64
+	// Per-device responses and RTT measurement fields (deviceID -> value)
+	Responses    map[string]int64 // Device timestamps (milliseconds)
+	SendTimes    map[string]int64 // Request send times (microseconds on the hub's monotonic clock)
+	ReceiveTimes map[string]int64 // Response receive times (microseconds on the hub's monotonic clock)
+	ResponseChan chan *models.GroupSyncRecord
+	TimeoutTimer *time.Timer
+	// Set when the request is aborted by the server; completes the request as FAILED
+	FailureReason string
+	// Set when a member disconnected before responding
+	DisconnectedDeviceID string
+}
+
+// CreateGroupPairing creates an in-memory pairing of two or more connected devices.
+// The reference device defaults to the first device of the reference device type
+// (see SetReferenceDeviceType), or the first device, when empty.
+func (h *Hub) CreateGroupPairing(deviceIDs []string, referenceDeviceID string) (*models.GroupPairing, error) {
+	if len(deviceIDs) < 2 {
+		return nil, fmt.Errorf("group pairing requires at least 2 devices")
+	}
+
+	seen := make(map[string]bool, len(deviceIDs))
+	for _, id := range deviceIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate device in group pairing: %s", id)
+		}
+		seen[id] = true
+	}
+
+	if referenceDeviceID != "" && !seen[referenceDeviceID] {
+		return nil, fmt.Errorf("reference device %s is not a member of the group", referenceDeviceID)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Check if all devices are connected
+	for _, id := range deviceIDs {
+		if _, ok := h.Clients[id]; !ok {
+			return nil, &DeviceNotConnectedError{DeviceID: id}
+		}
+	}
+
+	if referenceDeviceID == "" {
+		referenceDeviceID = deviceIDs[0]
+		for _, id := range deviceIDs {
+			if h.referenceDeviceType != "" && h.Clients[id].DeviceType == h.referenceDeviceType {
+				referenceDeviceID = id
+				break
+			}
+		}
+	}
+
+	pairing := &models.GroupPairing{
+		PairingID:         uuid.New().String(),
+		DeviceIDs:         append([]string(nil), deviceIDs...),
+		ReferenceDeviceID: referenceDeviceID,
+		CreatedAt:         time.Now(),
+	}
+
+	h.GroupPairings[pairing.PairingID] = pairing
+	h.updateGauges()
+	log.Printf("Group pairing created: %s (%v, reference: %s)", pairing.PairingID, deviceIDs, referenceDeviceID)
+
+	return pairing, nil
+}
+
+// GetGroupPairings returns all in-memory group pairings
+func (h *Hub) GetGroupPairings() []*models.GroupPairing {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	pairings := make([]*models.GroupPairing, 0, len(h.GroupPairings))
+	for _, pairing := range h.GroupPairings {
+		pairings = append(pairings, pairing)
+	}
+	return pairings
+}
+
+// DeleteGroupPairing removes a group pairing from memory
+func (h *Hub) DeleteGroupPairing(pairingID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.GroupPairings[pairingID]; !ok {
+		return &PairingNotFoundError{PairingID: pairingID}
+	}
+
+	delete(h.GroupPairings, pairingID)
+	h.updateGauges()
+	log.Printf("Group pairing deleted: %s", pairingID)
+	return nil
+}
+
+// IsGroupPairingRestored checks if a group pairing is already restored in memory
+func (h *Hub) IsGroupPairingRestored(pairingID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.GroupPairings[pairingID]
+	return ok
+}
+
+// RestoreGroupPairing restores a group pairing from DB to in-memory once all members are connected
+func (h *Hub) RestoreGroupPairing(pairing *models.GroupPairing) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.GroupPairings[pairing.PairingID]; ok {
+		return nil // Already restored, no error
+	}
+
+	for _, id := range pairing.DeviceIDs {
+		if _, ok := h.Clients[id]; !ok {
+			return &DeviceNotConnectedError{DeviceID: id}
+		}
+	}
+
+	h.GroupPairings[pairing.PairingID] = pairing
+	h.updateGauges()
+	return nil
+}
+
+// RequestGroupTimeSync sends a TIME_REQUEST to every member of a group pairing and waits
+// until all of them respond or the timeout expires
+func (h *Hub) RequestGroupTimeSync(pairingID string, timeout time.Duration) (*models.GroupSyncRecord, error) {
+	h.mu.RLock()
+	pairing, ok := h.GroupPairings[pairingID]
+	if !ok {
+		h.mu.RUnlock()
+		return nil, &PairingNotFoundError{PairingID: pairingID}
+	}
+
+	clients := make([]*Client, 0, len(pairing.DeviceIDs))
+	for _, id := range pairing.DeviceIDs {
+		client, ok := h.Clients[id]
+		if !ok {
+			err := h.disconnectedError(id)
+			h.mu.RUnlock()
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	requestID := uuid.New().String()
+	responseChan := make(chan *models.GroupSyncRecord, 1)
+
+	pendingReq := &PendingGroupRequest{
+		RequestID:         requestID,
+		PairingID:         pairingID,
+		DeviceIDs:         pairing.DeviceIDs,
+		ReferenceDeviceID: pairing.ReferenceDeviceID,
+		ServerRequestTime: time.Now().UnixMilli(),
+		Responses:         make(map[string]int64),
+		SendTimes:         make(map[string]int64),
+		ReceiveTimes:      make(map[string]int64),
+		ResponseChan:      responseChan,
+	}
+
+	h.mu.Lock()
+	if h.pendingLimitReached() {
+		h.mu.Unlock()
+		return nil, &ServerBusyError{MaxPendingRequests: h.maxPendingRequests}
+	}
+	h.PendingGroupRequests[requestID] = pendingReq
+	h.mu.Unlock()
+
+	// Set timeout
+	pendingReq.TimeoutTimer = time.AfterFunc(timeout, func() {
+		h.handleGroupTimeout(requestID)
+	})
+
+	timeReqMsg := models.TimeRequestMessage{
+		Type:      models.MessageTypeTimeRequest,
+		RequestID: requestID,
+		PairingID: pairingID,
+	}
+
+	// Send time request to all members simultaneously
+	for _, client := range clients {
+		go func(client *Client) {
+			// RTT START: Record send time for this member
+			sendTime := h.monotonic().Microseconds()
+			h.mu.Lock()
+			if req, ok := h.PendingGroupRequests[requestID]; ok {
+				req.SendTimes[client.DeviceID] = sendTime
+			}
+			h.mu.Unlock()
+
+			if err := client.SendMessage(timeReqMsg); err != nil {
+				log.Printf("Failed to send time request to device %s: %v", client.DeviceID, err)
+			}
+		}(client)
+	}
+
+	// Wait for response or timeout
+	record := <-responseChan
+	return record, nil
+}
+
+// handleGroupTimeResponse stores a TIME_RESPONSE belonging to a group request.
+// receiveMono is the hub's monotonic clock reading when the response arrived.
+// Returns false if the request ID is not a pending group request. Caller must hold h.mu.
+func (h *Hub) handleGroupTimeResponse(client *Client, resp *models.TimeResponseMessage, receiveMono time.Duration) bool {
+	pendingReq, ok := h.PendingGroupRequests[resp.RequestID]
+	if !ok {
+		return false
+	}
+
+	if !pendingReq.hasMember(client.DeviceID) {
+		log.Printf("Response from unexpected device: %s", client.DeviceID)
+		return true
+	}
+
+	// Keep the first response of a device, a retransmit would inflate its RTT
+	if _, answered := pendingReq.Responses[client.DeviceID]; answered {
+		log.Printf("Duplicate group time response from %s dropped: %s", client.DeviceID, resp.RequestID)
+		return true
+	}
+
+	pendingReq.Responses[client.DeviceID] = resp.Timestamp
+	pendingReq.ReceiveTimes[client.DeviceID] = receiveMono.Microseconds()
+
+	// Complete once every member has answered
+	if len(pendingReq.Responses) == len(pendingReq.DeviceIDs) {
+		h.completeGroupSyncRequest(pendingReq)
+	}
+	return true
+}
+
+func (h *Hub) handleGroupTimeout(requestID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pendingReq, ok := h.PendingGroupRequests[requestID]
+	if !ok {
+		return
+	}
+
+	log.Printf("Group time sync request timeout: %s", requestID)
+	metrics.SyncTimeoutsTotal.Inc()
+	h.completeGroupSyncRequest(pendingReq)
+}
+
+// completeGroupSyncRequest builds one record per non-reference member (member vs reference)
+// and delivers the result. Caller must hold h.mu.
+func (h *Hub) completeGroupSyncRequest(pendingReq *PendingGroupRequest) {
+	// Stop timeout timer
+	if pendingReq.TimeoutTimer != nil {
+		pendingReq.TimeoutTimer.Stop()
+	}
+
+	serverResponseTime := time.Now().UnixMilli()
+	refID := pendingReq.ReferenceDeviceID
+
+	result := &models.GroupSyncRecord{
+		PairingID:         pendingReq.PairingID,
+		ReferenceDeviceID: refID,
+		Records:           make([]*models.TimeSyncRecord, 0, len(pendingReq.DeviceIDs)-1),
+	}
+
+	if pendingReq.FailureReason != "" {
+		// Aborted by the server: discard partial responses
+		pendingReq.Responses = map[string]int64{}
+	}
+
+	switch len(pendingReq.Responses) {
+	case len(pendingReq.DeviceIDs):
+		result.Status = models.SyncStatusSuccess
+	case 0:
+		result.Status = models.SyncStatusFailed
+	default:
+		result.Status = models.SyncStatusPartial
+	}
+
+	for _, memberID := range pendingReq.DeviceIDs {
+		if memberID == refID {
+			continue
+		}
+
+		record := &models.TimeSyncRecord{
+			Device1ID:          memberID,
+			Device1Timestamp:   pendingReq.timestamp(memberID),
+			Device1RTT:         pendingReq.rtt(memberID),
+			Device2ID:          refID,
+			Device2Timestamp:   pendingReq.timestamp(refID),
+			Device2RTT:         pendingReq.rtt(refID),
+			ServerRequestTime:  pendingReq.ServerRequestTime,
+			ServerResponseTime: &serverResponseTime,
+			CreatedAt:          time.Now().UnixMilli(),
+		}
+		if client, ok := h.Clients[memberID]; ok {
+			record.Device1Type = client.DeviceType
+		}
+		if client, ok := h.Clients[refID]; ok {
+			record.Device2Type = client.DeviceType
+		}
+
+		// Determine status and RAW time difference (member - reference)
+		if record.Device1Timestamp != nil && record.Device2Timestamp != nil {
+			record.Status = models.SyncStatusSuccess
+			rawDiff := *record.Device1Timestamp - *record.Device2Timestamp
+			record.TimeDifference = &rawDiff
+		} else if record.Device1Timestamp != nil || record.Device2Timestamp != nil {
+			record.Status = models.SyncStatusPartial
+			msg := "One or more devices did not respond"
+			if id := pendingReq.DisconnectedDeviceID; id == memberID || id == refID {
+				msg = fmt.Sprintf("Device %s disconnected before responding", id)
+			}
+			record.ErrorMessage = &msg
+		} else if pendingReq.FailureReason != "" {
+			record.Status = models.SyncStatusFailed
+			msg := pendingReq.FailureReason
+			record.ErrorMessage = &msg
+		} else {
+			record.Status = models.SyncStatusFailed
+			msg := "Both devices failed to respond"
+			record.ErrorMessage = &msg
+		}
+
+		result.Records = append(result.Records, record)
+	}
+
+	// Update metrics
+	metrics.SyncRequestsTotal.Inc()
+	if result.Status != models.SyncStatusSuccess {
+		metrics.SyncFailuresTotal.WithLabelValues(string(result.Status)).Inc()
+	}
+	for _, deviceID := range pendingReq.DeviceIDs {
+		if client, ok := h.Clients[deviceID]; ok {
+			metrics.ObserveRTT(string(client.DeviceType), pendingReq.rtt(deviceID))
+		}
+	}
+
+	// Send result through channel
+	select {
+	case pendingReq.ResponseChan <- result:
+	default:
+	}
+
+	// Clean up
+	delete(h.PendingGroupRequests, pendingReq.RequestID)
+}
+
+// hasMember reports whether the device is one of the request's group members
+func (p *PendingGroupRequest) hasMember(deviceID string) bool {
+	for _, id := range p.DeviceIDs {
+		if id == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// timestamp returns the device's reported timestamp, or nil if it did not respond
+func (p *PendingGroupRequest) timestamp(deviceID string) *int64 {
+	ts, ok := p.Responses[deviceID]
+	if !ok {
+		return nil
+	}
+	return &ts
+}
+
+// rtt returns the device's RTT in microseconds, or nil if it did not respond
+func (p *PendingGroupRequest) rtt(deviceID string) *int64 {
+	receiveTime, ok := p.ReceiveTimes[deviceID]
+	sendTime := p.SendTimes[deviceID]
+	if !ok || sendTime <= 0 {
+		return nil
+	}
+	rtt := receiveTime - sendTime
+	return &rtt
+}
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// newTestGroup registers a connected client per device and a group pairing of them,
+// with the first device as the reference
+func newTestGroup(h *Hub, deviceIDs ...string) map[string]*Client {
+	clients := make(map[string]*Client, len(deviceIDs))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range deviceIDs {
+		clients[id] = &Client{Hub: h, DeviceID: id, DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+		h.Clients[id] = clients[id]
+	}
+	h.GroupPairings["group-1"] = &models.GroupPairing{PairingID: "group-1", DeviceIDs: deviceIDs, ReferenceDeviceID: deviceIDs[0]}
+	return clients
+}
+
+// startGroupSync runs RequestGroupTimeSync in the background and returns the request ID
+// sent to the members together with the channel its result is delivered on
+func startGroupSync(t *testing.T, h *Hub, clients map[string]*Client) (string, <-chan *models.GroupSyncRecord) {
+	t.Helper()
+	done := make(chan *models.GroupSyncRecord, 1)
+	go func() {
+		record, _ := h.RequestGroupTimeSync("group-1", time.Minute)
+		done <- record
+	}()
+	var requestID string
+	for _, client := range clients {
+		requestID = awaitTimeRequest(t, client)
+	}
+	return requestID, done
+}
+
+func awaitGroupResult(t *testing.T, done <-chan *models.GroupSyncRecord) *models.GroupSyncRecord {
+	t.Helper()
+	select {
+	case record := <-done:
+		return record
+	case <-time.After(2 * time.Second):
+		t.Fatal("RequestGroupTimeSync() did not return")
+	}
+	return nil
+}
+
+func TestRequestGroupTimeSync(t *testing.T) {
+	h, clocks := newFakeClockHub()
+	clocks.mono.Store(int64(time.Millisecond))
+	clients := newTestGroup(h, "psg-001", "watch-001", "watch-002")
+
+	requestID, done := startGroupSync(t, h, clients)
+	respond := func(deviceID string, elapsed time.Duration, timestamp int64) {
+		clocks.mono.Store(int64(elapsed))
+		h.HandleMessage(clients[deviceID], []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, timestamp)))
+	}
+	respond("psg-001", 3*time.Millisecond, 1_000_000)
+	respond("watch-001", 4*time.Millisecond, 1_000_040)
+	respond("watch-001", 9*time.Millisecond, 1_000_090) // Retransmit of the same response
+	respond("watch-002", 5*time.Millisecond, 999_970)
+
+	result := awaitGroupResult(t, done)
+	if result.Status != models.SyncStatusSuccess || len(result.Records) != 2 {
+		t.Fatalf("Status, records = %s, %d, expected %s with one record per non-reference member", result.Status, len(result.Records), models.SyncStatusSuccess)
+	}
+	expected := map[string]struct{ difference, rtt int64 }{
+		"watch-001": {40, 3000}, // The first response is kept
+		"watch-002": {-30, 4000},
+	}
+	for _, record := range result.Records {
+		want, ok := expected[record.Device1ID]
+		if !ok || record.Device2ID != "psg-001" {
+			t.Errorf("record %s vs %s, expected a member measured against psg-001", record.Device1ID, record.Device2ID)
+			continue
+		}
+		if record.TimeDifference == nil || *record.TimeDifference != want.difference {
+			t.Errorf("%s TimeDifference = %v, expected %d", record.Device1ID, ptrValue(record.TimeDifference), want.difference)
+		}
+		if record.Device1RTT == nil || *record.Device1RTT != want.rtt {
+			t.Errorf("%s Device1RTT = %v, expected %d", record.Device1ID, ptrValue(record.Device1RTT), want.rtt)
+		}
+	}
+	if count := h.PendingRequestCount(); count != 0 {
+		t.Errorf("PendingRequestCount() = %d, expected the group request to be removed", count)
+	}
+}
+
+func TestUnregisterCompletesPendingGroupRequests(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+	clients := newTestGroup(h, "psg-001", "watch-001", "watch-002")
+
+	requestID, done := startGroupSync(t, h, clients)
+	for _, deviceID := range []string{"psg-001", "watch-001"} {
+		h.HandleMessage(clients[deviceID], []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, time.Now().UnixMilli())))
+	}
+	h.Unregister <- clients["watch-002"]
+
+	result := awaitGroupResult(t, done)
+	if result.Status != models.SyncStatusPartial {
+		t.Errorf("Status = %s, expected %s", result.Status, models.SyncStatusPartial)
+	}
+	for _, record := range result.Records {
+		switch record.Device1ID {
+		case "watch-001":
+			if record.Status != models.SyncStatusSuccess {
+				t.Errorf("watch-001 Status = %s, expected the answered member to succeed", record.Status)
+			}
+		case "watch-002":
+			if record.ErrorMessage == nil || !strings.Contains(*record.ErrorMessage, "watch-002 disconnected") {
+				t.Errorf("watch-002 ErrorMessage = %v, expected it to name the disconnected device", record.ErrorMessage)
+			}
+		}
+	}
+	if count := h.PendingRequestCount(); count != 0 {
+		t.Errorf("PendingRequestCount() = %d, expected the group request to be removed", count)
+	}
+}
+
+func TestGroupRequestsCountTowardsPendingCap(t *testing.T) {
+	h := NewHub()
+	h.SetMaxPendingRequests(2)
+	newTestGroup(h, "psg-001", "watch-001")
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: "psg-001", Device2ID: "watch-001"}
+
+	h.PendingRequests["req-1"] = &PendingRequest{RequestID: "req-1", PairingID: "pair-1"}
+	h.PendingGroupRequests["req-2"] = &PendingGroupRequest{RequestID: "req-2", PairingID: "group-1"}
+
+	var busy *ServerBusyError
+	if _, err := h.RequestGroupTimeSync("group-1", time.Second); !errors.As(err, &busy) {
+		t.Errorf("RequestGroupTimeSync() error = %v, expected *ServerBusyError", err)
+	}
+	if _, err := h.RequestTimeSync("pair-1", time.Second, ""); !errors.As(err, &busy) {
+		t.Errorf("RequestTimeSync() error = %v, expected *ServerBusyError with a group request in flight", err)
+	}
+	if count := h.PendingRequestCount(); count != 2 {
+		t.Errorf("PendingRequestCount() = %d, expected both kinds of request to count", count)
+	}
+}
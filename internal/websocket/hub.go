@@ -1,12 +1,22 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"time-sync-server/internal/events"
+	"time-sync-server/internal/logging"
+	"time-sync-server/internal/metrics"
 	"time-sync-server/internal/models"
 )
 
@@ -17,9 +27,21 @@ type Hub struct {
 	// Active pairings (pairingID -> Pairing)
 	Pairings map[string]*models.Pairing
 
+	// Active group pairings of more than two devices (pairingID -> GroupPairing)
+	GroupPairings map[string]*models.GroupPairing
+
 	// Pending time sync requests (requestID -> PendingRequest)
 	PendingRequests map[string]*PendingRequest
 
+	// Pending group time sync requests (requestID -> PendingGroupRequest)
+	PendingGroupRequests map[string]*PendingGroupRequest
+
+	// Pending device-initiated pairing requests awaiting confirmation (requestID -> PendingPairRequest)
+	PendingPairRequests map[string]*PendingPairRequest
+
+	// Pending RTT probes awaiting RTT_PROBE_ACK (probeID -> PendingProbe)
+	PendingProbes map[string]*PendingProbe
+
 	// Register requests from the clients
 	Register chan *Client
 
@@ -29,39 +51,148 @@ type Hub struct {
 	// Pairing operator (set after initialization to avoid circular dependency)
 	pairingOperator PairingOperator
 
+	// Where connect/disconnect events are recorded (nil = not recorded)
+	eventStore DeviceEventStore
+
+	// Where connect/disconnect events are published for /ws/events (nil = not published)
+	events *events.EventBus
+
+	// Grace period after the first TIME_RESPONSE before completing a request as PARTIAL (0 = disabled)
+	partialTimeout time.Duration
+
+	// Maximum number of time sync requests awaiting responses (0 = unlimited)
+	maxPendingRequests int
+
+	// A device without a PONG for this long is reported unhealthy (0 = DefaultHealthThreshold)
+	healthThreshold time.Duration
+
+	// Device type two-device syncs are measured against, see SetReferenceDeviceType ("" = pairing order)
+	referenceDeviceType models.DeviceType
+
+	// What SendMessage does when a client's send buffer is full, see SendPolicy
+	sendPolicy  SendPolicy
+	sendTimeout time.Duration // How long SendPolicyBlock waits for room
+
+	// Time allowed to write a message to a client (0 = DefaultWriteWait)
+	writeWait time.Duration
+	// Connections dropped for exceeding the write deadline, per device across reconnects (deviceID -> count)
+	writeTimeouts map[string]int64
+
+	// How long the pairings of a disconnected device are kept (suspended) before they are purged (0 = purge immediately)
+	reconnectGrace time.Duration
+	// Disconnected devices within the reconnect grace period (deviceID -> suspension)
+	suspendedDevices map[string]*deviceSuspension
+
+	// Closed when Shutdown starts (stops the dead connection detector, rejects new clients)
+	shuttingDown chan struct{}
+	// Closed when the Run loop has stopped
+	stopped      chan struct{}
+	shutdownOnce sync.Once
+	stopOnce     sync.Once
+
+	// Structured logger for the sync flow (nil = slog.Default())
+	logger *slog.Logger
+
+	// RTTs are measured on Go's monotonic clock (time.Since(clockStart)) so that a wall clock step,
+	// e.g. by NTP, during a measurement cannot skew them. Wall clock readings are only used where
+	// they are compared with device clocks. Both can be replaced in tests (nil = real clocks).
+	clockStart time.Time
+	wallClock  func() time.Time
+	monoClock  func() time.Duration
+
 	mu sync.RWMutex
 }
 
+// deviceSuspension tracks a disconnected device whose pairings are kept until PurgeAt
+type deviceSuspension struct {
+	PurgeAt    time.Time
+	PurgeTimer *time.Timer
+}
+
 // PairingOperator interface to avoid circular dependency
 type PairingOperator interface {
 	OnDeviceConnected(deviceID string)
+	// CreatePairing creates and persists a device-initiated pairing. Devices that are already
+	// paired get their saved pairing back.
+	CreatePairing(device1ID, device2ID string) (*models.Pairing, error)
+}
+
+// DeviceEventStore persists device connect/disconnect events
+type DeviceEventStore interface {
+	SaveDeviceEvent(event *models.DeviceEvent) error
 }
 
 type PendingRequest struct {
-	RequestID          string
-	PairingID          string
-	Device1ID          string
-	Device2ID          string
-	Device1Response    *int64
-	Device2Response    *int64
-	ServerRequestTime  int64
+	RequestID         string
+	CorrelationID     string // Ties the log lines of this sync together (shared by the samples of a multi-sync)
+	PairingID         string
+	Device1ID         string
+	Device2ID         string
+	Device1Response   *int64
+	Device2Response   *int64
+	ServerRequestTime int64
 	// RTT measurement fields
 	Device1SendTime    int64  // Device1 request send time (microseconds)
 	Device2SendTime    int64  // Device2 request send time (microseconds)
 	Device1ReceiveTime *int64 // Device1 response receive time (microseconds)
 	Device2ReceiveTime *int64 // Device2 response receive time (microseconds)
-	ResponseChan       chan *models.TimeSyncRecord
-	TimeoutTimer       *time.Timer
+	// Monotonic clock readings the RTTs are computed from (see Hub.monotonic)
+	Device1SendMono    time.Duration
+	Device2SendMono    time.Duration
+	Device1ReceiveMono time.Duration
+	Device2ReceiveMono time.Duration
+	// Optional device-side times reported in TIME_RESPONSE (device clock, milliseconds)
+	Device1RecvTime  *int64 // When Device1 received the request
+	Device1ReplyTime *int64 // When Device1 sent its response
+	Device2RecvTime  *int64
+	Device2ReplyTime *int64
+	ResponseChan     chan *models.TimeSyncRecord
+	TimeoutTimer     *time.Timer
+	// Partial completion: once the first device answers, wait at most PartialTimeout for the other
+	PartialTimeout time.Duration
+	PartialTimer   *time.Timer
+	// Set when the request is aborted by the server; completes the request as FAILED
+	FailureReason string
+	// Set when a device disconnected before answering; the request completes without waiting
+	// for the timeout, as PARTIAL if the other device answered and FAILED otherwise
+	DisconnectedDeviceID string
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		Clients:         make(map[string]*Client),
-		Pairings:        make(map[string]*models.Pairing),
-		PendingRequests: make(map[string]*PendingRequest),
-		Register:        make(chan *Client),
-		Unregister:      make(chan *Client),
+		Clients:              make(map[string]*Client),
+		Pairings:             make(map[string]*models.Pairing),
+		GroupPairings:        make(map[string]*models.GroupPairing),
+		PendingRequests:      make(map[string]*PendingRequest),
+		PendingGroupRequests: make(map[string]*PendingGroupRequest),
+		PendingPairRequests:  make(map[string]*PendingPairRequest),
+		PendingProbes:        make(map[string]*PendingProbe),
+		suspendedDevices:     make(map[string]*deviceSuspension),
+		writeTimeouts:        make(map[string]int64),
+		sendPolicy:           SendPolicyDrop,
+		Register:             make(chan *Client),
+		Unregister:           make(chan *Client),
+		shuttingDown:         make(chan struct{}),
+		stopped:              make(chan struct{}),
+		clockStart:           time.Now(),
+	}
+}
+
+// now reads the wall clock, for timestamps that are combined with device clock readings
+func (h *Hub) now() time.Time {
+	if h.wallClock != nil {
+		return h.wallClock()
+	}
+	return time.Now()
+}
+
+// monotonic reads the monotonic clock as the time elapsed since the hub was created.
+// Differences of two readings are immune to wall clock adjustments.
+func (h *Hub) monotonic() time.Duration {
+	if h.monoClock != nil {
+		return h.monoClock()
 	}
+	return time.Since(h.clockStart)
 }
 
 func (h *Hub) Run() {
@@ -70,11 +201,28 @@ func (h *Hub) Run() {
 
 	for {
 		select {
+		case <-h.stopped:
+			return
+
 		case client := <-h.Register:
+			if h.isShuttingDown() {
+				// Refuse new connections during shutdown; WritePump sends the close frame
+				client.stop()
+				continue
+			}
+
 			h.mu.Lock()
 			h.Clients[client.DeviceID] = client
+			if suspension, ok := h.suspendedDevices[client.DeviceID]; ok {
+				// Reconnected within the grace period, its pairings are still in place
+				suspension.PurgeTimer.Stop()
+				delete(h.suspendedDevices, client.DeviceID)
+				client.log().Info("device reconnected within grace period, pairings resumed")
+			}
+			h.updateGauges()
 			h.mu.Unlock()
-			log.Printf("Client registered: %s (%s)", client.DeviceID, client.DeviceType)
+			client.log().Info("client registered", "device_type", client.DeviceType)
+			h.recordDeviceEvent(client, models.DeviceEventConnected, "")
 
 			// Send connected message
 			msg := models.ConnectedMessage{
@@ -84,31 +232,124 @@ func (h *Hub) Run() {
 			}
 			client.SendMessage(msg)
 
-			// Trigger pairing restoration (run in goroutine to avoid blocking)
-			if h.pairingOperator != nil {
-				go h.pairingOperator.OnDeviceConnected(client.DeviceID)
-			}
+			// Trigger pairing restoration (run in goroutine to avoid blocking),
+			// then tell the device which pairings are ready
+			go func(operator PairingOperator) {
+				if operator != nil {
+					operator.OnDeviceConnected(client.DeviceID)
+				}
+				h.sendPairingsRestored(client)
+			}(h.pairingOperator)
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
 			if _, ok := h.Clients[client.DeviceID]; ok {
+				// Complete before deleting the client so the records keep its device type
+				h.abortDeviceRequests(client.DeviceID)
 				delete(h.Clients, client.DeviceID)
-				close(client.Send)
-				log.Printf("Client unregistered: %s", client.DeviceID)
-
-				// Remove pairings involving this device
-				for pairingID, pairing := range h.Pairings {
-					if pairing.Device1ID == client.DeviceID || pairing.Device2ID == client.DeviceID {
-						delete(h.Pairings, pairingID)
-						log.Printf("Pairing removed: %s", pairingID)
+				client.stop()
+				reason := client.DisconnectReason()
+				client.log().Info("client unregistered", "reason", reason)
+				h.recordDeviceEvent(client, models.DeviceEventDisconnected, reason)
+
+				if h.reconnectGrace > 0 && !h.isShuttingDown() {
+					h.suspendDevice(client.DeviceID)
+				} else {
+					h.removeDevicePairings(client.DeviceID)
+				}
+
+				// Drop pairing requests this device was part of
+				for requestID, pending := range h.PendingPairRequests {
+					if pending.RequesterID == client.DeviceID || pending.TargetID == client.DeviceID {
+						pending.TimeoutTimer.Stop()
+						delete(h.PendingPairRequests, requestID)
+						log.Printf("Pair request dropped: %s", requestID)
 					}
 				}
+				h.updateGauges()
 			}
 			h.mu.Unlock()
 		}
 	}
 }
 
+// abortDeviceRequests completes the sync requests still waiting for a response from a device
+// that disconnected, since that response can no longer arrive. Caller must hold h.mu.
+func (h *Hub) abortDeviceRequests(deviceID string) {
+	for _, pendingReq := range h.PendingRequests {
+		waiting := (pendingReq.Device1ID == deviceID && pendingReq.Device1Response == nil) ||
+			(pendingReq.Device2ID == deviceID && pendingReq.Device2Response == nil)
+		if !waiting {
+			continue
+		}
+		pendingReq.logger(h.log()).Warn("device disconnected during time sync request", logging.KeyDeviceID, deviceID)
+		pendingReq.DisconnectedDeviceID = deviceID
+		h.completeSyncRequest(pendingReq)
+	}
+
+	for _, pendingReq := range h.PendingGroupRequests {
+		_, answered := pendingReq.Responses[deviceID]
+		if answered || !pendingReq.hasMember(deviceID) {
+			continue
+		}
+		log.Printf("Device %s disconnected during group time sync request %s", deviceID, pendingReq.RequestID)
+		pendingReq.DisconnectedDeviceID = deviceID
+		h.completeGroupSyncRequest(pendingReq)
+	}
+}
+
+// suspendDevice keeps the pairings of a disconnected device for the reconnect grace period
+// and purges them afterwards unless the device has reconnected. Caller must hold h.mu.
+func (h *Hub) suspendDevice(deviceID string) {
+	if old, ok := h.suspendedDevices[deviceID]; ok {
+		old.PurgeTimer.Stop()
+	}
+
+	suspension := &deviceSuspension{PurgeAt: time.Now().Add(h.reconnectGrace)}
+	suspension.PurgeTimer = time.AfterFunc(h.reconnectGrace, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// Reconnected (or suspended again) in the meantime
+		if h.suspendedDevices[deviceID] != suspension {
+			return
+		}
+		delete(h.suspendedDevices, deviceID)
+		h.log().Info("reconnect grace period expired", logging.KeyDeviceID, deviceID)
+		h.removeDevicePairings(deviceID)
+		h.updateGauges()
+	})
+	h.suspendedDevices[deviceID] = suspension
+
+	h.log().Info("device pairings suspended", logging.KeyDeviceID, deviceID, "grace", h.reconnectGrace.String())
+}
+
+// removeDevicePairings removes all pairings and group pairings involving the device. Caller must hold h.mu.
+func (h *Hub) removeDevicePairings(deviceID string) {
+	for pairingID, pairing := range h.Pairings {
+		if pairing.Device1ID == deviceID || pairing.Device2ID == deviceID {
+			delete(h.Pairings, pairingID)
+			log.Printf("Pairing removed: %s", pairingID)
+		}
+	}
+
+	for pairingID, pairing := range h.GroupPairings {
+		if pairing.HasDevice(deviceID) {
+			delete(h.GroupPairings, pairingID)
+			log.Printf("Group pairing removed: %s", pairingID)
+		}
+	}
+}
+
+// disconnectedError returns the error for a pairing member that is not connected.
+// Within the reconnect grace period it is a DeviceTemporarilyDisconnectedError. Caller must hold h.mu.
+func (h *Hub) disconnectedError(deviceID string) error {
+	if suspension, ok := h.suspendedDevices[deviceID]; ok {
+		return &DeviceTemporarilyDisconnectedError{DeviceID: deviceID, PurgeAt: suspension.PurgeAt}
+	}
+	return &DeviceNotConnectedError{DeviceID: deviceID}
+}
+
 // detectDeadConnections periodically checks for and closes dead connections
 func (h *Hub) detectDeadConnections() {
 	// Check interval: every 30 seconds
@@ -118,7 +359,13 @@ func (h *Hub) detectDeadConnections() {
 	// Timeout threshold: no PONG for 120 seconds
 	const deadConnectionTimeout = 120 * time.Second
 
-	for range ticker.C {
+	for {
+		select {
+		case <-h.shuttingDown:
+			return
+		case <-ticker.C:
+		}
+
 		h.mu.Lock()
 		now := time.Now()
 		deadClients := make([]*Client, 0)
@@ -136,6 +383,7 @@ func (h *Hub) detectDeadConnections() {
 		// Unregister dead clients
 		for _, client := range deadClients {
 			// Close the connection
+			client.setDisconnectReason(models.DisconnectReasonDeadConnection)
 			client.Conn.Close()
 			// This will trigger the client's ReadPump to exit and send Unregister
 		}
@@ -151,36 +399,68 @@ func (h *Hub) GetConnectedDevices() []*models.Device {
 		devices = append(devices, &models.Device{
 			DeviceID:    client.DeviceID,
 			DeviceType:  client.DeviceType,
+			Model:       client.Model,
+			Firmware:    client.Firmware,
 			ConnectedAt: client.ConnectedAt,
 		})
 	}
 	return devices
 }
 
+// GetConnections lists the live connections ordered by device ID
+func (h *Hub) GetConnections() []*models.ConnectionInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	connections := make([]*models.ConnectionInfo, 0, len(h.Clients))
+	for _, client := range h.Clients {
+		info := &models.ConnectionInfo{
+			DeviceID:        client.DeviceID,
+			DeviceType:      client.DeviceType,
+			Identity:        client.Identity,
+			ConnectedAt:     client.ConnectedAt,
+			LastRTT:         client.LastRTT,
+			SendBufferDepth: len(client.Send),
+			SendBufferSize:  cap(client.Send),
+			DroppedMessages: client.DroppedMessages(),
+		}
+		if client.Conn != nil {
+			info.RemoteAddr = client.Conn.RemoteAddr().String()
+		}
+		connections = append(connections, info)
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].DeviceID < connections[j].DeviceID
+	})
+	return connections
+}
+
+// CloseConnection forcibly closes a device's connection, e.g. one that is stuck.
+// The client's ReadPump then fails and unregisters it like any other disconnect.
+func (h *Hub) CloseConnection(deviceID string) error {
+	h.mu.RLock()
+	client, ok := h.Clients[deviceID]
+	h.mu.RUnlock()
+	if !ok {
+		return &DeviceNotConnectedError{DeviceID: deviceID}
+	}
+
+	client.log().Warn("closing connection on admin request")
+	client.setDisconnectReason(models.DisconnectReasonAdminClosed)
+	client.closeOnce.Do(func() { client.Conn.Close() })
+	return nil
+}
+
 // GetDeviceHealth retrieves health information for all connected devices
 func (h *Hub) GetDeviceHealth() []*models.DeviceHealth {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	const healthThreshold = 90 * time.Second // Consider unhealthy if no PONG for 90 seconds
-
 	healthList := make([]*models.DeviceHealth, 0, len(h.Clients))
 	now := time.Now()
 
 	for _, client := range h.Clients {
-		timeSinceLastPong := now.Sub(client.LastPongRecv).Milliseconds()
-		isHealthy := now.Sub(client.LastPongRecv) < healthThreshold
-
-		healthList = append(healthList, &models.DeviceHealth{
-			DeviceID:          client.DeviceID,
-			DeviceType:        client.DeviceType,
-			ConnectedAt:       client.ConnectedAt,
-			LastPingSent:      client.LastPingSent,
-			LastPongRecv:      client.LastPongRecv,
-			LastRTT:           client.LastRTT,
-			IsHealthy:         isHealthy,
-			TimeSinceLastPong: timeSinceLastPong,
-		})
+		healthList = append(healthList, h.deviceHealth(client, now))
 	}
 	return healthList
 }
@@ -195,21 +475,41 @@ func (h *Hub) GetDeviceHealthByID(deviceID string) (*models.DeviceHealth, error)
 		return nil, &DeviceNotConnectedError{DeviceID: deviceID}
 	}
 
-	const healthThreshold = 90 * time.Second
-	now := time.Now()
-	timeSinceLastPong := now.Sub(client.LastPongRecv).Milliseconds()
-	isHealthy := now.Sub(client.LastPongRecv) < healthThreshold
+	return h.deviceHealth(client, time.Now()), nil
+}
+
+// SetHealthThreshold sets how long a device may go without a PONG before it is reported
+// unhealthy. 0 uses DefaultHealthThreshold.
+func (h *Hub) SetHealthThreshold(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.healthThreshold = d
+}
+
+// deviceHealth reports a client's connection health as of now. Caller must hold h.mu.
+func (h *Hub) deviceHealth(client *Client, now time.Time) *models.DeviceHealth {
+	threshold := h.healthThreshold
+	if threshold == 0 {
+		threshold = DefaultHealthThreshold
+	}
+	sinceLastPong := now.Sub(client.LastPongRecv)
 
 	return &models.DeviceHealth{
 		DeviceID:          client.DeviceID,
 		DeviceType:        client.DeviceType,
+		Model:             client.Model,
+		Firmware:          client.Firmware,
 		ConnectedAt:       client.ConnectedAt,
 		LastPingSent:      client.LastPingSent,
 		LastPongRecv:      client.LastPongRecv,
 		LastRTT:           client.LastRTT,
-		IsHealthy:         isHealthy,
-		TimeSinceLastPong: timeSinceLastPong,
-	}, nil
+		IsHealthy:         sinceLastPong < threshold,
+		TimeSinceLastPong: sinceLastPong.Milliseconds(),
+		SendBufferDepth:   len(client.Send),
+		SendBufferSize:    cap(client.Send),
+		DroppedMessages:   client.DroppedMessages(),
+		WriteTimeouts:     h.writeTimeouts[client.DeviceID],
+	}
 }
 
 func (h *Hub) GetPairings() []*models.Pairing {
@@ -243,6 +543,7 @@ func (h *Hub) CreatePairing(device1ID, device2ID string) (*models.Pairing, error
 	}
 
 	h.Pairings[pairing.PairingID] = pairing
+	h.updateGauges()
 	log.Printf("Pairing created: %s (%s <-> %s)", pairing.PairingID, device1ID, device2ID)
 
 	return pairing, nil
@@ -257,11 +558,18 @@ func (h *Hub) DeletePairing(pairingID string) error {
 	}
 
 	delete(h.Pairings, pairingID)
+	h.updateGauges()
 	log.Printf("Pairing deleted: %s", pairingID)
 	return nil
 }
 
-func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration) (*models.TimeSyncRecord, error) {
+// RequestTimeSync sends a TIME_REQUEST to both devices of a pairing and waits for the result.
+// correlationID is attached to every log line of the request; a new one is generated if empty.
+func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration, correlationID string) (*models.TimeSyncRecord, error) {
+	if correlationID == "" {
+		correlationID = logging.NewCorrelationID()
+	}
+
 	h.mu.RLock()
 	pairing, ok := h.Pairings[pairingID]
 	if !ok {
@@ -271,14 +579,21 @@ func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration) (*models.
 
 	client1, ok1 := h.Clients[pairing.Device1ID]
 	client2, ok2 := h.Clients[pairing.Device2ID]
-	h.mu.RUnlock()
-
-	if !ok1 {
-		return nil, &DeviceNotConnectedError{DeviceID: pairing.Device1ID}
+	if !ok1 || !ok2 {
+		missing := pairing.Device1ID
+		if ok1 {
+			missing = pairing.Device2ID
+		}
+		err := h.disconnectedError(missing)
+		h.mu.RUnlock()
+		return nil, err
 	}
-	if !ok2 {
-		return nil, &DeviceNotConnectedError{DeviceID: pairing.Device2ID}
+	// The reference device answers as Device2, so TimeDifference (Device1 - Device2) and the
+	// RTTs the NTP selector compensates with are oriented against it
+	if h.swapForReference(client1.DeviceType, client2.DeviceType) {
+		client1, client2 = client2, client1
 	}
+	h.mu.RUnlock()
 
 	requestID := uuid.New().String()
 	serverRequestTime := time.Now().UnixMilli()
@@ -287,14 +602,20 @@ func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration) (*models.
 
 	pendingReq := &PendingRequest{
 		RequestID:         requestID,
+		CorrelationID:     correlationID,
 		PairingID:         pairingID,
-		Device1ID:         pairing.Device1ID,
-		Device2ID:         pairing.Device2ID,
+		Device1ID:         client1.DeviceID,
+		Device2ID:         client2.DeviceID,
 		ServerRequestTime: serverRequestTime,
 		ResponseChan:      responseChan,
 	}
 
 	h.mu.Lock()
+	if h.pendingLimitReached() {
+		h.mu.Unlock()
+		return nil, &ServerBusyError{MaxPendingRequests: h.maxPendingRequests}
+	}
+	pendingReq.PartialTimeout = h.partialTimeout
 	h.PendingRequests[requestID] = pendingReq
 	h.mu.Unlock()
 
@@ -310,32 +631,41 @@ func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration) (*models.
 		PairingID: pairingID,
 	}
 
+	logger := pendingReq.logger(h.log())
+	logger.Debug("time request created", "timeout", timeout.String())
+
 	// Send time request to both devices simultaneously
 	go func() {
 		// RTT START: Record send time for Device1
-		sendTime := time.Now().UnixMicro()
+		sendTime, sendMono := h.now().UnixMicro(), h.monotonic()
 		h.mu.Lock()
 		if req, ok := h.PendingRequests[requestID]; ok {
 			req.Device1SendTime = sendTime
+			req.Device1SendMono = sendMono
 		}
 		h.mu.Unlock()
 
 		if err := client1.SendMessage(timeReqMsg); err != nil {
-			log.Printf("Failed to send time request to device %s: %v", client1.DeviceID, err)
+			logger.Warn("failed to send time request", logging.KeyDeviceID, client1.DeviceID, "error", err)
+			return
 		}
+		logger.Debug("time request sent", logging.KeyDeviceID, client1.DeviceID)
 	}()
 	go func() {
 		// RTT START: Record send time for Device2
-		sendTime := time.Now().UnixMicro()
+		sendTime, sendMono := h.now().UnixMicro(), h.monotonic()
 		h.mu.Lock()
 		if req, ok := h.PendingRequests[requestID]; ok {
 			req.Device2SendTime = sendTime
+			req.Device2SendMono = sendMono
 		}
 		h.mu.Unlock()
 
 		if err := client2.SendMessage(timeReqMsg); err != nil {
-			log.Printf("Failed to send time request to device %s: %v", client2.DeviceID, err)
+			logger.Warn("failed to send time request", logging.KeyDeviceID, client2.DeviceID, "error", err)
+			return
 		}
+		logger.Debug("time request sent", logging.KeyDeviceID, client2.DeviceID)
 	}()
 
 	// Wait for response or timeout
@@ -344,17 +674,15 @@ func (h *Hub) RequestTimeSync(pairingID string, timeout time.Duration) (*models.
 }
 
 func (h *Hub) HandleMessage(client *Client, message []byte) {
-	// Debug: Log the raw message
-	log.Printf("Received raw message from %s: %s", client.DeviceID, string(message))
-	
+	logger := client.log()
+	logger.Debug("received message", "raw", string(message))
+
 	var baseMsg models.WSMessage
 	if err := json.Unmarshal(message, &baseMsg); err != nil {
-		log.Printf("Failed to unmarshal message from %s: %v, raw: %s", client.DeviceID, err, string(message))
+		logger.Warn("failed to unmarshal message", "error", err, "raw", string(message))
 		return
 	}
 
-	log.Printf("Parsed message type from %s: %s", client.DeviceID, baseMsg.Type)
-
 	switch baseMsg.Type {
 	case models.MessageTypeTimeResponse:
 		var timeResp models.TimeResponseMessage
@@ -380,21 +708,58 @@ func (h *Hub) HandleMessage(client *Client, message []byte) {
 		}
 		h.handlePong(client, &pongMsg)
 
+	case models.MessageTypePairRequest:
+		var pairReq models.PairRequestMessage
+		if err := json.Unmarshal(message, &pairReq); err != nil {
+			log.Printf("Failed to unmarshal PAIR_REQUEST message: %v", err)
+			return
+		}
+		h.handlePairRequest(client, &pairReq)
+
+	case models.MessageTypePairConfirm:
+		var pairConfirm models.PairConfirmMessage
+		if err := json.Unmarshal(message, &pairConfirm); err != nil {
+			log.Printf("Failed to unmarshal PAIR_CONFIRM message: %v", err)
+			return
+		}
+		h.handlePairConfirm(client, &pairConfirm)
+
+	case models.MessageTypeRTTProbeAck:
+		var ack models.RTTProbeAckMessage
+		if err := json.Unmarshal(message, &ack); err != nil {
+			log.Printf("Failed to unmarshal RTT_PROBE_ACK message: %v", err)
+			return
+		}
+		h.handleRTTProbeAck(client, &ack)
+
 	default:
-		log.Printf("Unknown message type: '%s' from client %s", baseMsg.Type, client.DeviceID)
+		logger.Warn("unknown message type", "type", baseMsg.Type)
 	}
 }
 
 func (h *Hub) handleTimeResponse(client *Client, resp *models.TimeResponseMessage) {
 	// RTT END: Record receive time before acquiring lock
-	receiveTime := time.Now().UnixMicro()
+	receiveTime, receiveMono := h.now().UnixMicro(), h.monotonic()
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	pendingReq, ok := h.PendingRequests[resp.RequestID]
 	if !ok {
-		log.Printf("No pending request found for requestID: %s", resp.RequestID)
+		if h.handleGroupTimeResponse(client, resp, receiveMono) {
+			return
+		}
+		client.log().Warn("no pending request for time response", logging.KeyRequestID, resp.RequestID)
+		return
+	}
+
+	logger := pendingReq.logger(h.log()).With(logging.KeyDeviceID, client.DeviceID)
+
+	// A retransmitted response must not overwrite the first one: its later receive time would
+	// inflate the RTT, so the earliest measurement wins
+	if (client.DeviceID == pendingReq.Device1ID && pendingReq.Device1Response != nil) ||
+		(client.DeviceID == pendingReq.Device2ID && pendingReq.Device2Response != nil) {
+		logger.Warn("duplicate time response dropped", "device_timestamp", resp.Timestamp)
 		return
 	}
 
@@ -402,17 +767,33 @@ func (h *Hub) handleTimeResponse(client *Client, resp *models.TimeResponseMessag
 	if client.DeviceID == pendingReq.Device1ID {
 		pendingReq.Device1Response = &resp.Timestamp
 		pendingReq.Device1ReceiveTime = &receiveTime
+		pendingReq.Device1ReceiveMono = receiveMono
+		pendingReq.Device1RecvTime = resp.RecvTime
+		pendingReq.Device1ReplyTime = resp.SendTime
 	} else if client.DeviceID == pendingReq.Device2ID {
 		pendingReq.Device2Response = &resp.Timestamp
 		pendingReq.Device2ReceiveTime = &receiveTime
+		pendingReq.Device2ReceiveMono = receiveMono
+		pendingReq.Device2RecvTime = resp.RecvTime
+		pendingReq.Device2ReplyTime = resp.SendTime
 	} else {
-		log.Printf("Response from unexpected device: %s", client.DeviceID)
+		logger.Warn("time response from unexpected device")
 		return
 	}
+	logger.Debug("time response received", "device_timestamp", resp.Timestamp)
 
 	// Check if we have both responses
 	if pendingReq.Device1Response != nil && pendingReq.Device2Response != nil {
 		h.completeSyncRequest(pendingReq)
+		return
+	}
+
+	// First response: give the other device a short grace period instead of the full timeout
+	if pendingReq.PartialTimeout > 0 && pendingReq.PartialTimer == nil {
+		requestID := pendingReq.RequestID
+		pendingReq.PartialTimer = time.AfterFunc(pendingReq.PartialTimeout, func() {
+			h.handlePartialTimeout(requestID)
+		})
 	}
 }
 
@@ -425,22 +806,48 @@ func (h *Hub) handleTimeout(requestID string) {
 		return
 	}
 
-	log.Printf("Time sync request timeout: %s", requestID)
+	pendingReq.logger(h.log()).Warn("time sync request timed out",
+		"device1_responded", pendingReq.Device1Response != nil,
+		"device2_responded", pendingReq.Device2Response != nil)
+	metrics.SyncTimeoutsTotal.Inc()
+	h.completeSyncRequest(pendingReq)
+}
+
+// handlePartialTimeout completes a request as PARTIAL when the second device did not answer
+// within the grace period after the first response
+func (h *Hub) handlePartialTimeout(requestID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pendingReq, ok := h.PendingRequests[requestID]
+	if !ok {
+		return
+	}
+
+	pendingReq.logger(h.log()).Warn("time sync request partial timeout, second device did not answer",
+		"grace_period", pendingReq.PartialTimeout.String())
 	h.completeSyncRequest(pendingReq)
 }
 
 func (h *Hub) completeSyncRequest(pendingReq *PendingRequest) {
-	// Stop timeout timer
+	// Stop timeout timers
 	if pendingReq.TimeoutTimer != nil {
 		pendingReq.TimeoutTimer.Stop()
 	}
+	if pendingReq.PartialTimer != nil {
+		pendingReq.PartialTimer.Stop()
+	}
 
 	serverResponseTime := time.Now().UnixMilli()
 
 	// Determine status
 	var status models.SyncStatus
 	var errorMsg *string
-	if pendingReq.Device1Response != nil && pendingReq.Device2Response != nil {
+	if pendingReq.FailureReason != "" {
+		status = models.SyncStatusFailed
+		msg := pendingReq.FailureReason
+		errorMsg = &msg
+	} else if pendingReq.Device1Response != nil && pendingReq.Device2Response != nil {
 		status = models.SyncStatusSuccess
 	} else if pendingReq.Device1Response != nil || pendingReq.Device2Response != nil {
 		status = models.SyncStatusPartial
@@ -451,6 +858,10 @@ func (h *Hub) completeSyncRequest(pendingReq *PendingRequest) {
 		msg := "Both devices failed to respond"
 		errorMsg = &msg
 	}
+	if pendingReq.DisconnectedDeviceID != "" && pendingReq.FailureReason == "" {
+		msg := fmt.Sprintf("Device %s disconnected before responding", pendingReq.DisconnectedDeviceID)
+		errorMsg = &msg
+	}
 
 	// Get device types
 	var device1Type, device2Type models.DeviceType
@@ -461,26 +872,37 @@ func (h *Hub) completeSyncRequest(pendingReq *PendingRequest) {
 		device2Type = client2.DeviceType
 	}
 
-	// Calculate RTT for each device
+	// Calculate RTT for each device from the monotonic readings
 	var device1RTT, device2RTT *int64
 	if pendingReq.Device1ReceiveTime != nil && pendingReq.Device1SendTime > 0 {
-		rtt := *pendingReq.Device1ReceiveTime - pendingReq.Device1SendTime
+		rtt := (pendingReq.Device1ReceiveMono - pendingReq.Device1SendMono).Microseconds()
 		device1RTT = &rtt
 	}
 	if pendingReq.Device2ReceiveTime != nil && pendingReq.Device2SendTime > 0 {
-		rtt := *pendingReq.Device2ReceiveTime - pendingReq.Device2SendTime
+		rtt := (pendingReq.Device2ReceiveMono - pendingReq.Device2SendMono).Microseconds()
 		device2RTT = &rtt
 	}
 
 	// Calculate RAW time difference (no network compensation)
 	// Network delay compensation will be applied by NTPSelector during multi-sampling
 	var timeDifference *int64
-	if pendingReq.Device1Response != nil && pendingReq.Device2Response != nil {
-		// Store raw difference: Device1Time - Device2Time
-		// Negative = Device1 is behind Device2
-		// Positive = Device1 is ahead of Device2
-		rawDiff := *pendingReq.Device1Response - *pendingReq.Device2Response
-		timeDifference = &rawDiff
+	offsetCompensated := false
+	if status == models.SyncStatusSuccess {
+		offset1, ok1 := fourTimestampOffset(pendingReq.Device1SendTime, pendingReq.Device1RecvTime, pendingReq.Device1ReplyTime, pendingReq.Device1ReceiveTime)
+		offset2, ok2 := fourTimestampOffset(pendingReq.Device2SendTime, pendingReq.Device2RecvTime, pendingReq.Device2ReplyTime, pendingReq.Device2ReceiveTime)
+		if ok1 && ok2 {
+			// Both devices reported their receive/send times: each offset to the server
+			// clock already excludes network delay and processing time
+			diff := int64(math.Round(offset1 - offset2))
+			timeDifference = &diff
+			offsetCompensated = true
+		} else {
+			// Store raw difference: Device1Time - Device2Time
+			// Negative = Device1 is behind Device2
+			// Positive = Device1 is ahead of Device2
+			rawDiff := *pendingReq.Device1Response - *pendingReq.Device2Response
+			timeDifference = &rawDiff
+		}
 	}
 
 	record := &models.TimeSyncRecord{
@@ -495,11 +917,35 @@ func (h *Hub) completeSyncRequest(pendingReq *PendingRequest) {
 		Device1RTT:         device1RTT,
 		Device2RTT:         device2RTT,
 		TimeDifference:     timeDifference,
+		OffsetCompensated:  offsetCompensated,
 		Status:             status,
 		ErrorMessage:       errorMsg,
 		CreatedAt:          time.Now().UnixMilli(),
 	}
 
+	// Update metrics
+	metrics.SyncRequestsTotal.Inc()
+	if status != models.SyncStatusSuccess {
+		metrics.SyncFailuresTotal.WithLabelValues(string(status)).Inc()
+	}
+	metrics.ObserveRTT(string(device1Type), device1RTT)
+	metrics.ObserveRTT(string(device2Type), device2RTT)
+
+	completeAttrs := []any{"status", string(status)}
+	if device1RTT != nil {
+		completeAttrs = append(completeAttrs, "device1_rtt_us", *device1RTT)
+	}
+	if device2RTT != nil {
+		completeAttrs = append(completeAttrs, "device2_rtt_us", *device2RTT)
+	}
+	if timeDifference != nil {
+		completeAttrs = append(completeAttrs, "time_difference_ms", *timeDifference, "offset_compensated", offsetCompensated)
+	}
+	if errorMsg != nil {
+		completeAttrs = append(completeAttrs, "error", *errorMsg)
+	}
+	pendingReq.logger(h.log()).Info("time sync completed", completeAttrs...)
+
 	// Send result through channel
 	select {
 	case pendingReq.ResponseChan <- record:
@@ -510,6 +956,25 @@ func (h *Hub) completeSyncRequest(pendingReq *PendingRequest) {
 	delete(h.PendingRequests, pendingReq.RequestID)
 }
 
+// fourTimestampOffset computes a device's clock offset to the server in milliseconds with the
+// NTP formula ((T2-T1)+(T3-T4))/2: T1/T4 are the server's request send and response receive
+// times (microseconds), T2/T3 the device's request receive and response send times
+// (milliseconds). Returns false if any timestamp is missing or the device times are inconsistent.
+func fourTimestampOffset(serverSendMicros int64, deviceRecv, deviceSend, serverRecvMicros *int64) (float64, bool) {
+	if serverSendMicros <= 0 || deviceRecv == nil || deviceSend == nil || serverRecvMicros == nil {
+		return 0, false
+	}
+	if *deviceSend < *deviceRecv {
+		return 0, false
+	}
+
+	t1 := float64(serverSendMicros) / 1000
+	t2 := float64(*deviceRecv)
+	t3 := float64(*deviceSend)
+	t4 := float64(*serverRecvMicros) / 1000
+	return ((t2 - t1) + (t3 - t4)) / 2, true
+}
+
 // handlePing handles incoming PING messages from clients and responds with PONG
 func (h *Hub) handlePing(client *Client, ping *models.PingMessage) {
 	// log.Printf("Received PING from client %s at %d", client.DeviceID, ping.Timestamp)
@@ -527,24 +992,186 @@ func (h *Hub) handlePing(client *Client, ping *models.PingMessage) {
 
 // handlePong handles incoming PONG messages from clients
 func (h *Hub) handlePong(client *Client, pong *models.PongMessage) {
-	now := time.Now()
-	client.LastPongRecv = now
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.LastPongRecv = time.Now()
 
 	// Calculate RTT if we have a recent ping
 	var rtt int64
 	if !client.LastPingSent.IsZero() {
-		rtt = now.Sub(client.LastPingSent).Milliseconds()
+		rtt = (h.monotonic() - client.lastPingMono).Milliseconds()
 		client.LastRTT = rtt
 	}
 
 	// log.Printf("Received PONG from client %s, RTT: %dms", client.DeviceID, rtt)
 }
 
+// SetLogger sets the structured logger for the sync flow. nil uses slog.Default().
+// Call before Run.
+func (h *Hub) SetLogger(logger *slog.Logger) {
+	h.logger = logger
+}
+
+// log returns the hub's structured logger
+func (h *Hub) log() *slog.Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+	return slog.Default()
+}
+
+// logger returns base with the request's correlation, request and pairing IDs attached
+func (r *PendingRequest) logger(base *slog.Logger) *slog.Logger {
+	return base.With(
+		logging.KeyCorrelationID, r.CorrelationID,
+		logging.KeyRequestID, r.RequestID,
+		logging.KeyPairingID, r.PairingID,
+	)
+}
+
 // SetPairingOperator sets the pairing operator (called after initialization to avoid circular dependency)
 func (h *Hub) SetPairingOperator(operator PairingOperator) {
 	h.pairingOperator = operator
 }
 
+// SetDeviceEventStore sets where connect/disconnect events are recorded. Call before Run.
+func (h *Hub) SetDeviceEventStore(store DeviceEventStore) {
+	h.eventStore = store
+}
+
+// SetEventBus sets where connect/disconnect events are published. Call before Run.
+func (h *Hub) SetEventBus(bus *events.EventBus) {
+	h.events = bus
+}
+
+// recordDeviceEvent publishes a connect/disconnect event and saves it in the background
+// so a slow database does not stall the Run loop
+func (h *Hub) recordDeviceEvent(client *Client, eventType models.DeviceEventType, reason string) {
+	now := time.Now()
+
+	busEventType := models.EventTypeDeviceConnected
+	if eventType == models.DeviceEventDisconnected {
+		busEventType = models.EventTypeDeviceDisconnected
+	}
+	h.events.Publish(busEventType, &models.DeviceConnectionEvent{
+		DeviceID:   client.DeviceID,
+		DeviceType: client.DeviceType,
+		Reason:     reason,
+		Timestamp:  now.UnixMilli(),
+	})
+
+	if h.eventStore == nil {
+		return
+	}
+	event := &models.DeviceEvent{
+		DeviceID:  client.DeviceID,
+		EventType: eventType,
+		Reason:    reason,
+		Timestamp: now,
+	}
+	go func(store DeviceEventStore) {
+		if err := store.SaveDeviceEvent(event); err != nil {
+			h.log().Warn("failed to record device event", logging.KeyDeviceID, client.DeviceID, "event_type", eventType, "error", err)
+		}
+	}(h.eventStore)
+}
+
+// SetSendPolicy sets how clients connecting after the call handle a full send buffer.
+// timeout is how long SendPolicyBlock waits for room before dropping the message.
+func (h *Hub) SetSendPolicy(policy SendPolicy, timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendPolicy = policy
+	h.sendTimeout = timeout
+}
+
+// SetWriteWait sets how long a write to clients connecting after the call may block before the
+// client is considered stalled and disconnected. 0 uses DefaultWriteWait.
+func (h *Hub) SetWriteWait(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeWait = d
+}
+
+// recordWriteTimeout counts a write deadline exceeded by a device and returns its new total
+func (h *Hub) recordWriteTimeout(deviceID string) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeTimeouts[deviceID]++
+	return h.writeTimeouts[deviceID]
+}
+
+// SetReconnectGracePeriod sets how long the pairings of a disconnected device are kept.
+// Sync requests against them fail with DeviceTemporarilyDisconnectedError until the device
+// reconnects; if it does not reconnect in time they are purged. 0 purges them immediately.
+func (h *Hub) SetReconnectGracePeriod(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconnectGrace = d
+}
+
+// SetPartialTimeout sets the grace period after the first TIME_RESPONSE of a request.
+// If the other device has not answered by then, the request completes as PARTIAL
+// instead of waiting for the full timeout. 0 disables partial completion.
+func (h *Hub) SetPartialTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.partialTimeout = d
+}
+
+// SetMaxPendingRequests caps the number of time sync requests awaiting responses.
+// Once the cap is reached RequestTimeSync fails with ServerBusyError instead of sending
+// another TIME_REQUEST. 0 removes the cap.
+func (h *Hub) SetMaxPendingRequests(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxPendingRequests = n
+}
+
+// SetReferenceDeviceType sets the device type offsets are measured against. When exactly one
+// device of a pairing has this type, its sync records list it as Device2, so TimeDifference is
+// positive when the other device is ahead of it; it is also the default reference of new group
+// pairings. "" keeps the pairing's device order.
+func (h *Hub) SetReferenceDeviceType(deviceType models.DeviceType) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.referenceDeviceType = deviceType
+}
+
+// swapForReference reports whether a pairing's devices must be swapped so that the device of the
+// reference type comes second. Caller must hold h.mu.
+func (h *Hub) swapForReference(device1Type, device2Type models.DeviceType) bool {
+	return h.referenceDeviceType != "" && device1Type == h.referenceDeviceType && device2Type != h.referenceDeviceType
+}
+
+// OrientPairing returns the devices of a pairing in the order its sync records use, see
+// SetReferenceDeviceType. Devices that are not connected keep the pairing's order.
+func (h *Hub) OrientPairing(device1ID, device2ID string) (string, string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client1, ok1 := h.Clients[device1ID]
+	client2, ok2 := h.Clients[device2ID]
+	if ok1 && ok2 && h.swapForReference(client1.DeviceType, client2.DeviceType) {
+		return device2ID, device1ID
+	}
+	return device1ID, device2ID
+}
+
+// PendingRequestCount returns the number of time sync requests awaiting responses,
+// including group requests
+func (h *Hub) PendingRequestCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.PendingRequests) + len(h.PendingGroupRequests)
+}
+
+// pendingLimitReached reports whether the in-flight requests, pairings and groups together,
+// reached the limit set by SetMaxPendingRequests. Caller must hold h.mu.
+func (h *Hub) pendingLimitReached() bool {
+	return h.maxPendingRequests > 0 && len(h.PendingRequests)+len(h.PendingGroupRequests) >= h.maxPendingRequests
+}
+
 // IsDeviceConnected checks if a device is currently connected
 func (h *Hub) IsDeviceConnected(deviceID string) bool {
 	h.mu.RLock()
@@ -553,6 +1180,17 @@ func (h *Hub) IsDeviceConnected(deviceID string) bool {
 	return ok
 }
 
+// GetDeviceType returns the type of a connected device; ok is false if it is not connected
+func (h *Hub) GetDeviceType(deviceID string) (deviceType models.DeviceType, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.Clients[deviceID]
+	if !ok {
+		return "", false
+	}
+	return client.DeviceType, true
+}
+
 // IsPairingRestored checks if a pairing is already restored in memory
 func (h *Hub) IsPairingRestored(pairingID string) bool {
 	h.mu.RLock()
@@ -580,9 +1218,129 @@ func (h *Hub) RestorePairing(pairing *models.Pairing) error {
 	}
 
 	h.Pairings[pairing.PairingID] = pairing
+	h.updateGauges()
 	return nil
 }
 
+// sendPairingsRestored sends PAIRINGS_RESTORED with the device's active pairings
+func (h *Hub) sendPairingsRestored(client *Client) {
+	msg := models.PairingsRestoredMessage{
+		Type:            models.MessageTypePairingsRestored,
+		DeviceID:        client.DeviceID,
+		PairingIDs:      []string{},
+		GroupPairingIDs: []string{},
+	}
+
+	h.mu.RLock()
+	if h.Clients[client.DeviceID] != client {
+		// Disconnected (or replaced) while its pairings were being restored
+		h.mu.RUnlock()
+		return
+	}
+	for _, pairing := range h.Pairings {
+		if pairing.Device1ID == client.DeviceID || pairing.Device2ID == client.DeviceID {
+			msg.PairingIDs = append(msg.PairingIDs, pairing.PairingID)
+		}
+	}
+	for _, group := range h.GroupPairings {
+		if slices.Contains(group.DeviceIDs, client.DeviceID) {
+			msg.GroupPairingIDs = append(msg.GroupPairingIDs, group.PairingID)
+		}
+	}
+	h.mu.RUnlock()
+
+	sort.Strings(msg.PairingIDs)
+	sort.Strings(msg.GroupPairingIDs)
+	client.SendMessage(msg)
+}
+
+// Shutdown stops the Hub: it stops the dead connection detector, sends a close frame to every
+// client, completes all pending sync requests as FAILED and waits until every client has
+// unregistered or ctx expires. The Run loop stops afterwards.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.shutdownOnce.Do(func() {
+		close(h.shuttingDown)
+	})
+
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.Clients))
+	for _, client := range h.Clients {
+		clients = append(clients, client)
+	}
+
+	// Drain pending requests
+	for _, pendingReq := range h.PendingRequests {
+		pendingReq.FailureReason = "server shutting down"
+		h.completeSyncRequest(pendingReq)
+	}
+	for _, pendingReq := range h.PendingGroupRequests {
+		pendingReq.FailureReason = "server shutting down"
+		h.completeGroupSyncRequest(pendingReq)
+	}
+	for requestID, pending := range h.PendingPairRequests {
+		pending.TimeoutTimer.Stop()
+		delete(h.PendingPairRequests, requestID)
+	}
+	h.mu.Unlock()
+
+	log.Printf("Shutting down hub (%d clients)", len(clients))
+
+	// Close every connection; ReadPump exits and unregisters the client
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, client := range clients {
+		if err := client.Conn.WriteControl(websocket.CloseMessage, closeMsg, client.writeDeadline()); err != nil {
+			log.Printf("Failed to send close frame to device %s: %v", client.DeviceID, err)
+		}
+		client.setDisconnectReason(models.DisconnectReasonServerShutdown)
+		client.Conn.Close()
+	}
+
+	// Wait for all clients to unregister
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var err error
+	for err == nil && h.connectedCount() > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			log.Printf("Hub shutdown timed out with %d client(s) still registered", h.connectedCount())
+		case <-ticker.C:
+		}
+	}
+
+	// Stop the Run loop
+	h.stopOnce.Do(func() {
+		close(h.stopped)
+	})
+
+	log.Printf("Hub stopped")
+	return err
+}
+
+// isShuttingDown reports whether Shutdown has been called
+func (h *Hub) isShuttingDown() bool {
+	select {
+	case <-h.shuttingDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// connectedCount returns the number of registered clients
+func (h *Hub) connectedCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.Clients)
+}
+
+// updateGauges refreshes the connection and pairing gauges. Caller must hold h.mu.
+func (h *Hub) updateGauges() {
+	metrics.ConnectedDevices.Set(float64(len(h.Clients)))
+	metrics.ActivePairings.Set(float64(len(h.Pairings) + len(h.GroupPairings)))
+}
+
 // Custom errors
 type DeviceNotConnectedError struct {
 	DeviceID string
@@ -592,6 +1350,26 @@ func (e *DeviceNotConnectedError) Error() string {
 	return "device not connected: " + e.DeviceID
 }
 
+// DeviceTemporarilyDisconnectedError is returned for a pairing whose device disconnected
+// less than the reconnect grace period ago; the pairing is purged at PurgeAt unless it reconnects
+type DeviceTemporarilyDisconnectedError struct {
+	DeviceID string
+	PurgeAt  time.Time
+}
+
+func (e *DeviceTemporarilyDisconnectedError) Error() string {
+	return "device temporarily disconnected: " + e.DeviceID
+}
+
+// ServerBusyError is returned when MaxPendingRequests time sync requests are already awaiting responses
+type ServerBusyError struct {
+	MaxPendingRequests int
+}
+
+func (e *ServerBusyError) Error() string {
+	return fmt.Sprintf("server busy: %d time sync requests already pending", e.MaxPendingRequests)
+}
+
 type PairingNotFoundError struct {
 	PairingID string
 }
@@ -0,0 +1,201 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// fakeClocks replaces the hub's wall and monotonic clocks with values the test sets
+type fakeClocks struct {
+	wall atomic.Int64 // Unix microseconds
+	mono atomic.Int64 // Nanoseconds since the hub was created
+}
+
+func newFakeClockHub() (*Hub, *fakeClocks) {
+	clocks := &fakeClocks{}
+	clocks.wall.Store(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).UnixMicro())
+	h := NewHub()
+	h.wallClock = func() time.Time { return time.UnixMicro(clocks.wall.Load()) }
+	h.monoClock = func() time.Duration { return time.Duration(clocks.mono.Load()) }
+	return h, clocks
+}
+
+// awaitTimeRequest returns the request ID of the TIME_REQUEST queued for client
+func awaitTimeRequest(t *testing.T, client *Client) string {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var req models.TimeRequestMessage
+		if err := json.Unmarshal(data, &req); err != nil || req.Type != models.MessageTypeTimeRequest {
+			t.Fatalf("queued message %s, expected a TIME_REQUEST", data)
+		}
+		return req.RequestID
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no TIME_REQUEST sent to %s", client.DeviceID)
+	}
+	return ""
+}
+
+func TestRequestTimeSyncRTTIgnoresWallClockJump(t *testing.T) {
+	h, clocks := newFakeClockHub()
+	device1 := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+	device2 := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+	h.Clients[device1.DeviceID] = device1
+	h.Clients[device2.DeviceID] = device2
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: device1.DeviceID, Device2ID: device2.DeviceID}
+
+	type result struct {
+		record *models.TimeSyncRecord
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		record, err := h.RequestTimeSync("pair-1", 5*time.Second, "")
+		done <- result{record, err}
+	}()
+	requestID := awaitTimeRequest(t, device1)
+	awaitTimeRequest(t, device2)
+
+	// The server's wall clock is stepped back an hour while the request is in flight
+	clocks.wall.Add(-time.Hour.Microseconds())
+	respond := func(client *Client, elapsed time.Duration) {
+		clocks.mono.Store(int64(elapsed))
+		msg := fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, time.Now().UnixMilli())
+		h.HandleMessage(client, []byte(msg))
+	}
+	respond(device1, 3*time.Millisecond)
+	respond(device2, 4*time.Millisecond)
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RequestTimeSync() did not return")
+	}
+	if res.err != nil {
+		t.Fatalf("RequestTimeSync() error = %v", res.err)
+	}
+	record := res.record
+	if record.Device1RTT == nil || *record.Device1RTT != 3000 || record.Device2RTT == nil || *record.Device2RTT != 4000 {
+		t.Errorf("Device1RTT, Device2RTT = %v, %v, expected 3000 and 4000 microseconds", ptrValue(record.Device1RTT), ptrValue(record.Device2RTT))
+	}
+}
+
+func TestRequestTimeSyncKeepsFirstOfDuplicateResponses(t *testing.T) {
+	h, clocks := newFakeClockHub()
+	device1 := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+	device2 := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+	h.Clients[device1.DeviceID] = device1
+	h.Clients[device2.DeviceID] = device2
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: device1.DeviceID, Device2ID: device2.DeviceID}
+
+	done := make(chan *models.TimeSyncRecord, 1)
+	go func() {
+		record, _ := h.RequestTimeSync("pair-1", 5*time.Second, "")
+		done <- record
+	}()
+	requestID := awaitTimeRequest(t, device1)
+	awaitTimeRequest(t, device2)
+
+	respond := func(client *Client, elapsed time.Duration, timestamp int64) {
+		clocks.mono.Store(int64(elapsed))
+		h.HandleMessage(client, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, timestamp)))
+	}
+	respond(device1, 3*time.Millisecond, 1_000_000)
+	respond(device1, 9*time.Millisecond, 1_000_006) // Retransmit of the same response
+	respond(device2, 4*time.Millisecond, 1_000_050)
+
+	var record *models.TimeSyncRecord
+	select {
+	case record = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RequestTimeSync() did not return")
+	}
+	if record.Device1RTT == nil || *record.Device1RTT != 3000 {
+		t.Errorf("Device1RTT = %v, expected 3000 microseconds from the first response", ptrValue(record.Device1RTT))
+	}
+	if record.Device1Timestamp == nil || *record.Device1Timestamp != 1_000_000 {
+		t.Errorf("Device1Timestamp = %v, expected 1000000 from the first response", ptrValue(record.Device1Timestamp))
+	}
+	if record.Status != models.SyncStatusSuccess {
+		t.Errorf("Status = %s, expected %s", record.Status, models.SyncStatusSuccess)
+	}
+}
+
+func TestHandlePongRTTIgnoresWallClockJump(t *testing.T) {
+	h, clocks := newFakeClockHub()
+	client := &Client{Hub: h, DeviceID: "watch-001", Send: make(chan []byte, 4)}
+
+	clocks.mono.Store(int64(time.Second))
+	client.sendAppPing()
+
+	// A wall clock step between PING and PONG must not show up in the RTT
+	client.LastPingSent = client.LastPingSent.Add(time.Hour)
+	clocks.wall.Add(-time.Hour.Microseconds())
+	clocks.mono.Add(int64(25 * time.Millisecond))
+	h.HandleMessage(client, []byte(fmt.Sprintf(`{"type": %q, "timestamp": %d}`, models.MessageTypePong, time.Now().UnixMilli())))
+
+	if client.LastRTT != 25 {
+		t.Errorf("LastRTT = %dms, expected 25ms", client.LastRTT)
+	}
+}
+
+func TestAppPingPongConcurrentWithHealth(t *testing.T) {
+	h, clocks := newFakeClockHub()
+	client := &Client{Hub: h, DeviceID: "watch-001", Send: make(chan []byte, 256)}
+	h.mu.Lock()
+	h.Clients[client.DeviceID] = client
+	h.mu.Unlock()
+
+	// PINGs go out on WritePump while PONGs arrive on ReadPump and health is read by the API;
+	// run with -race to catch unguarded ping fields
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			clocks.mono.Add(int64(time.Millisecond))
+			client.sendAppPing()
+			<-client.Send
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		pong := []byte(fmt.Sprintf(`{"type": %q, "timestamp": %d}`, models.MessageTypePong, time.Now().UnixMilli()))
+		for i := 0; i < rounds; i++ {
+			h.HandleMessage(client, pong)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := h.GetDeviceHealthByID(client.DeviceID); err != nil {
+				t.Errorf("GetDeviceHealthByID() error = %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	health, err := h.GetDeviceHealthByID(client.DeviceID)
+	if err != nil {
+		t.Fatalf("GetDeviceHealthByID() error = %v", err)
+	}
+	if health.LastRTT < 0 || health.LastPingSent.IsZero() {
+		t.Errorf("LastRTT, LastPingSent = %d, %v, expected a PING and a non-negative RTT", health.LastRTT, health.LastPingSent)
+	}
+}
+
+func ptrValue(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestClient connects a peer to the hub over a real WebSocket and registers its client
+// without starting the pumps. Returns the client and the peer side of the connection.
+func dialTestClient(t *testing.T, h *Hub, deviceID string) (*Client, *websocket.Conn) {
+	t.Helper()
+	upgraded := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		upgraded <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	client := NewClient(h, <-upgraded, deviceID, models.DeviceTypeWatch, 0, 0)
+	h.mu.Lock()
+	h.Clients[deviceID] = client
+	h.mu.Unlock()
+	return client, peer
+}
+
+// awaitCloseFrame reads from peer until the connection closes and returns the close code
+func awaitCloseFrame(t *testing.T, peer *websocket.Conn) int {
+	t.Helper()
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := peer.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("ReadMessage() error = %v, expected a close frame", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func TestShutdownClosesConnectionsAndDrainsRequests(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+	device1, peer1 := dialTestClient(t, h, "psg-001")
+	device2, peer2 := dialTestClient(t, h, "watch-001")
+	for _, client := range []*Client{device1, device2} {
+		go client.ReadPump()
+		go client.WritePump()
+	}
+	h.mu.Lock()
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: "psg-001", Device2ID: "watch-001"}
+	h.mu.Unlock()
+
+	// A request the devices never answer
+	done := make(chan *models.TimeSyncRecord, 1)
+	go func() {
+		record, _ := h.RequestTimeSync("pair-1", time.Minute, "")
+		done <- record
+	}()
+	deadline := time.After(2 * time.Second)
+	for h.PendingRequestCount() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("time sync request was not registered")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	select {
+	case record := <-done:
+		if record.Status != models.SyncStatusFailed || record.ErrorMessage == nil || *record.ErrorMessage != "server shutting down" {
+			t.Errorf("Status, ErrorMessage = %s, %v, expected FAILED with the shutdown reason", record.Status, record.ErrorMessage)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RequestTimeSync() still waiting after Shutdown")
+	}
+	if count := h.PendingRequestCount(); count != 0 {
+		t.Errorf("PendingRequestCount() = %d, expected the requests to be drained", count)
+	}
+
+	for _, peer := range []*websocket.Conn{peer1, peer2} {
+		if code := awaitCloseFrame(t, peer); code != websocket.CloseGoingAway {
+			t.Errorf("close code = %d, expected %d (going away)", code, websocket.CloseGoingAway)
+		}
+	}
+	if count := h.connectedCount(); count != 0 {
+		t.Errorf("connectedCount() = %d, expected every client to unregister", count)
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+	// Without a ReadPump the client never unregisters
+	dialTestClient(t, h, "watch-001")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := h.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, expected context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown() returned after %s, expected it to give up at the context deadline", elapsed)
+	}
+
+	// The Run loop is stopped even though a client is left behind
+	select {
+	case <-h.stopped:
+	default:
+		t.Error("Run loop not stopped after the shutdown timed out")
+	}
+}
+
+func TestShutdownConcurrentCalls(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	// Every call closes the stop channel at most once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.Shutdown(context.Background()); err != nil {
+				t.Errorf("Shutdown() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
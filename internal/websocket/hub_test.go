@@ -0,0 +1,383 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+func TestUnregisterCompletesPendingSyncRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		device1Answers bool
+		expectedStatus models.SyncStatus
+	}{
+		{name: "other device answered", device1Answers: true, expectedStatus: models.SyncStatusPartial},
+		{name: "no device answered", expectedStatus: models.SyncStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub()
+			go h.Run()
+			device1 := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+			device2 := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+			h.mu.Lock()
+			h.Clients[device1.DeviceID] = device1
+			h.Clients[device2.DeviceID] = device2
+			h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: device1.DeviceID, Device2ID: device2.DeviceID}
+			h.mu.Unlock()
+
+			done := make(chan *models.TimeSyncRecord, 1)
+			go func() {
+				record, _ := h.RequestTimeSync("pair-1", time.Minute, "")
+				done <- record
+			}()
+			requestID := awaitTimeRequest(t, device1)
+			awaitTimeRequest(t, device2)
+
+			if tt.device1Answers {
+				msg := fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, time.Now().UnixMilli())
+				h.HandleMessage(device1, []byte(msg))
+			}
+			h.Unregister <- device2
+
+			select {
+			case record := <-done:
+				if record.Status != tt.expectedStatus {
+					t.Errorf("Status = %s, expected %s", record.Status, tt.expectedStatus)
+				}
+				if record.ErrorMessage == nil || !strings.Contains(*record.ErrorMessage, "watch-001 disconnected") {
+					t.Errorf("ErrorMessage = %v, expected it to name the disconnected device", record.ErrorMessage)
+				}
+				if record.Device2Type != models.DeviceTypeWatch {
+					t.Errorf("Device2Type = %q, expected the departed device's type to be kept", record.Device2Type)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("RequestTimeSync() still waiting after the device disconnected")
+			}
+			if count := h.PendingRequestCount(); count != 0 {
+				t.Errorf("PendingRequestCount() = %d, expected the request to be removed", count)
+			}
+		})
+	}
+}
+
+func TestRequestTimeSyncCompletesPartialAfterGracePeriod(t *testing.T) {
+	h := NewHub()
+	h.SetPartialTimeout(50 * time.Millisecond)
+	device1 := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+	device2 := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+	h.Clients[device1.DeviceID] = device1
+	h.Clients[device2.DeviceID] = device2
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: device1.DeviceID, Device2ID: device2.DeviceID}
+
+	done := make(chan *models.TimeSyncRecord, 1)
+	start := time.Now()
+	go func() {
+		record, _ := h.RequestTimeSync("pair-1", time.Minute, "")
+		done <- record
+	}()
+	requestID := awaitTimeRequest(t, device1)
+	awaitTimeRequest(t, device2)
+
+	// Only the PSG answers; the watch stays silent
+	msg := fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, time.Now().UnixMilli())
+	h.HandleMessage(device1, []byte(msg))
+
+	select {
+	case record := <-done:
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("RequestTimeSync() returned after %s, expected the grace period instead of the full timeout", elapsed)
+		}
+		if record.Status != models.SyncStatusPartial {
+			t.Errorf("Status = %s, expected %s", record.Status, models.SyncStatusPartial)
+		}
+		if record.Device1Timestamp == nil || record.Device2Timestamp != nil {
+			t.Errorf("Device1Timestamp, Device2Timestamp = %v, %v, expected only the PSG's answer", ptrValue(record.Device1Timestamp), ptrValue(record.Device2Timestamp))
+		}
+		if record.TimeDifference != nil {
+			t.Errorf("TimeDifference = %d, expected none without both answers", *record.TimeDifference)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RequestTimeSync() did not complete after the grace period")
+	}
+	if count := h.PendingRequestCount(); count != 0 {
+		t.Errorf("PendingRequestCount() = %d, expected the request to be removed", count)
+	}
+}
+
+func TestRequestTimeSyncOrientsAgainstReferenceDeviceType(t *testing.T) {
+	// The watch runs 50ms ahead of the PSG; the PSG's link is slower
+	tests := []struct {
+		referenceType      models.DeviceType
+		expectedDevice1    string
+		expectedDifference int64
+		expectedDevice1RTT int64
+	}{
+		{"", "psg-001", -50, 6000},
+		{models.DeviceTypePSG, "watch-001", 50, 2000},
+		{models.DeviceTypeWatch, "psg-001", -50, 6000},
+	}
+	for _, tt := range tests {
+		t.Run("reference "+string(tt.referenceType), func(t *testing.T) {
+			h, clocks := newFakeClockHub()
+			h.SetReferenceDeviceType(tt.referenceType)
+			psg := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+			watch := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+			h.Clients[psg.DeviceID] = psg
+			h.Clients[watch.DeviceID] = watch
+			h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: psg.DeviceID, Device2ID: watch.DeviceID}
+
+			done := make(chan *models.TimeSyncRecord, 1)
+			go func() {
+				record, _ := h.RequestTimeSync("pair-1", 5*time.Second, "")
+				done <- record
+			}()
+			requestID := awaitTimeRequest(t, psg)
+			awaitTimeRequest(t, watch)
+
+			respond := func(client *Client, elapsed time.Duration, timestamp int64) {
+				clocks.mono.Store(int64(elapsed))
+				h.HandleMessage(client, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "timestamp": %d}`, models.MessageTypeTimeResponse, requestID, timestamp)))
+			}
+			respond(watch, 2*time.Millisecond, 1_000_050)
+			respond(psg, 6*time.Millisecond, 1_000_000)
+
+			var record *models.TimeSyncRecord
+			select {
+			case record = <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("RequestTimeSync() did not return")
+			}
+			if record.Device1ID != tt.expectedDevice1 || record.TimeDifference == nil || *record.TimeDifference != tt.expectedDifference {
+				t.Errorf("Device1ID, TimeDifference = %s, %v, expected %s, %d", record.Device1ID, ptrValue(record.TimeDifference), tt.expectedDevice1, tt.expectedDifference)
+			}
+			// The RTTs follow the devices, so the NTP selector compensates in the same orientation
+			if record.Device1RTT == nil || *record.Device1RTT != tt.expectedDevice1RTT {
+				t.Errorf("Device1RTT = %v, expected %d", ptrValue(record.Device1RTT), tt.expectedDevice1RTT)
+			}
+			if device1, device2 := h.OrientPairing(psg.DeviceID, watch.DeviceID); device1 != record.Device1ID || device2 != record.Device2ID {
+				t.Errorf("OrientPairing() = %s, %s, expected the record's order %s, %s", device1, device2, record.Device1ID, record.Device2ID)
+			}
+		})
+	}
+}
+
+func TestCreateGroupPairingDefaultsToReferenceDeviceType(t *testing.T) {
+	h := NewHub()
+	h.SetReferenceDeviceType(models.DeviceTypePSG)
+	for id, deviceType := range map[string]models.DeviceType{"watch-001": models.DeviceTypeWatch, "psg-001": models.DeviceTypePSG, "mobile-001": models.DeviceTypeMobile} {
+		h.Clients[id] = &Client{Hub: h, DeviceID: id, DeviceType: deviceType}
+	}
+
+	group, err := h.CreateGroupPairing([]string{"watch-001", "psg-001", "mobile-001"}, "")
+	if err != nil {
+		t.Fatalf("CreateGroupPairing() error = %v", err)
+	}
+	if group.ReferenceDeviceID != "psg-001" {
+		t.Errorf("ReferenceDeviceID = %s, expected the PSG", group.ReferenceDeviceID)
+	}
+
+	group, err = h.CreateGroupPairing([]string{"watch-001", "psg-001"}, "watch-001")
+	if err != nil {
+		t.Fatalf("CreateGroupPairing() error = %v", err)
+	}
+	if group.ReferenceDeviceID != "watch-001" {
+		t.Errorf("ReferenceDeviceID = %s, expected the explicit reference to win", group.ReferenceDeviceID)
+	}
+}
+
+func TestDeviceHealthThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		expected  time.Duration
+	}{
+		{"default", 0, DefaultHealthThreshold},
+		{"configured", 30 * time.Second, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub()
+			h.SetHealthThreshold(tt.threshold)
+			now := time.Now()
+			h.Clients["watch-001"] = &Client{Hub: h, DeviceID: "watch-001", LastPongRecv: now.Add(-tt.expected + time.Second)}
+			h.Clients["psg-001"] = &Client{Hub: h, DeviceID: "psg-001", LastPongRecv: now.Add(-tt.expected - time.Second)}
+
+			if health, err := h.GetDeviceHealthByID("watch-001"); err != nil || !health.IsHealthy {
+				t.Errorf("device silent for just under %s: health = %+v, %v, expected healthy", tt.expected, health, err)
+			}
+			if health, err := h.GetDeviceHealthByID("psg-001"); err != nil || health.IsHealthy {
+				t.Errorf("device silent for just over %s: health = %+v, %v, expected unhealthy", tt.expected, health, err)
+			}
+			for _, health := range h.GetDeviceHealth() {
+				if health.IsHealthy != (health.DeviceID == "watch-001") {
+					t.Errorf("GetDeviceHealth() reports %s IsHealthy = %v", health.DeviceID, health.IsHealthy)
+				}
+			}
+		})
+	}
+}
+
+// awaitPairing waits until pair-1 is present in (or gone from) the hub
+func awaitPairing(t *testing.T, h *Hub, present bool) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		h.mu.RLock()
+		_, ok := h.Pairings["pair-1"]
+		h.mu.RUnlock()
+		if ok == present {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("pair-1 present = %v, expected %v", ok, present)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// awaitConnected waits until the device is registered with (or unregistered from) the hub
+func awaitConnected(t *testing.T, h *Hub, deviceID string, connected bool) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for h.IsDeviceConnected(deviceID) != connected {
+		select {
+		case <-deadline:
+			t.Fatalf("IsDeviceConnected(%s) = %v, expected %v", deviceID, !connected, connected)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestReconnectGracePeriod(t *testing.T) {
+	const grace = 200 * time.Millisecond
+	h := NewHub()
+	h.SetReconnectGracePeriod(grace)
+	go h.Run()
+	connect := func(deviceID string) *Client {
+		client := &Client{Hub: h, DeviceID: deviceID, DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 16)}
+		h.Register <- client
+		awaitConnected(t, h, deviceID, true)
+		// Wait for the end of registration so the restore does not race the disconnect
+		var restored models.PairingsRestoredMessage
+		for restored.Type != models.MessageTypePairingsRestored {
+			select {
+			case data := <-client.Send:
+				json.Unmarshal(data, &restored)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("no PAIRINGS_RESTORED sent to %s", deviceID)
+			}
+		}
+		return client
+	}
+	disconnect := func(client *Client) {
+		h.Unregister <- client
+		awaitConnected(t, h, client.DeviceID, false)
+	}
+
+	connect("psg-001")
+	watch := connect("watch-001")
+	h.mu.Lock()
+	h.Pairings["pair-1"] = &models.Pairing{PairingID: "pair-1", Device1ID: "psg-001", Device2ID: "watch-001"}
+	h.mu.Unlock()
+
+	// While suspended the pairing is kept, but syncs report the device as temporarily away
+	disconnect(watch)
+	var away *DeviceTemporarilyDisconnectedError
+	if _, err := h.RequestTimeSync("pair-1", time.Second, ""); !errors.As(err, &away) {
+		t.Errorf("RequestTimeSync() error = %v, expected *DeviceTemporarilyDisconnectedError", err)
+	}
+
+	// Reconnecting inside the window cancels the purge
+	watch = connect("watch-001")
+	time.Sleep(2 * grace)
+	awaitPairing(t, h, true)
+
+	// Staying away past the window removes the pairing
+	disconnect(watch)
+	start := time.Now()
+	awaitPairing(t, h, false)
+	if elapsed := time.Since(start); elapsed < grace/2 {
+		t.Errorf("pairing removed after %s, expected it to be kept for the %s grace period", elapsed, grace)
+	}
+	h.mu.RLock()
+	suspended := len(h.suspendedDevices)
+	h.mu.RUnlock()
+	if suspended != 0 {
+		t.Errorf("suspendedDevices = %d, expected the expired suspension to be removed", suspended)
+	}
+}
+
+// restoringOperator restores pair-1 once both its devices are connected, slowly like a database lookup
+type restoringOperator struct {
+	hub *Hub
+}
+
+func (op *restoringOperator) OnDeviceConnected(string) {
+	time.Sleep(50 * time.Millisecond)
+	op.hub.RestorePairing(&models.Pairing{PairingID: "pair-1", Device1ID: "psg-001", Device2ID: "watch-001"})
+}
+
+func (op *restoringOperator) CreatePairing(device1ID, device2ID string) (*models.Pairing, error) {
+	return op.hub.CreatePairing(device1ID, device2ID)
+}
+
+// awaitRegistrationMessages returns the types of the messages queued for client up to and
+// including PAIRINGS_RESTORED, and that message
+func awaitRegistrationMessages(t *testing.T, client *Client) ([]models.MessageType, models.PairingsRestoredMessage) {
+	t.Helper()
+	var types []models.MessageType
+	for {
+		select {
+		case data := <-client.Send:
+			var restored models.PairingsRestoredMessage
+			if err := json.Unmarshal(data, &restored); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", data, err)
+			}
+			types = append(types, restored.Type)
+			if restored.Type == models.MessageTypePairingsRestored {
+				return types, restored
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no PAIRINGS_RESTORED sent to %s after %v", client.DeviceID, types)
+		}
+	}
+}
+
+func TestPairingsRestoredAfterRestoration(t *testing.T) {
+	h := NewHub()
+	h.SetPairingOperator(&restoringOperator{hub: h})
+	go h.Run()
+
+	psg := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 16)}
+	h.Register <- psg
+	if _, restored := awaitRegistrationMessages(t, psg); len(restored.PairingIDs) != 0 {
+		t.Errorf("psg-001 PairingIDs = %v, expected none before its partner connects", restored.PairingIDs)
+	}
+
+	watch := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 16)}
+	h.Register <- watch
+	types, restored := awaitRegistrationMessages(t, watch)
+	if len(types) != 2 || types[0] != models.MessageTypeConnected {
+		t.Errorf("messages = %v, expected CONNECTED followed by PAIRINGS_RESTORED", types)
+	}
+
+	// By the time the device hears about it, the pairing is in the hub and can be synced
+	if len(restored.PairingIDs) != 1 || restored.PairingIDs[0] != "pair-1" {
+		t.Errorf("PairingIDs = %v, expected [pair-1]", restored.PairingIDs)
+	}
+	if !h.IsPairingRestored("pair-1") {
+		t.Error("pair-1 not in the hub when PAIRINGS_RESTORED was sent")
+	}
+	var notFound *PairingNotFoundError
+	if _, err := h.RequestTimeSync("pair-1", 10*time.Millisecond, ""); errors.As(err, &notFound) {
+		t.Errorf("RequestTimeSync() error = %v right after PAIRINGS_RESTORED", err)
+	}
+}
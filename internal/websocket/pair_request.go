@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"time-sync-server/internal/models"
+)
+
+// Time the target device has to answer a PAIR_REQUEST with PAIR_CONFIRM
+const pairConfirmTimeout = 30 * time.Second
+
+// PendingPairRequest is a device-initiated pairing request awaiting confirmation from the target
+type PendingPairRequest struct {
+	RequestID    string
+	RequesterID  string
+	TargetID     string
+	CreatedAt    time.Time
+	TimeoutTimer *time.Timer
+}
+
+// handlePairRequest handles a PAIR_REQUEST sent by a device that wants to pair with another device.
+// If the target does not need to confirm, the pairing is created immediately.
+// Otherwise the request is forwarded to the target and completed by handlePairConfirm.
+func (h *Hub) handlePairRequest(client *Client, req *models.PairRequestMessage) {
+	targetID := req.TargetDeviceID
+
+	if targetID == "" || targetID == client.DeviceID {
+		h.sendPairResult(client, &models.PairResultMessage{
+			Type:      models.MessageTypePairResult,
+			RequestID: req.RequestID,
+			Status:    models.PairResultFailed,
+			Device1ID: client.DeviceID,
+			Device2ID: targetID,
+			Error:     "invalid target device",
+		})
+		return
+	}
+
+	h.mu.Lock()
+	target, ok := h.Clients[targetID]
+	if !ok {
+		h.mu.Unlock()
+		h.sendPairResult(client, &models.PairResultMessage{
+			Type:      models.MessageTypePairResult,
+			RequestID: req.RequestID,
+			Status:    models.PairResultFailed,
+			Device1ID: client.DeviceID,
+			Device2ID: targetID,
+			Error:     (&DeviceNotConnectedError{DeviceID: targetID}).Error(),
+		})
+		return
+	}
+
+	if !req.RequireConfirm {
+		h.mu.Unlock()
+		// Creating the pairing hits the database, so keep it off the device's read loop
+		go h.completePairRequest(req.RequestID, client.DeviceID, targetID)
+		return
+	}
+
+	requestID := uuid.New().String()
+	pending := &PendingPairRequest{
+		RequestID:   requestID,
+		RequesterID: client.DeviceID,
+		TargetID:    targetID,
+		CreatedAt:   time.Now(),
+	}
+	pending.TimeoutTimer = time.AfterFunc(pairConfirmTimeout, func() {
+		h.handlePairTimeout(requestID)
+	})
+	h.PendingPairRequests[requestID] = pending
+	h.mu.Unlock()
+
+	log.Printf("Pair request %s: %s -> %s (awaiting confirmation)", requestID, client.DeviceID, targetID)
+
+	// Forward the request to the target device
+	forward := models.PairRequestMessage{
+		Type:           models.MessageTypePairRequest,
+		RequestID:      requestID,
+		TargetDeviceID: targetID,
+		RequesterID:    client.DeviceID,
+		RequireConfirm: true,
+	}
+	if err := target.SendMessage(forward); err != nil {
+		log.Printf("Failed to forward pair request to device %s: %v", targetID, err)
+	}
+}
+
+// handlePairConfirm handles the target device's answer to a forwarded PAIR_REQUEST
+func (h *Hub) handlePairConfirm(client *Client, confirm *models.PairConfirmMessage) {
+	h.mu.Lock()
+	pending, ok := h.PendingPairRequests[confirm.RequestID]
+	if !ok {
+		h.mu.Unlock()
+		log.Printf("No pending pair request found for requestID: %s", confirm.RequestID)
+		return
+	}
+	if pending.TargetID != client.DeviceID {
+		h.mu.Unlock()
+		log.Printf("Pair confirmation from unexpected device: %s", client.DeviceID)
+		return
+	}
+
+	pending.TimeoutTimer.Stop()
+	delete(h.PendingPairRequests, confirm.RequestID)
+	requester := h.Clients[pending.RequesterID]
+	h.mu.Unlock()
+
+	if !confirm.Accepted {
+		log.Printf("Pair request %s rejected by %s", pending.RequestID, client.DeviceID)
+		if requester != nil {
+			h.sendPairResult(requester, &models.PairResultMessage{
+				Type:      models.MessageTypePairResult,
+				RequestID: pending.RequestID,
+				Status:    models.PairResultRejected,
+				Device1ID: pending.RequesterID,
+				Device2ID: pending.TargetID,
+			})
+		}
+		return
+	}
+
+	go h.completePairRequest(pending.RequestID, pending.RequesterID, pending.TargetID)
+}
+
+// handlePairTimeout fails a pairing request the target did not answer in time
+func (h *Hub) handlePairTimeout(requestID string) {
+	h.mu.Lock()
+	pending, ok := h.PendingPairRequests[requestID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.PendingPairRequests, requestID)
+	requester := h.Clients[pending.RequesterID]
+	h.mu.Unlock()
+
+	log.Printf("Pair request timeout: %s (%s -> %s)", requestID, pending.RequesterID, pending.TargetID)
+
+	if requester != nil {
+		h.sendPairResult(requester, &models.PairResultMessage{
+			Type:      models.MessageTypePairResult,
+			RequestID: requestID,
+			Status:    models.PairResultTimeout,
+			Device1ID: pending.RequesterID,
+			Device2ID: pending.TargetID,
+			Error:     "target device did not confirm in time",
+		})
+	}
+}
+
+// completePairRequest creates the pairing through the pairing operator, which persists it and
+// returns the saved pairing if the devices were already paired, then notifies both devices
+func (h *Hub) completePairRequest(requestID, requesterID, targetID string) {
+	result := &models.PairResultMessage{
+		Type:      models.MessageTypePairResult,
+		RequestID: requestID,
+		Device1ID: requesterID,
+		Device2ID: targetID,
+	}
+
+	create := h.CreatePairing
+	if operator := h.pairingOperator; operator != nil {
+		create = operator.CreatePairing
+	}
+
+	pairing, err := create(requesterID, targetID)
+	if err != nil {
+		result.Status = models.PairResultFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = models.PairResultAccepted
+		result.PairingID = pairing.PairingID
+	}
+
+	h.mu.RLock()
+	requester := h.Clients[requesterID]
+	target := h.Clients[targetID]
+	h.mu.RUnlock()
+
+	if requester != nil {
+		h.sendPairResult(requester, result)
+	}
+	if target != nil && result.Status == models.PairResultAccepted {
+		h.sendPairResult(target, result)
+	}
+}
+
+// sendPairResult sends a PAIR_RESULT message to a device
+func (h *Hub) sendPairResult(client *Client, result *models.PairResultMessage) {
+	if err := client.SendMessage(result); err != nil {
+		log.Printf("Failed to send PAIR_RESULT to device %s: %v", client.DeviceID, err)
+	}
+}
@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"time-sync-server/internal/models"
+)
+
+// recordingOperator creates pairings in the hub and records them
+type recordingOperator struct {
+	hub     *Hub
+	created chan *models.Pairing
+}
+
+func (op *recordingOperator) OnDeviceConnected(string) {}
+
+func (op *recordingOperator) CreatePairing(device1ID, device2ID string) (*models.Pairing, error) {
+	pairing, err := op.hub.CreatePairing(device1ID, device2ID)
+	if err == nil {
+		op.created <- pairing
+	}
+	return pairing, err
+}
+
+// newPairRequestHub registers a connected requester (psg-001) and target (watch-001)
+func newPairRequestHub() (*Hub, *recordingOperator, *Client, *Client) {
+	h := NewHub()
+	operator := &recordingOperator{hub: h, created: make(chan *models.Pairing, 1)}
+	h.SetPairingOperator(operator)
+	requester := &Client{Hub: h, DeviceID: "psg-001", DeviceType: models.DeviceTypePSG, Send: make(chan []byte, 4)}
+	target := &Client{Hub: h, DeviceID: "watch-001", DeviceType: models.DeviceTypeWatch, Send: make(chan []byte, 4)}
+	h.Clients[requester.DeviceID] = requester
+	h.Clients[target.DeviceID] = target
+	return h, operator, requester, target
+}
+
+// awaitPairMessage reads the next message queued for client into msg, failing unless it is of
+// the expected type
+func awaitPairMessage(t *testing.T, client *Client, msgType models.MessageType, msg interface{}) {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var envelope struct {
+			Type models.MessageType `json:"type"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil || envelope.Type != msgType {
+			t.Fatalf("queued message %s, expected a %s", data, msgType)
+		}
+		if err := json.Unmarshal(data, msg); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", data, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no %s sent to %s", msgType, client.DeviceID)
+	}
+}
+
+// forwardPairRequest sends a PAIR_REQUEST that needs confirmation and returns the request
+// the target received
+func forwardPairRequest(t *testing.T, h *Hub, requester, target *Client) models.PairRequestMessage {
+	t.Helper()
+	h.HandleMessage(requester, []byte(fmt.Sprintf(`{"type": %q, "targetDeviceId": %q, "requireConfirm": true}`,
+		models.MessageTypePairRequest, target.DeviceID)))
+
+	var forwarded models.PairRequestMessage
+	awaitPairMessage(t, target, models.MessageTypePairRequest, &forwarded)
+	if forwarded.RequestID == "" || forwarded.RequesterID != requester.DeviceID {
+		t.Fatalf("forwarded request = %+v, expected a request ID and requester %s", forwarded, requester.DeviceID)
+	}
+	return forwarded
+}
+
+func TestPairRequestAccepted(t *testing.T) {
+	h, operator, requester, target := newPairRequestHub()
+	forwarded := forwardPairRequest(t, h, requester, target)
+
+	h.HandleMessage(target, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "accepted": true}`,
+		models.MessageTypePairConfirm, forwarded.RequestID)))
+
+	// Both devices are told about the new pairing
+	for _, client := range []*Client{requester, target} {
+		var result models.PairResultMessage
+		awaitPairMessage(t, client, models.MessageTypePairResult, &result)
+		if result.Status != models.PairResultAccepted || result.PairingID == "" || result.RequestID != forwarded.RequestID {
+			t.Errorf("%s result = %+v, expected ACCEPTED with the pairing ID", client.DeviceID, result)
+		}
+	}
+
+	select {
+	case pairing := <-operator.created:
+		if pairing.Device1ID != "psg-001" || pairing.Device2ID != "watch-001" {
+			t.Errorf("created pairing %s <-> %s, expected the requester first", pairing.Device1ID, pairing.Device2ID)
+		}
+		if pairings := h.GetPairings(); len(pairings) != 1 || pairings[0].PairingID != pairing.PairingID {
+			t.Errorf("GetPairings() = %v, expected only %s", pairings, pairing.PairingID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("CreatePairing() not called")
+	}
+	if len(h.PendingPairRequests) != 0 {
+		t.Errorf("PendingPairRequests = %d, expected the request to be removed", len(h.PendingPairRequests))
+	}
+}
+
+func TestPairRequestRejected(t *testing.T) {
+	h, operator, requester, target := newPairRequestHub()
+	forwarded := forwardPairRequest(t, h, requester, target)
+
+	h.HandleMessage(target, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "accepted": false}`,
+		models.MessageTypePairConfirm, forwarded.RequestID)))
+
+	var result models.PairResultMessage
+	awaitPairMessage(t, requester, models.MessageTypePairResult, &result)
+	if result.Status != models.PairResultRejected || result.PairingID != "" {
+		t.Errorf("result = %+v, expected REJECTED without a pairing", result)
+	}
+
+	// Only the requester is told, and no pairing is created
+	if len(target.Send) != 0 {
+		t.Errorf("target has %d queued messages, expected none", len(target.Send))
+	}
+	if pairings := h.GetPairings(); len(pairings) != 0 {
+		t.Errorf("GetPairings() = %d, expected no pairing", len(pairings))
+	}
+	select {
+	case pairing := <-operator.created:
+		t.Errorf("CreatePairing() created %s for a rejected request", pairing.PairingID)
+	default:
+	}
+}
+
+func TestPairRequestTimeout(t *testing.T) {
+	h, _, requester, target := newPairRequestHub()
+	forwarded := forwardPairRequest(t, h, requester, target)
+
+	// Fire the confirmation timeout instead of waiting for it
+	h.mu.RLock()
+	pending := h.PendingPairRequests[forwarded.RequestID]
+	h.mu.RUnlock()
+	if pending == nil {
+		t.Fatalf("no pending pair request %s", forwarded.RequestID)
+	}
+	pending.TimeoutTimer.Stop()
+	h.handlePairTimeout(forwarded.RequestID)
+
+	var result models.PairResultMessage
+	awaitPairMessage(t, requester, models.MessageTypePairResult, &result)
+	if result.Status != models.PairResultTimeout || result.Error == "" {
+		t.Errorf("result = %+v, expected TIMEOUT with an error", result)
+	}
+
+	// A late confirmation is ignored
+	h.HandleMessage(target, []byte(fmt.Sprintf(`{"type": %q, "requestId": %q, "accepted": true}`,
+		models.MessageTypePairConfirm, forwarded.RequestID)))
+	if pairings := h.GetPairings(); len(pairings) != 0 {
+		t.Errorf("GetPairings() = %d, expected the late confirmation to be ignored", len(pairings))
+	}
+	if len(requester.Send) != 0 {
+		t.Errorf("requester has %d queued messages, expected only the timeout result", len(requester.Send))
+	}
+}
+
+// savedPairingOperator answers every pairing with the saved pair-123, restored in the hub
+type savedPairingOperator struct {
+	hub *Hub
+}
+
+func (op *savedPairingOperator) OnDeviceConnected(string) {}
+
+func (op *savedPairingOperator) CreatePairing(device1ID, device2ID string) (*models.Pairing, error) {
+	pairing := &models.Pairing{PairingID: "pair-123", Device1ID: device2ID, Device2ID: device1ID}
+	return pairing, op.hub.RestorePairing(pairing)
+}
+
+func TestPairRequestResultCarriesSavedPairingID(t *testing.T) {
+	h, _, requester, target := newPairRequestHub()
+	h.SetPairingOperator(&savedPairingOperator{hub: h})
+
+	h.HandleMessage(requester, []byte(fmt.Sprintf(`{"type": %q, "targetDeviceId": %q}`,
+		models.MessageTypePairRequest, target.DeviceID)))
+
+	for _, client := range []*Client{requester, target} {
+		var result models.PairResultMessage
+		awaitPairMessage(t, client, models.MessageTypePairResult, &result)
+		if result.Status != models.PairResultAccepted || result.PairingID != "pair-123" {
+			t.Errorf("%s result = %+v, expected ACCEPTED with the saved pair-123", client.DeviceID, result)
+		}
+	}
+	if pairings := h.GetPairings(); len(pairings) != 1 || pairings[0].PairingID != "pair-123" {
+		t.Errorf("GetPairings() = %v, expected only pair-123", pairings)
+	}
+}
@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"time-sync-server/internal/models"
+)
+
+// Time a device has to answer an RTT_PROBE
+const rttProbeTimeout = 5 * time.Second
+
+// PendingProbe is an RTT_PROBE awaiting its RTT_PROBE_ACK
+type PendingProbe struct {
+	ProbeID  string
+	DeviceID string
+	SendTime int64      // Probe send time (microseconds on the hub's monotonic clock)
+	AckChan  chan int64 // Receives the ack receive time (microseconds on the hub's monotonic clock)
+}
+
+// ProbeRTTTimeoutError is returned when a device does not acknowledge an RTT probe in time
+type ProbeRTTTimeoutError struct {
+	DeviceID string
+}
+
+func (e *ProbeRTTTimeoutError) Error() string {
+	return fmt.Sprintf("device %s did not acknowledge RTT probe within %v", e.DeviceID, rttProbeTimeout)
+}
+
+// ProbeRTT sends an RTT_PROBE to the device and waits for the ack.
+// Returns the round trip time in microseconds. Unlike the app-level PING/PONG,
+// probes do not affect connection liveness tracking.
+func (h *Hub) ProbeRTT(deviceID string) (int64, error) {
+	h.mu.RLock()
+	client, ok := h.Clients[deviceID]
+	h.mu.RUnlock()
+	if !ok {
+		return 0, &DeviceNotConnectedError{DeviceID: deviceID}
+	}
+
+	probe := &PendingProbe{
+		ProbeID:  uuid.New().String(),
+		DeviceID: deviceID,
+		AckChan:  make(chan int64, 1),
+	}
+
+	h.mu.Lock()
+	h.PendingProbes[probe.ProbeID] = probe
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.PendingProbes, probe.ProbeID)
+		h.mu.Unlock()
+	}()
+
+	// RTT START: Record send time
+	probe.SendTime = h.monotonic().Microseconds()
+	msg := models.RTTProbeMessage{
+		Type:      models.MessageTypeRTTProbe,
+		ProbeID:   probe.ProbeID,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := client.SendMessage(msg); err != nil {
+		return 0, fmt.Errorf("failed to send RTT probe to device %s: %w", deviceID, err)
+	}
+
+	select {
+	case receiveTime := <-probe.AckChan:
+		return receiveTime - probe.SendTime, nil
+	case <-time.After(rttProbeTimeout):
+		return 0, &ProbeRTTTimeoutError{DeviceID: deviceID}
+	}
+}
+
+// handleRTTProbeAck completes the pending probe the ack belongs to
+func (h *Hub) handleRTTProbeAck(client *Client, ack *models.RTTProbeAckMessage) {
+	// RTT END: Record receive time before acquiring lock
+	receiveTime := h.monotonic().Microseconds()
+
+	h.mu.RLock()
+	probe, ok := h.PendingProbes[ack.ProbeID]
+	h.mu.RUnlock()
+
+	if !ok {
+		log.Printf("No pending RTT probe found for probeID: %s", ack.ProbeID)
+		return
+	}
+	if probe.DeviceID != client.DeviceID {
+		log.Printf("RTT probe ack from unexpected device: %s", client.DeviceID)
+		return
+	}
+
+	select {
+	case probe.AckChan <- receiveTime:
+	default:
+	}
+}